@@ -21,6 +21,7 @@ var (
 	_ = pflag.Int("health.port", 0, "port of health http server")
 	_ = pflag.String("health.liveness", "/healthz", "endpoint for liveness checks")
 	_ = pflag.String("health.readiness", "/ready", "endpoint for readiness checks")
+	_ = pflag.String("health.metrics", "/metrics", "endpoint for prometheus metrics")
 
 	// Jaeger
 	_ = pflag.String("jaeger.service-name", "gomsg_boat", "")
@@ -44,6 +45,37 @@ var (
 
 	// gRPC servers
 	_ = pflag.String("station.name", "gomsg://station", "name of station server")
+
+	// redis
+	_ = pflag.String("redis.address", "127.0.0.1", "")
+	_ = pflag.Int("redis.port", 9379, "")
+	_ = pflag.Bool("redis.cluster", false, "connect to a redis cluster with redis.cluster-addrs, redis.address and redis.port are ignored")
+	_ = pflag.StringSlice("redis.cluster-addrs", []string{"127.0.0.1:7000"}, "some nodes of the redis cluster, the others are discovered")
+
+	// offline, must be the same storage as carrier
+	_ = pflag.String("offline.driver", "redis", "storage of offline messages, redis or sql, bolt can only be opened by one process")
+	_ = pflag.String("offline.sql.dialect", "postgres", "postgres, mysql or sqlite3")
+	_ = pflag.String("offline.sql.dsn", "", "data source name of offline.sql.dialect")
+	_ = pflag.String("offline.bolt.path", "gomsg-offline.db", "file of the embedded offline storage, can only be opened by one process")
+
+	// audit, must be the same as station and carrier
+	_ = pflag.Bool("audit.enabled", false, "record the acks of clients for the audit query of station")
+	_ = pflag.Duration("audit.retention", 72*time.Hour, "audit trail of a message is kept for this long after its last event")
+	_ = pflag.Int("audit.max-events", 100, "max events kept for each message")
+
+	// lease
+	_ = pflag.Duration("lease.interval", 5*time.Second, "interval of renewing the lease of this boat")
+	_ = pflag.Duration("lease.ttl", 30*time.Second, "sessions of this boat are reaped by station if the lease is not renewed within this")
+
+	// client requests in loop
+	_ = pflag.Duration("client.request-timeout", 10*time.Second, "timeout of each read or sync request of a client")
+	_ = pflag.Int("client.max-pending-requests", 4, "max read or sync requests of a session in progress, the others are rejected")
+	_ = pflag.StringSlice("client.trusted-proxies", []string{}, "ips or cidrs of proxies whose x-forwarded-for is honoured, empty means always the peer address")
+
+	// sync
+	_ = pflag.Duration("sync.expire", 2160*time.Hour, "only offline messages sent within this are synced, usually the same as offline.expire of carrier")
+	_ = pflag.Int64("sync.default-limit", 50, "page size of a sync request without limit")
+	_ = pflag.Int64("sync.max-limit", 200, "max page size of a sync request")
 )
 
 func init() {
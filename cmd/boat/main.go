@@ -174,8 +174,26 @@ func main() {
 	stationCli, stationConn := NewStationClient(ctx, logger, etcdCli)
 	defer stationConn.Close()
 
+	// 初始化离线消息存储，用于客户端分页拉取
+	redisPool := resource.NewRedisPool(logger)
+	defer redisPool.Close()
+
+	offstore, offstoreCloser := resource.NewOfflineStore(ctx, logger, redisPool)
+	defer offstoreCloser.Close()
+
+	// 消息审计轨迹，记录客户端的ack，未开启时为nil
+	auditStore := resource.NewAuditStore(logger, redisPool)
+	defer auditStore.Close()
+
 	// 初始化boat
-	boat.Init(applicationId, logger, stationCli)
+	cfg := boat.Config{}
+	if err := viper.Unmarshal(&cfg); err != nil {
+		logger.Fatalln("Unmarshal viper to config failed:", err)
+	}
+	if err := boat.Init(cfg, applicationId, logger, stationCli, offstore, auditStore); err != nil {
+		logger.Fatalln("Init boat failed:", err)
+	}
+	defer boat.Stop()
 
 	// 启动server
 	sigC := make(chan os.Signal, 1)
@@ -12,6 +12,8 @@ import (
 var (
 	// Config
 	_ = pflag.String("config.file", "", "path of the configuration file")
+	_ = pflag.String("config.etcd-key", "", "etcd key of the hot-reloadable configuration, empty means disabled")
+	_ = pflag.String("config.etcd-format", "yaml", "format of the value at config.etcd-key")
 
 	// Logging
 	_ = pflag.String("logging.level", "debug", "log level of application")
@@ -21,17 +23,22 @@ var (
 	_ = pflag.Int("health.port", 0, "port of health http server")
 	_ = pflag.String("health.liveness", "/healthz", "endpoint for liveness checks")
 	_ = pflag.String("health.readiness", "/ready", "endpoint for readiness checks")
+	_ = pflag.String("health.metrics", "/metrics", "endpoint for prometheus metrics")
 
 	// etcd
 	_ = pflag.StringSlice("etcd.endpoints", []string{"http://127.0.0.1:8379"}, "")
 	_ = pflag.Duration("etcd.dial-timeout", 5*time.Second, "")
 
+	// registry
+	_ = pflag.Int("registry.ttl", 10, "ttl of the consumer.priority-topic registration checked by station")
+
 	// Jaeger
 	_ = pflag.String("jaeger.service-name", "gomsg_carrier", "")
 	_ = pflag.String("jaeger.collector-endpoint", "http://localhost:24268", "endpoint of Jaeger collector")
 
 	// platform
-	_                            = pflag.StringSlice("platform.names", []string{"mobile", "desktop"}, "")
+	// 更细的平台配置(ack-wait/offline-expire/disable-offline/notification-provider/max-retries)需通过配置文件的 platform.configs 设置
+	_                            = pflag.StringSlice("platform.names", []string{"mobile", "desktop"}, "ignored if platform.configs is set")
 	flagPlatformMaxOfflineCounts = pflag.StringToInt("platform.max-offline-counts", map[string]int{
 		"mobile":  -1,
 		"desktop": 80,
@@ -39,9 +46,39 @@ var (
 
 	_ = pflag.Duration("offline.expire", 2160*time.Hour, "90 days")
 	_ = pflag.Int64("offline.batch-count", 80, "")
+	_ = pflag.String("offline.driver", "redis", "storage of offline messages, redis, sql or bolt")
+	_ = pflag.String("offline.sql.dialect", "postgres", "postgres, mysql or sqlite3")
+	_ = pflag.String("offline.sql.dsn", "", "data source name of offline.sql.dialect")
+	_ = pflag.String("offline.bolt.path", "gomsg-offline.db", "file of the embedded offline storage, can only be opened by one process")
+
+	// audit
+	_ = pflag.Bool("audit.enabled", false, "record the lifecycle events of each message for the audit query of station")
+	_ = pflag.Duration("audit.retention", 72*time.Hour, "audit trail of a message is kept for this long after its last event")
+	_ = pflag.Int("audit.max-events", 100, "max events kept for each message")
+
+	// notification
+	_ = pflag.String("notification.topic", "molon-msg-notification", "")
+
+	// receipt
+	_ = pflag.String("receipt.topic", "molon-msg-receipt", "topic of delivery receipts, empty means disabled")
 
-	// kafka and consumer
+	// mq
+	_ = pflag.String("mq.driver", "kafka", "kafka, redis(streams) or nats(jetstream), ordered delivery and batch-window of carrier require kafka")
 	_ = pflag.StringSlice("kafka.brokers", []string{"127.0.0.1:9092"}, "")
+	_ = pflag.String("kafka.version", "1.0.0", "kafka version of brokers, consumer groups require >= 0.10.2")
+	_ = pflag.String("kafka.balance-strategy", "range", "partition balance strategy of consumer groups, range or roundrobin, cooperative-sticky is rejected as sarama v1.21 does not support cooperative rebalancing")
+	_ = pflag.Duration("kafka.drain-timeout", 10*time.Second, "max time waiting for in-flight messages to be acked before partitions are released")
+	_ = pflag.Duration("kafka.ack-timeout", 5*time.Minute, "messages not acked within this are reported as stuck (not redelivered), 0 means never")
+	_ = pflag.Duration("kafka.lag-interval", time.Minute, "interval of updating the consumer lag gauge and logging it, 0 means disabled")
+	_ = pflag.String("mq.redis.address", "127.0.0.1", "")
+	_ = pflag.Int("mq.redis.port", 9379, "")
+	_ = pflag.Int64("mq.redis.max-len", 1000000, "approximate max length of each stream")
+	_ = pflag.Duration("mq.redis.claim-idle", time.Minute, "unacked messages idle longer than this are redelivered, messages being handled are renewed periodically")
+	_ = pflag.String("mq.nats.url", "nats://127.0.0.1:4222", "")
+	_ = pflag.Duration("mq.nats.ack-wait", time.Minute, "unacked messages are redelivered after this")
+	_ = pflag.Duration("mq.nats.max-age", 72*time.Hour, "max age of messages in each stream")
+
+	// consumer
 	_ = pflag.String("consumer.group", "molon-msg-group", "")
 	_ = pflag.Int("consumer.concurrency", 100, "") // 消费topic的协程数
 	_ = pflag.String("consumer.topic", "molon-msg", "")
@@ -50,14 +87,24 @@ var (
 	_ = pflag.Duration("consumer.retry-delay", 10*time.Second, "")
 	_ = pflag.Int64("consumer.max-retries", 6, "")
 	_ = pflag.String("consumer.dlq-topic", "molon-msg-dlq", "dead letter queue")
+	_ = pflag.Duration("consumer.batch-window", 0, "coalesce ToUid payloads of the same uid within this window, 0 means disabled")
+	_ = pflag.Int("consumer.batch-max-payloads", 32, "max payloads coalesced into one batch")
+	_ = pflag.Bool("consumer.ordered", false, "per-uid ordered delivery, requires producer.ordered of station")
+	_ = pflag.Duration("consumer.ordered-block-ttl", 10*time.Minute, "max time later payloads of a uid wait for an earlier one in retry")
+	_ = pflag.String("consumer.priority-topic", "", "topic of high priority payloads, should be the same as producer.priority-topic of station, empty means disabled")
+	_ = pflag.Int("consumer.priority-concurrency", 20, "") // 消费priority topic的协程数
 
 	// redis
 	_ = pflag.String("redis.address", "127.0.0.1", "")
 	_ = pflag.Int("redis.port", 9379, "")
+	_ = pflag.Bool("redis.cluster", false, "connect to a redis cluster with redis.cluster-addrs, redis.address and redis.port are ignored")
+	_ = pflag.StringSlice("redis.cluster-addrs", []string{"127.0.0.1:7000"}, "some nodes of the redis cluster, the others are discovered")
 
 	// gRPC servers
 	_ = pflag.String("boat.name-prefix", "gomsg://boat-", "name-prefix of boat server")
 	_ = pflag.Duration("boat.ack-wait", 2*time.Second, "ack-wait from boat server")
+	_ = pflag.Int("boat.push-concurrency", 8, "max concurrent pushes to boat servers for one payload")
+	_ = pflag.Duration("boat.push-timeout", 6*time.Second, "overall deadline of pushes to boat servers for one payload")
 )
 
 func init() {
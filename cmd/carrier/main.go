@@ -16,27 +16,19 @@ import (
 
 	"github.com/molon/gomsg/internal/app/carrier"
 	"github.com/molon/gomsg/internal/pb/boatpb"
+	"github.com/molon/gomsg/internal/pkg/mq"
 	"github.com/molon/gomsg/internal/pkg/resource"
+	"github.com/molon/gomsg/internal/pkg/sessionstore"
 	"github.com/molon/pkg/clientstore"
 	"github.com/molon/pkg/errors"
 	"github.com/molon/pkg/grpc/timeout"
 	"github.com/molon/pkg/server"
 	"github.com/molon/pkg/tracing/otgrpc"
 
-	kafkaclient "github.com/uber-go/kafka-client"
-	"github.com/uber-go/kafka-client/kafka"
-	"github.com/uber-go/tally"
-	"go.uber.org/zap"
-
-	"github.com/Shopify/sarama"
 	etcd "github.com/coreos/etcd/clientv3"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 )
 
-const (
-	kafkaCluserName = "msg_cluster"
-)
-
 var dialOptions = []grpc.DialOption{
 	grpc.WithInsecure(),
 	grpc.WithInitialWindowSize(1 << 24),
@@ -84,103 +76,6 @@ func StartBoatClientStore(ctx context.Context, logger *logrus.Logger, etcdCli *e
 	return cs
 }
 
-func NewKafkaConsumeClient() kafkaclient.Client {
-	brokers := map[string][]string{
-		kafkaCluserName: viper.GetStringSlice("kafka.brokers"),
-	}
-
-	zapLogger := zap.NewNop()
-
-	// sarama.Logger = logger
-	// zapLogger, _ = zap.NewDevelopment()
-
-	client := kafkaclient.New(
-		kafka.NewStaticNameResolver(nil, brokers),
-		zapLogger,
-		tally.NoopScope,
-	)
-	return client
-}
-
-func StartKafkaConsumer(ctx context.Context, logger *logrus.Logger, client kafkaclient.Client) kafka.Consumer {
-	config := kafka.NewConsumerConfig(
-		viper.GetString("consumer.group"),
-		kafka.ConsumerTopicList{
-			kafka.ConsumerTopic{
-				Topic: kafka.Topic{
-					Name:    viper.GetString("consumer.topic"),
-					Cluster: kafkaCluserName,
-				},
-			},
-		},
-	)
-	config.Offsets.Initial.Offset = kafka.OffsetOldest
-	config.Concurrency = viper.GetInt("consumer.concurrency")
-
-	consumer, err := client.NewConsumer(config)
-	if err != nil {
-		logger.Fatalln("Create consumer failed:", err)
-	}
-
-	if err := consumer.Start(); err != nil {
-		logger.Fatalln("Start consumer failed:", err)
-	}
-
-	logger.Infof("Start consume [%s] at kafka brokers %v",
-		viper.GetString("consumer.topic"),
-		viper.GetStringSlice("kafka.brokers"),
-	)
-
-	return consumer
-}
-
-func StartKafkaConsumerForRetryTopic(ctx context.Context, logger *logrus.Logger, client kafkaclient.Client) kafka.Consumer {
-	config := kafka.NewConsumerConfig(
-		viper.GetString("consumer.group"),
-		kafka.ConsumerTopicList{
-			kafka.ConsumerTopic{
-				Topic: kafka.Topic{
-					Name:    viper.GetString("consumer.retry-topic"),
-					Cluster: kafkaCluserName,
-				},
-			},
-		},
-	)
-	config.Offsets.Initial.Offset = kafka.OffsetOldest
-	config.Concurrency = viper.GetInt("consumer.retry-concurrency")
-
-	consumer, err := client.NewConsumer(config)
-	if err != nil {
-		logger.Fatalln("Create consumer failed:", err)
-	}
-
-	if err := consumer.Start(); err != nil {
-		logger.Fatalln("Start consumer failed:", err)
-	}
-
-	logger.Infof("Start consume [%s] at kafka brokers %v",
-		viper.GetString("consumer.retry-topic"),
-		viper.GetStringSlice("kafka.brokers"),
-	)
-
-	return consumer
-}
-
-func NewKafkaProducer(logger *logrus.Logger) sarama.SyncProducer {
-	kc := sarama.NewConfig()
-	kc.Producer.RequiredAcks = sarama.WaitForAll
-	kc.Producer.Retry.Max = 10
-	kc.Producer.Return.Successes = true
-	pub, err := sarama.NewSyncProducer(viper.GetStringSlice("kafka.brokers"), kc)
-	if err != nil {
-		logger.Fatalln("Create kafka producer failed:", err)
-	}
-
-	logger.Infof("Create producer at kafka brokers %v", viper.GetStringSlice("kafka.brokers"))
-
-	return pub
-}
-
 func main() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
@@ -204,31 +99,67 @@ func main() {
 	boatStore := StartBoatClientStore(ctx, logger, etcdCli)
 	defer boatStore.Stop()
 
-	// 初始化kafka生产者
-	producer := NewKafkaProducer(logger)
+	// 初始化mq生产者
+	producer := resource.NewMQProducer(logger)
 	defer producer.Close()
 
-	// 启动kafka consumer
-	kcli := NewKafkaConsumeClient()
-	consumer := StartKafkaConsumer(ctx, logger, kcli)
+	// 启动mq consumer
+	consumer := resource.StartMQConsumer(logger, viper.GetString("consumer.topic"), viper.GetInt("consumer.concurrency"))
 	defer func() {
 		consumer.Stop()
 		<-consumer.Closed()
 	}()
-	retryConsumer := StartKafkaConsumerForRetryTopic(ctx, logger, kcli)
+	retryConsumer := resource.StartMQConsumer(logger, viper.GetString("consumer.retry-topic"), viper.GetInt("consumer.retry-concurrency"))
 	defer func() {
-		consumer.Stop()
-		<-consumer.Closed()
+		retryConsumer.Stop()
+		<-retryConsumer.Closed()
 	}()
+	var priorityConsumer mq.Consumer
+	if topic := viper.GetString("consumer.priority-topic"); topic != "" {
+		priorityConsumer = resource.StartMQConsumer(logger, topic, viper.GetInt("consumer.priority-concurrency"))
+		defer func() {
+			priorityConsumer.Stop()
+			<-priorityConsumer.Closed()
+		}()
+
+		// 供station启动时检查 producer.priority-topic
+		register := resource.RegisterPriorityTopic(ctx, logger, etcdCli, topic, viper.GetInt64("registry.ttl"))
+		defer register.Close()
+	}
 
-	// 开启主程 内部config 可以直接unmarshal进来
+	// 开启主程 内部config 可以直接unmarshal进来，etcd里若有配置则以其覆盖
+	etcdCfg := resource.NewEtcdConfig(ctx, logger, etcdCli)
+	v, err := etcdCfg.Viper()
+	if err != nil {
+		logger.Fatalln("Merge config from etcd failed:", err)
+	}
 	cfg := carrier.Config{}
-	if err := viper.Unmarshal(&cfg); err != nil {
+	if err := v.Unmarshal(&cfg); err != nil {
 		logger.Fatalln("Unmarshal viper to config failed:", err)
 	}
-	carrier.Start(ctx, logger, cfg, boatStore, producer, consumer, retryConsumer, redisPool)
+	// 初始化离线消息存储
+	offstore, offstoreCloser := resource.NewOfflineStore(ctx, logger, redisPool)
+	defer offstoreCloser.Close()
+
+	// 消息审计轨迹，未开启时为nil，Close时写入缓冲里剩余的
+	auditStore := resource.NewAuditStore(logger, redisPool)
+	defer auditStore.Close()
+
+	carrier.Start(ctx, logger, cfg, boatStore, producer, consumer, retryConsumer, priorityConsumer, sessionstore.NewStore(logger, redisPool), offstore, auditStore)
 	defer carrier.Stop()
 
+	// 监听etcd里的配置变更，热更新
+	etcdCfg.Watch(ctx, func(v *viper.Viper) {
+		cfg := carrier.Config{}
+		if err := v.Unmarshal(&cfg); err != nil {
+			logger.Errorf("Unmarshal viper to config failed, keep the old one: %+v", err)
+			return
+		}
+		if err := carrier.Reload(cfg); err != nil {
+			logger.Errorf("Reload carrier config failed, keep the old one: %+v", err)
+		}
+	})
+
 	// 启动服务
 	doneC := make(chan error, 2)
 	sigC := make(chan os.Signal, 1)
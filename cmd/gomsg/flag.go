@@ -0,0 +1,149 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var (
+	// Config
+	_ = pflag.String("config.file", "", "path of the configuration file")
+
+	// Logging
+	_ = pflag.String("logging.level", "debug", "log level of application")
+
+	// Health
+	_ = pflag.String("health.address", "0.0.0.0", "address of health http server")
+	_ = pflag.Int("health.port", 0, "port of health http server")
+	_ = pflag.String("health.liveness", "/healthz", "endpoint for liveness checks")
+	_ = pflag.String("health.readiness", "/ready", "endpoint for readiness checks")
+	_ = pflag.String("health.metrics", "/metrics", "endpoint for prometheus metrics")
+
+	// gRPC of station
+	_ = pflag.String("grpc.address", "127.0.0.1", "adress of gRPC server")
+	_ = pflag.Int("grpc.port", 0, "port of gRPC server")
+
+	// http of station
+	_ = pflag.String("http.address", "0.0.0.0", "adress of http server")
+	_ = pflag.Int("http.port", 8080, "port of http server")
+
+	// gRPC for client
+	_ = pflag.String("loop.address", "0.0.0.0", "adress of loop gRPC server")
+	_ = pflag.Int("loop.port", 9999, "port of loop gRPC server")
+
+	// redis
+	_ = pflag.Bool("redis.embedded", true, "use an embedded redis-compatible storage, redis.address, redis.port and redis.cluster are ignored")
+	_ = pflag.String("redis.address", "127.0.0.1", "")
+	_ = pflag.Int("redis.port", 9379, "")
+	_ = pflag.Bool("redis.cluster", false, "connect to a redis cluster with redis.cluster-addrs, redis.address and redis.port are ignored")
+	_ = pflag.StringSlice("redis.cluster-addrs", []string{"127.0.0.1:7000"}, "some nodes of the redis cluster, the others are discovered")
+
+	// mq，进程内队列不保证同一用户的消息按顺序消费，所以不支持有序投递和合并推送
+	_ = pflag.Duration("mq.redeliver-after", time.Minute, "unacked messages of the in-process queue are redelivered after this")
+
+	// producer
+	_ = pflag.String("producer.topic", "molon-msg", "")
+	_ = pflag.String("producer.receipt-topic", "molon-msg-receipt", "topic of read receipts, empty means disabled")
+	_ = pflag.String("producer.priority-topic", "", "topic of high priority push payloads, empty means the same as producer.topic")
+	_ = pflag.String("producer.presence-topic", "", "topic of session online/offline events, empty means disabled")
+
+	// outbox
+	_ = pflag.Duration("outbox.interval", time.Second, "interval of relaying the outbox of session tasks to mq")
+	_ = pflag.Int("outbox.batch-count", 100, "")
+	_ = pflag.Duration("outbox.retry-after", 10*time.Second, "claimed outbox messages not relayed within this are claimed again")
+
+	// reaper
+	_ = pflag.Duration("reaper.interval", 5*time.Second, "interval of reaping sessions of boats whose lease expired")
+	_ = pflag.Int("reaper.batch-count", 10, "max boats claimed each time")
+	_ = pflag.Duration("reaper.retry-after", time.Minute, "claimed boats not reaped within this are claimed again")
+
+	// platform
+	// 更细的平台配置(ack-wait/offline-expire/disable-offline/notification-provider/max-retries)需通过配置文件的 platform.configs 设置
+	_                            = pflag.StringSlice("platform.names", []string{"mobile", "desktop"}, "ignored if platform.configs is set")
+	flagPlatformMaxOfflineCounts = pflag.StringToInt("platform.max-offline-counts", map[string]int{
+		"mobile":  -1,
+		"desktop": 80,
+	}, "-1 means infinity")
+
+	_ = pflag.Duration("offline.expire", 2160*time.Hour, "90 days")
+	_ = pflag.Int64("offline.batch-count", 80, "")
+	_ = pflag.String("offline.driver", "redis", "storage of offline messages, redis, sql or bolt")
+	_ = pflag.String("offline.sql.dialect", "postgres", "postgres, mysql or sqlite3")
+	_ = pflag.String("offline.sql.dsn", "", "data source name of offline.sql.dialect")
+	_ = pflag.String("offline.bolt.path", "gomsg-offline.db", "file of the embedded offline storage, can only be opened by one process")
+
+	// lease
+	_ = pflag.Duration("lease.interval", 5*time.Second, "interval of renewing the lease of this boat")
+	_ = pflag.Duration("lease.ttl", 30*time.Second, "sessions of this boat are reaped by station if the lease is not renewed within this")
+
+	// client requests in loop
+	_ = pflag.Duration("client.request-timeout", 10*time.Second, "timeout of each read or sync request of a client")
+	_ = pflag.Int("client.max-pending-requests", 4, "max read or sync requests of a session in progress, the others are rejected")
+	_ = pflag.StringSlice("client.trusted-proxies", []string{}, "ips or cidrs of proxies whose x-forwarded-for is honoured, empty means always the peer address")
+
+	// sync
+	_ = pflag.Duration("sync.expire", 2160*time.Hour, "only offline messages sent within this are synced, usually the same as offline.expire")
+	_ = pflag.Int64("sync.default-limit", 50, "page size of a sync request without limit")
+	_ = pflag.Int64("sync.max-limit", 200, "max page size of a sync request")
+
+	// audit
+	_ = pflag.Bool("audit.enabled", false, "record the lifecycle events of each message for the audit query")
+	_ = pflag.Duration("audit.retention", 72*time.Hour, "audit trail of a message is kept for this long after its last event")
+	_ = pflag.Int("audit.max-events", 100, "max events kept for each message")
+
+	// unread
+	_ = pflag.StringSlice("unread.platforms", []string{"mobile", "desktop"}, "platforms counted if the request does not specify, usually the same as platform.names")
+	_ = pflag.Duration("unread.expire", 2160*time.Hour, "only offline messages sent within this are counted, usually the same as offline.expire")
+
+	// query
+	_ = pflag.Bool("query.expose-session-metadata", false, "return device id, remote ip and claims in the unauthenticated sessions query, enable only if the query api is internal")
+
+	// notification
+	_ = pflag.String("notification.topic", "molon-msg-notification", "")
+
+	// receipt
+	_ = pflag.String("receipt.topic", "molon-msg-receipt", "topic of delivery receipts, empty means disabled")
+
+	// consumer
+	_ = pflag.String("consumer.group", "molon-msg-group", "")
+	_ = pflag.Int("consumer.concurrency", 100, "") // 消费topic的协程数
+	_ = pflag.String("consumer.topic", "molon-msg", "")
+	_ = pflag.Int("consumer.retry-concurrency", 10, "") // 消费retry topic的协程数
+	_ = pflag.String("consumer.retry-topic", "molon-msg-retry", "")
+	_ = pflag.Duration("consumer.retry-delay", 10*time.Second, "")
+	_ = pflag.Int64("consumer.max-retries", 6, "")
+	_ = pflag.String("consumer.dlq-topic", "molon-msg-dlq", "dead letter queue")
+	_ = pflag.Int("consumer.priority-concurrency", 20, "") // 消费priority topic的协程数
+
+	// boat
+	_ = pflag.String("boat.name-prefix", "gomsg://boat-", "name-prefix of boat server")
+	_ = pflag.Duration("boat.ack-wait", 2*time.Second, "ack-wait from boat server")
+	_ = pflag.Int("boat.push-concurrency", 8, "max concurrent pushes to boat servers for one payload")
+	_ = pflag.Duration("boat.push-timeout", 6*time.Second, "overall deadline of pushes to boat servers for one payload")
+
+	// auth
+	_ = pflag.String("auth.uid", "molon", "uid returned by the built-in example auth if the client does not carry uid metadata")
+	_ = pflag.String("auth.platform", "mobile", "platform returned by the built-in example auth if the client does not carry platform metadata")
+)
+
+func init() {
+	pflag.Parse()
+	viper.BindPFlags(pflag.CommandLine)
+
+	// BindPFlags 不能很好地支持map
+	viper.Set("platform.max-offline-counts", *flagPlatformMaxOfflineCounts)
+
+	viper.AutomaticEnv()
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+
+	if viper.GetString("config.file") != "" {
+		viper.SetConfigFile(viper.GetString("config.file"))
+		if err := viper.ReadInConfig(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+// all-in-one 模式，boat/station/carrier 以及示例auth跑在同一进程里
+// 使用进程内的消息队列和直接调用的客户端，可选内嵌的redis兼容存储，无需etcd/kafka/jaeger
+// 仅适用于本地开发和小规模部署
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang/protobuf/proto"
+	gateway_runtime "github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/rs/xid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+
+	"github.com/molon/gomsg/example/auth/authserver"
+	"github.com/molon/gomsg/internal/app/boat"
+	"github.com/molon/gomsg/internal/app/carrier"
+	"github.com/molon/gomsg/internal/app/station"
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/gomsg/internal/pkg/mq/memmq"
+	"github.com/molon/gomsg/internal/pkg/resource"
+	"github.com/molon/gomsg/internal/pkg/sessionstore"
+	"github.com/molon/gomsg/pb/pushpb"
+	"github.com/molon/gomsg/pb/querypb"
+	"github.com/molon/pkg/server"
+	"github.com/molon/pkg/server/gateway"
+)
+
+var serverKeepaliveOptions = []grpc.ServerOption{
+	grpc.KeepaliveParams(keepalive.ServerParameters{
+		MaxConnectionIdle:     0, // infinity
+		MaxConnectionAge:      0, // infinity
+		MaxConnectionAgeGrace: 0, // infinity
+		Time:                  time.Duration(time.Second * 10),
+		Timeout:               time.Duration(time.Second * 3),
+	}),
+	grpc.KeepaliveEnforcementPolicy(
+		keepalive.EnforcementPolicy{
+			MinTime:             time.Second * 5, // 必须要小于调用者的ClientParameters.Time配置
+			PermitWithoutStream: true,            // 要和调用者保持一致
+		},
+	),
+}
+
+var loopServerKeepaliveOptions = []grpc.ServerOption{
+	grpc.KeepaliveParams(keepalive.ServerParameters{
+		MaxConnectionIdle:     0, // infinity
+		MaxConnectionAge:      0, // infinity
+		MaxConnectionAgeGrace: 0, // infinity
+		Time:                  time.Duration(time.Second * 300),
+		Timeout:               time.Duration(time.Second * 20),
+	}),
+	grpc.KeepaliveEnforcementPolicy(
+		keepalive.EnforcementPolicy{
+			MinTime:             time.Second * 200, // 必须要小于调用者的ClientParameters.Time配置
+			PermitWithoutStream: true,              // 为true表示即使客户端在idle时候发送心跳也不会被踢出
+		},
+	),
+}
+
+// station的gRPC服务以及push的http网关
+func StationServer(logger *logrus.Logger) (*server.Server, net.Listener, net.Listener) {
+	grpcServer, err := station.NewGRPCServer(serverKeepaliveOptions...)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	grpcL, err := net.Listen("tcp", fmt.Sprintf("%s:%d", viper.GetString("grpc.address"), viper.GetInt("grpc.port")))
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	s, err := server.NewServer(
+		server.WithGRPCServer(grpcServer),
+		server.WithGateway(
+			gateway.WithGatewayOptions(
+				gateway_runtime.WithForwardResponseOption(
+					func(ctx context.Context, w http.ResponseWriter, resp proto.Message) error {
+						w.Header().Set("Cache-Control", "no-cache, no-store, max-age=0, must-revalidate")
+						return nil
+					},
+				),
+				gateway_runtime.WithMarshalerOption("application/json", &gateway_runtime.JSONPb{
+					OrigName:     true,
+					EnumsAsInts:  true,
+					EmitDefaults: true,
+				}),
+			),
+			gateway.WithEndpointRegistration("/v1/",
+				pushpb.RegisterPushHandlerFromEndpoint,
+				querypb.RegisterQueryHandlerFromEndpoint,
+			),
+			gateway.WithServerAddress(grpcL.Addr().String()),
+		),
+	)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	httpL, err := net.Listen("tcp", fmt.Sprintf("%s:%d", viper.GetString("http.address"), viper.GetInt("http.port")))
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	logger.Infof("Serving station gRPC at %v", grpcL.Addr())
+	logger.Infof("Serving station http at %v", httpL.Addr())
+
+	return s, grpcL, httpL
+}
+
+func LoopServer(ctx context.Context, logger *logrus.Logger) (*server.Server, io.Closer, net.Listener) {
+	grpcServer, closer, err := boat.NewLoopServer(ctx, loopServerKeepaliveOptions...)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	s, err := server.NewServer(
+		server.WithGRPCServer(grpcServer),
+	)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	grpcL, err := net.Listen("tcp", fmt.Sprintf("%s:%d", viper.GetString("loop.address"), viper.GetInt("loop.port")))
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	logger.Infof("Serving loop gRPC at %v", grpcL.Addr())
+
+	return s, closer, grpcL
+}
+
+// 启动内嵌的redis兼容存储，并将 redis.address/redis.port 指向它，不再使用集群
+func StartEmbeddedRedis(logger *logrus.Logger) *miniredis.Miniredis {
+	mr, err := miniredis.Run()
+	if err != nil {
+		logger.Fatalln("Start embedded redis failed:", err)
+	}
+
+	host, port, err := net.SplitHostPort(mr.Addr())
+	if err != nil {
+		logger.Fatalln(err)
+	}
+	viper.Set("redis.address", host)
+	viper.Set("redis.port", port)
+	viper.Set("redis.cluster", false)
+
+	logger.Infof("Start embedded redis at %s", mr.Addr())
+
+	return mr
+}
+
+// 只有一个boat，直接映射到其进程内客户端
+type localBoatStore map[string]interface{}
+
+func (s localBoatStore) Get(target string) (interface{}, bool) {
+	cli, ok := s[target]
+	return cli, ok
+}
+
+func main() {
+	runtime.GOMAXPROCS(runtime.NumCPU())
+
+	logger := resource.NewLogger()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 服务唯一标识
+	applicationId := xid.New().String()
+	logger.Infof("Application ID: %s", applicationId)
+
+	// 初始化redis
+	if viper.GetBool("redis.embedded") {
+		mr := StartEmbeddedRedis(logger)
+		defer mr.Close()
+	}
+	redisPool := resource.NewRedisPool(logger)
+	defer redisPool.Close()
+
+	// 进程内mq
+	broker := memmq.NewBroker(viper.GetDuration("mq.redeliver-after"))
+	producer := broker.Producer()
+
+	// 初始化离线消息存储，station、boat和carrier共用
+	offstore, offstoreCloser := resource.NewOfflineStore(ctx, logger, redisPool)
+	defer offstoreCloser.Close()
+
+	// 会话存储，station和carrier共用
+	sstore := sessionstore.NewStore(logger, redisPool)
+
+	// 消息审计轨迹，station、boat和carrier共用，未开启时为nil
+	auditStore := resource.NewAuditStore(logger, redisPool)
+	defer auditStore.Close()
+
+	// station和carrier在同一进程，carrier消费的priority topic直接取station投递的
+	viper.Set("consumer.priority-topic", viper.GetString("producer.priority-topic"))
+
+	// 初始化station
+	stationCfg := station.Config{}
+	if err := viper.Unmarshal(&stationCfg); err != nil {
+		logger.Fatalln("Unmarshal viper to config failed:", err)
+	}
+	if err := station.Init(stationCfg, logger, authserver.NewLocalClient(&authserver.Server{
+		Uid:      viper.GetString("auth.uid"),
+		Platform: viper.GetString("auth.platform"),
+	}), sstore, producer, offstore, auditStore); err != nil {
+		logger.Fatalln("Init station failed:", err)
+	}
+	defer station.Stop()
+
+	// 初始化boat
+	boatCfg := boat.Config{}
+	if err := viper.Unmarshal(&boatCfg); err != nil {
+		logger.Fatalln("Unmarshal viper to config failed:", err)
+	}
+	if err := boat.Init(boatCfg, applicationId, logger, station.NewLocalClient(), offstore, auditStore); err != nil {
+		logger.Fatalln("Init boat failed:", err)
+	}
+	defer boat.Stop()
+
+	// 启动carrier
+	group := viper.GetString("consumer.group")
+	consumer := broker.NewConsumer(group, viper.GetString("consumer.topic"), viper.GetInt("consumer.concurrency"))
+	retryConsumer := broker.NewConsumer(group, viper.GetString("consumer.retry-topic"), viper.GetInt("consumer.retry-concurrency"))
+	consumer.Start()
+	retryConsumer.Start()
+	defer func() {
+		consumer.Stop()
+		retryConsumer.Stop()
+		<-consumer.Closed()
+		<-retryConsumer.Closed()
+	}()
+	var priorityConsumer mq.Consumer
+	if topic := viper.GetString("consumer.priority-topic"); topic != "" {
+		c := broker.NewConsumer(group, topic, viper.GetInt("consumer.priority-concurrency"))
+		c.Start()
+		defer func() {
+			c.Stop()
+			<-c.Closed()
+		}()
+		priorityConsumer = c
+	}
+
+	boatStore := localBoatStore{
+		viper.GetString("boat.name-prefix") + applicationId: boat.NewLocalClient(),
+	}
+
+	carrierCfg := carrier.Config{}
+	if err := viper.Unmarshal(&carrierCfg); err != nil {
+		logger.Fatalln("Unmarshal viper to config failed:", err)
+	}
+	carrierCfg.MQ.Driver = "memory"
+	carrier.Start(ctx, logger, carrierCfg, boatStore, producer, consumer, retryConsumer, priorityConsumer, sstore, offstore, auditStore)
+	defer carrier.Stop()
+
+	// 启动服务
+	doneC := make(chan error, 5)
+	sigC := make(chan os.Signal, 1)
+
+	stationS, grpcL, httpL := StationServer(logger)
+	go func() { doneC <- stationS.Serve(grpcL, httpL) }()
+	defer server.GracefulStop(stationS)
+
+	loopS, loopCloser, loopL := LoopServer(ctx, logger)
+	go func() { doneC <- loopS.Serve(loopL, nil) }()
+	defer server.GracefulStop(loopS)
+	defer loopCloser.Close() // 这个是为了主动要求stream立即关闭，否则grpc.GracefulStop会一直等待
+
+	// 健康检查
+	healthS, healthL := resource.NewHealthChecker(logger, nil)
+	go func() { doneC <- healthS.Serve(nil, healthL) }()
+	defer server.GracefulStop(healthS)
+
+	// 结束清理
+	signal.Notify(sigC, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-sigC
+		doneC <- nil
+	}()
+	if err := <-doneC; err != nil {
+		logger.Errorln(err)
+	}
+}
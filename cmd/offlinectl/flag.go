@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const usage = `Inspect and maintain the redis offline store.
+
+Usage:
+  offlinectl [flags] list <uid> [platform...]            print offline messages of the user per platform
+  offlinectl [flags] get <seq>                           print content, refcount and ttl of a message
+  offlinectl [flags] delete <uid> <platform> <seq>...    delete offline messages of the user
+  offlinectl [flags] expire <uid> <duration> [platform...] delete offline messages sent before now-duration
+  offlinectl [flags] orphans [--repair]                  find contents whose refcount went wrong, repair them safely with --repair
+  offlinectl [flags] migrate-keys [--dry-run]            move keys written before the {hashtag} layout to the current one
+
+Flags:
+`
+
+var (
+	// Config
+	_ = pflag.String("config.file", "", "path of the configuration file")
+
+	// Logging
+	_ = pflag.String("logging.level", "info", "log level of application")
+
+	// redis
+	_ = pflag.String("redis.address", "127.0.0.1", "")
+	_ = pflag.Int("redis.port", 9379, "")
+	_ = pflag.Bool("redis.cluster", false, "connect to a redis cluster with redis.cluster-addrs, redis.address and redis.port are ignored")
+	_ = pflag.StringSlice("redis.cluster-addrs", []string{"127.0.0.1:7000"}, "some nodes of the redis cluster, the others are discovered")
+
+	// platform
+	_ = pflag.StringSlice("platform.names", []string{"mobile", "desktop"}, "platforms used if the command does not specify")
+
+	// orphans
+	_ = pflag.Bool("repair", false, "repair the orphans found, otherwise only print them")
+
+	// migrate-keys
+	_ = pflag.Bool("dry-run", false, "only count the legacy keys, use --logging.level=debug to print them")
+)
+
+func init() {
+	pflag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		pflag.PrintDefaults()
+	}
+	pflag.Parse()
+	viper.BindPFlags(pflag.CommandLine)
+
+	viper.AutomaticEnv()
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+
+	if viper.GetString("config.file") != "" {
+		viper.SetConfigFile(viper.GetString("config.file"))
+		if err := viper.ReadInConfig(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
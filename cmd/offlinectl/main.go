@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/molon/gomsg/internal/pkg/offline/redisoffline"
+	"github.com/molon/gomsg/internal/pkg/resource"
+	"github.com/molon/gomsg/pb/msgpb"
+	"github.com/molon/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func main() {
+	logger := resource.NewLogger()
+	ctx := context.Background()
+
+	args := pflag.Args()
+	if len(args) <= 0 {
+		pflag.Usage()
+		os.Exit(2)
+	}
+
+	redisPool := resource.NewRedisPool(logger)
+	defer redisPool.Close()
+
+	store, err := redisoffline.InitStore(ctx, redisPool)
+	if err != nil {
+		logger.Fatalln("Init redis offline store failed:", err)
+	}
+
+	if err := run(ctx, logger, store, args[0], args[1:]); err != nil {
+		logger.Fatalf("%s failed: %+v", args[0], err)
+	}
+}
+
+func run(ctx context.Context, logger *logrus.Logger, store *redisoffline.Store, cmd string, args []string) error {
+	switch cmd {
+	case "list":
+		if len(args) < 1 {
+			return errors.Errorf("usage: list <uid> [platform...]")
+		}
+		return list(ctx, store, args[0], platformsOf(args[1:]))
+	case "get":
+		if len(args) != 1 {
+			return errors.Errorf("usage: get <seq>")
+		}
+		return get(ctx, store, args[0])
+	case "delete":
+		if len(args) < 3 {
+			return errors.Errorf("usage: delete <uid> <platform> <seq>...")
+		}
+		if err := store.Delete(ctx, args[0], args[1], args[2:]); err != nil {
+			return err
+		}
+		logger.Infof("Deleted %d offline messages of %s on %s", len(args[2:]), args[0], args[1])
+		return nil
+	case "expire":
+		if len(args) < 2 {
+			return errors.Errorf("usage: expire <uid> <duration> [platform...]")
+		}
+		expire, err := time.ParseDuration(args[1])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		return expireUid(ctx, logger, store, args[0], expire, platformsOf(args[2:]))
+	case "orphans":
+		return orphans(ctx, logger, store, viper.GetBool("repair"))
+	case "migrate-keys":
+		return migrateKeys(ctx, logger, store, viper.GetBool("dry-run"))
+	}
+	return errors.Errorf("unknown command: %s", cmd)
+}
+
+func platformsOf(args []string) []string {
+	if len(args) > 0 {
+		return args
+	}
+	return viper.GetStringSlice("platform.names")
+}
+
+func list(ctx context.Context, store *redisoffline.Store, uid string, platforms []string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	for _, platform := range platforms {
+		entries, err := store.Entries(ctx, uid, platform)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "[%s] %d messages\n", platform, len(entries))
+		if len(entries) <= 0 {
+			continue
+		}
+
+		fmt.Fprintln(w, "SEQ\tSEND TIME\tPRIORITY\tREFS\tCONTENT")
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n",
+				e.Seq,
+				e.SendTime.Format(time.RFC3339),
+				e.Priority,
+				e.Refs,
+				describe(e.Msg),
+			)
+		}
+	}
+	return nil
+}
+
+func get(ctx context.Context, store *redisoffline.Store, seq string) error {
+	c, err := store.Lookup(ctx, seq)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "SEQ\t%s\n", c.Seq)
+	if c.HasRefs {
+		fmt.Fprintf(w, "REFS\t%d\n", c.Refs)
+	} else {
+		fmt.Fprintf(w, "REFS\t<missing>\n")
+	}
+	switch {
+	case c.TTL == -2:
+		fmt.Fprintf(w, "TTL\t<missing>\n")
+	case c.TTL < 0:
+		fmt.Fprintf(w, "TTL\t<none>\n")
+	default:
+		fmt.Fprintf(w, "TTL\t%s\n", c.TTL.Round(time.Second))
+	}
+	fmt.Fprintf(w, "CONTENT\t%s\n", describe(c.Msg))
+	if c.Msg != nil {
+		fmt.Fprintf(w, "MESSAGE\t%s\n", c.Msg.String())
+	}
+	return nil
+}
+
+func expireUid(ctx context.Context, logger *logrus.Logger, store *redisoffline.Store, uid string, expire time.Duration, platforms []string) error {
+	// 只清理过期的，不限制数目
+	platformToMaxOMCount := map[string]int{}
+	for _, platform := range platforms {
+		platformToMaxOMCount[platform] = -1
+	}
+
+	platformToExpiredSeqs, _, err := store.Clean(ctx, uid, expire, platformToMaxOMCount)
+	if err != nil {
+		return err
+	}
+
+	for _, platform := range platforms {
+		logger.Infof("Expired %d offline messages of %s on %s", len(platformToExpiredSeqs[platform]), uid, platform)
+	}
+	return nil
+}
+
+func orphans(ctx context.Context, logger *logrus.Logger, store *redisoffline.Store, repair bool) error {
+	found, err := store.FindOrphans(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "SEQ\tCONTENT\tREFS\tACTUAL\tACTION")
+	for _, o := range found {
+		content := "ok"
+		if !o.HasMsg {
+			content = "<missing>"
+		}
+		refs := "<missing>"
+		if o.HasRefs {
+			refs = fmt.Sprint(o.Refs)
+		}
+
+		action := "-"
+		if repair {
+			action, err = store.RepairOrphan(ctx, o)
+			if err != nil {
+				logger.Warnf("Repair orphan(%s) failed: %+v", o.Seq, err)
+				action = "failed"
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", o.Seq, content, refs, o.Actual, action)
+	}
+	return nil
+}
+
+func migrateKeys(ctx context.Context, logger *logrus.Logger, store *redisoffline.Store, dryRun bool) error {
+	n, err := store.MigrateLegacyKeys(ctx, dryRun, func(oldKey string, newKey string) {
+		logger.Debugf("Migrate %s -> %s", oldKey, newKey)
+	})
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		logger.Infof("%d legacy keys to migrate", n)
+		return nil
+	}
+	logger.Infof("Migrated %d legacy keys", n)
+	return nil
+}
+
+// 消息内容的简要描述，完整内容用get查看
+func describe(msg *msgpb.Message) string {
+	if msg == nil {
+		return "<missing>"
+	}
+
+	parts := []string{}
+	if body := msg.GetBody(); body != nil {
+		parts = append(parts, fmt.Sprintf("body=%s(%d bytes)", body.GetTypeUrl(), len(body.GetValue())))
+	}
+	if msg.GetCategory() != "" {
+		parts = append(parts, "category="+msg.GetCategory())
+	}
+	if msg.GetCollapseKey() != "" {
+		parts = append(parts, "collapse="+msg.GetCollapseKey())
+	}
+	if exp := msg.GetExpireAt(); exp != nil {
+		parts = append(parts, "expire_at="+time.Unix(exp.GetSeconds(), 0).Format(time.RFC3339))
+	}
+	return strings.Join(parts, " ")
+}
@@ -12,6 +12,8 @@ import (
 var (
 	// Config
 	_ = pflag.String("config.file", "", "path of the configuration file")
+	_ = pflag.String("config.etcd-key", "", "etcd key of the hot-reloadable configuration, empty means disabled")
+	_ = pflag.String("config.etcd-format", "yaml", "format of the value at config.etcd-key")
 
 	// Logging
 	_ = pflag.String("logging.level", "debug", "log level of application")
@@ -21,6 +23,7 @@ var (
 	_ = pflag.Int("health.port", 0, "port of health http server")
 	_ = pflag.String("health.liveness", "/healthz", "endpoint for liveness checks")
 	_ = pflag.String("health.readiness", "/ready", "endpoint for readiness checks")
+	_ = pflag.String("health.metrics", "/metrics", "endpoint for prometheus metrics")
 
 	// Jaeger
 	_ = pflag.String("jaeger.service-name", "gomsg_station", "")
@@ -37,10 +40,56 @@ var (
 	// redis
 	_ = pflag.String("redis.address", "127.0.0.1", "")
 	_ = pflag.String("redis.port", "9379", "")
+	_ = pflag.Bool("redis.cluster", false, "connect to a redis cluster with redis.cluster-addrs, redis.address and redis.port are ignored")
+	_ = pflag.StringSlice("redis.cluster-addrs", []string{"127.0.0.1:7000"}, "some nodes of the redis cluster, the others are discovered")
 
-	// kafka and producer
+	// mq
+	_ = pflag.String("mq.driver", "kafka", "kafka, redis(streams) or nats(jetstream), ordered delivery and batch-window of carrier require kafka")
 	_ = pflag.StringSlice("kafka.brokers", []string{"127.0.0.1:9092"}, "")
+	_ = pflag.String("mq.redis.address", "127.0.0.1", "")
+	_ = pflag.Int("mq.redis.port", 9379, "")
+	_ = pflag.Int64("mq.redis.max-len", 1000000, "approximate max length of each stream")
+	_ = pflag.Duration("mq.redis.claim-idle", time.Minute, "unacked messages idle longer than this are redelivered, messages being handled are renewed periodically")
+	_ = pflag.String("mq.nats.url", "nats://127.0.0.1:4222", "")
+	_ = pflag.Duration("mq.nats.ack-wait", time.Minute, "unacked messages are redelivered after this")
+	_ = pflag.Duration("mq.nats.max-age", 72*time.Hour, "max age of messages in each stream")
+
+	// producer
 	_ = pflag.String("producer.topic", "molon-msg", "")
+	_ = pflag.Bool("producer.partition-by-uid", false, "partition ToUid payloads by uid, required by consumer.batch-window of carrier")
+	_ = pflag.Bool("producer.ordered", false, "per-uid ordered delivery, implies partition-by-uid and requires consumer.ordered of carrier")
+	_ = pflag.Duration("producer.ordered-block-ttl", 10*time.Minute, "max time later payloads of a uid wait for an earlier one handed off to the outbox")
+	_ = pflag.String("producer.receipt-topic", "molon-msg-receipt", "topic of read receipts, empty means disabled")
+	_ = pflag.String("producer.priority-topic", "", "topic of high priority push payloads, requires consumer.priority-topic of carrier, empty means the same as producer.topic")
+	_ = pflag.String("producer.presence-topic", "", "topic of session online/offline events, empty means disabled")
+
+	// outbox
+	_ = pflag.Duration("outbox.interval", time.Second, "interval of relaying the outbox of session tasks to mq")
+	_ = pflag.Int("outbox.batch-count", 100, "")
+	_ = pflag.Duration("outbox.retry-after", 10*time.Second, "claimed outbox messages not relayed within this are claimed again")
+
+	// reaper
+	_ = pflag.Duration("reaper.interval", 5*time.Second, "interval of reaping sessions of boats whose lease expired")
+	_ = pflag.Int("reaper.batch-count", 10, "max boats claimed each time")
+	_ = pflag.Duration("reaper.retry-after", time.Minute, "claimed boats not reaped within this are claimed again")
+
+	// offline, must be the same storage as carrier
+	_ = pflag.String("offline.driver", "redis", "storage of offline messages, redis or sql, bolt can only be opened by one process")
+	_ = pflag.String("offline.sql.dialect", "postgres", "postgres, mysql or sqlite3")
+	_ = pflag.String("offline.sql.dsn", "", "data source name of offline.sql.dialect")
+	_ = pflag.String("offline.bolt.path", "gomsg-offline.db", "file of the embedded offline storage, can only be opened by one process")
+
+	// audit, must be the same as carrier and boat
+	_ = pflag.Bool("audit.enabled", false, "record the lifecycle events of each message for the audit query")
+	_ = pflag.Duration("audit.retention", 72*time.Hour, "audit trail of a message is kept for this long after its last event")
+	_ = pflag.Int("audit.max-events", 100, "max events kept for each message")
+
+	// unread
+	_ = pflag.StringSlice("unread.platforms", []string{"mobile", "desktop"}, "platforms counted if the request does not specify, usually the same as platform.names of carrier")
+	_ = pflag.Duration("unread.expire", 2160*time.Hour, "only offline messages sent within this are counted, usually the same as offline.expire of carrier")
+
+	// query
+	_ = pflag.Bool("query.expose-session-metadata", false, "return device id, remote ip and claims in the unauthenticated sessions query, enable only if the query api is internal")
 
 	// gRPC servers
 	_ = pflag.String("auth.name", "example://auth", "name of auth server")
@@ -14,10 +14,12 @@ import (
 	"github.com/molon/pkg/grpc/timeout"
 
 	"github.com/molon/gomsg/pb/pushpb"
+	"github.com/molon/gomsg/pb/querypb"
 
 	"github.com/golang/protobuf/proto"
 
 	"github.com/molon/gomsg/internal/pkg/resource"
+	"github.com/molon/gomsg/internal/pkg/sessionstore"
 	"github.com/molon/gomsg/pb/authpb"
 	"github.com/molon/pkg/server"
 	"google.golang.org/grpc"
@@ -30,8 +32,6 @@ import (
 	gateway_runtime "github.com/grpc-ecosystem/grpc-gateway/runtime"
 	"github.com/molon/pkg/server/gateway"
 
-	"github.com/Shopify/sarama"
-
 	etcd "github.com/coreos/etcd/clientv3"
 	etcdnaming "github.com/coreos/etcd/clientv3/naming"
 	"github.com/molon/pkg/registry"
@@ -106,7 +106,10 @@ func Server(logger *logrus.Logger) (*server.Server, net.Listener, net.Listener)
 					EmitDefaults: true,
 				}),
 			),
-			gateway.WithEndpointRegistration("/v1/", pushpb.RegisterPushHandlerFromEndpoint),
+			gateway.WithEndpointRegistration("/v1/",
+				pushpb.RegisterPushHandlerFromEndpoint,
+				querypb.RegisterQueryHandlerFromEndpoint,
+			),
 			gateway.WithServerAddress(grpcL.Addr().String()),
 		),
 	)
@@ -143,21 +146,6 @@ func NewAuthClient(ctx context.Context, logger *logrus.Logger, etcdCli *etcd.Cli
 	return authpb.NewAuthClient(conn), conn
 }
 
-func NewKafkaProducer(logger *logrus.Logger) sarama.SyncProducer {
-	kc := sarama.NewConfig()
-	kc.Producer.RequiredAcks = sarama.WaitForAll
-	kc.Producer.Retry.Max = 10
-	kc.Producer.Return.Successes = true
-	pub, err := sarama.NewSyncProducer(viper.GetStringSlice("kafka.brokers"), kc)
-	if err != nil {
-		logger.Fatalln("Create kafka producer failed:", err)
-	}
-
-	logger.Infof("Create producer at kafka brokers %v", viper.GetStringSlice("kafka.brokers"))
-
-	return pub
-}
-
 func main() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
@@ -177,22 +165,50 @@ func main() {
 	redisPool := resource.NewRedisPool(logger)
 	defer redisPool.Close()
 
-	// 初始化kafka生产者
-	kp := NewKafkaProducer(logger)
-	defer kp.Close()
+	// 初始化离线消息存储，用于查询未读数
+	offstore, offstoreCloser := resource.NewOfflineStore(ctx, logger, redisPool)
+	defer offstoreCloser.Close()
+
+	// 初始化mq生产者
+	mp := resource.NewMQProducer(logger)
+	defer mp.Close()
 
 	// 连接gRPC服务
 	authCli, authConn := NewAuthClient(ctx, logger, etcdCli)
 	defer authConn.Close()
 
-	// 初始化 内部config 可以直接unmarshal进来
+	// 初始化 内部config 可以直接unmarshal进来，etcd里若有配置则以其覆盖
+	etcdCfg := resource.NewEtcdConfig(ctx, logger, etcdCli)
+	v, err := etcdCfg.Viper()
+	if err != nil {
+		logger.Fatalln("Merge config from etcd failed:", err)
+	}
 	cfg := station.Config{}
-	if err := viper.Unmarshal(&cfg); err != nil {
+	if err := v.Unmarshal(&cfg); err != nil {
 		logger.Fatalln("Unmarshal viper to config failed:", err)
 	}
-	if err := station.Init(cfg, logger, authCli, redisPool, kp); err != nil {
+	// 高优先级的推送任务要有carrier消费
+	if err := resource.CheckPriorityTopic(ctx, logger, etcdCli, cfg.Producer.PriorityTopic); err != nil {
+		logger.Fatalln("Check producer.priority-topic failed:", err)
+	}
+	auditStore := resource.NewAuditStore(logger, redisPool)
+	defer auditStore.Close()
+	if err := station.Init(cfg, logger, authCli, sessionstore.NewStore(logger, redisPool), mp, offstore, auditStore); err != nil {
 		logger.Fatalln("Init station failed:", err)
 	}
+	defer station.Stop()
+
+	// 监听etcd里的配置变更，热更新
+	etcdCfg.Watch(ctx, func(v *viper.Viper) {
+		cfg := station.Config{}
+		if err := v.Unmarshal(&cfg); err != nil {
+			logger.Errorf("Unmarshal viper to config failed, keep the old one: %+v", err)
+			return
+		}
+		if err := station.Reload(cfg); err != nil {
+			logger.Errorf("Reload station config failed, keep the old one: %+v", err)
+		}
+	})
 
 	// 启动服务
 	doneC := make(chan error, 3)
@@ -0,0 +1,51 @@
+// 示例鉴权服务的实现，example/auth 以gRPC提供，all-in-one 模式下进程内直接调用
+package authserver
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/molon/gomsg/internal/pkg/inproc"
+	"github.com/molon/gomsg/pb/authpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// 客户端可以通过 uid/platform 的metadata指定身份，便于本地调试多用户，没有的话使用默认值
+type Server struct {
+	Uid      string
+	Platform string
+}
+
+func (s *Server) Auth(ctx context.Context, in *empty.Empty) (*authpb.AuthResponse, error) {
+	// 其实应该是解jwt的逻辑，这里先偷懒
+	out := &authpb.AuthResponse{
+		Uid:      s.Uid,
+		Platform: s.Platform,
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("uid"); len(v) > 0 && v[0] != "" {
+			out.Uid = v[0]
+		}
+		if v := md.Get("platform"); len(v) > 0 && v[0] != "" {
+			out.Platform = v[0]
+		}
+	}
+
+	return out, nil
+}
+
+// 进程内直接调用的客户端，CallOption会被忽略
+func NewLocalClient(s *Server) authpb.AuthClient {
+	return &localClient{s: s}
+}
+
+type localClient struct {
+	s *Server
+}
+
+func (c *localClient) Auth(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*authpb.AuthResponse, error) {
+	out, err := c.s.Auth(ctx, in)
+	return out, inproc.StatusError(err)
+}
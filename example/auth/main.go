@@ -14,10 +14,10 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/keepalive"
 
-	"github.com/golang/protobuf/ptypes/empty"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_logrus "github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
 	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
+	"github.com/molon/gomsg/example/auth/authserver"
 	"github.com/molon/gomsg/internal/pkg/resource"
 	"github.com/molon/gomsg/pb/authpb"
 	"github.com/molon/pkg/errors"
@@ -71,7 +71,10 @@ func GRPCServer(logger *logrus.Logger) (*server.Server, net.Listener) {
 		),
 	)
 
-	authpb.RegisterAuthServer(srv, &grpcServer{})
+	authpb.RegisterAuthServer(srv, &authserver.Server{
+		Uid:      "molon",
+		Platform: "mobile",
+	})
 
 	s, err := server.NewServer(
 		server.WithGRPCServer(srv),
@@ -105,16 +108,6 @@ func NewEtcdClient(ctx context.Context, logger *logrus.Logger) *etcd.Client {
 	return cli
 }
 
-type grpcServer struct{}
-
-func (s *grpcServer) Auth(ctx context.Context, in *empty.Empty) (*authpb.AuthResponse, error) {
-	// 其实应该是解jwt的逻辑，这里先偷懒
-	return &authpb.AuthResponse{
-		Uid:      "molon",
-		Platform: "mobile",
-	}, nil
-}
-
 func main() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
@@ -0,0 +1,99 @@
+package boat
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/molon/pkg/errors"
+)
+
+type Config struct {
+	// 向station续约，租约到期后其上的会话登记会被清理
+	Lease struct {
+		Interval time.Duration
+		// 租约时长，应该是心跳间隔的数倍，以容忍偶尔的心跳失败
+		TTL time.Duration `mapstructure:"ttl"`
+	}
+	// 客户端在loop里发起的请求(已读回执、拉取离线消息)，异步执行不阻塞recv loop
+	Client struct {
+		// 单个请求的超时
+		RequestTimeout time.Duration `mapstructure:"request-timeout"`
+		// 每个会话同时进行的请求数目上限，超出的直接拒绝
+		MaxPendingRequests int `mapstructure:"max-pending-requests"`
+		// 可信代理的IP或CIDR，只有对端是这些地址时才采用其 x-forwarded-for，为空则一律以对端地址为客户端IP
+		TrustedProxies []string `mapstructure:"trusted-proxies"`
+	}
+	// 客户端分页拉取离线消息
+	Sync struct {
+		// 只拉取发出时间在此之内的，一般和carrier的 offline.expire 一致
+		Expire time.Duration
+		// 请求未指定limit时使用
+		DefaultLimit int64 `mapstructure:"default-limit"`
+		MaxLimit     int64 `mapstructure:"max-limit"`
+	}
+}
+
+func (cfg *Config) Valid() error {
+	if cfg.Lease.Interval <= 0 {
+		return errors.Errorf("lease.interval must > 0")
+	}
+
+	if cfg.Lease.TTL <= cfg.Lease.Interval {
+		return errors.Errorf("lease.ttl must > lease.interval")
+	}
+
+	if cfg.Client.RequestTimeout <= 0 {
+		return errors.Errorf("client.request-timeout must > 0")
+	}
+
+	if cfg.Client.MaxPendingRequests <= 0 {
+		return errors.Errorf("client.max-pending-requests must > 0")
+	}
+
+	if _, err := parseTrustedProxies(cfg.Client.TrustedProxies); err != nil {
+		return err
+	}
+
+	if cfg.Sync.Expire <= 0 {
+		return errors.Errorf("sync.expire must > 0")
+	}
+
+	if cfg.Sync.DefaultLimit <= 0 {
+		return errors.Errorf("sync.default-limit must > 0")
+	}
+
+	if cfg.Sync.MaxLimit < cfg.Sync.DefaultLimit {
+		return errors.Errorf("sync.max-limit must >= sync.default-limit")
+	}
+
+	return nil
+}
+
+// 单个IP视为只包含其自身的网段
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		p = strings.TrimSpace(p)
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, errors.Errorf("client.trusted-proxies: invalid ip %q", p)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipnet, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, errors.Errorf("client.trusted-proxies: invalid cidr %q", p)
+		}
+		nets = append(nets, ipnet)
+	}
+	return nets, nil
+}
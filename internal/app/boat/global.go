@@ -2,9 +2,12 @@ package boat
 
 import (
 	"context"
+	"net"
 	"sync"
 
 	"github.com/molon/gomsg/internal/pb/stationpb"
+	"github.com/molon/gomsg/internal/pkg/audit"
+	"github.com/molon/gomsg/internal/pkg/offline"
 	"github.com/sirupsen/logrus"
 )
 
@@ -15,20 +18,56 @@ type globalCtx struct {
 	mu sync.RWMutex
 
 	ctx           context.Context
+	config        Config
 	applicationId string
 	sessionStore  *SessionStore
 	stationCli    stationpb.StationClient
+	offstore      offline.Store
+	leaser        *leaser
+	// 未开启审计时为nil
+	audit *audit.Store
+	// client.trusted-proxies 解析后的结果
+	trustedProxies []*net.IPNet
 }
 
+// offstore 用于客户端分页拉取离线消息，需和carrier使用同一存储
+// auditStore 用于记录客户端的ack，需和station/carrier使用同一存储
 func Init(
+	config Config,
 	applicationId string,
 	logger *logrus.Logger,
 	stationCli stationpb.StationClient,
-) {
+	offstore offline.Store,
+	auditStore *audit.Store,
+) error {
+	if err := config.Valid(); err != nil {
+		return err
+	}
+
+	trustedProxies, err := parseTrustedProxies(config.Client.TrustedProxies)
+	if err != nil {
+		return err
+	}
+
 	plog = logrus.NewEntry(logger)
 	global = &globalCtx{
-		applicationId: applicationId,
-		sessionStore:  NewSessionStore(),
-		stationCli:    stationCli,
+		config:         config,
+		applicationId:  applicationId,
+		sessionStore:   NewSessionStore(),
+		stationCli:     stationCli,
+		offstore:       offstore,
+		leaser:         newLeaser(),
+		audit:          auditStore,
+		trustedProxies: trustedProxies,
 	}
+
+	registerSendQueueDepth(global.sessionStore)
+	global.leaser.start()
+
+	return nil
+}
+
+// 停止续约，之后租约到期其上残留的会话登记会被station清理
+func Stop() {
+	global.leaser.stop()
 }
@@ -19,6 +19,7 @@ import (
 	"github.com/molon/gomsg/pb/errorpb"
 	"github.com/molon/gomsg/pb/msgpb"
 	"github.com/molon/pkg/errors"
+	"github.com/molon/pkg/tracing/otgrpc"
 )
 
 func NewGRPCServer(opts ...grpc.ServerOption) (*grpc.Server, error) {
@@ -36,6 +37,11 @@ func NewGRPCServer(opts ...grpc.ServerOption) (*grpc.Server, error) {
 
 			// 	return err
 			// })),
+			// 延续carrier的trace，一定要在褪去堆栈信息之前调用，这样jaeger会记录详细一些
+			otgrpc.UnaryServerInterceptor(
+				otgrpc.WithRequstBody(true),
+				otgrpc.WithResponseBody(true),
+			),
 			// recovery住，返回带有堆栈信息的错误
 			grpc_recovery.UnaryServerInterceptor(
 				grpc_recovery.WithRecoveryHandler(
@@ -90,7 +96,7 @@ func (s *grpcServer) PushMessages(ctx context.Context, in *boatpb.PushMessagesRe
 		return nil, errors.WithStack(err)
 	}
 
-	if err := sess.Send(sm, dur); err != nil {
+	if err := sess.Send(ctx, sm, dur); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,108 @@
+package boat
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/molon/gomsg/internal/pb/stationpb"
+	"github.com/molon/gomsg/pb/errorpb"
+	"github.com/molon/pkg/errors"
+	"github.com/molon/pkg/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 定期向station续约，崩溃之后租约到期，station会清理此boat上的会话登记
+type leaser struct {
+	tomb   *util.LoopTomb
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// 是否曾经续约成功过
+	leased bool
+	// 租约当前的代数，会话登记时带上，原子读写
+	gen int64
+}
+
+func newLeaser() *leaser {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &leaser{
+		tomb:   util.NewLoopTomb(),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (l *leaser) start() {
+	l.tomb.Go(l.loop)
+}
+
+func (l *leaser) stop() {
+	l.cancel()
+	l.tomb.Close() // stop and wait
+}
+
+func (l *leaser) loop(stopC <-chan struct{}) {
+	for {
+		if err := l.renew(); err != nil {
+			plog.Warnf("Renew lease failed: %+v", err)
+		}
+
+		timer := time.NewTimer(global.config.Lease.Interval)
+		select {
+		case <-timer.C:
+		case <-stopC:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (l *leaser) renew() error {
+	ctx, cancel := context.WithTimeout(l.ctx, global.config.Lease.Interval)
+	defer cancel()
+
+	out, err := global.stationCli.Heartbeat(ctx, &stationpb.HeartbeatRequest{
+		BoatId: global.applicationId,
+		Ttl:    ptypes.DurationProto(global.config.Lease.TTL),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// 先更新代数再踢出，之后登记的会话都是新的代数，不会被正在进行的清理删除
+	// 读到旧代数的会话在此之前就已经在sessionStore里了，会一并被踢出
+	atomic.StoreInt64(&l.gen, out.GetLeaseGen())
+
+	// 曾经续约成功过，租约却不存在了，说明心跳中断太久，会话登记已被清理
+	// 这些会话收不到推送了，踢出让客户端重连
+	if out.GetLost() && l.leased {
+		l.kickoutAll()
+	}
+	l.leased = true
+
+	return nil
+}
+
+func (l *leaser) leaseGen() int64 {
+	return atomic.LoadInt64(&l.gen)
+}
+
+func (l *leaser) kickoutAll() {
+	sesses := global.sessionStore.All()
+	plog.Warnf("Lease lost, kickout %d sessions", len(sesses))
+
+	st, _ := status.
+		New(codes.Unavailable, errorpb.Code_SESSION_EXPIRED.String()).
+		WithDetails(&errorpb.Detail{
+			Code: errorpb.Code_SESSION_EXPIRED,
+		})
+	err := errors.WithStack(st.Err())
+
+	// Kickout会等待会话清理完毕，不阻塞心跳
+	for _, sess := range sesses {
+		go sess.Kickout(err)
+	}
+}
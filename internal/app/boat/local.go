@@ -0,0 +1,35 @@
+package boat
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/molon/gomsg/internal/pb/boatpb"
+	"github.com/molon/gomsg/internal/pkg/inproc"
+	"google.golang.org/grpc"
+)
+
+// 进程内直接调用的客户端，all-in-one 模式下使用，需要在Init之后使用
+// 不会经过gRPC的拦截器，CallOption也会被忽略
+func NewLocalClient() boatpb.BoatClient {
+	return &localClient{}
+}
+
+type localClient struct {
+	s grpcServer
+}
+
+func (c *localClient) PushMessages(ctx context.Context, in *boatpb.PushMessagesRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out, err := c.s.PushMessages(ctx, in)
+	return out, inproc.StatusError(err)
+}
+
+func (c *localClient) BoardcastRoom(ctx context.Context, in *boatpb.BoardcastRoomRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out, err := c.s.BoardcastRoom(ctx, in)
+	return out, inproc.StatusError(err)
+}
+
+func (c *localClient) Kickout(ctx context.Context, in *boatpb.KickoutRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out, err := c.s.Kickout(ctx, in)
+	return out, inproc.StatusError(err)
+}
@@ -3,10 +3,14 @@ package boat
 import (
 	"context"
 	"io"
+	"net"
+	"strings"
 	"sync"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_logrus "github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
@@ -105,7 +109,7 @@ func (s *loopServer) LoopV1(stream msgpb.Msg_LoopV1Server) error {
 	}()
 
 	// 服务内会话管理
-	sess := global.sessionStore.NewSession()
+	sess := global.sessionStore.NewSession(ctx)
 	sid := sess.sid
 	plog.Debugf("New session: %s", sid)
 	defer func() {
@@ -114,32 +118,105 @@ func (s *loopServer) LoopV1(stream msgpb.Msg_LoopV1Server) error {
 	}()
 
 	// 登记会话，要传出stream.Context()以便于传递鉴权信息等
-	out, err := global.stationCli.Connect(stream.Context(), &stationpb.ConnectRequest{
-		BoatId: global.applicationId,
-		Sid:    sid,
-	})
+	// 代数要在会话加入sessionStore之后读取，租约丢失时才能确保其被踢出
+	gen := global.leaser.leaseGen()
+	in := &stationpb.ConnectRequest{
+		BoatId:   global.applicationId,
+		Sid:      sid,
+		LeaseGen: gen,
+	}
+	fillClientInfo(stream.Context(), in)
+	out, err := global.stationCli.Connect(stream.Context(), in)
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	defer func(uid string) {
+	defer func(uid, platform string) {
 		// 删除会话登记，因为很可能是因为ctx触发的，但是disconnect是一定要执行的，所以这里不用ctx
 		_, err := global.stationCli.Disconnect(context.Background(), &stationpb.DisconnectRequest{
-			BoatId: global.applicationId,
-			Sid:    sid,
-			Uid:    uid,
+			BoatId:    global.applicationId,
+			Sid:       sid,
+			Uid:       uid,
+			Platform:  platform,
+			LeaseLost: global.leaser.leaseGen() != gen,
 		})
 		if err != nil {
 			plog.Errorf("Disconnect failed: %v", err)
 		}
-	}(out.GetUid())
+	}(out.GetUid(), out.GetPlatform())
 
 	// 更新会话详细信息以备用
 	sess.Update(out.GetUid(), out.GetPlatform())
 
+	sessionsGauge.WithLabelValues(out.GetPlatform()).Inc()
+	defer sessionsGauge.WithLabelValues(out.GetPlatform()).Dec()
+
 	// 执行最终loop
 	return grpcLoop(ctx, sess, stream)
 }
 
+// 从连接里获取客户端信息，客户端通过metadata传递版本和设备ID
+// x-forwarded-for 可被客户端随意伪造，只有对端是可信代理时才采用，取从右往左第一个不可信的地址
+func fillClientInfo(ctx context.Context, in *stationpb.ConnectRequest) {
+	var xff []string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		first := func(key string) string {
+			if vals := md.Get(key); len(vals) > 0 {
+				return vals[0]
+			}
+			return ""
+		}
+
+		in.ClientVersion = first("x-client-version")
+		in.DeviceId = first("x-device-id")
+		in.UserAgent = first("user-agent")
+		// 客户端自行Sync离线消息时关闭连接时的推送
+		in.NoOfflinePush = first("x-offline-push") == "off"
+		for _, val := range md.Get("x-forwarded-for") {
+			for _, hop := range strings.Split(val, ",") {
+				if hop = strings.TrimSpace(hop); hop != "" {
+					xff = append(xff, hop)
+				}
+			}
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		in.RemoteIp = clientIP(host, xff)
+	}
+}
+
+func clientIP(peerIP string, xff []string) string {
+	if !isTrustedProxy(peerIP) {
+		return peerIP
+	}
+
+	ip := peerIP
+	for i := len(xff) - 1; i >= 0; i-- {
+		ip = xff[i]
+		if !isTrustedProxy(ip) {
+			break
+		}
+	}
+	return ip
+}
+
+func isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, ipnet := range global.trustedProxies {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func grpcLoop(ctx context.Context, sess *Session, stream msgpb.Msg_LoopV1Server) error {
 	defer close(sess.doneC)
 
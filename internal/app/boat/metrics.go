@@ -0,0 +1,55 @@
+package boat
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// 推送结果
+const (
+	pushSent      = "sent"   // 无需ack，已放入发送队列
+	pushAcked     = "acked"  // 客户端已ack
+	pushNoAck     = "no_ack" // 等待ack超时
+	pushQueueFull = "queue_full"
+)
+
+var (
+	sessionsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomsg",
+		Subsystem: "boat",
+		Name:      "sessions",
+		Help:      "Online sessions on this boat.",
+	}, []string{"platform"})
+
+	pushesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gomsg",
+		Subsystem: "boat",
+		Name:      "pushes_total",
+		Help:      "Payloads pushed to sessions by result.",
+	}, []string{"platform", "result"})
+
+	ackLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gomsg",
+		Subsystem: "boat",
+		Name:      "ack_latency_seconds",
+		Help:      "Latency from enqueueing a payload to receiving its ack.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"platform"})
+)
+
+// 所有会话发送队列里的消息数之和，持续增长说明客户端收得太慢
+// 在Init里注册，采集时只读传入的store，不碰 global
+func registerSendQueueDepth(store *SessionStore) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "gomsg",
+		Subsystem: "boat",
+		Name:      "send_queue_depth",
+		Help:      "Payloads waiting in the send queues of all sessions.",
+	}, func() float64 {
+		depth := 0
+		for _, sess := range store.All() {
+			depth += len(sess.sendC)
+		}
+		return float64(depth)
+	})
+}
@@ -1,15 +1,20 @@
 package boat
 
 import (
+	"context"
 	"sync"
 	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/molon/gomsg/internal/pb/stationpb"
+	"github.com/molon/gomsg/internal/pkg/audit"
 	"github.com/molon/gomsg/pb/errorpb"
 	"github.com/molon/gomsg/pb/msgpb"
 	"github.com/molon/pkg/errors"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
 	"github.com/rs/xid"
 )
 
@@ -24,8 +29,11 @@ func NewSessionStore() *SessionStore {
 	}
 }
 
-func (ss *SessionStore) NewSession() *Session {
+// ctx结束即会话结束，会话的异步请求也会随之取消
+func (ss *SessionStore) NewSession(ctx context.Context) *Session {
 	sess := &Session{
+		ctx:      ctx,
+		pendingC: make(chan struct{}, global.config.Client.MaxPendingRequests),
 		sid:      xid.New().String(),
 		kickoutC: make(chan struct{}, 1),
 		doneC:    make(chan struct{}, 1),
@@ -47,6 +55,16 @@ func (ss *SessionStore) Get(sid string) *Session {
 	return sess
 }
 
+func (ss *SessionStore) All() []*Session {
+	ss.mu.RLock()
+	sesses := make([]*Session, 0, len(ss.sessions))
+	for _, sess := range ss.sessions {
+		sesses = append(sesses, sess)
+	}
+	ss.mu.RUnlock()
+	return sesses
+}
+
 func (ss *SessionStore) Delete(sid string) {
 	ss.mu.Lock()
 	delete(ss.sessions, sid)
@@ -56,6 +74,10 @@ func (ss *SessionStore) Delete(sid string) {
 type Session struct {
 	mu sync.RWMutex
 
+	ctx context.Context
+	// 进行中的异步请求
+	pendingC chan struct{}
+
 	sid      string
 	uid      string
 	platform string
@@ -69,13 +91,34 @@ type Session struct {
 	ackCs map[string]chan struct{}
 }
 
-func (sess *Session) Send(m *msgpb.ServerPayload, ackWait time.Duration) error {
+func (sess *Session) Send(ctx context.Context, m *msgpb.ServerPayload, ackWait time.Duration) (rerr error) {
+	sess.mu.RLock()
+	uid, platform := sess.uid, sess.platform
+	sess.mu.RUnlock()
+
+	// 有上游trace(例如carrier的推送)才记录，包括入队和等待ack
+	span := opentracing.NoopTracer{}.StartSpan("")
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		span = parent.Tracer().StartSpan("boat.Session.Send", opentracing.ChildOf(parent.Context()))
+		span.SetTag("sid", sess.sid)
+		span.SetTag("need_ack", m.GetNeedAck())
+	}
+	defer func() {
+		if rerr != nil {
+			ext.Error.Set(span, true)
+			span.LogKV("error", rerr.Error())
+		}
+		span.Finish()
+	}()
+
 	// 执行发送，最多50微秒超时吧，此时肯定消息堆积严重了
 	t := time.NewTimer(50 * time.Microsecond)
 	select {
 	case sess.sendC <- m:
 		t.Stop() // for gc
+		span.LogKV("event", "enqueued")
 	case <-t.C:
+		pushesCounter.WithLabelValues(platform, pushQueueFull).Inc()
 		st, _ := status.
 			Newf(codes.Unavailable, "too many msgs sent to the client").
 			WithDetails(&errorpb.Detail{
@@ -85,6 +128,7 @@ func (sess *Session) Send(m *msgpb.ServerPayload, ackWait time.Duration) error {
 	}
 
 	if !m.GetNeedAck() {
+		pushesCounter.WithLabelValues(platform, pushSent).Inc()
 		return nil
 	}
 
@@ -100,12 +144,17 @@ func (sess *Session) Send(m *msgpb.ServerPayload, ackWait time.Duration) error {
 		sess.mu.Unlock()
 	}(seq)
 
+	sentAt := time.Now()
 	t = time.NewTimer(ackWait)
 	select {
 	case <-ackC:
 		t.Stop() // for gc
+		ackLatency.WithLabelValues(platform).Observe(time.Since(sentAt).Seconds())
+		pushesCounter.WithLabelValues(platform, pushAcked).Inc()
+		sess.auditAcked(uid, platform, m)
 		return nil
 	case <-t.C:
+		pushesCounter.WithLabelValues(platform, pushNoAck).Inc()
 		// 这里返回一个特别的错误码，告知调用者是未ack，然后调用者要决定是否要踢除连接或者其他
 		st, _ := status.
 			Newf(codes.Internal, "client ack timeout").
@@ -126,6 +175,33 @@ func (sess *Session) recv(m *msgpb.ClientPayload) error {
 		if ok {
 			ackC <- struct{}{}
 		}
+	case *msgpb.ClientPayload_Read:
+		sess.mu.RLock()
+		uid, platform := sess.uid, sess.platform
+		sess.mu.RUnlock()
+
+		// 不阻塞recv loop，已读回执丢了也无大碍，打印日志即可
+		in := &stationpb.ReadRequest{
+			Sid:      sess.sid,
+			Uid:      uid,
+			Platform: platform,
+			Seqs:     t.Read.GetSeqs(),
+		}
+		if !sess.goRequest(func(ctx context.Context) {
+			if _, err := global.stationCli.Read(ctx, in); err != nil {
+				plog.Warnf("Read failed: %+v", err)
+			}
+		}) {
+			plog.Warnf("Too many pending requests of %s, read dropped", sess.sid)
+		}
+	case *msgpb.ClientPayload_Sync:
+		// 不阻塞recv loop，和其他请求一样受会话生命周期和并发数限制
+		if !sess.goRequest(func(ctx context.Context) {
+			sess.sync(ctx, m.GetSeq(), t.Sync)
+		}) {
+			plog.Warnf("Too many pending requests of %s, sync rejected", sess.sid)
+			sess.rejectSync(m.GetSeq())
+		}
 	case *msgpb.ClientPayload_Sub:
 		// TODO: 还没实现这个玩意
 		return errors.Statusf(codes.Unimplemented, "unimplemented")
@@ -136,6 +212,24 @@ func (sess *Session) recv(m *msgpb.ClientPayload) error {
 	return nil
 }
 
+// 在会话的生命周期内异步执行客户端的请求，有超时，返回false表示进行中的请求太多而被拒绝
+func (sess *Session) goRequest(f func(ctx context.Context)) bool {
+	select {
+	case sess.pendingC <- struct{}{}:
+	default:
+		return false
+	}
+
+	go func() {
+		defer func() { <-sess.pendingC }()
+
+		ctx, cancel := context.WithTimeout(sess.ctx, global.config.Client.RequestTimeout)
+		defer cancel()
+		f(ctx)
+	}()
+	return true
+}
+
 func (sess *Session) Update(uid, platform string) {
 	sess.mu.Lock()
 	sess.uid = uid
@@ -165,3 +259,22 @@ func (sess *Session) loopError() error {
 	sess.mu.RUnlock()
 	return loopErr
 }
+
+// 客户端ack的是整个payload，其中的消息都记为acked，经缓冲写入不拖慢推送
+func (sess *Session) auditAcked(uid string, platform string, m *msgpb.ServerPayload) {
+	msgs := m.GetMsgsWrapper().GetMsgs()
+	if global.audit == nil || len(msgs) <= 0 {
+		return
+	}
+
+	seqs := make([]string, len(msgs))
+	for i, msg := range msgs {
+		seqs[i] = msg.GetSeq()
+	}
+	global.audit.RecordAsync(uid, seqs, audit.Event{
+		Stage:    audit.StageAcked,
+		Platform: platform,
+		Sid:      sess.sid,
+		Bid:      global.applicationId,
+	})
+}
@@ -0,0 +1,76 @@
+package boat
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/molon/gomsg/internal/pkg/inproc"
+	"github.com/molon/gomsg/pb/errorpb"
+	"github.com/molon/gomsg/pb/msgpb"
+	"github.com/rs/xid"
+)
+
+// 分页拉取离线消息，只读取不删除，客户端自行控制节奏
+func (sess *Session) sync(ctx context.Context, seq string, in *msgpb.SyncRequest) {
+	sess.mu.RLock()
+	uid, platform := sess.uid, sess.platform
+	sess.mu.RUnlock()
+
+	cfg := global.config.Sync
+	limit := int64(in.GetLimit())
+	if limit <= 0 {
+		limit = cfg.DefaultLimit
+	}
+	if limit > cfg.MaxLimit {
+		limit = cfg.MaxLimit
+	}
+
+	resp := &msgpb.SyncResponse{
+		Seq: seq,
+	}
+
+	msgs, next, err := global.offstore.Scan(ctx, uid, platform, cfg.Expire, in.GetCursor(), limit)
+	if err != nil {
+		st := status.Convert(inproc.StatusError(err))
+		if st.Code() == codes.InvalidArgument {
+			resp.Code = errorpb.Code_INVALID_CURSOR
+		} else {
+			plog.Warnf("Sync offline messages of %s(%s) failed: %+v", uid, platform, err)
+			resp.Code = errorpb.Code_UNKNOWN
+		}
+		resp.Msg = st.Message()
+	} else {
+		resp.Msgs = msgs
+		resp.NextCursor = next
+	}
+
+	if err := sess.Send(ctx, &msgpb.ServerPayload{
+		Seq: xid.New().String(),
+		Body: &msgpb.ServerPayload_SyncResp{
+			SyncResp: resp,
+		},
+	}, 0); err != nil {
+		plog.Warnf("Send sync response to %s failed: %+v", sess.sid, err)
+	}
+}
+
+// 告知客户端请求被拒绝，稍后重试，Send入队不会阻塞多久，直接在recv loop里发送
+func (sess *Session) rejectSync(seq string) {
+	ctx, cancel := context.WithTimeout(sess.ctx, global.config.Client.RequestTimeout)
+	defer cancel()
+
+	if err := sess.Send(ctx, &msgpb.ServerPayload{
+		Seq: xid.New().String(),
+		Body: &msgpb.ServerPayload_SyncResp{
+			SyncResp: &msgpb.SyncResponse{
+				Seq:  seq,
+				Code: errorpb.Code_TOO_MANY_REQUESTS,
+				Msg:  "too many pending requests",
+			},
+		},
+	}, 0); err != nil {
+		plog.Warnf("Send sync response to %s failed: %+v", sess.sid, err)
+	}
+}
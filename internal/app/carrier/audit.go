@@ -0,0 +1,72 @@
+package carrier
+
+import (
+	"github.com/molon/gomsg/internal/pb/mqpb"
+	"github.com/molon/gomsg/internal/pkg/audit"
+	"github.com/molon/gomsg/internal/pkg/sessionstore"
+	"github.com/molon/gomsg/pb/msgpb"
+)
+
+// 记录某用户若干消息的同一审计事件，未开启审计时 global.audit 为 nil，什么都不做
+// 都在消费路径上，经缓冲批量写入
+func auditMsgs(uid string, msgs []*msgpb.Message, ev audit.Event) {
+	if global.audit == nil || len(msgs) <= 0 {
+		return
+	}
+
+	seqs := make([]string, len(msgs))
+	for i, msg := range msgs {
+		seqs[i] = msg.GetSeq()
+	}
+	global.audit.RecordAsync(uid, seqs, ev)
+}
+
+func auditSeqs(uid string, seqs []string, ev audit.Event) {
+	global.audit.RecordAsync(uid, seqs, ev)
+}
+
+// 会话相关的审计事件
+func sessionEvent(stage string, sess sessionstore.Session, detail string) audit.Event {
+	return audit.Event{
+		Stage:    stage,
+		Platform: sess.Platform,
+		Sid:      sess.Sid,
+		Bid:      sess.Bid,
+		Detail:   detail,
+	}
+}
+
+// 成功推送给会话，和 deliveredReceipts 对应，acked 由boat在收到客户端的ack时记录
+func auditPushed(sess sessionstore.Session, msgs []*msgpb.Message) {
+	auditMsgs(sess.Uid, msgs, sessionEvent(audit.StagePushed, sess, ""))
+}
+
+// 离线消息过期或者超出最大数目被清理，和 cleanedReceipts 对应
+func auditOfflineCleaned(uid string, platformToExpiredSeqs map[string][]string, platformToEvictedSeqs map[string][]string) {
+	for platform, seqs := range platformToExpiredSeqs {
+		auditSeqs(uid, seqs, audit.Event{
+			Stage:    audit.StageExpired,
+			Platform: platform,
+			Detail:   "offline",
+		})
+	}
+	for platform, seqs := range platformToEvictedSeqs {
+		auditSeqs(uid, seqs, audit.Event{
+			Stage:    audit.StageEvicted,
+			Platform: platform,
+		})
+	}
+}
+
+// 被丢进死信队列，和 dlqReceipts 对应，只有发给uid的消息才有意义
+func auditDeadLetter(pb *mqpb.Payload) {
+	toUid := pb.GetToUid()
+	if toUid == nil {
+		return
+	}
+
+	auditMsgs(toUid.GetUid(), toUid.GetMsgs(), audit.Event{
+		Stage:  audit.StageDeadLetter,
+		Detail: "retry=" + retryLabel(pb.GetRetryCount()),
+	})
+}
@@ -0,0 +1,166 @@
+package carrier
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/molon/gomsg/internal/pb/mqpb"
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/rs/xid"
+)
+
+// 将短时间内同一uid的ToUid消息合并起来一并投递，减少对boat的调用以及ack等待次数
+// 这依赖于同一uid的消息会落入同一分区，即station那边需要开启 producer.partition-by-uid
+type coalescer struct {
+	ctx         context.Context
+	window      time.Duration
+	maxPayloads int
+
+	mu      sync.Mutex
+	batches map[string]*batch
+	c       chan *batch
+}
+
+// 一批待合并的ToUid消息
+type batch struct {
+	uid      string
+	payloads []*mqpb.Payload
+	msgs     []mq.Delivery
+
+	timer   *time.Timer
+	flushed bool
+}
+
+// window<=0 表示不开启合并，此时返回nil
+func newCoalescer(ctx context.Context, window time.Duration, maxPayloads int) *coalescer {
+	if window <= 0 {
+		return nil
+	}
+
+	return &coalescer{
+		ctx:         ctx,
+		window:      window,
+		maxPayloads: maxPayloads,
+		batches:     map[string]*batch{},
+		c:           make(chan *batch),
+	}
+}
+
+// 窗口期结束的批次会从这里吐出来
+func (co *coalescer) flushC() <-chan *batch {
+	if co == nil {
+		return nil // 永远阻塞
+	}
+	return co.c
+}
+
+// 尝试攒下消息，absorbed 为 true 表示已经攒下了，调用者无需再处理
+// 若返回了 flushed 则调用者需要立即处理此批次
+func (co *coalescer) add(m mq.Delivery, pb *mqpb.Payload) (flushed *batch, absorbed bool) {
+	if co == nil {
+		return nil, false
+	}
+
+	// 只合并首次消费的ToUid消息，重试的消息已经是部分平台的了，没必要合并
+	toUid := pb.GetToUid()
+	if toUid == nil || pb.GetRetryCount() > 0 {
+		return nil, false
+	}
+
+	co.mu.Lock()
+	defer co.mu.Unlock()
+
+	b, ok := co.batches[toUid.GetUid()]
+	if ok && !b.compatible(toUid) {
+		// 不能合并的话，之前攒的就先吐出去
+		co.takeLocked(b)
+		flushed = b
+		ok = false
+	}
+
+	if !ok {
+		b = &batch{
+			uid: toUid.GetUid(),
+		}
+		b.timer = time.AfterFunc(co.window, func() {
+			co.mu.Lock()
+			if b.flushed {
+				co.mu.Unlock()
+				return
+			}
+			co.takeLocked(b)
+			co.mu.Unlock()
+
+			select {
+			case co.c <- b:
+			case <-co.ctx.Done():
+				// 结束时未处理的消息都没有ack，mq那边会重新投递
+			}
+		})
+		co.batches[b.uid] = b
+	}
+
+	b.payloads = append(b.payloads, pb)
+	b.msgs = append(b.msgs, m)
+
+	// 攒够了就直接吐出去，consumer.batch-max-payloads>=2，所以这里flushed一定为nil
+	if len(b.payloads) >= co.maxPayloads {
+		co.takeLocked(b)
+		return b, true
+	}
+
+	return flushed, true
+}
+
+func (co *coalescer) takeLocked(b *batch) {
+	b.flushed = true
+	b.timer.Stop()
+	if co.batches[b.uid] == b {
+		delete(co.batches, b.uid)
+	}
+}
+
+// 平台配置以及reserve都相同的才能合并
+func (b *batch) compatible(toUid *mqpb.ToUid) bool {
+	first := b.payloads[0].GetToUid()
+	return proto.Equal(first.GetPlatformConfig(), toUid.GetPlatformConfig()) &&
+		proto.Equal(first.GetReserve(), toUid.GetReserve())
+}
+
+// 合并为一个ToUid消息，消息按顺序排列且去重
+func (b *batch) payload() *mqpb.Payload {
+	if len(b.payloads) == 1 {
+		return b.payloads[0]
+	}
+
+	first := b.payloads[0]
+	toUid := &mqpb.ToUid{
+		Uid:            b.uid,
+		PlatformConfig: first.GetToUid().GetPlatformConfig(),
+		Reserve:        first.GetToUid().GetReserve(),
+	}
+
+	seqs := map[string]bool{}
+	for _, pb := range b.payloads {
+		for _, msg := range pb.GetToUid().GetMsgs() {
+			if seqs[msg.GetSeq()] {
+				continue
+			}
+			seqs[msg.GetSeq()] = true
+			toUid.Msgs = append(toUid.Msgs, msg)
+		}
+	}
+
+	// 以最早到达的消息生产时间为准，离线存储的过期时间也就按保守的算
+	// trace也只能延续最早到达的那个
+	return &mqpb.Payload{
+		Seq:       xid.New().String(),
+		Timestamp: first.GetTimestamp(),
+		Trace:     first.GetTrace(),
+		Body: &mqpb.Payload_ToUid{
+			ToUid: toUid,
+		},
+	}
+}
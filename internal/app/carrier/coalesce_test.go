@@ -0,0 +1,153 @@
+package carrier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/molon/gomsg/internal/pb/mqpb"
+	"github.com/molon/gomsg/pb/msgpb"
+	"github.com/molon/gomsg/pb/pushpb"
+)
+
+type nopDelivery struct{}
+
+func (nopDelivery) Topic() string { return "msg" }
+func (nopDelivery) Key() []byte   { return nil }
+func (nopDelivery) Value() []byte { return nil }
+func (nopDelivery) Ack() error    { return nil }
+func (nopDelivery) Nack() error   { return nil }
+
+func toUidPayload(seq string, uid string, msgSeqs ...string) *mqpb.Payload {
+	toUid := &mqpb.ToUid{
+		Uid: uid,
+	}
+	for _, s := range msgSeqs {
+		toUid.Msgs = append(toUid.Msgs, &msgpb.Message{Seq: s})
+	}
+	return &mqpb.Payload{
+		Seq:       seq,
+		Timestamp: ptypes.TimestampNow(),
+		Trace:     map[string]string{"payload": seq},
+		Body: &mqpb.Payload_ToUid{
+			ToUid: toUid,
+		},
+	}
+}
+
+func msgSeqs(pb *mqpb.Payload) []string {
+	var ret []string
+	for _, msg := range pb.GetToUid().GetMsgs() {
+		ret = append(ret, msg.GetSeq())
+	}
+	return ret
+}
+
+func newTestCoalescer(t *testing.T, window time.Duration, maxPayloads int) *coalescer {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return newCoalescer(ctx, window, maxPayloads)
+}
+
+func TestCoalesceFlushOnTimer(t *testing.T) {
+	co := newTestCoalescer(t, 20*time.Millisecond, 10)
+
+	for _, pb := range []*mqpb.Payload{
+		toUidPayload("p1", "u1", "m1"),
+		toUidPayload("p2", "u1", "m2"),
+	} {
+		if flushed, absorbed := co.add(nopDelivery{}, pb); flushed != nil || !absorbed {
+			t.Fatalf("add %s: got %v %v", pb.GetSeq(), flushed, absorbed)
+		}
+	}
+
+	select {
+	case b := <-co.flushC():
+		if b.uid != "u1" || len(b.payloads) != 2 || len(b.msgs) != 2 {
+			t.Fatalf("flushed: got uid %s with %d payloads", b.uid, len(b.payloads))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("batch not flushed by timer")
+	}
+}
+
+func TestCoalesceFlushOnMaxPayloads(t *testing.T) {
+	co := newTestCoalescer(t, time.Hour, 2)
+
+	if flushed, absorbed := co.add(nopDelivery{}, toUidPayload("p1", "u1", "m1")); flushed != nil || !absorbed {
+		t.Fatalf("add p1: got %v %v", flushed, absorbed)
+	}
+	flushed, absorbed := co.add(nopDelivery{}, toUidPayload("p2", "u1", "m2"))
+	if flushed == nil || !absorbed || len(flushed.payloads) != 2 {
+		t.Fatalf("add p2: got %v %v", flushed, absorbed)
+	}
+
+	// 已经吐出去的批次，计时器不能再吐一次
+	select {
+	case b := <-co.flushC():
+		t.Fatalf("unexpected flush of %s", b.uid)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestCoalesceSkip(t *testing.T) {
+	var nilCo *coalescer
+	if flushed, absorbed := nilCo.add(nopDelivery{}, toUidPayload("p1", "u1", "m1")); flushed != nil || absorbed {
+		t.Fatalf("nil coalescer: got %v %v", flushed, absorbed)
+	}
+
+	co := newTestCoalescer(t, time.Hour, 10)
+	retried := toUidPayload("p1", "u1", "m1")
+	retried.RetryCount = 1
+	if flushed, absorbed := co.add(nopDelivery{}, retried); flushed != nil || absorbed {
+		t.Fatalf("retried payload: got %v %v", flushed, absorbed)
+	}
+}
+
+// 平台配置不同的不能合并，之前攒的先吐出去
+func TestCoalesceIncompatible(t *testing.T) {
+	co := newTestCoalescer(t, time.Hour, 10)
+
+	co.add(nopDelivery{}, toUidPayload("p1", "u1", "m1"))
+
+	pb := toUidPayload("p2", "u1", "m2")
+	pb.GetToUid().PlatformConfig = &pushpb.PlatformConfig{Platforms: []string{"mobile"}}
+	flushed, absorbed := co.add(nopDelivery{}, pb)
+	if flushed == nil || !absorbed || len(flushed.payloads) != 1 || flushed.payloads[0].GetSeq() != "p1" {
+		t.Fatalf("add p2: got %v %v", flushed, absorbed)
+	}
+}
+
+func TestBatchPayload(t *testing.T) {
+	p1 := toUidPayload("p1", "u1", "m1", "m2")
+	p2 := toUidPayload("p2", "u1", "m2", "m3")
+	p2.Timestamp = ptypes.TimestampNow()
+	p2.Timestamp.Seconds += 10
+
+	b := &batch{
+		uid:      "u1",
+		payloads: []*mqpb.Payload{p1, p2},
+	}
+	pb := b.payload()
+
+	if got := msgSeqs(pb); len(got) != 3 || got[0] != "m1" || got[1] != "m2" || got[2] != "m3" {
+		t.Fatalf("msgs: got %v, want [m1 m2 m3]", got)
+	}
+	// 合并出来的是新消息，需要新的seq
+	if pb.GetSeq() == "" || pb.GetSeq() == p1.GetSeq() || pb.GetSeq() == p2.GetSeq() {
+		t.Fatalf("seq: got %q", pb.GetSeq())
+	}
+	if pb.GetTimestamp() != p1.GetTimestamp() {
+		t.Fatalf("timestamp: got %v, want %v", pb.GetTimestamp(), p1.GetTimestamp())
+	}
+	if pb.GetTrace()["payload"] != "p1" {
+		t.Fatalf("trace: got %v, want the first one", pb.GetTrace())
+	}
+
+	// 只有一个的话原样返回
+	single := &batch{uid: "u1", payloads: []*mqpb.Payload{p1}}
+	if single.payload() != p1 {
+		t.Fatal("single payload should be returned as is")
+	}
+}
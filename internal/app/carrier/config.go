@@ -6,16 +6,39 @@ import (
 	"github.com/molon/pkg/errors"
 )
 
+// 整理后的平台配置，未单独配置的项使用全局配置
 type platformConfig struct {
-	name            string
-	maxOfflineCount int
-	// 暂时就这一个配置
+	name                 string
+	maxOfflineCount      int
+	ackWait              time.Duration
+	offlineExpire        time.Duration
+	allowOffline         bool
+	notificationProvider string
+	maxRetries           int64
+}
+
+// 配置文件里的单个平台配置，零值表示使用全局配置
+type PlatformConfig struct {
+	// 最大离线消息数目，-1表示无限制，未设置则使用 platform.max-offline-counts 里的
+	MaxOfflineCount *int `mapstructure:"max-offline-count"`
+	// 等待客户端ack的时间
+	AckWait time.Duration `mapstructure:"ack-wait"`
+	// 离线消息过期时间
+	OfflineExpire time.Duration `mapstructure:"offline-expire"`
+	// 不允许离线存储
+	DisableOffline bool `mapstructure:"disable-offline"`
+	// 通知提供方，为空则不投递通知
+	NotificationProvider string `mapstructure:"notification-provider"`
+	// 此平台的最大重试次数，达到后即转为离线处理，不得大于 consumer.max-retries
+	MaxRetries int64 `mapstructure:"max-retries"`
 }
 
 type Config struct {
 	Boat struct {
-		NamePrefix string        `mapstructure:"name-prefix"`
-		AckWait    time.Duration `mapstructure:"ack-wait"`
+		NamePrefix      string        `mapstructure:"name-prefix"`
+		AckWait         time.Duration `mapstructure:"ack-wait"`
+		PushConcurrency int           `mapstructure:"push-concurrency"`
+		PushTimeout     time.Duration `mapstructure:"push-timeout"`
 	}
 	Consumer struct {
 		Concurrency      int
@@ -25,15 +48,35 @@ type Config struct {
 		RetryDelay       time.Duration `mapstructure:"retry-delay"`
 		MaxRetries       int64         `mapstructure:"max-retries"`
 		DLQTopic         string        `mapstructure:"dlq-topic"`
+		BatchWindow      time.Duration `mapstructure:"batch-window"`
+		BatchMaxPayloads int           `mapstructure:"batch-max-payloads"`
+		Ordered          bool
+		OrderedBlockTTL  time.Duration `mapstructure:"ordered-block-ttl"`
+		// 高优先级消息的topic，为空则不单独消费
+		PriorityTopic       string `mapstructure:"priority-topic"`
+		PriorityConcurrency int    `mapstructure:"priority-concurrency"`
 	}
 	Platform struct {
+		// 若设置了 Configs 则以其为准，否则以 Names 和 MaxOfflineCounts 为准
 		Names            []string
 		MaxOfflineCounts map[string]int `mapstructure:"max-offline-counts"`
+		Configs          map[string]PlatformConfig
 	}
 	Offline struct {
 		BatchCount int64 `mapstructure:"batch-count"`
 		Expire     time.Duration
 	}
+	Notification struct {
+		Topic string
+	}
+	Receipt struct {
+		// 为空则不投递回执
+		Topic string
+	}
+	MQ struct {
+		// kafka/redis/nats，all-in-one 模式下为 memory
+		Driver string
+	}
 
 	pcfgs map[string]platformConfig
 }
@@ -43,6 +86,14 @@ func (cfg *Config) Validate() error {
 		return errors.Errorf("boat.ack-wait must >= 250ms")
 	}
 
+	if cfg.Boat.PushConcurrency <= 0 {
+		return errors.Errorf("boat.push-concurrency must > 0")
+	}
+
+	if cfg.Boat.PushTimeout < cfg.Boat.AckWait {
+		return errors.Errorf("boat.push-timeout must >= boat.ack-wait")
+	}
+
 	if cfg.Consumer.Concurrency <= 0 {
 		return errors.Errorf("consumer.concurrency must > 0")
 	}
@@ -55,11 +106,64 @@ func (cfg *Config) Validate() error {
 		return errors.Errorf("consumer.retry-concurrency must > 0")
 	}
 
+	if len(cfg.Consumer.PriorityTopic) > 0 {
+		if cfg.Consumer.PriorityTopic == cfg.Consumer.Topic || cfg.Consumer.PriorityTopic == cfg.Consumer.RetryTopic {
+			return errors.Errorf("consumer.priority-topic cant equal to consumer.topic or consumer.retry-topic")
+		}
+
+		if cfg.Consumer.PriorityConcurrency <= 0 {
+			return errors.Errorf("consumer.priority-concurrency must > 0")
+		}
+
+		// 分开消费的话就无法保证同一用户的消息有序了
+		if cfg.Consumer.Ordered {
+			return errors.Errorf("consumer.priority-topic is not supported with consumer.ordered")
+		}
+	}
+
 	if cfg.Consumer.MaxRetries <= 0 {
 		return errors.Errorf("consumer.max-retries must > 0")
 	}
 
-	if len(cfg.Platform.Names) <= 0 {
+	// 只有kafka能保证同一key的消息被同一消费者按顺序消费
+	if cfg.MQ.Driver != "kafka" {
+		if cfg.Consumer.Ordered {
+			return errors.Errorf("consumer.ordered requires mq.driver kafka")
+		}
+		if cfg.Consumer.BatchWindow > 0 {
+			return errors.Errorf("consumer.batch-window requires mq.driver kafka")
+		}
+	}
+
+	if cfg.Consumer.BatchWindow > 0 {
+		if cfg.Consumer.BatchWindow > cfg.Boat.AckWait {
+			return errors.Errorf("consumer.batch-window must <= boat.ack-wait")
+		}
+
+		if cfg.Consumer.BatchMaxPayloads < 2 {
+			return errors.Errorf("consumer.batch-max-payloads must >= 2")
+		}
+	}
+
+	if cfg.Consumer.Ordered {
+		if cfg.Consumer.BatchWindow > 0 {
+			return errors.Errorf("consumer.batch-window is not supported with consumer.ordered")
+		}
+
+		if cfg.Consumer.OrderedBlockTTL < cfg.Consumer.RetryDelay {
+			return errors.Errorf("consumer.ordered-block-ttl must >= consumer.retry-delay")
+		}
+	}
+
+	names := cfg.Platform.Names
+	if len(cfg.Platform.Configs) > 0 {
+		names = []string{}
+		for name := range cfg.Platform.Configs {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) <= 0 {
 		return errors.Errorf("platform.names is empty")
 	}
 
@@ -73,16 +177,132 @@ func (cfg *Config) Validate() error {
 
 	// 整理平台配置为便利版本
 	cfg.pcfgs = map[string]platformConfig{}
-	for _, name := range cfg.Platform.Names {
+	for _, name := range names {
+		pcfg, err := cfg.tidyPlatformConfig(name)
+		if err != nil {
+			return err
+		}
+
+		cfg.pcfgs[name] = pcfg
+	}
+	return nil
+}
+
+func (cfg *Config) tidyPlatformConfig(name string) (platformConfig, error) {
+	c := cfg.Platform.Configs[name]
+
+	pcfg := platformConfig{
+		name:                 name,
+		ackWait:              cfg.Boat.AckWait,
+		offlineExpire:        cfg.Offline.Expire,
+		allowOffline:         !c.DisableOffline,
+		notificationProvider: c.NotificationProvider,
+		maxRetries:           cfg.Consumer.MaxRetries,
+	}
+
+	if c.MaxOfflineCount != nil {
+		pcfg.maxOfflineCount = *c.MaxOfflineCount
+	} else {
 		maxOfflineCount, ok := cfg.Platform.MaxOfflineCounts[name]
 		if !ok {
-			return errors.Errorf("max-offline-count of %s is not set", name)
+			return pcfg, errors.Errorf("max-offline-count of %s is not set", name)
 		}
+		pcfg.maxOfflineCount = maxOfflineCount
+	}
 
-		cfg.pcfgs[name] = platformConfig{
-			name:            name,
-			maxOfflineCount: maxOfflineCount,
+	if c.AckWait > 0 {
+		if c.AckWait < 250*time.Millisecond {
+			return pcfg, errors.Errorf("ack-wait of %s must >= 250ms", name)
+		}
+		if c.AckWait > cfg.Boat.PushTimeout {
+			return pcfg, errors.Errorf("ack-wait of %s must <= boat.push-timeout", name)
 		}
+		pcfg.ackWait = c.AckWait
 	}
-	return nil
+
+	if c.OfflineExpire > 0 {
+		pcfg.offlineExpire = c.OfflineExpire
+	}
+
+	if c.MaxRetries > 0 {
+		if c.MaxRetries > cfg.Consumer.MaxRetries {
+			return pcfg, errors.Errorf("max-retries of %s must <= consumer.max-retries", name)
+		}
+		pcfg.maxRetries = c.MaxRetries
+	}
+
+	if len(pcfg.notificationProvider) > 0 && len(cfg.Notification.Topic) < 1 {
+		return pcfg, errors.Errorf("notification.topic must be non-empty when notification-provider of %s is set", name)
+	}
+
+	return pcfg, nil
+}
+
+// 获取某平台的配置，若此平台已经不在配置里了，则使用全局配置
+func (cfg *Config) platformConfig(name string) platformConfig {
+	if pcfg, ok := cfg.pcfgs[name]; ok {
+		return pcfg
+	}
+
+	return platformConfig{
+		name:            name,
+		maxOfflineCount: -1,
+		ackWait:         cfg.Boat.AckWait,
+		offlineExpire:   cfg.Offline.Expire,
+		allowOffline:    true,
+		maxRetries:      cfg.Consumer.MaxRetries,
+	}
+}
+
+// 将运行时无法变更的配置项还原为旧值，返回有变更的项
+func (cfg *Config) keepImmutable(old *Config) []string {
+	changed := []string{}
+
+	if cfg.MQ.Driver != old.MQ.Driver {
+		changed = append(changed, "mq.driver")
+		cfg.MQ.Driver = old.MQ.Driver
+	}
+	if cfg.Boat.NamePrefix != old.Boat.NamePrefix {
+		changed = append(changed, "boat.name-prefix")
+		cfg.Boat.NamePrefix = old.Boat.NamePrefix
+	}
+	// 消费相关的这些都在启动时就确定了
+	if cfg.Consumer.Topic != old.Consumer.Topic {
+		changed = append(changed, "consumer.topic")
+		cfg.Consumer.Topic = old.Consumer.Topic
+	}
+	if cfg.Consumer.Concurrency != old.Consumer.Concurrency {
+		changed = append(changed, "consumer.concurrency")
+		cfg.Consumer.Concurrency = old.Consumer.Concurrency
+	}
+	if cfg.Consumer.RetryTopic != old.Consumer.RetryTopic {
+		changed = append(changed, "consumer.retry-topic")
+		cfg.Consumer.RetryTopic = old.Consumer.RetryTopic
+	}
+	if cfg.Consumer.RetryConcurrency != old.Consumer.RetryConcurrency {
+		changed = append(changed, "consumer.retry-concurrency")
+		cfg.Consumer.RetryConcurrency = old.Consumer.RetryConcurrency
+	}
+	if cfg.Consumer.BatchWindow != old.Consumer.BatchWindow {
+		changed = append(changed, "consumer.batch-window")
+		cfg.Consumer.BatchWindow = old.Consumer.BatchWindow
+	}
+	if cfg.Consumer.BatchMaxPayloads != old.Consumer.BatchMaxPayloads {
+		changed = append(changed, "consumer.batch-max-payloads")
+		cfg.Consumer.BatchMaxPayloads = old.Consumer.BatchMaxPayloads
+	}
+	if cfg.Consumer.Ordered != old.Consumer.Ordered {
+		changed = append(changed, "consumer.ordered")
+		cfg.Consumer.Ordered = old.Consumer.Ordered
+	}
+	if cfg.Consumer.PriorityTopic != old.Consumer.PriorityTopic {
+		changed = append(changed, "consumer.priority-topic")
+		cfg.Consumer.PriorityTopic = old.Consumer.PriorityTopic
+	}
+	if cfg.Consumer.PriorityConcurrency != old.Consumer.PriorityConcurrency {
+		changed = append(changed, "consumer.priority-concurrency")
+		cfg.Consumer.PriorityConcurrency = old.Consumer.PriorityConcurrency
+	}
+
+	return changed
 }
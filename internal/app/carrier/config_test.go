@@ -0,0 +1,151 @@
+package carrier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func testConfig() Config {
+	cfg := Config{}
+	cfg.Boat.NamePrefix = "boat-"
+	cfg.Boat.AckWait = time.Second
+	cfg.Boat.PushConcurrency = 10
+	cfg.Boat.PushTimeout = 5 * time.Second
+	cfg.Consumer.Concurrency = 10
+	cfg.Consumer.Topic = "msg"
+	cfg.Consumer.RetryTopic = "msg-retry"
+	cfg.Consumer.RetryConcurrency = 10
+	cfg.Consumer.RetryDelay = time.Second
+	cfg.Consumer.MaxRetries = 5
+	cfg.Platform.Names = []string{"mobile", "desktop"}
+	cfg.Platform.MaxOfflineCounts = map[string]int{"mobile": -1, "desktop": 80}
+	cfg.Offline.BatchCount = 100
+	cfg.Offline.Expire = time.Hour
+	cfg.MQ.Driver = "kafka"
+	return cfg
+}
+
+func TestTidyPlatformConfig(t *testing.T) {
+	oneHundred := 100
+
+	cases := []struct {
+		name  string
+		pcfg  PlatformConfig
+		valid bool
+	}{
+		{"default", PlatformConfig{}, true},
+		{"ack-wait", PlatformConfig{AckWait: 2 * time.Second}, true},
+		{"ack-wait too short", PlatformConfig{AckWait: 100 * time.Millisecond}, false},
+		{"ack-wait equal to push-timeout", PlatformConfig{AckWait: 5 * time.Second}, true},
+		{"ack-wait beyond push-timeout", PlatformConfig{AckWait: 6 * time.Second}, false},
+		{"max-retries", PlatformConfig{MaxRetries: 2}, true},
+		{"max-retries equal to the ceiling", PlatformConfig{MaxRetries: 5}, true},
+		{"max-retries beyond the ceiling", PlatformConfig{MaxRetries: 6}, false},
+		{"notification-provider without topic", PlatformConfig{NotificationProvider: "apns"}, false},
+		{"max-offline-count", PlatformConfig{MaxOfflineCount: &oneHundred}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Platform.Configs = map[string]PlatformConfig{"mobile": c.pcfg}
+			err := cfg.Validate()
+			if c.valid && err != nil {
+				t.Fatalf("Validate: %+v", err)
+			}
+			if !c.valid && err == nil {
+				t.Fatal("Validate: want error")
+			}
+		})
+	}
+}
+
+// 未单独配置的项使用全局配置，不在 Configs 里的平台也使用全局配置
+func TestPlatformConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.Platform.Configs = map[string]PlatformConfig{
+		"mobile":  {AckWait: 2 * time.Second, MaxRetries: 2, DisableOffline: true},
+		"desktop": {OfflineExpire: 2 * time.Hour},
+	}
+	// 设置了 Configs 的话 max-offline-count 仍可从 MaxOfflineCounts 获取
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %+v", err)
+	}
+
+	mobile := cfg.platformConfig("mobile")
+	if mobile.ackWait != 2*time.Second || mobile.maxRetries != 2 || mobile.allowOffline ||
+		mobile.offlineExpire != time.Hour || mobile.maxOfflineCount != -1 {
+		t.Fatalf("mobile: got %+v", mobile)
+	}
+
+	desktop := cfg.platformConfig("desktop")
+	if desktop.ackWait != time.Second || desktop.maxRetries != 5 || !desktop.allowOffline ||
+		desktop.offlineExpire != 2*time.Hour || desktop.maxOfflineCount != 80 {
+		t.Fatalf("desktop: got %+v", desktop)
+	}
+
+	web := cfg.platformConfig("web")
+	if web.ackWait != time.Second || web.maxRetries != 5 || !web.allowOffline || web.maxOfflineCount != -1 {
+		t.Fatalf("web: got %+v", web)
+	}
+}
+
+func initReloadable(t *testing.T, cfg Config) {
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %+v", err)
+	}
+
+	plog = logrus.New()
+	plog.SetLevel(logrus.ErrorLevel)
+	global = &globalCtx{}
+	global.config.Store(&cfg)
+}
+
+func TestReloadKeepImmutable(t *testing.T) {
+	initReloadable(t, testConfig())
+
+	cfg := testConfig()
+	cfg.MQ.Driver = "redis"
+	cfg.Boat.NamePrefix = "other-"
+	cfg.Consumer.Topic = "other"
+	cfg.Consumer.Concurrency = 20
+	// 在redis下是不合法的，但会被还原为旧值，所以按kafka校验
+	cfg.Consumer.Ordered = true
+	cfg.Consumer.OrderedBlockTTL = time.Minute
+	// 可以热更新的
+	cfg.Boat.AckWait = 2 * time.Second
+	cfg.Consumer.MaxRetries = 8
+	if err := Reload(cfg); err != nil {
+		t.Fatalf("Reload: %+v", err)
+	}
+
+	got := global.cfg()
+	if got.MQ.Driver != "kafka" || got.Boat.NamePrefix != "boat-" || got.Consumer.Topic != "msg" ||
+		got.Consumer.Concurrency != 10 || got.Consumer.Ordered {
+		t.Fatalf("immutable fields changed: %+v", got)
+	}
+	if got.Boat.AckWait != 2*time.Second || got.Consumer.MaxRetries != 8 {
+		t.Fatalf("mutable fields not reloaded: %+v", got)
+	}
+	if pcfg := got.platformConfig("mobile"); pcfg.ackWait != 2*time.Second || pcfg.maxRetries != 8 {
+		t.Fatalf("platform config not reloaded: %+v", pcfg)
+	}
+}
+
+// 校验不通过的继续使用旧配置
+func TestReloadInvalid(t *testing.T) {
+	initReloadable(t, testConfig())
+	old := global.cfg()
+
+	cfg := testConfig()
+	cfg.Consumer.MaxRetries = 3
+	cfg.Platform.Configs = map[string]PlatformConfig{"mobile": {MaxRetries: 5}}
+	if err := Reload(cfg); err == nil {
+		t.Fatal("Reload: want error")
+	}
+	if global.cfg() != old {
+		t.Fatal("config replaced by an invalid one")
+	}
+}
@@ -2,23 +2,27 @@ package carrier
 
 import (
 	"context"
+	"hash/fnv"
 	"time"
 
-	"github.com/Shopify/sarama"
 	"github.com/molon/pkg/errors"
 	"github.com/sirupsen/logrus"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/molon/gomsg/internal/pb/mqpb"
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/gomsg/internal/pkg/mqtrace"
 	"github.com/molon/pkg/util"
-	"github.com/uber-go/kafka-client/kafka"
 )
 
 type consumer struct {
-	kp      sarama.SyncProducer
-	kc      kafka.Consumer
-	retryKc kafka.Consumer
+	mp      mq.Producer
+	mc      mq.Consumer
+	retryMc mq.Consumer
+	// 高优先级消息的消费，可为nil
+	priorityMc mq.Consumer
+	co         *coalescer
 
 	tomb   *util.LoopTomb
 	ctx    context.Context
@@ -27,16 +31,19 @@ type consumer struct {
 
 func newConsumer(
 	ctx context.Context,
-	kp sarama.SyncProducer,
-	kc kafka.Consumer,
-	retryKc kafka.Consumer,
+	mp mq.Producer,
+	mc mq.Consumer,
+	retryMc mq.Consumer,
+	priorityMc mq.Consumer,
 ) *consumer {
 	ctx, cancel := context.WithCancel(ctx)
 
 	c := &consumer{
-		kp:      kp,
-		kc:      kc,
-		retryKc: retryKc,
+		mp:         mp,
+		mc:         mc,
+		retryMc:    retryMc,
+		priorityMc: priorityMc,
+		co:         newCoalescer(ctx, global.cfg().Consumer.BatchWindow, global.cfg().Consumer.BatchMaxPayloads),
 
 		ctx:    ctx,
 		cancel: cancel,
@@ -50,18 +57,74 @@ func (c *consumer) start() {
 	// 两种topic的消费分开是为了不互相占用吞吐量，重试那块里的消费要服从于retry-delay
 
 	// 常规topic消费
-	for index := 0; index < global.config.Consumer.Concurrency; index++ {
-		c.tomb.Go(func(stopC <-chan struct{}) {
-			c.messageLoop(c.kc, stopC)
-		})
-	}
+	c.startLoops(c.mc, global.cfg().Consumer.Concurrency)
 
 	// 重试topic的消费
-	for index := 0; index < global.config.Consumer.RetryConcurrency; index++ {
+	c.startLoops(c.retryMc, global.cfg().Consumer.RetryConcurrency)
+
+	// 高优先级topic的消费，不受常规topic积压的影响，重试则和常规的一样走重试topic
+	if c.priorityMc != nil {
+		c.startLoops(c.priorityMc, global.cfg().Consumer.PriorityConcurrency)
+	}
+}
+
+func (c *consumer) startLoops(mqConsumer mq.Consumer, concurrency int) {
+	// 非有序模式下，所有协程抢着消费即可
+	if !global.cfg().Consumer.Ordered {
+		for index := 0; index < concurrency; index++ {
+			c.tomb.Go(func(stopC <-chan struct{}) {
+				c.messageLoop(mqConsumer.Messages(), stopC)
+			})
+		}
+		return
+	}
+
+	// 有序模式下，按key(即uid)分发给固定的协程，保证同一uid的消息按顺序消费
+	msgCs := make([]chan mq.Delivery, concurrency)
+	for index := range msgCs {
+		msgC := make(chan mq.Delivery)
+		msgCs[index] = msgC
 		c.tomb.Go(func(stopC <-chan struct{}) {
-			c.messageLoop(c.retryKc, stopC)
+			c.messageLoop(msgC, stopC)
 		})
 	}
+
+	c.tomb.Go(func(stopC <-chan struct{}) {
+		c.dispatchLoop(mqConsumer.Messages(), msgCs, stopC)
+	})
+}
+
+func (c *consumer) dispatchLoop(srcC <-chan mq.Delivery, msgCs []chan mq.Delivery, stopC <-chan struct{}) {
+	defer func() {
+		for _, msgC := range msgCs {
+			close(msgC)
+		}
+	}()
+
+	for {
+		select {
+		case m, ok := <-srcC:
+			if !ok {
+				return
+			}
+
+			h := fnv.New32a()
+			h.Write(m.Key())
+			msgC := msgCs[h.Sum32()%uint32(len(msgCs))]
+
+			select {
+			case msgC <- m:
+			case <-stopC:
+				return
+			case <-c.ctx.Done():
+				return
+			}
+		case <-stopC:
+			return
+		case <-c.ctx.Done():
+			return
+		}
+	}
 }
 
 func (c *consumer) stop() {
@@ -69,14 +132,14 @@ func (c *consumer) stop() {
 	c.tomb.Close() // stop and wait
 }
 
-func (c *consumer) messageLoop(kafkaConsumer kafka.Consumer, stopC <-chan struct{}) {
+func (c *consumer) messageLoop(msgC <-chan mq.Delivery, stopC <-chan struct{}) {
 	logger := global.logger.WithFields(logrus.Fields{
 		"method": "messageLoop",
 	})
 
 	for {
 		select {
-		case m, ok := <-kafkaConsumer.Messages():
+		case m, ok := <-msgC:
 			if !ok {
 				logger.Infoln("Consumer is closed")
 				return
@@ -87,6 +150,7 @@ func (c *consumer) messageLoop(kafkaConsumer kafka.Consumer, stopC <-chan struct
 				logger.Fatalf("messageLoop: %+v", err)
 				return
 			}
+			consumedCounter.WithLabelValues(m.Topic()).Inc()
 
 			// debug下才打印
 			// logger.Debugf("\n%v:%v Retry:%d Topic:%s\n%v",
@@ -96,11 +160,11 @@ func (c *consumer) messageLoop(kafkaConsumer kafka.Consumer, stopC <-chan struct
 			// 	m.Topic(),
 			// 	util.ProtoToJSONString(pb))
 
-			// 判断是否应该delay重试的消息
-			if pb.GetRetryCount() > 0 && global.config.Consumer.RetryDelay > 0 {
+			// 判断是否应该delay重试的消息，有序模式下等待中的消息虽然没计入重试次数，但也需要delay
+			if retryDelay := global.cfg().Consumer.RetryDelay; (pb.GetRetryCount() > 0 || pb.GetLastAttemptAt() != nil) && retryDelay > 0 {
 				lastAttemptAt, _ := util.FromTimestampProto(pb.GetLastAttemptAt())
 				if !lastAttemptAt.IsZero() {
-					sleepDuration := global.config.Consumer.RetryDelay + lastAttemptAt.Sub(time.Now())
+					sleepDuration := retryDelay + lastAttemptAt.Sub(time.Now())
 					if sleepDuration > 0 {
 						logger.Debugf("delayMsg: delay %v", sleepDuration)
 						timer := time.NewTimer(sleepDuration)
@@ -119,60 +183,176 @@ func (c *consumer) messageLoop(kafkaConsumer kafka.Consumer, stopC <-chan struct
 				}
 			}
 
-			// 执行消费
-			ret, err := process(c.ctx, pb)
-			if err != nil {
-				// 若返回错误，则直接让mq去重试了
-				logger.Errorf("process: %+v", err)
+			// 可合并的消息先攒着，等待窗口期结束或者攒够了再一并处理
+			// 不开启合并时 c.co 为 nil，其方法都是安全的
+			flushed, absorbed := c.co.add(m, pb)
+			if flushed != nil {
+				c.handle(flushed.payload(), flushed.msgs...)
+			}
+			if absorbed {
 				continue
 			}
 
-			if ret != nil { // 如果返回不为nil，则说明返回消息体未消费成功，需要重试或者标为死信
-				var topic string
-				// 已达到最大重试次数，说明已经没有重试的必要了，丢进死信队列
-				if ret.RetryCount >= global.config.Consumer.MaxRetries {
-					topic = global.config.Consumer.DLQTopic
-					logger.Errorf("已经达到最大重试次数，丢进死信队列: %s", ret.GetSeq())
-				} else {
-					logger.Errorf("当前重试次数: %d，丢进重试队列: %s", ret.GetRetryCount(), ret.GetSeq())
-					// 重试次数+1 丢进重试队列
-					ret.RetryCount++
-					topic = global.config.Consumer.RetryTopic
-				}
+			c.handle(pb, m)
+		case b := <-c.co.flushC():
+			c.handle(b.payload(), b.msgs...)
+		case <-stopC:
+			return
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
 
-				// 设置最后尝试时间
-				ret.LastAttemptAt = ptypes.TimestampNow()
+// 执行消费，成功后会ack掉所有的源消息，失败则nack掉，让mq尽快重新投递
+func (c *consumer) handle(pb *mqpb.Payload, ms ...mq.Delivery) {
+	logger := global.logger.WithFields(logrus.Fields{
+		"method": "handle",
+	})
 
-				// 构造ProducerMessage
-				b, err := proto.Marshal(ret)
-				if err != nil {
-					logger.WithError(err).Fatalf("proto.Marshal")
-					return
-				}
+	// 整个消费过程使用同一份配置，避免热更新时前后不一致
+	cfg := global.cfg()
 
-				pm := &sarama.ProducerMessage{
-					Topic: topic,
-					Key:   sarama.StringEncoder(ret.Seq), // 主要是为了kafka分区而已，此key不用
-					Value: sarama.ByteEncoder(b),
-				}
+	// 延续生产者的trace，推送至boat的调用也在其中
+	span, ctx := mqtrace.StartSpan(c.ctx, "carrier.handle", ms[0].Topic(), pb)
+	defer span.Finish()
 
-				// 执行发送，若返回错误，只能让mq去重试了
-				if _, _, err := c.kp.SendMessage(pm); err != nil {
-					logger.WithError(err).Errorf("SendMessage")
-					continue
-				}
-			}
+	// 有序模式下，若此用户之前有消息在重试中，则不计入重试次数，直接丢进重试队列等待
+	var oseq int64
+	if cfg.Consumer.Ordered {
+		oseq = orderSeq(pb.GetToUid())
+	}
+	if oseq > 0 {
+		uid := pb.GetToUid().GetUid()
+		blocked, err := global.sstore.IsBlocked(ctx, uid, pb.GetSeq(), oseq)
+		if err != nil {
+			logger.Errorf("IsBlocked: %+v", err)
+			nackAll(ms)
+			return
+		}
 
-			// 一般都会ack掉，除非上面continue了
-			m.Ack()
-		case <-stopC:
+		if blocked {
+			logger.Debugf("有之前的消息在重试中，丢进重试队列等待: %s", pb.GetSeq())
+			if err := global.sstore.MarkPending(ctx, uid, pb.GetSeq(), oseq, cfg.Consumer.OrderedBlockTTL); err != nil {
+				logger.Errorf("MarkPending: %+v", err)
+				nackAll(ms)
+				return
+			}
+			if err := c.republish(ctx, cfg.Consumer.RetryTopic, pb); err != nil {
+				logger.WithError(err).Errorf("republish")
+				nackAll(ms)
+				return
+			}
+			ackAll(ms)
 			return
-		case <-c.ctx.Done():
+		}
+	}
+
+	ret, err := process(ctx, pb)
+	if err != nil {
+		// 若返回错误，则直接让mq去重试了
+		logger.Errorf("process: %+v", err)
+		nackAll(ms)
+		return
+	}
+
+	pending := false
+	if ret != nil { // 如果返回不为nil，则说明返回消息体未消费成功，需要重试或者标为死信
+		var topic string
+		// 已达到最大重试次数，说明已经没有重试的必要了，丢进死信队列
+		if ret.RetryCount >= cfg.Consumer.MaxRetries {
+			topic = cfg.Consumer.DLQTopic
+			logger.Errorf("已经达到最大重试次数，丢进死信队列: %s", ret.GetSeq())
+		} else {
+			logger.Errorf("当前重试次数: %d，丢进重试队列: %s", ret.GetRetryCount(), ret.GetSeq())
+			// 重试次数+1 丢进重试队列
+			ret.RetryCount++
+			topic = cfg.Consumer.RetryTopic
+			pending = true
+		}
+
+		// 有序模式下，重试中的消息要阻塞此用户后续的消息，要在投递之前标记
+		if oseq > 0 && pending {
+			if err := global.sstore.MarkPending(ctx, pb.GetToUid().GetUid(), ret.GetSeq(), oseq, cfg.Consumer.OrderedBlockTTL); err != nil {
+				logger.Errorf("MarkPending: %+v", err)
+				nackAll(ms)
+				return
+			}
+		}
+
+		// 执行发送，若返回错误，只能让mq去重试了
+		if err := c.republish(ctx, topic, ret); err != nil {
+			logger.WithError(err).Errorf("republish")
+			nackAll(ms)
 			return
 		}
+
+		if pending {
+			retriesCounter.WithLabelValues(retryLabel(ret.GetRetryCount())).Inc()
+		} else {
+			deadLettersCounter.Inc()
+			pubReceipts(dlqReceipts(ret)...)
+			auditDeadLetter(ret)
+		}
+	}
+
+	// 有序模式下，处理完毕或者进了死信队列的消息就不应该再阻塞后续消息了
+	if oseq > 0 && !pending {
+		if err := global.sstore.UnmarkPending(ctx, pb.GetToUid().GetUid(), pb.GetSeq()); err != nil {
+			// 最多等到过期自动解除
+			logger.Warnf("UnmarkPending: %+v", err)
+		}
+	}
+
+	// 一般都会ack掉，除非上面nack了
+	ackAll(ms)
+}
+
+func ackAll(ms []mq.Delivery) {
+	for _, m := range ms {
+		m.Ack()
+	}
+}
+
+func nackAll(ms []mq.Delivery) {
+	for _, m := range ms {
+		m.Nack()
 	}
 }
 
+// 重新投递至某个topic，重试时延续当前的trace
+func (c *consumer) republish(ctx context.Context, topic string, pb *mqpb.Payload) error {
+	// 设置最后尝试时间
+	pb.LastAttemptAt = ptypes.TimestampNow()
+	mqtrace.Inject(ctx, pb)
+
+	// 构造ProducerMessage
+	b, err := proto.Marshal(pb)
+	if err != nil {
+		global.logger.WithError(err).Fatalf("proto.Marshal")
+		return errors.WithStack(err)
+	}
+
+	// 主要是为了kafka分区而已，此key不用
+	// 有序模式下则需要以uid分区，保证同一uid的重试消息也是按顺序消费的
+	key := pb.GetSeq()
+	if toUid := pb.GetToUid(); toUid != nil && global.cfg().Consumer.Ordered {
+		key = toUid.GetUid()
+	}
+
+	pm := &mq.Message{
+		Topic: topic,
+		Key:   key,
+		Value: b,
+	}
+
+	if err := c.mp.Publish(pm); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
 // 返回的payload为需要重新投递出去的玩意
 func process(ctx context.Context, pb *mqpb.Payload) (out *mqpb.Payload, rerr error) {
 	defer func() {
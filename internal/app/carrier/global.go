@@ -2,29 +2,33 @@ package carrier
 
 import (
 	"context"
+	"sync/atomic"
 
-	"github.com/gomodule/redigo/redis"
+	"github.com/molon/gomsg/internal/pkg/audit"
+	"github.com/molon/gomsg/internal/pkg/mq"
 	"github.com/molon/gomsg/internal/pkg/offline"
 	"github.com/molon/gomsg/internal/pkg/sessionstore"
-	"github.com/molon/pkg/clientstore"
 	"github.com/sirupsen/logrus"
-
-	"github.com/uber-go/kafka-client/kafka"
-
-	"github.com/Shopify/sarama"
 )
 
+// boat服务客户端的来源，key为 boat.name-prefix+boat服务ID
+// 一般是 clientstore.Store，all-in-one 模式下则是进程内的实现
+type BoatStore interface {
+	Get(target string) (interface{}, bool)
+}
+
 var global *globalCtx
 var plog *logrus.Logger
 
 type globalCtx struct {
-	config    Config
+	config    atomic.Value // *Config，可热更新
 	logger    *logrus.Logger
-	boatStore *clientstore.Store
-	producer  sarama.SyncProducer
-	redisPool *redis.Pool
-	sstore    *sessionstore.Store
-	offstore  *offline.Store
+	boatStore BoatStore
+	producer  mq.Producer
+	sstore    sessionstore.SessionStore
+	offstore  offline.Store
+	// 未开启审计时为nil
+	audit *audit.Store
 
 	c *consumer
 }
@@ -33,38 +37,59 @@ func Start(
 	ctx context.Context,
 	logger *logrus.Logger,
 	config Config,
-	boatStore *clientstore.Store,
-	producer sarama.SyncProducer,
-	kc kafka.Consumer,
-	retryKc kafka.Consumer,
-	redisPool *redis.Pool,
+	boatStore BoatStore,
+	producer mq.Producer,
+	mc mq.Consumer,
+	retryMc mq.Consumer,
+	priorityMc mq.Consumer, // 未设置 consumer.priority-topic 则为nil
+	sstore sessionstore.SessionStore,
+	offstore offline.Store,
+	auditStore *audit.Store,
 ) {
 	if err := config.Validate(); err != nil {
 		logger.Fatalf("Start carrier failed: %+v", err)
 	}
 
-	offstore, err := offline.InitStore(ctx, redisPool)
-	if err != nil {
-		logger.Fatalf("Start carrier failed: %+v", err)
-	}
-
 	plog = logger
 
 	global = &globalCtx{
-		config:    config,
 		logger:    logger,
 		boatStore: boatStore,
 		producer:  producer,
-		redisPool: redisPool,
 
-		sstore:   sessionstore.NewStore(logger, redisPool),
+		sstore:   sstore,
 		offstore: offstore,
-		c:        newConsumer(ctx, producer, kc, retryKc),
+		audit:    auditStore,
 	}
+	global.config.Store(&config)
 
+	global.c = newConsumer(ctx, producer, mc, retryMc, priorityMc)
 	global.c.start()
 }
 
+// 当前配置，不要修改其内容
+func (g *globalCtx) cfg() *Config {
+	return g.config.Load().(*Config)
+}
+
+// 热更新配置，校验不通过则返回错误，继续使用旧配置
+// 运行时无法变更的配置项会保持旧值，以保持之后的结果来校验
+func Reload(config Config) error {
+	changed := config.keepImmutable(global.cfg())
+
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	if len(changed) > 0 {
+		plog.Warnf("Reload carrier config: %v can not be changed at runtime, restart to apply", changed)
+	}
+
+	global.config.Store(&config)
+	plog.Infof("Reload carrier config")
+	return nil
+}
+
 func Stop() {
 	global.c.stop()
 }
@@ -0,0 +1,52 @@
+package carrier
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	consumedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gomsg",
+		Subsystem: "carrier",
+		Name:      "consumed_total",
+		Help:      "Payloads consumed from mq.",
+	}, []string{"topic"})
+
+	// retry 为重新投递之后的重试次数，即第几轮重试
+	retriesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gomsg",
+		Subsystem: "carrier",
+		Name:      "retries_total",
+		Help:      "Payloads republished to the retry topic by retry count.",
+	}, []string{"retry"})
+
+	deadLettersCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "gomsg",
+		Subsystem: "carrier",
+		Name:      "dead_letters_total",
+		Help:      "Payloads republished to the dead letter topic.",
+	})
+
+	offlineWritesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gomsg",
+		Subsystem: "carrier",
+		Name:      "offline_writes_total",
+		Help:      "Messages written to the offline store.",
+	}, []string{"platform", "result"})
+
+	// 从station收到推送请求到推送至会话成功，包括期间的重试
+	deliveryLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gomsg",
+		Subsystem: "carrier",
+		Name:      "delivery_latency_seconds",
+		Help:      "Latency from the push request to the successful push to a session.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 14),
+	}, []string{"platform"})
+)
+
+func retryLabel(retryCount int64) string {
+	return strconv.FormatInt(retryCount, 10)
+}
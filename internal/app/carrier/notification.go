@@ -1,29 +1,61 @@
 package carrier
 
-// func notify(ctx context.Context, noti *mqpb.Notification) error {
-// 	pb := &mqpb.Payload{
-// 		Seq:       xid.New().String(),
-// 		Timestamp: ptypes.TimestampNow(),
-// 		Body: &mqpb.Payload_Notification{
-// 			Notification: noti,
-// 		},
-// 	}
-
-// 	b, err := proto.Marshal(pb)
-// 	if err != nil {
-// 		global.logger.WithError(err).Fatalf("proto.Marshal")
-// 		return errors.WithStack(err)
-// 	}
-
-// 	pm := &sarama.ProducerMessage{
-// 		Topic: topic,
-// 		Key:   sarama.StringEncoder(pb.Seq), // 主要是为了kafka分区而已，此key不用
-// 		Value: sarama.ByteEncoder(b),
-// 	}
-
-// 	// 执行发送，若返回错误，只能让mq去重试了
-// 	if _, _, err := c.kp.SendMessage(pm); err != nil {
-// 		logger.WithError(err).Errorf("SendMessage")
-// 		continue
-// 	}
-// }
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/molon/gomsg/internal/pb/mqpb"
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/gomsg/internal/pkg/mqtrace"
+	"github.com/molon/gomsg/pb/msgpb"
+	"github.com/molon/pkg/errors"
+	"github.com/rs/xid"
+)
+
+// 投递通知mq消息，具体的推送由订阅 notification.topic 的服务根据 provider 去做
+// 附带此平台当前的离线消息数目作为角标，获取失败不影响通知，不带角标，免得下游把角标清零
+func notify(ctx context.Context, uid string, pcfg platformConfig, msg *msgpb.Message) error {
+	var badge *wrappers.Int64Value
+	count, _, err := global.offstore.Count(ctx, uid, pcfg.name, pcfg.offlineExpire, false)
+	if err != nil {
+		global.logger.WithError(err).Warnf("offstore.Count")
+	} else {
+		badge = &wrappers.Int64Value{Value: count}
+	}
+
+	pb := &mqpb.Payload{
+		Seq:       xid.New().String(),
+		Timestamp: ptypes.TimestampNow(),
+		Body: &mqpb.Payload_Notification{
+			Notification: &mqpb.Notification{
+				Uid:      uid,
+				Platform: pcfg.name,
+				Msg:      msg,
+				Provider: pcfg.notificationProvider,
+				Badge:    badge,
+			},
+		},
+	}
+	// 下游的推送服务也可以延续此trace
+	mqtrace.Inject(ctx, pb)
+
+	b, err := proto.Marshal(pb)
+	if err != nil {
+		global.logger.WithError(err).Fatalf("proto.Marshal")
+		return errors.WithStack(err)
+	}
+
+	pm := &mq.Message{
+		Topic: global.cfg().Notification.Topic,
+		Key:   uid, // 以uid分区，便于下游按用户合并通知
+		Value: b,
+	}
+
+	if err := global.producer.Publish(pm); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package carrier
+
+import (
+	"github.com/molon/gomsg/internal/pb/mqpb"
+)
+
+// ToUid消息的顺序号，即其消息中最小的uid_seq，0表示无需保证顺序
+// 处理中的标记由 sessionstore 存储，见其 IsBlocked/MarkPending/UnmarkPending
+func orderSeq(toUid *mqpb.ToUid) int64 {
+	var ret int64
+	for _, msg := range toUid.GetMsgs() {
+		if msg.GetUidSeq() > 0 && (ret == 0 || msg.GetUidSeq() < ret) {
+			ret = msg.GetUidSeq()
+		}
+	}
+	return ret
+}
@@ -0,0 +1,136 @@
+package carrier
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/molon/gomsg/internal/pb/mqpb"
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/gomsg/internal/pkg/sessionstore"
+	"github.com/molon/gomsg/pb/msgpb"
+	"github.com/rs/xid"
+)
+
+// 投递回执事件，receipt.topic 为空则不投递
+// 回执只是尽力而为，失败了打印日志即可，不能影响消息本身的处理
+// 由于消息会重试，回执也可能重复，订阅方需要自行去重
+func pubReceipts(receipts ...*mqpb.Receipt) {
+	topic := global.cfg().Receipt.Topic
+	if len(topic) < 1 || len(receipts) <= 0 {
+		return
+	}
+
+	now := ptypes.TimestampNow()
+	pms := []*mq.Message{}
+	for _, receipt := range receipts {
+		if len(receipt.GetMsgSeqs()) <= 0 {
+			continue
+		}
+
+		pb := &mqpb.Payload{
+			Seq:       xid.New().String(),
+			Timestamp: now,
+			Body: &mqpb.Payload_Receipt{
+				Receipt: receipt,
+			},
+		}
+
+		b, err := proto.Marshal(pb)
+		if err != nil {
+			plog.Warnf("pubReceipts: %+v", err)
+			return
+		}
+
+		pms = append(pms, &mq.Message{
+			Topic: topic,
+			Key:   receipt.GetUid(), // 以uid分区，同一用户的回执尽量有序
+			Value: b,
+		})
+	}
+
+	if len(pms) <= 0 {
+		return
+	}
+
+	if err := global.producer.Publish(pms...); err != nil {
+		plog.Warnf("pubReceipts: %+v", err)
+	}
+}
+
+// 成功投递给会话的回执，需要ack的消息记为ACKED，其他的记为DELIVERED
+func deliveredReceipts(sess sessionstore.Session, msgs []*msgpb.Message) []*mqpb.Receipt {
+	var delivered, acked []string
+	for _, msg := range msgs {
+		if msg.GetOptions()&msgpb.MessageOption_NEED_ACK > 0 {
+			acked = append(acked, msg.GetSeq())
+		} else {
+			delivered = append(delivered, msg.GetSeq())
+		}
+	}
+
+	ret := []*mqpb.Receipt{}
+	if len(delivered) > 0 {
+		ret = append(ret, &mqpb.Receipt{
+			Event:    mqpb.Receipt_DELIVERED,
+			Uid:      sess.Uid,
+			Platform: sess.Platform,
+			Sid:      sess.Sid,
+			MsgSeqs:  delivered,
+		})
+	}
+	if len(acked) > 0 {
+		ret = append(ret, &mqpb.Receipt{
+			Event:    mqpb.Receipt_ACKED,
+			Uid:      sess.Uid,
+			Platform: sess.Platform,
+			Sid:      sess.Sid,
+			MsgSeqs:  acked,
+		})
+	}
+	return ret
+}
+
+// 离线消息被清理的回执，过期的记为EXPIRED，超出数目的记为EVICTED
+func cleanedReceipts(uid string, platformToExpiredSeqs map[string][]string, platformToEvictedSeqs map[string][]string) []*mqpb.Receipt {
+	ret := []*mqpb.Receipt{}
+	for platform, seqs := range platformToExpiredSeqs {
+		ret = append(ret, &mqpb.Receipt{
+			Event:    mqpb.Receipt_EXPIRED,
+			Uid:      uid,
+			Platform: platform,
+			MsgSeqs:  seqs,
+		})
+	}
+	for platform, seqs := range platformToEvictedSeqs {
+		ret = append(ret, &mqpb.Receipt{
+			Event:    mqpb.Receipt_EVICTED,
+			Uid:      uid,
+			Platform: platform,
+			MsgSeqs:  seqs,
+		})
+	}
+	return ret
+}
+
+// 被丢进死信队列的回执，只有发给uid的消息才有意义
+func dlqReceipts(pb *mqpb.Payload) []*mqpb.Receipt {
+	toUid := pb.GetToUid()
+	if toUid == nil {
+		return nil
+	}
+
+	seqs := make([]string, len(toUid.GetMsgs()))
+	for i, msg := range toUid.GetMsgs() {
+		seqs[i] = msg.GetSeq()
+	}
+
+	ret := []*mqpb.Receipt{}
+	for platform := range tidyPlatformConfigs(global.cfg(), toUid.GetPlatformConfig()) {
+		ret = append(ret, &mqpb.Receipt{
+			Event:    mqpb.Receipt_DROPPED_TO_DLQ,
+			Uid:      toUid.GetUid(),
+			Platform: platform,
+			MsgSeqs:  seqs,
+		})
+	}
+	return ret
+}
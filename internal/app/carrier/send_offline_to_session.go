@@ -11,6 +11,7 @@ import (
 	"github.com/molon/gomsg/internal/pb/boatpb"
 
 	"github.com/molon/gomsg/internal/pb/mqpb"
+	"github.com/molon/gomsg/internal/pkg/audit"
 	"github.com/molon/gomsg/internal/pkg/sessionstore"
 )
 
@@ -45,26 +46,35 @@ func sendOfflineToSession(ctx context.Context, in *mqpb.SendOfflineToSession) er
 	}
 
 	// 开始执行消息下发，直到无离线消息了或者出错就返回
-	ackWait := ptypes.DurationProto(global.config.Boat.AckWait)
+	cfg := global.cfg()
+	pcfg := cfg.platformConfig(sess.Platform)
+	ackWait := ptypes.DurationProto(pcfg.ackWait)
 
 	// 简单2分钟超时，防止异常后一直绷着，然后等下次重试
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 
 	for {
-		msgs, deleteFunc, err := global.offstore.Read(ctx, sess.Uid, sess.Platform, global.config.Offline.Expire, global.config.Offline.BatchCount)
+		msgs, deleteFunc, err := global.offstore.Read(ctx, sess.Uid, sess.Platform, pcfg.offlineExpire, cfg.Offline.BatchCount)
 		if err != nil {
 			return err
 		}
 
-		if len(msgs) < 1 {
+		// deleteFunc为空说明已经没有离线消息了
+		if deleteFunc == nil {
 			// 下发完毕，执行一下clean返回
-			if err := global.offstore.Clean(ctx, sess.Uid, global.config.Offline.Expire, map[string]int{sess.Platform: -1}); err != nil {
+			platformToExpiredSeqs, _, err := global.offstore.Clean(ctx, sess.Uid, pcfg.offlineExpire, map[string]int{sess.Platform: -1})
+			if err != nil {
 				plog.Warnf("Clean failed: %+v", err)
 			}
+			pubReceipts(cleanedReceipts(sess.Uid, platformToExpiredSeqs, nil)...)
+			auditOfflineCleaned(sess.Uid, platformToExpiredSeqs, nil)
 
 			return nil
-		} else {
+		}
+
+		// 读取到的可能都是已过期的，此时无需下发，直接清理掉即可
+		if len(msgs) > 0 {
 			if _, err := cli.PushMessages(ctx, &boatpb.PushMessagesRequest{
 				Sid:     sess.Sid,
 				AckWait: ackWait,
@@ -78,11 +88,13 @@ func sendOfflineToSession(ctx context.Context, in *mqpb.SendOfflineToSession) er
 				// 返回错误，等待重试
 				return errors.WithStack(err)
 			}
+			pubReceipts(deliveredReceipts(sess, msgs)...)
+			auditMsgs(sess.Uid, msgs, sessionEvent(audit.StageReplayed, sess, ""))
+		}
 
-			// 清理已读取的
-			if err := deleteFunc(ctx); err != nil {
-				return err
-			}
+		// 清理已读取的
+		if err := deleteFunc(ctx); err != nil {
+			return err
 		}
 	}
 }
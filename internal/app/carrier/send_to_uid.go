@@ -2,7 +2,13 @@ package carrier
 
 import (
 	"context"
+	"sync"
+	"time"
 
+	"github.com/molon/gomsg/internal/pkg/audit"
+	"github.com/molon/gomsg/internal/pkg/metrics"
+	"github.com/molon/gomsg/internal/pkg/offline"
+	"github.com/molon/gomsg/internal/pkg/sessionstore"
 	"github.com/molon/gomsg/pb/msgpb"
 	"github.com/molon/pkg/util"
 
@@ -12,16 +18,17 @@ import (
 	"github.com/molon/gomsg/pb/pushpb"
 
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/sirupsen/logrus"
 )
 
-func tidyPlatformConfigs(pushPcfg *pushpb.PlatformConfig) map[string]platformConfig {
+func tidyPlatformConfigs(cfg *Config, pushPcfg *pushpb.PlatformConfig) map[string]platformConfig {
 	ret := map[string]platformConfig{}
 
 	// 若没配置，说明是全平台
 	if pushPcfg == nil ||
 		(len(pushPcfg.Platforms) <= 0 && len(pushPcfg.WithoutPlatforms) <= 0) {
-		for k, v := range global.config.pcfgs {
+		for k, v := range cfg.pcfgs {
 			ret[k] = v
 		}
 		return ret
@@ -30,7 +37,7 @@ func tidyPlatformConfigs(pushPcfg *pushpb.PlatformConfig) map[string]platformCon
 	// 若指定，则筛出有效的，无效的忽略
 	if len(pushPcfg.Platforms) > 0 {
 		for _, name := range pushPcfg.Platforms {
-			if val, ok := global.config.pcfgs[name]; ok {
+			if val, ok := cfg.pcfgs[name]; ok {
 				ret[name] = val
 			}
 		}
@@ -39,7 +46,7 @@ func tidyPlatformConfigs(pushPcfg *pushpb.PlatformConfig) map[string]platformCon
 	}
 
 	// 若指定忽略，则筛出没指定的返回
-	for k, v := range global.config.pcfgs {
+	for k, v := range cfg.pcfgs {
 		exists := false
 		for _, name := range pushPcfg.WithoutPlatforms {
 			if name == k {
@@ -55,23 +62,128 @@ func tidyPlatformConfigs(pushPcfg *pushpb.PlatformConfig) map[string]platformCon
 	return ret
 }
 
+// 单个会话的投递结果
+type pushResult int
+
+const (
+	// 投递成功
+	pushSucceeded pushResult = iota
+	// 投递失败，但会话依然认定是有效的
+	pushFailed
+	// 会话已无效
+	pushInvalid
+)
+
+// 并发向所有会话投递消息，返回的结果列表和传入的会话列表一一对应
+// 并发数受限于 boat.push-concurrency，这样即使某用户会话很多也不会瞬间打出太多请求
+func pushToSessions(ctx context.Context, logger *logrus.Entry, concurrency int, pcfgs map[string]platformConfig, plat2Sesses map[string][]sessionstore.Session, msgs []*msgpb.Message) map[string][]pushResult {
+	var (
+		wg   sync.WaitGroup
+		semC = make(chan struct{}, concurrency)
+		ret  = map[string][]pushResult{}
+	)
+
+	for plat, sesses := range plat2Sesses {
+		results := make([]pushResult, len(sesses))
+		ret[plat] = results
+
+		// 各平台的ack等待时间可能不同
+		ackWait := ptypes.DurationProto(pcfgs[plat].ackWait)
+
+		for i, sess := range sesses {
+			semC <- struct{}{}
+			wg.Add(1)
+			go func(result *pushResult, sess sessionstore.Session, ackWait *duration.Duration) {
+				defer func() {
+					<-semC
+					wg.Done()
+				}()
+
+				*result = pushToSession(ctx, logger, sess, ackWait, msgs)
+			}(&results[i], sess, ackWait)
+		}
+	}
+
+	wg.Wait()
+	return ret
+}
+
+func pushToSession(ctx context.Context, logger *logrus.Entry, sess sessionstore.Session, ackWait *duration.Duration, msgs []*msgpb.Message) pushResult {
+	ll := logger.WithFields(logrus.Fields{
+		"plat": sess.Platform,
+		"bid":  sess.Bid,
+		"sid":  sess.Sid,
+	})
+
+	cli, ok, err := boatClient(sess.Bid)
+	if err != nil {
+		ll.WithError(err).Debugf("boatClient")
+		// 这里一般理解是网络异常，姑且认为会话还是有效的
+		return pushFailed
+	}
+
+	if !ok { // 对应boat服务不存在，对应会话也认为无效
+		ll.Debugf("boat is not exists, so session is invalid")
+		return pushInvalid
+	}
+
+	if _, err := cli.PushMessages(ctx, &boatpb.PushMessagesRequest{
+		Sid:     sess.Sid,
+		AckWait: ackWait,
+		Msgs:    msgs,
+	}); err != nil {
+		if equalErrCode(err, errorpb.Code_SESSION_NOT_FOUND) {
+			// boat告知会话不存在，则会话无效
+			ll.Debugf("boat returns Code_SESSION_NOT_FOUND, so session is invalid")
+			return pushInvalid
+		}
+		// TODO: 如果返回NOT_ACK是否要踢出会话呢？
+
+		ll.WithError(err).Errorf("PushMessages")
+		// 只要没发现是Code_SESSION_NOT_FOUND，依然认定会话是有效的
+		return pushFailed
+	}
+
+	return pushSucceeded
+}
+
 func sendToUid(ctx context.Context, payload *mqpb.Payload, pb *mqpb.ToUid) *mqpb.ToUid {
 	logger := global.logger.WithFields(logrus.Fields{
 		"method": "sendToUid",
 		"uid":    pb.GetUid(),
 	})
 
+	// 整个消费过程使用同一份配置，避免热更新时前后不一致
+	cfg := global.cfg()
+
 	var (
 		// 整理出此次要发送平台的所有配置信息
-		allPcfgs = tidyPlatformConfigs(pb.GetPlatformConfig())
+		allPcfgs = tidyPlatformConfigs(cfg, pb.GetPlatformConfig())
 		// 需要重试的平台名称列表
 		needRetryPlats []string
 		// 需要离线的平台名称列表
 		needOfflinePlats []string
 		// 发现失效的会话ID列表
 		invalidSids []string
+		// 需要投递的回执列表
+		receipts []*mqpb.Receipt
 	)
 
+	auditMsgs(pb.GetUid(), pb.GetMsgs(), audit.Event{
+		Stage:  audit.StageConsumed,
+		Detail: "retry=" + retryLabel(payload.GetRetryCount()),
+	})
+
+	// 已过期的消息直接丢弃，不再投递
+	plats := make([]string, 0, len(allPcfgs))
+	for plat := range allPcfgs {
+		plats = append(plats, plat)
+	}
+	if !dropExpiredMsgs(ctx, logger, pb, plats) {
+		logger.Debugf("消息均已过期")
+		return nil
+	}
+
 	// 找到目标已存储的所有会话，此时可能会包含一些已经无效的会话
 	plat2Sesses, err := global.sstore.GetPlatformToSessions(ctx, pb.GetUid())
 	if err != nil {
@@ -96,54 +208,37 @@ func sendToUid(ctx context.Context, payload *mqpb.Payload, pb *mqpb.ToUid) *mqpb
 		}
 	}
 
-	// 执行投递
-	ackWait := ptypes.DurationProto(global.config.Boat.AckWait)
+	// 推送请求的时间，重试时不变
+	sendTime, _ := util.FromTimestampProto(payload.GetTimestamp())
+
+	// 执行投递，所有平台的所有会话并发进行，整体受 boat.push-timeout 限制
+	// 超时之后未完成的投递会返回错误，按投递失败处理，等待重试
+	pushCtx, cancel := context.WithTimeout(ctx, cfg.Boat.PushTimeout)
+	plat2Results := pushToSessions(pushCtx, logger, cfg.Boat.PushConcurrency, allPcfgs, plat2Sesses, pb.GetMsgs())
+	cancel()
+
 	for plat, sesses := range plat2Sesses {
 		// 每个会话都会去投递
 		// 但只有每个平台的第一个有效会话投递成功才可认定 此消息对此用户在此平台 已经确认完毕
 		validSessCount := 0
 		firstValidSuccess := false
-		for _, sess := range sesses {
-			ll := logger.WithFields(logrus.Fields{
-				"plat": sess.Platform,
-				"bid":  sess.Bid,
-				"sid":  sess.Sid,
-			})
-
-			cli, ok, err := boatClient(sess.Bid)
-			if err != nil {
-				ll.WithError(err).Debugf("boatClient")
-				// 这里一般理解是网络异常，姑且认为会话还是有效的
-				validSessCount++
-				continue
-			}
-
-			if !ok { // 对应boat服务不存在，对应会话也认为无效
-				invalidSids = append(invalidSids, sess.Sid)
-				ll.Debugf("boat is not exists, so session is invalid")
-				continue
-			}
-
-			if _, err := cli.PushMessages(ctx, &boatpb.PushMessagesRequest{
-				Sid:     sess.Sid,
-				AckWait: ackWait,
-				Msgs:    pb.GetMsgs(),
-			}); err == nil {
+		for i, ret := range plat2Results[plat] {
+			switch ret {
+			case pushInvalid:
+				invalidSids = append(invalidSids, sesses[i].Sid)
+				auditMsgs(pb.GetUid(), pb.GetMsgs(), sessionEvent(audit.StagePushFailed, sesses[i], "session invalid"))
+			case pushSucceeded:
+				receipts = append(receipts, deliveredReceipts(sesses[i], pb.GetMsgs())...)
+				auditPushed(sesses[i], pb.GetMsgs())
+				if !sendTime.IsZero() {
+					deliveryLatency.WithLabelValues(plat).Observe(time.Since(sendTime).Seconds())
+				}
 				validSessCount++
 				if validSessCount == 1 {
 					firstValidSuccess = true
 				}
-			} else {
-				if equalErrCode(err, errorpb.Code_SESSION_NOT_FOUND) {
-					// boat告知会话不存在，则会话无效
-					invalidSids = append(invalidSids, sess.Sid)
-					ll.Debugf("boat returns Code_SESSION_NOT_FOUND, so session is invalid")
-					continue
-				}
-				// TODO: 如果返回NOT_ACK是否要踢出会话呢？
-
-				ll.WithError(err).Errorf("PushMessages")
-				// 只要没发现是Code_SESSION_NOT_FOUND，依然认定会话是有效的
+			default:
+				auditMsgs(pb.GetUid(), pb.GetMsgs(), sessionEvent(audit.StagePushFailed, sesses[i], "push failed"))
 				validSessCount++
 			}
 		}
@@ -168,49 +263,101 @@ func sendToUid(ctx context.Context, payload *mqpb.Payload, pb *mqpb.ToUid) *mqpb
 		}
 	}
 
-	// 检测是否达到最大重试次数，如果是 则应该将 needRetryPlats 合并到 needOfflinePlats 里
+	// 检测各平台是否达到其最大重试次数，如果是 则应该将其从 needRetryPlats 挪到 needOfflinePlats 里
 	// 因为此时已经不能相信客户端能完成反馈了，此消息留给离线处理去保证不丢失吧
-	if payload.GetRetryCount() >= global.config.Consumer.MaxRetries {
-		needOfflinePlats = append(needOfflinePlats, needRetryPlats...)
-		needRetryPlats = []string{} // 清空
-
-		logger.Debugf("retryCount>=%d, so merge needRetryPlats to needOfflinePlats", global.config.Consumer.MaxRetries)
+	// 各平台的最大重试次数不会大于 consumer.max-retries
+	if len(needRetryPlats) > 0 {
+		retryPlats := []string{}
+		for _, plat := range needRetryPlats {
+			if maxRetries := allPcfgs[plat].maxRetries; payload.GetRetryCount() >= maxRetries {
+				needOfflinePlats = append(needOfflinePlats, plat)
+				logger.Debugf("retryCount>=%d, so move %s to needOfflinePlats", maxRetries, plat)
+				continue
+			}
+			retryPlats = append(retryPlats, plat)
+		}
+		needRetryPlats = retryPlats
 	}
 
 	// 尝试做离线处理
 	// - 离线处理失败的plat要记录到needRetryPlats里，但是由于离线处理是最后一道关卡，在触及最大重试次数之后，consumer那边估计就会直接将其丢进死信队列了，只能后续手动处理了，这也是木有办法的办法了
+	// - 不允许离线存储的平台只会投递通知
 	if len(needOfflinePlats) > 0 {
-		sendTime, _ := util.FromTimestampProto(payload.GetTimestamp())
-		platformToMaxOMCount := map[string]int{}
 		for _, plat := range needOfflinePlats {
+			pcfg := allPcfgs[plat]
+			writtenSeqs := []string{}
+			expiredSeqs := []string{}
 			for _, msg := range pb.GetMsgs() {
-				if msg.GetOptions()&msgpb.MessageOption_NEED_OFFLINE > 0 {
-					if err := global.offstore.Write(ctx, pb.GetUid(),
-						plat, msg, sendTime, global.config.Offline.Expire); err != nil {
+				if pcfg.allowOffline && msg.GetOptions()&msgpb.MessageOption_NEED_OFFLINE > 0 {
+					err := global.offstore.Write(ctx, pb.GetUid(), plat, msg, sendTime, pcfg.offlineExpire)
+					if err == offline.ErrExpired {
+						// 期间刚好过期了，没有存储，也就没必要通知了
+						offlineWritesCounter.WithLabelValues(plat, "expired").Inc()
+						expiredSeqs = append(expiredSeqs, msg.GetSeq())
+						continue
+					}
+					offlineWritesCounter.WithLabelValues(plat, metrics.ResultOf(err)).Inc()
+					if err != nil {
 						logger.WithError(err).Errorf("offstore.Write")
 						// 错了就直接放弃这个plat吧
 						needRetryPlats = append(needRetryPlats, plat)
 						break
 					}
 					// 只要有成功写入就记录
-					platformToMaxOMCount[plat] = allPcfgs[plat].maxOfflineCount
+					writtenSeqs = append(writtenSeqs, msg.GetSeq())
 				}
 
-				if msg.GetOptions()&msgpb.MessageOption_NEED_NOTIFICATION > 0 {
-					// TODO: 如果需要通知，则投递通知mq消息
+				// 如果需要通知，且此平台配置了通知提供方，则投递通知mq消息
+				if msg.GetOptions()&msgpb.MessageOption_NEED_NOTIFICATION > 0 && len(pcfg.notificationProvider) > 0 {
+					if err := notify(ctx, pb.GetUid(), pcfg, msg); err != nil {
+						logger.WithError(err).Errorf("notify")
+						needRetryPlats = append(needRetryPlats, plat)
+						break
+					}
 				}
 			}
-		}
-		if len(platformToMaxOMCount) > 0 {
-			if err := global.offstore.Clean(ctx, pb.GetUid(), global.config.Offline.Expire, platformToMaxOMCount); err != nil {
-				logger.WithError(err).Errorf("offstore.Clean")
-				// 这里返回错误打印一下即可
+
+			if len(expiredSeqs) > 0 {
+				receipts = append(receipts, &mqpb.Receipt{
+					Event:    mqpb.Receipt_EXPIRED,
+					Uid:      pb.GetUid(),
+					Platform: plat,
+					MsgSeqs:  expiredSeqs,
+				})
+				auditSeqs(pb.GetUid(), expiredSeqs, audit.Event{
+					Stage:    audit.StageExpired,
+					Platform: plat,
+				})
+			}
+
+			// 各平台的过期时间可能不同，所以分开清理
+			if len(writtenSeqs) > 0 {
+				receipts = append(receipts, &mqpb.Receipt{
+					Event:    mqpb.Receipt_STORED_OFFLINE,
+					Uid:      pb.GetUid(),
+					Platform: plat,
+					MsgSeqs:  writtenSeqs,
+				})
+				auditSeqs(pb.GetUid(), writtenSeqs, audit.Event{
+					Stage:    audit.StageStoredOffline,
+					Platform: plat,
+				})
+
+				platformToExpiredSeqs, platformToEvictedSeqs, err := global.offstore.Clean(ctx, pb.GetUid(), pcfg.offlineExpire, map[string]int{plat: pcfg.maxOfflineCount})
+				if err != nil {
+					logger.WithError(err).Errorf("offstore.Clean")
+					// 这里返回错误打印一下即可
+				}
+				receipts = append(receipts, cleanedReceipts(pb.GetUid(), platformToExpiredSeqs, platformToEvictedSeqs)...)
+				auditOfflineCleaned(pb.GetUid(), platformToExpiredSeqs, platformToEvictedSeqs)
 			}
 		}
 	}
 
-	// 若需重试，则返回那些平台
-	if len(needRetryPlats) > 0 {
+	pubReceipts(receipts...)
+
+	// 若需重试，则返回那些平台，重试前先丢弃期间已过期的消息
+	if len(needRetryPlats) > 0 && dropExpiredMsgs(ctx, logger, pb, needRetryPlats) {
 		logger.Debugf("needRetryPlats: %+v", needRetryPlats)
 		pb.PlatformConfig = &pushpb.PlatformConfig{
 			Platforms: needRetryPlats,
@@ -222,3 +369,32 @@ func sendToUid(ctx context.Context, payload *mqpb.Payload, pb *mqpb.ToUid) *mqpb
 	// 完全处理OK返回空即可
 	return nil
 }
+
+// 丢弃已过期的消息并投递对应平台的回执和审计事件，返回是否还有未过期的消息
+func dropExpiredMsgs(ctx context.Context, logger *logrus.Entry, pb *mqpb.ToUid, plats []string) bool {
+	msgs, expiredSeqs := splitExpired(pb.GetMsgs(), time.Now())
+	if len(expiredSeqs) > 0 {
+		logger.Debugf("drop expired msgs: %v", expiredSeqs)
+		pb.Msgs = msgs
+
+		receipts := []*mqpb.Receipt{}
+		for _, plat := range plats {
+			receipts = append(receipts, &mqpb.Receipt{
+				Event:    mqpb.Receipt_EXPIRED,
+				Uid:      pb.GetUid(),
+				Platform: plat,
+				MsgSeqs:  expiredSeqs,
+			})
+		}
+		pubReceipts(receipts...)
+
+		for _, plat := range plats {
+			auditSeqs(pb.GetUid(), expiredSeqs, audit.Event{
+				Stage:    audit.StageExpired,
+				Platform: plat,
+			})
+		}
+	}
+
+	return len(pb.GetMsgs()) > 0
+}
@@ -2,15 +2,18 @@ package carrier
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/golang/protobuf/ptypes"
 	"github.com/molon/gomsg/internal/pb/boatpb"
 	"github.com/molon/pkg/errors"
 	"github.com/molon/gomsg/pb/errorpb"
+	"github.com/molon/gomsg/pb/msgpb"
 	"google.golang.org/grpc/status"
 )
 
 func boatClient(boatId string) (boatpb.BoatClient, bool, error) {
-	target := fmt.Sprintf("%s%s", global.config.Boat.NamePrefix, boatId)
+	target := fmt.Sprintf("%s%s", global.cfg().Boat.NamePrefix, boatId)
 
 	cli, ok := global.boatStore.Get(target)
 	if !ok {
@@ -36,3 +39,22 @@ func equalErrCode(err error, code errorpb.Code) bool {
 	}
 	return false
 }
+
+// 分离出已过期的消息，返回未过期的消息列表和已过期的seq列表
+func splitExpired(msgs []*msgpb.Message, now time.Time) ([]*msgpb.Message, []string) {
+	var (
+		valid       []*msgpb.Message
+		expiredSeqs []string
+	)
+	for _, msg := range msgs {
+		if msg.GetExpireAt() != nil {
+			expireAt, err := ptypes.Timestamp(msg.GetExpireAt())
+			if err == nil && !now.Before(expireAt) {
+				expiredSeqs = append(expiredSeqs, msg.GetSeq())
+				continue
+			}
+		}
+		valid = append(valid, msg)
+	}
+	return valid, expiredSeqs
+}
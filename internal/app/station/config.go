@@ -1,10 +1,50 @@
 package station
 
-import "github.com/molon/pkg/errors"
+import (
+	"time"
+
+	"github.com/molon/pkg/errors"
+)
 
 type Config struct {
 	Producer struct {
-		Topic string
+		Topic          string
+		PartitionByUid bool `mapstructure:"partition-by-uid"`
+		Ordered        bool
+		// 有序模式下交给outbox的消息会阻塞此用户后续的消息，最多阻塞这么久，一般和carrier的 consumer.ordered-block-ttl 一致
+		OrderedBlockTTL time.Duration `mapstructure:"ordered-block-ttl"`
+		// 为空则不投递已读回执
+		ReceiptTopic string `mapstructure:"receipt-topic"`
+		// 高优先级消息的推送任务投递至此，为空则和普通的一样投递至 Topic
+		PriorityTopic string `mapstructure:"priority-topic"`
+		// 会话上下线事件投递至此，为空则不投递
+		PresenceTopic string `mapstructure:"presence-topic"`
+	}
+	// 会话相关任务(踢出/下发离线消息)的outbox投递
+	Outbox struct {
+		Interval   time.Duration
+		BatchCount int `mapstructure:"batch-count"`
+		// 认领之后多久未投递成功则可被重新认领
+		RetryAfter time.Duration `mapstructure:"retry-after"`
+	}
+	// boat租约到期后清理其会话
+	Reaper struct {
+		Interval   time.Duration
+		BatchCount int `mapstructure:"batch-count"`
+		// 认领之后多久未清理完则可被重新认领
+		RetryAfter time.Duration `mapstructure:"retry-after"`
+	}
+	// 未读(离线)消息数目的查询
+	Unread struct {
+		// 请求未指定平台时统计这些，一般和carrier的 platform.names 一致
+		Platforms []string
+		// 只统计发出时间在此之内的，一般和carrier的 offline.expire 一致
+		Expire time.Duration
+	}
+	// Query 服务本身不鉴权
+	Query struct {
+		// Query.Sessions 是否返回设备ID、客户端IP和claims，只在查询接口仅内网可达时开启
+		ExposeSessionMetadata bool `mapstructure:"expose-session-metadata"`
 	}
 }
 
@@ -13,5 +53,76 @@ func (cfg *Config) Valid() error {
 		return errors.Errorf("producer.topic must be non-empty")
 	}
 
+	// 分开投递的话就无法保证同一用户的消息有序了
+	if len(cfg.Producer.PriorityTopic) > 0 {
+		if cfg.Producer.Ordered {
+			return errors.Errorf("producer.priority-topic is not supported with producer.ordered")
+		}
+
+		if cfg.Producer.PriorityTopic == cfg.Producer.Topic {
+			return errors.Errorf("producer.priority-topic cant equal to producer.topic")
+		}
+	}
+
+	if cfg.Producer.Ordered && cfg.Producer.OrderedBlockTTL <= 0 {
+		return errors.Errorf("producer.ordered-block-ttl must > 0")
+	}
+
+	if cfg.Outbox.Interval <= 0 {
+		return errors.Errorf("outbox.interval must > 0")
+	}
+
+	if cfg.Outbox.BatchCount <= 0 {
+		return errors.Errorf("outbox.batch-count must > 0")
+	}
+
+	if cfg.Outbox.RetryAfter <= 0 {
+		return errors.Errorf("outbox.retry-after must > 0")
+	}
+
+	if cfg.Reaper.Interval <= 0 {
+		return errors.Errorf("reaper.interval must > 0")
+	}
+
+	if cfg.Reaper.BatchCount <= 0 {
+		return errors.Errorf("reaper.batch-count must > 0")
+	}
+
+	if cfg.Reaper.RetryAfter <= 0 {
+		return errors.Errorf("reaper.retry-after must > 0")
+	}
+
+	if len(cfg.Unread.Platforms) <= 0 {
+		return errors.Errorf("unread.platforms is empty")
+	}
+
+	if cfg.Unread.Expire <= 0 {
+		return errors.Errorf("unread.expire must > 0")
+	}
+
 	return nil
 }
+
+// 和carrier的消费配置相对应的项不能在运行时变更，否则可能投递到无人消费的topic或者破坏有序
+// 将其恢复为旧值，返回被恢复的配置项名称
+func (cfg *Config) keepImmutable(old *Config) []string {
+	changed := []string{}
+	if cfg.Producer.Topic != old.Producer.Topic {
+		changed = append(changed, "producer.topic")
+		cfg.Producer.Topic = old.Producer.Topic
+	}
+	if cfg.Producer.PriorityTopic != old.Producer.PriorityTopic {
+		changed = append(changed, "producer.priority-topic")
+		cfg.Producer.PriorityTopic = old.Producer.PriorityTopic
+	}
+	if cfg.Producer.PartitionByUid != old.Producer.PartitionByUid {
+		changed = append(changed, "producer.partition-by-uid")
+		cfg.Producer.PartitionByUid = old.Producer.PartitionByUid
+	}
+	if cfg.Producer.Ordered != old.Producer.Ordered {
+		changed = append(changed, "producer.ordered")
+		cfg.Producer.Ordered = old.Producer.Ordered
+	}
+
+	return changed
+}
@@ -1,15 +1,19 @@
 package station
 
 import (
-	"github.com/Shopify/sarama"
-	"github.com/gomodule/redigo/redis"
+	"sync/atomic"
+
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_logrus "github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
 	grpc_recovery "github.com/grpc-ecosystem/go-grpc-middleware/recovery"
 	"github.com/molon/gomsg/internal/pb/stationpb"
+	"github.com/molon/gomsg/internal/pkg/audit"
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/gomsg/internal/pkg/offline"
 	"github.com/molon/gomsg/internal/pkg/sessionstore"
 	"github.com/molon/gomsg/pb/authpb"
 	"github.com/molon/gomsg/pb/pushpb"
+	"github.com/molon/gomsg/pb/querypb"
 	"github.com/molon/pkg/errors"
 	"github.com/molon/pkg/tracing/otgrpc"
 	"github.com/sirupsen/logrus"
@@ -21,20 +25,26 @@ var global *globalCtx
 var plog *logrus.Entry
 
 type globalCtx struct {
-	config    Config
-	authCli   authpb.AuthClient
-	redisPool *redis.Pool
-	producer  sarama.SyncProducer
+	config   atomic.Value // *Config，可热更新
+	authCli  authpb.AuthClient
+	producer mq.Producer
+	offstore offline.Store
+	// 未开启审计时为nil
+	audit *audit.Store
 
-	sstore *sessionstore.Store
+	sstore sessionstore.StationStore
+	relay  *relay
+	reaper *reaper
 }
 
 func Init(
 	config Config,
 	logger *logrus.Logger,
 	authCli authpb.AuthClient,
-	redisPool *redis.Pool,
-	producer sarama.SyncProducer,
+	sstore sessionstore.StationStore,
+	producer mq.Producer,
+	offstore offline.Store,
+	auditStore *audit.Store,
 ) error {
 	if err := config.Valid(); err != nil {
 		return err
@@ -42,12 +52,48 @@ func Init(
 
 	plog = logrus.NewEntry(logger)
 	global = &globalCtx{
-		config:   config,
 		authCli:  authCli,
 		producer: producer,
-		sstore:   sessionstore.NewStore(logger, redisPool),
+		offstore: offstore,
+		audit:    auditStore,
+		sstore:   sstore,
+		relay:    newRelay(),
+		reaper:   newReaper(),
+	}
+	global.config.Store(&config)
+
+	global.relay.start()
+	global.reaper.start()
+
+	return nil
+}
+
+// 停止outbox的投递以及boat会话的清理，需在producer关闭之前调用
+func Stop() {
+	global.reaper.stop()
+	global.relay.stop()
+}
+
+// 当前配置，不要修改其内容
+func (g *globalCtx) cfg() *Config {
+	return g.config.Load().(*Config)
+}
+
+// 热更新配置，校验不通过则返回错误，继续使用旧配置
+// 运行时无法变更的配置项会保持旧值，以保持之后的结果来校验
+func Reload(config Config) error {
+	changed := config.keepImmutable(global.cfg())
+
+	if err := config.Valid(); err != nil {
+		return err
+	}
+
+	if len(changed) > 0 {
+		plog.Warnf("Reload station config: %v can not be changed at runtime, restart to apply", changed)
 	}
 
+	global.config.Store(&config)
+	plog.Infof("Reload station config")
 	return nil
 }
 
@@ -79,5 +125,6 @@ func NewGRPCServer(opts ...grpc.ServerOption) (*grpc.Server, error) {
 	s := grpc.NewServer(opts...)
 	stationpb.RegisterStationServer(s, &grpcServer{})
 	pushpb.RegisterPushServer(s, &pushGrpcServer{})
+	querypb.RegisterQueryServer(s, &queryGrpcServer{})
 	return s, nil
 }
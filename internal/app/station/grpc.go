@@ -2,7 +2,10 @@ package station
 
 import (
 	"context"
+	"time"
 
+	"github.com/golang/protobuf/ptypes"
+	"github.com/molon/gomsg/internal/pb/mqpb"
 	"google.golang.org/grpc/codes"
 
 	"github.com/molon/gomsg/pb/errorpb"
@@ -38,28 +41,56 @@ func (s *grpcServer) Connect(ctx context.Context, in *stationpb.ConnectRequest)
 		return nil, err
 	}
 
+	// 要通知踢出的老会话以及要下发的离线消息，和会话一起原子写入outbox，由relay保证投递
+	outbox := []*sessionstore.OutboxMessage{}
 	if len(platformToSessions[out.GetPlatform()]) > 0 {
 		kickSids := []string{}
 		for _, sess := range platformToSessions[out.GetPlatform()] {
 			kickSids = append(kickSids, sess.Sid)
 		}
-		// 要通知踢出这些会话
-		pubKickoutSessions(out.GetUid(), kickSids, errorpb.Code_NEW_SESSION_ON_SAME_PLATFORM)
+		msgs, err := kickoutSessionsOutbox(ctx, out.GetUid(), kickSids, errorpb.Code_NEW_SESSION_ON_SAME_PLATFORM)
+		if err != nil {
+			return nil, err
+		}
+		outbox = append(outbox, msgs...)
+	}
+
+	// 客户端自行分页拉取离线消息的，不推送，推送成功的会被删除，会拉取不到历史
+	if !in.GetNoOfflinePush() {
+		msgs, err := sendOfflineToSessionsOutbox(ctx, out.GetUid(), []string{in.GetSid()})
+		if err != nil {
+			return nil, err
+		}
+		outbox = append(outbox, msgs...)
 	}
 
 	// 记录新会话信息
-	if err := global.sstore.SetSession(ctx, sessionstore.Session{
+	sess := sessionstore.Session{
 		Bid:      in.GetBoatId(),
 		Sid:      in.GetSid(),
 		Uid:      out.GetUid(),
 		Platform: out.GetPlatform(),
-	}); err != nil {
+		LeaseGen: in.GetLeaseGen(),
+		Metadata: &sessionstore.Metadata{
+			ConnectedAt:   time.Now(),
+			ClientVersion: in.GetClientVersion(),
+			DeviceId:      in.GetDeviceId(),
+			RemoteIp:      in.GetRemoteIp(),
+			UserAgent:     in.GetUserAgent(),
+			Claims:        out.GetClaims(),
+		},
+	}
+	if err := global.sstore.SetSessionWithOutbox(ctx, sess, outbox); err != nil {
 		return nil, err
 	}
 
-	// 尝试下发离线消息
-	if err := pubSendOfflineToSessions(out.GetUid(), []string{in.GetSid()}); err != nil {
-		plog.Warnf("pubSendOfflineToSessions failed: %+v", err)
+	// 尽快投递，不必等relay的下一轮
+	global.relay.wakeup()
+	connectsCounter.WithLabelValues(sess.Platform).Inc()
+
+	// 上下线事件丢了也无大碍，打印日志即可
+	if err := pubPresence(mqpb.Presence_ONLINE, false, sess); err != nil {
+		plog.Warnf("Publish presence failed: %+v", err)
 	}
 
 	return &stationpb.ConnectResponse{
@@ -71,8 +102,75 @@ func (s *grpcServer) Connect(ctx context.Context, in *stationpb.ConnectRequest)
 // boat服务在新会话断开之后应该调用此方法
 // 内部会删除对应会话信息
 func (s *grpcServer) Disconnect(ctx context.Context, in *stationpb.DisconnectRequest) (*empty.Empty, error) {
+	sess := sessionstore.Session{
+		Bid:      in.GetBoatId(),
+		Sid:      in.GetSid(),
+		Uid:      in.GetUid(),
+		Platform: in.GetPlatform(),
+	}
+
+	// 附加信息随下线事件一起投递，读取失败也不影响断开
+	registered := true
+	sesses, err := global.sstore.GetSidToSession(ctx, in.GetUid(), sessionstore.WithSids([]string{in.GetSid()}), sessionstore.WithMetadata())
+	if err != nil {
+		plog.Warnf("Get session failed: %+v", err)
+	} else if existing, ok := sesses[in.GetSid()]; ok {
+		sess.Metadata = existing.Metadata
+	} else {
+		registered = false
+	}
+
 	if err := global.sstore.DeleteSessions(ctx, in.GetUid(), []string{in.GetSid()}); err != nil {
 		return nil, err
 	}
+
+	// 租约丢失过且登记已不在，说明已被reaper清理并投递过下线事件，不再重复
+	if in.GetLeaseLost() && !registered {
+		return &empty.Empty{}, nil
+	}
+	disconnectsCounter.WithLabelValues(sess.Platform, "false").Inc()
+
+	// 会话登记可能已被清理(例如被踢出)，但依然是此时才真正下线
+	if err := pubPresence(mqpb.Presence_OFFLINE, false, sess); err != nil {
+		plog.Warnf("Publish presence failed: %+v", err)
+	}
 	return &empty.Empty{}, nil
 }
+
+// boat服务收到会话的已读回执后应该调用此方法
+// 内部会将其投递到回执事件流里
+func (s *grpcServer) Read(ctx context.Context, in *stationpb.ReadRequest) (*empty.Empty, error) {
+	if len(in.GetSeqs()) <= 0 {
+		return &empty.Empty{}, nil
+	}
+
+	if err := pubReadReceipt(in.GetUid(), in.GetPlatform(), in.GetSid(), in.GetSeqs()); err != nil {
+		return nil, err
+	}
+	return &empty.Empty{}, nil
+}
+
+// boat服务应该定期调用此方法续约，租约到期后其会话登记会被reaper清理
+func (s *grpcServer) Heartbeat(ctx context.Context, in *stationpb.HeartbeatRequest) (*stationpb.HeartbeatResponse, error) {
+	if len(in.GetBoatId()) < 1 {
+		return nil, errors.Statusf(codes.InvalidArgument, "boat_id is required")
+	}
+
+	ttl, err := ptypes.Duration(in.GetTtl())
+	if err != nil {
+		return nil, errors.Statusf(codes.InvalidArgument, "invalid ttl: %v", err)
+	}
+	if ttl <= 0 {
+		return nil, errors.Statusf(codes.InvalidArgument, "ttl must > 0")
+	}
+
+	lost, gen, err := global.sstore.RenewLease(ctx, in.GetBoatId(), ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stationpb.HeartbeatResponse{
+		Lost:     lost,
+		LeaseGen: gen,
+	}, nil
+}
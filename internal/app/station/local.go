@@ -0,0 +1,40 @@
+package station
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/molon/gomsg/internal/pb/stationpb"
+	"github.com/molon/gomsg/internal/pkg/inproc"
+	"google.golang.org/grpc"
+)
+
+// 进程内直接调用的客户端，all-in-one 模式下使用，需要在Init之后使用
+// 不会经过gRPC的拦截器，CallOption也会被忽略
+func NewLocalClient() stationpb.StationClient {
+	return &localClient{}
+}
+
+type localClient struct {
+	s grpcServer
+}
+
+func (c *localClient) Connect(ctx context.Context, in *stationpb.ConnectRequest, opts ...grpc.CallOption) (*stationpb.ConnectResponse, error) {
+	out, err := c.s.Connect(ctx, in)
+	return out, inproc.StatusError(err)
+}
+
+func (c *localClient) Disconnect(ctx context.Context, in *stationpb.DisconnectRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out, err := c.s.Disconnect(ctx, in)
+	return out, inproc.StatusError(err)
+}
+
+func (c *localClient) Read(ctx context.Context, in *stationpb.ReadRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out, err := c.s.Read(ctx, in)
+	return out, inproc.StatusError(err)
+}
+
+func (c *localClient) Heartbeat(ctx context.Context, in *stationpb.HeartbeatRequest, opts ...grpc.CallOption) (*stationpb.HeartbeatResponse, error) {
+	out, err := c.s.Heartbeat(ctx, in)
+	return out, inproc.StatusError(err)
+}
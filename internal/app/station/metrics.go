@@ -0,0 +1,63 @@
+package station
+
+import (
+	"time"
+
+	"github.com/molon/gomsg/internal/pkg/metrics"
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	pushRequestsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gomsg",
+		Subsystem: "station",
+		Name:      "push_requests_total",
+		Help:      "Push requests by result.",
+	}, []string{"result"})
+
+	pushPayloadsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "gomsg",
+		Subsystem: "station",
+		Name:      "push_payloads_total",
+		Help:      "Payloads published for push requests, one per target uid.",
+	})
+
+	publishLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gomsg",
+		Subsystem: "station",
+		Name:      "publish_duration_seconds",
+		Help:      "Latency of publishing to mq.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 12),
+	}, []string{"topic", "result"})
+
+	connectsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gomsg",
+		Subsystem: "station",
+		Name:      "connects_total",
+		Help:      "Sessions connected.",
+	}, []string{"platform"})
+
+	// reaped 为 true 的是boat租约到期后被清理的
+	disconnectsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gomsg",
+		Subsystem: "station",
+		Name:      "disconnects_total",
+		Help:      "Sessions disconnected.",
+	}, []string{"platform", "reaped"})
+)
+
+// 投递至mq并记录耗时，同一批消息以第一条的topic为准
+func publish(msgs ...*mq.Message) error {
+	if len(msgs) <= 0 {
+		return nil
+	}
+
+	start := time.Now()
+	err := global.producer.Publish(msgs...)
+
+	publishLatency.WithLabelValues(msgs[0].Topic, metrics.ResultOf(err)).Observe(time.Since(start).Seconds())
+
+	return err
+}
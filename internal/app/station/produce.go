@@ -1,26 +1,31 @@
 package station
 
 import (
+	"context"
+
 	"github.com/golang/protobuf/ptypes"
 	"github.com/molon/pkg/errors"
 
-	"github.com/Shopify/sarama"
 	"github.com/golang/protobuf/proto"
 	"github.com/molon/gomsg/internal/pb/mqpb"
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/gomsg/internal/pkg/mqtrace"
+	"github.com/molon/gomsg/internal/pkg/sessionstore"
 	"github.com/molon/gomsg/pb/errorpb"
 	"github.com/rs/xid"
 )
 
-func pubUidSids(uid string, sids []string, fillBody func(payload *mqpb.Payload, uid string, sid string)) error {
+// 构造针对某uid的若干会话的任务，写入outbox后由relay投递
+func uidSidsOutbox(ctx context.Context, uid string, sids []string, fillBody func(payload *mqpb.Payload, uid string, sid string)) ([]*sessionstore.OutboxMessage, error) {
 	if len(uid) < 1 {
-		return errors.Errorf("uid is empty")
+		return nil, errors.Errorf("uid is empty")
 	}
 	if len(sids) <= 0 {
-		return errors.Errorf("sids is empty")
+		return nil, errors.Errorf("sids is empty")
 	}
 
 	now := ptypes.TimestampNow()
-	msgs := []*sarama.ProducerMessage{}
+	msgs := []*sessionstore.OutboxMessage{}
 
 	for _, sid := range sids {
 		mw := &mqpb.Payload{
@@ -29,30 +34,28 @@ func pubUidSids(uid string, sids []string, fillBody func(payload *mqpb.Payload,
 			RetryCount: 0,
 		}
 		fillBody(mw, uid, sid)
+		mqtrace.Inject(ctx, mw)
 
 		b, err := proto.Marshal(mw)
 		if err != nil {
-			return errors.WithStack(err)
+			return nil, errors.WithStack(err)
 		}
 
-		m := &sarama.ProducerMessage{
-			Key:   sarama.StringEncoder(uid), // 主要是为了kafka分区，也能尽可能保证相同uid的消息都被同一个消费者进行消费
-			Topic: global.config.Producer.Topic,
-			Value: sarama.ByteEncoder(b),
+		m := &sessionstore.OutboxMessage{
+			Id:    mw.Seq,
+			Key:   uid, // 主要是为了kafka分区，也能尽可能保证相同uid的消息都被同一个消费者进行消费
+			Topic: global.cfg().Producer.Topic,
+			Value: b,
 		}
 
 		msgs = append(msgs, m)
 	}
 
-	if err := global.producer.SendMessages(msgs); err != nil {
-		return errors.WithStack(err)
-	}
-
-	return nil
+	return msgs, nil
 }
 
-func pubKickoutSessions(uid string, sids []string, code errorpb.Code) error {
-	if err := pubUidSids(uid, sids,
+func kickoutSessionsOutbox(ctx context.Context, uid string, sids []string, code errorpb.Code) ([]*sessionstore.OutboxMessage, error) {
+	return uidSidsOutbox(ctx, uid, sids,
 		func(payload *mqpb.Payload, uid string, sid string) {
 			payload.Body = &mqpb.Payload_KickoutSession{
 				KickoutSession: &mqpb.KickoutSession{
@@ -62,16 +65,11 @@ func pubKickoutSessions(uid string, sids []string, code errorpb.Code) error {
 				},
 			}
 		},
-	); err != nil {
-		return err
-	}
-
-	plog.Debugf("KickoutSessions %v(%v)", uid, sids)
-	return nil
+	)
 }
 
-func pubSendOfflineToSessions(uid string, sids []string) error {
-	if err := pubUidSids(uid, sids,
+func sendOfflineToSessionsOutbox(ctx context.Context, uid string, sids []string) ([]*sessionstore.OutboxMessage, error) {
+	return uidSidsOutbox(ctx, uid, sids,
 		func(payload *mqpb.Payload, uid string, sid string) {
 			payload.Body = &mqpb.Payload_SendOfflineToSession{
 				SendOfflineToSession: &mqpb.SendOfflineToSession{
@@ -80,10 +78,91 @@ func pubSendOfflineToSessions(uid string, sids []string) error {
 				},
 			}
 		},
-	); err != nil {
-		return err
+	)
+}
+
+// 投递已读回执，producer.receipt-topic 为空则忽略
+func pubReadReceipt(uid string, platform string, sid string, seqs []string) error {
+	topic := global.cfg().Producer.ReceiptTopic
+	if len(topic) < 1 {
+		return nil
+	}
+
+	mw := &mqpb.Payload{
+		Seq:       xid.New().String(),
+		Timestamp: ptypes.TimestampNow(),
+		Body: &mqpb.Payload_Receipt{
+			Receipt: &mqpb.Receipt{
+				Event:    mqpb.Receipt_READ,
+				Uid:      uid,
+				Platform: platform,
+				Sid:      sid,
+				MsgSeqs:  seqs,
+			},
+		},
+	}
+
+	b, err := proto.Marshal(mw)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := publish(&mq.Message{
+		Key:   uid,
+		Topic: topic,
+		Value: b,
+	}); err != nil {
+		return errors.WithStack(err)
+	}
+
+	plog.Debugf("ReadReceipt %v(%v) %v", uid, sid, seqs)
+	return nil
+}
+
+// 投递会话上下线事件，producer.presence-topic 为空则忽略
+// reaped 表示是因为所在boat的租约到期而被清理的
+func pubPresence(event mqpb.Presence_Event, reaped bool, sesses ...sessionstore.Session) error {
+	topic := global.cfg().Producer.PresenceTopic
+	if len(topic) < 1 || len(sesses) <= 0 {
+		return nil
+	}
+
+	now := ptypes.TimestampNow()
+	msgs := make([]*mq.Message, len(sesses))
+	for i, sess := range sesses {
+		mw := &mqpb.Payload{
+			Seq:       xid.New().String(),
+			Timestamp: now,
+			Body: &mqpb.Payload_Presence{
+				Presence: &mqpb.Presence{
+					Event:    event,
+					Uid:      sess.Uid,
+					Platform: sess.Platform,
+					Sid:      sess.Sid,
+					BoatId:   sess.Bid,
+					Reaped:   reaped,
+					// 上下线事件只投递至内部mq，附加信息完整保留
+					Metadata: metadataToPB(sess.Metadata, true),
+				},
+			},
+		}
+
+		b, err := proto.Marshal(mw)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		msgs[i] = &mq.Message{
+			Key:   sess.Uid,
+			Topic: topic,
+			Value: b,
+		}
+	}
+
+	if err := publish(msgs...); err != nil {
+		return errors.WithStack(err)
 	}
 
-	plog.Debugf("SendOfflineToSessions %v(%v)", uid, sids)
+	plog.Debugf("Presence %v %d sessions", event, len(sesses))
 	return nil
 }
@@ -2,13 +2,20 @@ package station
 
 import (
 	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
 
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
 
-	"github.com/Shopify/sarama"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/molon/gomsg/internal/pb/mqpb"
+	"github.com/molon/gomsg/internal/pkg/audit"
+	"github.com/molon/gomsg/internal/pkg/metrics"
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/gomsg/internal/pkg/mqtrace"
 	"github.com/molon/gomsg/pb/msgpb"
 	"github.com/molon/gomsg/pb/pushpb"
 	"github.com/molon/pkg/errors"
@@ -17,22 +24,63 @@ import (
 
 type pushGrpcServer struct{}
 
-func (s *pushGrpcServer) Push(ctx context.Context, in *pushpb.PushRequest) (*empty.Empty, error) {
+func (s *pushGrpcServer) Push(ctx context.Context, in *pushpb.PushRequest) (_ *empty.Empty, rerr error) {
+	defer func() {
+		pushRequestsCounter.WithLabelValues(metrics.ResultOf(rerr)).Inc()
+	}()
+
 	msgCount := len(in.GetMsgBodies())
 	if msgCount <= 0 {
 		return &empty.Empty{}, nil
 	}
 
+	// 整个请求使用同一份配置，避免热更新时前后不一致
+	cfg := global.cfg()
+
 	// 根据request构造出一堆mq消息，以uid为粒度分发
 	now := ptypes.TimestampNow()
 
+	// 根据存活时间计算出过期时间
+	var expireAt *timestamp.Timestamp
+	if in.GetMsgTtl() != nil {
+		ttl, err := ptypes.Duration(in.GetMsgTtl())
+		if err != nil {
+			return nil, errors.Statusf(codes.InvalidArgument, "invalid msg_ttl: %v", err)
+		}
+		if ttl <= 0 {
+			return nil, errors.Statusf(codes.InvalidArgument, "msg_ttl must > 0")
+		}
+		expireAt, _ = ptypes.TimestampProto(time.Now().Add(ttl))
+	}
+
+	if _, ok := msgpb.MessagePriority_name[int32(in.GetMsgPriority())]; !ok {
+		return nil, errors.Statusf(codes.InvalidArgument, "invalid msg_priority: %d", in.GetMsgPriority())
+	}
+
+	// 高优先级的走单独的topic，不受普通消息积压的影响
+	topic := cfg.Producer.Topic
+	if in.GetMsgPriority() > msgpb.MessagePriority_NORMAL && len(cfg.Producer.PriorityTopic) > 0 {
+		topic = cfg.Producer.PriorityTopic
+	}
+
 	// 先给消息挨个生成seq
 	seqs := make([]string, msgCount)
 	for i := 0; i < msgCount; i++ {
 		seqs[i] = xid.New().String()
 	}
 
-	pms := []*sarama.ProducerMessage{}
+	// 有序模式下需要给每个用户的消息分配单调递增的序号
+	var uid2LastSeq map[string]int64
+	if cfg.Producer.Ordered {
+		var err error
+		uid2LastSeq, err = global.sstore.IncrUidSeqs(ctx, in.GetUids(), int64(msgCount))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pbs := []*mqpb.Payload{}
+	pms := []*mq.Message{}
 	for _, uid := range in.GetUids() {
 		opts, ok := in.GetExclusiveMsgOptions()[uid]
 		if !ok {
@@ -46,11 +94,19 @@ func (s *pushGrpcServer) Push(ctx context.Context, in *pushpb.PushRequest) (*emp
 
 		msgs := []*msgpb.Message{}
 		for i, body := range in.GetMsgBodies() {
-			msgs = append(msgs, &msgpb.Message{
-				Seq:     seqs[i],
-				Options: opts,
-				Body:    body,
-			})
+			msg := &msgpb.Message{
+				Seq:         seqs[i],
+				Options:     opts,
+				Body:        body,
+				ExpireAt:    expireAt,
+				Category:    in.GetMsgCategory(),
+				Priority:    in.GetMsgPriority(),
+				CollapseKey: in.GetMsgCollapseKey(),
+			}
+			if lastSeq, ok := uid2LastSeq[uid]; ok {
+				msg.UidSeq = lastSeq - int64(msgCount-1-i)
+			}
+			msgs = append(msgs, msg)
 		}
 
 		pb := &mqpb.Payload{
@@ -67,24 +123,74 @@ func (s *pushGrpcServer) Push(ctx context.Context, in *pushpb.PushRequest) (*emp
 			},
 		}
 
+		// carrier据此延续此次请求的trace
+		mqtrace.Inject(ctx, pb)
+
 		b, err := proto.Marshal(pb)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
 
-		pm := &sarama.ProducerMessage{
-			Key:   sarama.StringEncoder(pb.Seq), // 仅仅为了kafka分区而已
-			Topic: global.config.Producer.Topic,
-			Value: sarama.ByteEncoder(b),
+		// 默认仅仅为了kafka分区而已，打散到各分区
+		// 若需carrier合并同一uid的消息或者有序投递，则需要以uid分区
+		key := pb.Seq
+		if cfg.Producer.PartitionByUid || cfg.Producer.Ordered {
+			key = uid
+		}
+
+		pm := &mq.Message{
+			Key:   key,
+			Topic: topic,
+			Value: b,
 		}
 
+		pbs = append(pbs, pb)
 		pms = append(pms, pm)
 	}
 
+	// 投递之前就打上时间，异步写入即使晚于carrier的记录，查询时按时间排序也不会乱
+	auditUids(in.GetUids(), seqs, audit.Event{Stage: audit.StageAccepted, Detail: topic})
+
 	// 投递至mq
-	if err := global.producer.SendMessages(pms); err != nil {
-		return nil, errors.WithStack(err)
+	if err := publish(pms...); err != nil {
+		if uid2LastSeq == nil {
+			auditUids(in.GetUids(), seqs, audit.Event{Stage: audit.StagePublishFailed, Detail: topic})
+			return nil, errors.WithStack(err)
+		}
+		plog.Warnf("Publish ordered payloads failed, hand off to outbox: %+v", err)
+
+		var failed []int
+		if perr, ok := err.(*mq.PublishError); ok {
+			failed = perr.Failed
+		} else {
+			failed = make([]int, len(pms))
+			for i := range pms {
+				failed[i] = i
+			}
+		}
+
+		failedUids, err := handoffOrdered(ctx, failed, in.GetUids(), pbs, pms, uid2LastSeq, int64(msgCount))
+		if err != nil {
+			auditUids(failedUids, seqs, audit.Event{Stage: audit.StagePublishFailed, Detail: topic})
+			return nil, err
+		}
 	}
+	pushPayloadsCounter.Add(float64(len(pms)))
 
 	return &empty.Empty{}, nil
 }
+
+// 为各用户的同一批消息记录同一事件，异步写入不阻塞推送
+func auditUids(uids []string, seqs []string, ev audit.Event) {
+	if global.audit == nil {
+		return
+	}
+
+	// 同一事件的时间要一致
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	for _, uid := range uids {
+		global.audit.RecordAsync(uid, seqs, ev)
+	}
+}
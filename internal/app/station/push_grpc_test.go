@@ -0,0 +1,103 @@
+package station
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/any"
+	"github.com/molon/gomsg/internal/pb/mqpb"
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/gomsg/internal/pkg/sessionstore"
+	"github.com/molon/gomsg/pb/pushpb"
+	"github.com/molon/pkg/errors"
+)
+
+// 首次发布时让指定下标的消息失败，其余的照常发布
+type partialProducer struct {
+	memProducer
+	failOnce []int
+}
+
+func (p *partialProducer) Publish(msgs ...*mq.Message) error {
+	p.mu.Lock()
+	failed := p.failOnce
+	p.failOnce = nil
+	p.mu.Unlock()
+
+	if len(failed) <= 0 {
+		return p.memProducer.Publish(msgs...)
+	}
+
+	isFailed := map[int]bool{}
+	for _, i := range failed {
+		isFailed[i] = true
+	}
+	for i, m := range msgs {
+		if !isFailed[i] {
+			p.memProducer.Publish(m)
+		}
+	}
+	return &mq.PublishError{
+		Failed: failed,
+		Err:    errors.Errorf("broker unavailable"),
+	}
+}
+
+// 各用户收到的ToUid消息
+func (p *memProducer) toUidPayloads(t *testing.T) map[string][]*mqpb.Payload {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ret := map[string][]*mqpb.Payload{}
+	for _, m := range p.msgs {
+		pb := &mqpb.Payload{}
+		if err := proto.Unmarshal(m.Value, pb); err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		if uid := pb.GetToUid().GetUid(); uid != "" {
+			ret[uid] = append(ret[uid], pb)
+		}
+	}
+	return ret
+}
+
+// 有序模式下部分投递失败，只有失败的交给outbox，且阻塞此用户后续的消息
+func TestPushOrderedPartlyFailed(t *testing.T) {
+	cfg := memStationConfig()
+	cfg.Producer.Ordered = true
+	producer := &partialProducer{failOnce: []int{1}}
+	sstore := sessionstore.NewMemoryStore()
+	initMemStationWith(t, cfg, sstore, producer)
+
+	_, err := (&pushGrpcServer{}).Push(context.Background(), &pushpb.PushRequest{
+		Uids:      []string{"u1", "u2", "u3"},
+		MsgBodies: []*any.Any{{TypeUrl: "test"}},
+	})
+	if err != nil {
+		t.Fatalf("Push: %+v", err)
+	}
+
+	// 交出去的由relay稍后投递
+	if n, err := global.relay.relayOnce(); err != nil || n != 1 {
+		t.Fatalf("relayOnce: got %d %v, want 1", n, err)
+	}
+	pbs := producer.toUidPayloads(t)
+	for _, uid := range []string{"u1", "u2", "u3"} {
+		if len(pbs[uid]) != 1 {
+			t.Fatalf("payloads of %s: got %d, want 1", uid, len(pbs[uid]))
+		}
+	}
+
+	ctx := context.Background()
+	handedOff := pbs["u2"][0]
+	if blocked, err := sstore.IsBlocked(ctx, "u2", handedOff.GetSeq(), 1); err != nil || blocked {
+		t.Fatalf("IsBlocked of the handed off one: got %v %v, want false", blocked, err)
+	}
+	if blocked, err := sstore.IsBlocked(ctx, "u2", "later", 2); err != nil || !blocked {
+		t.Fatalf("IsBlocked of a later one: got %v %v, want true", blocked, err)
+	}
+	if blocked, err := sstore.IsBlocked(ctx, "u1", "later", 2); err != nil || blocked {
+		t.Fatalf("IsBlocked of u1: got %v %v, want false", blocked, err)
+	}
+}
@@ -0,0 +1,123 @@
+package station
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/molon/gomsg/internal/pkg/sessionstore"
+	"github.com/molon/gomsg/pb/querypb"
+	"github.com/molon/pkg/errors"
+	"google.golang.org/grpc/codes"
+)
+
+type queryGrpcServer struct{}
+
+func (s *queryGrpcServer) Unread(ctx context.Context, in *querypb.UnreadRequest) (*querypb.UnreadResponse, error) {
+	if in.GetUid() == "" {
+		return nil, errors.Statusf(codes.InvalidArgument, "uid is empty")
+	}
+
+	cfg := global.cfg()
+
+	platforms := in.GetPlatforms()
+	if len(platforms) <= 0 {
+		platforms = cfg.Unread.Platforms
+	}
+
+	resp := &querypb.UnreadResponse{
+		Platforms: map[string]*querypb.PlatformUnread{},
+	}
+	for _, platform := range platforms {
+		count, categories, err := global.offstore.Count(ctx, in.GetUid(), platform, cfg.Unread.Expire, in.GetByCategory())
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Platforms[platform] = &querypb.PlatformUnread{
+			Count:      count,
+			Categories: categories,
+		}
+	}
+
+	return resp, nil
+}
+
+func (s *queryGrpcServer) Sessions(ctx context.Context, in *querypb.SessionsRequest) (*querypb.SessionsResponse, error) {
+	if in.GetUid() == "" {
+		return nil, errors.Statusf(codes.InvalidArgument, "uid is empty")
+	}
+
+	sesses, err := global.sstore.GetSessions(ctx, in.GetUid(), sessionstore.WithMetadata())
+	if err != nil {
+		return nil, err
+	}
+
+	expose := global.cfg().Query.ExposeSessionMetadata
+	resp := &querypb.SessionsResponse{
+		Sessions: make([]*querypb.Session, len(sesses)),
+	}
+	for i, sess := range sesses {
+		resp.Sessions[i] = &querypb.Session{
+			Sid:      sess.Sid,
+			Platform: sess.Platform,
+			BoatId:   sess.Bid,
+			Metadata: metadataToPB(sess.Metadata, expose),
+		}
+	}
+
+	return resp, nil
+}
+
+func (s *queryGrpcServer) Audit(ctx context.Context, in *querypb.AuditRequest) (*querypb.AuditResponse, error) {
+	if in.GetUid() == "" {
+		return nil, errors.Statusf(codes.InvalidArgument, "uid is empty")
+	}
+	if in.GetSeq() == "" {
+		return nil, errors.Statusf(codes.InvalidArgument, "seq is empty")
+	}
+	if global.audit == nil {
+		return nil, errors.Statusf(codes.FailedPrecondition, "audit is disabled")
+	}
+
+	evs, err := global.audit.Timeline(ctx, in.GetUid(), in.GetSeq())
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &querypb.AuditResponse{
+		Events: make([]*querypb.AuditEvent, len(evs)),
+	}
+	for i, ev := range evs {
+		ts, _ := ptypes.TimestampProto(ev.Time)
+		resp.Events[i] = &querypb.AuditEvent{
+			Timestamp: ts,
+			Stage:     ev.Stage,
+			Platform:  ev.Platform,
+			Sid:       ev.Sid,
+			BoatId:    ev.Bid,
+			Detail:    ev.Detail,
+		}
+	}
+
+	return resp, nil
+}
+
+// 未开启 query.expose-session-metadata 时隐去设备ID、客户端IP和claims
+func metadataToPB(md *sessionstore.Metadata, expose bool) *querypb.SessionMetadata {
+	if md == nil {
+		return nil
+	}
+
+	connectedAt, _ := ptypes.TimestampProto(md.ConnectedAt)
+	pb := &querypb.SessionMetadata{
+		ConnectedAt:   connectedAt,
+		ClientVersion: md.ClientVersion,
+		UserAgent:     md.UserAgent,
+	}
+	if expose {
+		pb.DeviceId = md.DeviceId
+		pb.RemoteIp = md.RemoteIp
+		pb.Claims = md.Claims
+	}
+	return pb
+}
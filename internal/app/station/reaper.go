@@ -0,0 +1,95 @@
+package station
+
+import (
+	"context"
+	"time"
+
+	"github.com/molon/gomsg/internal/pb/mqpb"
+	"github.com/molon/gomsg/internal/pkg/sessionstore"
+	"github.com/molon/pkg/util"
+)
+
+// 清理租约到期(例如崩溃)的boat上的会话登记，并投递其下线事件
+// 否则这些会话会一直被认为在线，直到有消息推送给它们时才会被carrier发现
+// 多个station实例可以同时运行，同一boat在认领期内只会被一个实例清理
+type reaper struct {
+	tomb   *util.LoopTomb
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newReaper() *reaper {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &reaper{
+		tomb:   util.NewLoopTomb(),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (r *reaper) start() {
+	r.tomb.Go(r.loop)
+}
+
+func (r *reaper) stop() {
+	r.cancel()
+	r.tomb.Close() // stop and wait
+}
+
+func (r *reaper) loop(stopC <-chan struct{}) {
+	for {
+		cfg := global.cfg().Reaper
+
+		// 一批满了说明可能还有，继续认领
+		for {
+			n, err := r.reapOnce()
+			if err != nil {
+				plog.Warnf("Reap boats failed: %+v", err)
+				break
+			}
+			if n < cfg.BatchCount {
+				break
+			}
+		}
+
+		timer := time.NewTimer(cfg.Interval)
+		select {
+		case <-timer.C:
+		case <-stopC:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (r *reaper) reapOnce() (int, error) {
+	cfg := global.cfg().Reaper
+
+	bids, err := global.sstore.ClaimExpiredBoats(r.ctx, cfg.BatchCount, cfg.RetryAfter)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, bid := range bids {
+		count := 0
+		// 失败的等认领过期后会被重新清理
+		if err := global.sstore.ReapBoat(r.ctx, bid, func(sesses []sessionstore.Session) error {
+			count += len(sesses)
+			for _, sess := range sesses {
+				disconnectsCounter.WithLabelValues(sess.Platform, "true").Inc()
+			}
+			// 下线事件丢了也无大碍，不影响清理
+			if err := pubPresence(mqpb.Presence_OFFLINE, true, sesses...); err != nil {
+				plog.Warnf("Publish presence failed: %+v", err)
+			}
+			return nil
+		}); err != nil {
+			plog.Warnf("Reap boat(%s) failed: %+v", bid, err)
+			continue
+		}
+
+		plog.Infof("Reap boat(%s) with %d sessions", bid, count)
+	}
+
+	return len(bids), nil
+}
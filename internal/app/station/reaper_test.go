@@ -0,0 +1,181 @@
+package station
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/molon/gomsg/internal/pb/mqpb"
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/gomsg/internal/pkg/sessionstore"
+	"github.com/sirupsen/logrus"
+)
+
+type memProducer struct {
+	mu   sync.Mutex
+	msgs []*mq.Message
+}
+
+func (p *memProducer) Publish(msgs ...*mq.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.msgs = append(p.msgs, msgs...)
+	return nil
+}
+
+func (p *memProducer) Close() error {
+	return nil
+}
+
+// 下线事件里的sid
+func (p *memProducer) offlineSids(t *testing.T) map[string]bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sids := map[string]bool{}
+	for _, m := range p.msgs {
+		pb := &mqpb.Payload{}
+		if err := proto.Unmarshal(m.Value, pb); err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		if pb.GetPresence().GetEvent() == mqpb.Presence_OFFLINE && pb.GetPresence().GetReaped() {
+			sids[pb.GetPresence().GetSid()] = true
+		}
+	}
+	return sids
+}
+
+func memStationConfig() Config {
+	cfg := Config{}
+	cfg.Producer.Topic = "msg"
+	cfg.Producer.PresenceTopic = "presence"
+	cfg.Producer.OrderedBlockTTL = time.Hour
+	cfg.Outbox.Interval = time.Hour
+	cfg.Outbox.BatchCount = 10
+	cfg.Outbox.RetryAfter = time.Hour
+	cfg.Reaper.Interval = time.Hour
+	cfg.Reaper.BatchCount = 10
+	cfg.Reaper.RetryAfter = time.Millisecond
+	cfg.Unread.Platforms = []string{"mobile"}
+	cfg.Unread.Expire = time.Hour
+	return cfg
+}
+
+// 以进程内的会话存储初始化station，后台的清理每小时才执行一次，测试里直接调用 reapOnce
+func initMemStation(t *testing.T) (*sessionstore.MemoryStore, *memProducer) {
+	sstore := sessionstore.NewMemoryStore()
+	producer := &memProducer{}
+	initMemStationWith(t, memStationConfig(), sstore, producer)
+	return sstore, producer
+}
+
+func initMemStationWith(t *testing.T, cfg Config, sstore sessionstore.StationStore, producer mq.Producer) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	if err := Init(cfg, logger, nil, sstore, producer, nil, nil); err != nil {
+		t.Fatalf("Init: %+v", err)
+	}
+	t.Cleanup(Stop)
+}
+
+func renewLease(t *testing.T, sstore *sessionstore.MemoryStore, bid string, ttl time.Duration) int64 {
+	_, gen, err := sstore.RenewLease(context.Background(), bid, ttl)
+	if err != nil {
+		t.Fatalf("RenewLease: %+v", err)
+	}
+	return gen
+}
+
+func setSession(t *testing.T, sstore *sessionstore.MemoryStore, sess sessionstore.Session) {
+	if err := sstore.SetSession(context.Background(), sess); err != nil {
+		t.Fatalf("SetSession: %+v", err)
+	}
+}
+
+func sids(t *testing.T, sstore *sessionstore.MemoryStore, uid string) map[string]bool {
+	sesses, err := sstore.GetSidToSession(context.Background(), uid)
+	if err != nil {
+		t.Fatalf("GetSidToSession: %+v", err)
+	}
+	ret := map[string]bool{}
+	for sid := range sesses {
+		ret[sid] = true
+	}
+	return ret
+}
+
+func TestReapExpiredBoat(t *testing.T) {
+	sstore, producer := initMemStation(t)
+
+	gen := renewLease(t, sstore, "b1", 50*time.Millisecond)
+	renewLease(t, sstore, "b2", time.Hour)
+	setSession(t, sstore, sessionstore.Session{Sid: "s1", Uid: "u1", Bid: "b1", Platform: "mobile", LeaseGen: gen})
+	setSession(t, sstore, sessionstore.Session{Sid: "s2", Uid: "u2", Bid: "b1", Platform: "desktop", LeaseGen: gen})
+	setSession(t, sstore, sessionstore.Session{Sid: "s3", Uid: "u1", Bid: "b2", Platform: "mobile", LeaseGen: 1})
+
+	time.Sleep(100 * time.Millisecond)
+
+	n, err := global.reaper.reapOnce()
+	if err != nil {
+		t.Fatalf("reapOnce: %+v", err)
+	}
+	if n != 1 {
+		t.Fatalf("reapOnce: got %d boats, want 1", n)
+	}
+
+	if got := sids(t, sstore, "u1"); len(got) != 1 || !got["s3"] {
+		t.Fatalf("sessions of u1: got %v, want s3", got)
+	}
+	if got := sids(t, sstore, "u2"); len(got) != 0 {
+		t.Fatalf("sessions of u2: got %v, want none", got)
+	}
+	if got := producer.offlineSids(t); len(got) != 2 || !got["s1"] || !got["s2"] {
+		t.Fatalf("offline presence: got %v, want s1 s2", got)
+	}
+
+	// 已清理完毕，不会被再次认领
+	time.Sleep(5 * time.Millisecond)
+	if n, err := global.reaper.reapOnce(); err != nil || n != 0 {
+		t.Fatalf("reapOnce again: got %d %v, want 0", n, err)
+	}
+}
+
+// 认领之后boat恢复续约，新登记的会话代数更大，不能被清理
+func TestReapFencedByLeaseGen(t *testing.T) {
+	sstore, producer := initMemStation(t)
+
+	gen := renewLease(t, sstore, "b1", 50*time.Millisecond)
+	setSession(t, sstore, sessionstore.Session{Sid: "s1", Uid: "u1", Bid: "b1", Platform: "mobile", LeaseGen: gen})
+
+	time.Sleep(100 * time.Millisecond)
+	bids, err := sstore.ClaimExpiredBoats(context.Background(), 10, time.Millisecond)
+	if err != nil || len(bids) != 1 {
+		t.Fatalf("ClaimExpiredBoats: got %v %v", bids, err)
+	}
+
+	newGen := renewLease(t, sstore, "b1", time.Hour)
+	if newGen <= gen {
+		t.Fatalf("RenewLease: got gen %d, want > %d", newGen, gen)
+	}
+	setSession(t, sstore, sessionstore.Session{Sid: "s2", Uid: "u1", Bid: "b1", Platform: "mobile", LeaseGen: newGen})
+
+	// 之前的认领已过期，被重新认领后清理
+	time.Sleep(5 * time.Millisecond)
+	n, err := global.reaper.reapOnce()
+	if err != nil {
+		t.Fatalf("reapOnce: %+v", err)
+	}
+	if n != 1 {
+		t.Fatalf("reapOnce: got %d boats, want 1", n)
+	}
+
+	if got := sids(t, sstore, "u1"); len(got) != 1 || !got["s2"] {
+		t.Fatalf("sessions of u1: got %v, want s2", got)
+	}
+	if got := producer.offlineSids(t); len(got) != 1 || !got["s1"] {
+		t.Fatalf("offline presence: got %v, want s1", got)
+	}
+}
@@ -0,0 +1,106 @@
+package station
+
+import (
+	"context"
+	"time"
+
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/pkg/util"
+)
+
+// 将outbox里的任务投递至mq，投递成功后才删除，失败的等lease过后会被重新认领
+// 多个station实例可以同时运行，同一任务在lease内只会被一个实例认领
+type relay struct {
+	wakeupC chan struct{}
+	tomb    *util.LoopTomb
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+func newRelay() *relay {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &relay{
+		wakeupC: make(chan struct{}, 1),
+		tomb:    util.NewLoopTomb(),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func (r *relay) start() {
+	r.tomb.Go(r.loop)
+}
+
+func (r *relay) stop() {
+	r.cancel()
+	r.tomb.Close() // stop and wait
+}
+
+// 有新任务写入时调用，不阻塞
+func (r *relay) wakeup() {
+	select {
+	case r.wakeupC <- struct{}{}:
+	default:
+	}
+}
+
+func (r *relay) loop(stopC <-chan struct{}) {
+	for {
+		cfg := global.cfg().Outbox
+
+		// 一批满了说明可能还有，继续认领
+		for {
+			n, err := r.relayOnce()
+			if err != nil {
+				plog.Warnf("Relay outbox failed: %+v", err)
+				break
+			}
+			if n < cfg.BatchCount {
+				break
+			}
+		}
+
+		timer := time.NewTimer(cfg.Interval)
+		select {
+		case <-timer.C:
+		case <-r.wakeupC:
+			timer.Stop()
+		case <-stopC:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (r *relay) relayOnce() (int, error) {
+	cfg := global.cfg().Outbox
+
+	obms, err := global.sstore.ClaimOutbox(r.ctx, cfg.BatchCount, cfg.RetryAfter)
+	if err != nil {
+		return 0, err
+	}
+	if len(obms) <= 0 {
+		return 0, nil
+	}
+
+	msgs := make([]*mq.Message, len(obms))
+	for i, obm := range obms {
+		msgs[i] = &mq.Message{
+			Topic: obm.Topic,
+			Key:   obm.Key,
+			Value: obm.Value,
+		}
+	}
+
+	if err := publish(msgs...); err != nil {
+		return 0, err
+	}
+
+	// 删除失败的话会在lease之后被重复投递，carrier对这些任务的消费是幂等的
+	if err := global.sstore.DeleteOutbox(r.ctx, obms); err != nil {
+		return 0, err
+	}
+
+	plog.Debugf("Relay %d outbox messages", len(obms))
+	return len(obms), nil
+}
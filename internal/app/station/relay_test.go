@@ -0,0 +1,107 @@
+package station
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/molon/gomsg/internal/pkg/sessionstore"
+	"github.com/molon/pkg/errors"
+)
+
+// 可以让删除失败，模拟投递成功之后还没来得及删除就挂掉
+type flakyStore struct {
+	*sessionstore.MemoryStore
+
+	mu         sync.Mutex
+	failDelete bool
+}
+
+func (s *flakyStore) DeleteOutbox(ctx context.Context, msgs []*sessionstore.OutboxMessage) error {
+	s.mu.Lock()
+	fail := s.failDelete
+	s.failDelete = false
+	s.mu.Unlock()
+
+	if fail {
+		return errors.Errorf("connection lost")
+	}
+	return s.MemoryStore.DeleteOutbox(ctx, msgs)
+}
+
+func addOutbox(t *testing.T, sstore sessionstore.StationStore, uid string, ids ...string) {
+	msgs := []*sessionstore.OutboxMessage{}
+	for _, id := range ids {
+		msgs = append(msgs, &sessionstore.OutboxMessage{Id: id, Topic: "msg", Key: uid, Value: []byte(id)})
+	}
+	if err := sstore.AddOutbox(context.Background(), uid, msgs); err != nil {
+		t.Fatalf("AddOutbox: %+v", err)
+	}
+}
+
+func (p *memProducer) values() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ret := []string{}
+	for _, m := range p.msgs {
+		ret = append(ret, string(m.Value))
+	}
+	return ret
+}
+
+func TestRelayPublishThenDelete(t *testing.T) {
+	sstore, producer := initMemStation(t)
+	addOutbox(t, sstore, "u1", "a", "b")
+	addOutbox(t, sstore, "u2", "c")
+
+	n, err := global.relay.relayOnce()
+	if err != nil || n != 3 {
+		t.Fatalf("relayOnce: got %d %v, want 3", n, err)
+	}
+	if got := producer.values(); len(got) != 3 {
+		t.Fatalf("published: got %v, want 3 messages", got)
+	}
+
+	// 已删除，不会再被认领
+	if n, err := global.relay.relayOnce(); err != nil || n != 0 {
+		t.Fatalf("relayOnce again: got %d %v, want 0", n, err)
+	}
+}
+
+// 投递之后删除失败的，认领过期后会被重新认领并再次投递，carrier那边对此是幂等的
+func TestRelayReclaimAfterCrash(t *testing.T) {
+	cfg := memStationConfig()
+	cfg.Outbox.RetryAfter = 50 * time.Millisecond
+	sstore := &flakyStore{MemoryStore: sessionstore.NewMemoryStore(), failDelete: true}
+	producer := &memProducer{}
+	initMemStationWith(t, cfg, sstore, producer)
+
+	addOutbox(t, sstore, "u1", "a")
+
+	if _, err := global.relay.relayOnce(); err == nil {
+		t.Fatal("relayOnce: want error when delete fails")
+	}
+	if got := producer.values(); len(got) != 1 || got[0] != "a" {
+		t.Fatalf("published: got %v, want [a]", got)
+	}
+
+	// 认领还没过期
+	if n, err := global.relay.relayOnce(); err != nil || n != 0 {
+		t.Fatalf("relayOnce within lease: got %d %v, want 0", n, err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if n, err := global.relay.relayOnce(); err != nil || n != 1 {
+		t.Fatalf("relayOnce after lease: got %d %v, want 1", n, err)
+	}
+	if got := producer.values(); len(got) != 2 || got[1] != "a" {
+		t.Fatalf("published: got %v, want [a a]", got)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if n, err := global.relay.relayOnce(); err != nil || n != 0 {
+		t.Fatalf("relayOnce after delete: got %d %v, want 0", n, err)
+	}
+}
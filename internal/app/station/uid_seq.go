@@ -0,0 +1,76 @@
+package station
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/molon/gomsg/internal/pb/mqpb"
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/gomsg/internal/pkg/sessionstore"
+	"github.com/molon/pkg/errors"
+	"github.com/rs/xid"
+)
+
+// 有序模式下投递失败时调用，序号已经分配出去了，直接返回错误会留下永久的空缺，客户端会一直认为有消息缺失
+// 所以把投递失败的那些交给outbox稍后投递，写入outbox也失败的再尝试归还序号
+// failed 为投递失败的消息下标，已投递成功的不能再交出去，否则会重复
+// 交出去的消息在被carrier处理之前，此用户后续的消息都要等待，所以先标记为等待中
+// 返回交接失败的uid
+func handoffOrdered(ctx context.Context, failed []int, uids []string, pbs []*mqpb.Payload, pms []*mq.Message, uid2LastSeq map[string]int64, count int64) ([]string, error) {
+	cfg := global.cfg()
+
+	var rerr error
+	var failedUids []string
+	for _, i := range failed {
+		uid := uids[i]
+		err := handoffOne(ctx, uid, pbs[i], pms[i], uid2LastSeq[uid]-count+1, cfg.Producer.OrderedBlockTTL)
+		if err == nil {
+			continue
+		}
+
+		plog.Warnf("Hand off ordered payload of %s to outbox failed: %+v", uid, err)
+		failedUids = append(failedUids, uid)
+		if rerr == nil {
+			rerr = err
+		}
+
+		ok, err := global.sstore.RollbackUidSeq(ctx, uid, uid2LastSeq[uid], count)
+		if err != nil {
+			plog.Errorf("Rollback uid seq of %s failed, seqs up to %d are skipped: %+v", uid, uid2LastSeq[uid], err)
+		} else if !ok {
+			plog.Errorf("Rollback uid seq of %s failed for later allocations, seqs up to %d are skipped", uid, uid2LastSeq[uid])
+		}
+	}
+
+	global.relay.wakeup()
+
+	// 部分用户已经投递或者交接成功了，调用方重试的话这些用户会收到重复的消息
+	if rerr != nil && len(failedUids) < len(uids) {
+		return failedUids, errors.Statusf(codes.Internal, "push partly applied, failed for %d of %d uids: %v", len(failedUids), len(uids), rerr)
+	}
+	return failedUids, rerr
+}
+
+func handoffOne(ctx context.Context, uid string, pb *mqpb.Payload, pm *mq.Message, oseq int64, ttl time.Duration) error {
+	if err := global.sstore.MarkPending(ctx, uid, pb.GetSeq(), oseq, ttl); err != nil {
+		return err
+	}
+
+	err := global.sstore.AddOutbox(ctx, uid, []*sessionstore.OutboxMessage{{
+		Id:    xid.New().String(),
+		Topic: pm.Topic,
+		Key:   pm.Key,
+		Value: pm.Value,
+	}})
+	if err == nil {
+		return nil
+	}
+
+	// 不会再被投递了，不能阻塞后续的消息
+	if err := global.sstore.UnmarkPending(ctx, uid, pb.GetSeq()); err != nil {
+		plog.Warnf("Unmark pending payload %s of %s failed: %+v", pb.GetSeq(), uid, err)
+	}
+	return err
+}
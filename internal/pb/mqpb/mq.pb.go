@@ -12,6 +12,8 @@ It has these top-level messages:
 	KickoutSession
 	SendOfflineToSession
 	Notification
+	Receipt
+	Presence
 	Payload
 */
 package mqpb
@@ -21,9 +23,11 @@ import fmt "fmt"
 import math "math"
 import google_protobuf "github.com/golang/protobuf/ptypes/any"
 import google_protobuf1 "github.com/golang/protobuf/ptypes/timestamp"
+import google_protobuf2 "github.com/golang/protobuf/ptypes/wrappers"
 import msgpb "github.com/molon/gomsg/pb/msgpb"
 import pushpb "github.com/molon/gomsg/pb/pushpb"
 import errorpb "github.com/molon/gomsg/pb/errorpb"
+import querypb "github.com/molon/gomsg/pb/querypb"
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
@@ -36,6 +40,76 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type Receipt_Event int32
+
+const (
+	Receipt_UNKNOWN Receipt_Event = 0
+	// 已投递给会话，消息不需要ack时以此为准
+	Receipt_DELIVERED Receipt_Event = 1
+	// 已投递给会话且客户端已ack
+	Receipt_ACKED Receipt_Event = 2
+	// 已存储为离线消息
+	Receipt_STORED_OFFLINE Receipt_Event = 3
+	// 消息已过期被丢弃，或者离线消息已过期被清理
+	Receipt_EXPIRED Receipt_Event = 4
+	// 达到最大重试次数被丢进死信队列
+	Receipt_DROPPED_TO_DLQ Receipt_Event = 5
+	// 客户端已读
+	Receipt_READ Receipt_Event = 6
+	// 离线消息超出最大数目被清理
+	Receipt_EVICTED Receipt_Event = 7
+)
+
+var Receipt_Event_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "DELIVERED",
+	2: "ACKED",
+	3: "STORED_OFFLINE",
+	4: "EXPIRED",
+	5: "DROPPED_TO_DLQ",
+	6: "READ",
+	7: "EVICTED",
+}
+var Receipt_Event_value = map[string]int32{
+	"UNKNOWN":        0,
+	"DELIVERED":      1,
+	"ACKED":          2,
+	"STORED_OFFLINE": 3,
+	"EXPIRED":        4,
+	"DROPPED_TO_DLQ": 5,
+	"READ":           6,
+	"EVICTED":        7,
+}
+
+func (x Receipt_Event) String() string {
+	return proto.EnumName(Receipt_Event_name, int32(x))
+}
+func (Receipt_Event) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{4, 0} }
+
+type Presence_Event int32
+
+const (
+	Presence_UNKNOWN Presence_Event = 0
+	Presence_ONLINE  Presence_Event = 1
+	Presence_OFFLINE Presence_Event = 2
+)
+
+var Presence_Event_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "ONLINE",
+	2: "OFFLINE",
+}
+var Presence_Event_value = map[string]int32{
+	"UNKNOWN": 0,
+	"ONLINE":  1,
+	"OFFLINE": 2,
+}
+
+func (x Presence_Event) String() string {
+	return proto.EnumName(Presence_Event_name, int32(x))
+}
+func (Presence_Event) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{5, 0} }
+
 // 发给uid的常规消息
 type ToUid struct {
 	// 接收目标
@@ -152,6 +226,10 @@ type Notification struct {
 	Platform string `protobuf:"bytes,2,opt,name=platform" json:"platform,omitempty"`
 	// 消息内容
 	Msg *msgpb.Message `protobuf:"bytes,3,opt,name=msg" json:"msg,omitempty"`
+	// 通知提供方，由接收平台的配置决定，例如 apns/fcm
+	Provider string `protobuf:"bytes,4,opt,name=provider" json:"provider,omitempty"`
+	// 此用户此平台当前的未读(离线)消息数目，用于设置角标，获取失败时为空，下游不应修改角标
+	Badge *google_protobuf2.Int64Value `protobuf:"bytes,5,opt,name=badge" json:"badge,omitempty"`
 }
 
 func (m *Notification) Reset()                    { *m = Notification{} }
@@ -180,24 +258,162 @@ func (m *Notification) GetMsg() *msgpb.Message {
 	return nil
 }
 
+func (m *Notification) GetProvider() string {
+	if m != nil {
+		return m.Provider
+	}
+	return ""
+}
+
+func (m *Notification) GetBadge() *google_protobuf2.Int64Value {
+	if m != nil {
+		return m.Badge
+	}
+	return nil
+}
+
+// 消息回执，投递到回执topic供业务方订阅
+type Receipt struct {
+	Event Receipt_Event `protobuf:"varint,1,opt,name=event,enum=mqpb.Receipt_Event" json:"event,omitempty"`
+	// 接收目标
+	Uid string `protobuf:"bytes,2,opt,name=uid" json:"uid,omitempty"`
+	// 接收平台
+	Platform string `protobuf:"bytes,3,opt,name=platform" json:"platform,omitempty"`
+	// 接收会话，仅 DELIVERED/ACKED/READ 有值
+	Sid string `protobuf:"bytes,4,opt,name=sid" json:"sid,omitempty"`
+	// 消息seq列表
+	MsgSeqs []string `protobuf:"bytes,5,rep,name=msg_seqs,json=msgSeqs" json:"msg_seqs,omitempty"`
+}
+
+func (m *Receipt) Reset()                    { *m = Receipt{} }
+func (m *Receipt) String() string            { return proto.CompactTextString(m) }
+func (*Receipt) ProtoMessage()               {}
+func (*Receipt) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *Receipt) GetEvent() Receipt_Event {
+	if m != nil {
+		return m.Event
+	}
+	return Receipt_UNKNOWN
+}
+
+func (m *Receipt) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *Receipt) GetPlatform() string {
+	if m != nil {
+		return m.Platform
+	}
+	return ""
+}
+
+func (m *Receipt) GetSid() string {
+	if m != nil {
+		return m.Sid
+	}
+	return ""
+}
+
+func (m *Receipt) GetMsgSeqs() []string {
+	if m != nil {
+		return m.MsgSeqs
+	}
+	return nil
+}
+
+// 会话上下线事件，投递到presence topic供业务方订阅
+type Presence struct {
+	Event    Presence_Event `protobuf:"varint,1,opt,name=event,enum=mqpb.Presence_Event" json:"event,omitempty"`
+	Uid      string         `protobuf:"bytes,2,opt,name=uid" json:"uid,omitempty"`
+	Platform string         `protobuf:"bytes,3,opt,name=platform" json:"platform,omitempty"`
+	Sid      string         `protobuf:"bytes,4,opt,name=sid" json:"sid,omitempty"`
+	// 会话所在boat服务ID
+	BoatId string `protobuf:"bytes,5,opt,name=boat_id,json=boatId" json:"boat_id,omitempty"`
+	// 是否因为所在boat的租约到期而被清理，而不是正常断开
+	Reaped bool `protobuf:"varint,6,opt,name=reaped" json:"reaped,omitempty"`
+	// 会话的附加信息，没有记录则为空
+	Metadata *querypb.SessionMetadata `protobuf:"bytes,7,opt,name=metadata" json:"metadata,omitempty"`
+}
+
+func (m *Presence) Reset()                    { *m = Presence{} }
+func (m *Presence) String() string            { return proto.CompactTextString(m) }
+func (*Presence) ProtoMessage()               {}
+func (*Presence) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *Presence) GetEvent() Presence_Event {
+	if m != nil {
+		return m.Event
+	}
+	return Presence_UNKNOWN
+}
+
+func (m *Presence) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *Presence) GetPlatform() string {
+	if m != nil {
+		return m.Platform
+	}
+	return ""
+}
+
+func (m *Presence) GetSid() string {
+	if m != nil {
+		return m.Sid
+	}
+	return ""
+}
+
+func (m *Presence) GetBoatId() string {
+	if m != nil {
+		return m.BoatId
+	}
+	return ""
+}
+
+func (m *Presence) GetReaped() bool {
+	if m != nil {
+		return m.Reaped
+	}
+	return false
+}
+
+func (m *Presence) GetMetadata() *querypb.SessionMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
 // mq消息wrap
 type Payload struct {
 	Seq           string                      `protobuf:"bytes,1,opt,name=seq" json:"seq,omitempty"`
 	Timestamp     *google_protobuf1.Timestamp `protobuf:"bytes,2,opt,name=timestamp" json:"timestamp,omitempty"`
 	RetryCount    int64                       `protobuf:"varint,3,opt,name=retry_count,json=retryCount" json:"retry_count,omitempty"`
 	LastAttemptAt *google_protobuf1.Timestamp `protobuf:"bytes,4,opt,name=last_attempt_at,json=lastAttemptAt" json:"last_attempt_at,omitempty"`
+	Trace         map[string]string           `protobuf:"bytes,5,rep,name=trace" json:"trace,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	// Types that are valid to be assigned to Body:
 	//	*Payload_ToUid
 	//	*Payload_KickoutSession
 	//	*Payload_SendOfflineToSession
 	//	*Payload_Notification
+	//	*Payload_Receipt
+	//	*Payload_Presence
 	Body isPayload_Body `protobuf_oneof:"Body"`
 }
 
 func (m *Payload) Reset()                    { *m = Payload{} }
 func (m *Payload) String() string            { return proto.CompactTextString(m) }
 func (*Payload) ProtoMessage()               {}
-func (*Payload) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*Payload) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
 type isPayload_Body interface{ isPayload_Body() }
 
@@ -213,11 +429,19 @@ type Payload_SendOfflineToSession struct {
 type Payload_Notification struct {
 	Notification *Notification `protobuf:"bytes,14,opt,name=notification,oneof"`
 }
+type Payload_Receipt struct {
+	Receipt *Receipt `protobuf:"bytes,15,opt,name=receipt,oneof"`
+}
+type Payload_Presence struct {
+	Presence *Presence `protobuf:"bytes,16,opt,name=presence,oneof"`
+}
 
 func (*Payload_ToUid) isPayload_Body()                {}
 func (*Payload_KickoutSession) isPayload_Body()       {}
 func (*Payload_SendOfflineToSession) isPayload_Body() {}
 func (*Payload_Notification) isPayload_Body()         {}
+func (*Payload_Receipt) isPayload_Body()              {}
+func (*Payload_Presence) isPayload_Body()             {}
 
 func (m *Payload) GetBody() isPayload_Body {
 	if m != nil {
@@ -254,6 +478,13 @@ func (m *Payload) GetLastAttemptAt() *google_protobuf1.Timestamp {
 	return nil
 }
 
+func (m *Payload) GetTrace() map[string]string {
+	if m != nil {
+		return m.Trace
+	}
+	return nil
+}
+
 func (m *Payload) GetToUid() *ToUid {
 	if x, ok := m.GetBody().(*Payload_ToUid); ok {
 		return x.ToUid
@@ -282,6 +513,20 @@ func (m *Payload) GetNotification() *Notification {
 	return nil
 }
 
+func (m *Payload) GetReceipt() *Receipt {
+	if x, ok := m.GetBody().(*Payload_Receipt); ok {
+		return x.Receipt
+	}
+	return nil
+}
+
+func (m *Payload) GetPresence() *Presence {
+	if x, ok := m.GetBody().(*Payload_Presence); ok {
+		return x.Presence
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*Payload) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _Payload_OneofMarshaler, _Payload_OneofUnmarshaler, _Payload_OneofSizer, []interface{}{
@@ -289,6 +534,8 @@ func (*Payload) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error
 		(*Payload_KickoutSession)(nil),
 		(*Payload_SendOfflineToSession)(nil),
 		(*Payload_Notification)(nil),
+		(*Payload_Receipt)(nil),
+		(*Payload_Presence)(nil),
 	}
 }
 
@@ -316,6 +563,16 @@ func _Payload_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Notification); err != nil {
 			return err
 		}
+	case *Payload_Receipt:
+		b.EncodeVarint(15<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Receipt); err != nil {
+			return err
+		}
+	case *Payload_Presence:
+		b.EncodeVarint(16<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Presence); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("Payload.Body has unexpected type %T", x)
@@ -358,6 +615,22 @@ func _Payload_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer
 		err := b.DecodeMessage(msg)
 		m.Body = &Payload_Notification{msg}
 		return true, err
+	case 15: // Body.receipt
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Receipt)
+		err := b.DecodeMessage(msg)
+		m.Body = &Payload_Receipt{msg}
+		return true, err
+	case 16: // Body.presence
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Presence)
+		err := b.DecodeMessage(msg)
+		m.Body = &Payload_Presence{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -387,6 +660,16 @@ func _Payload_OneofSizer(msg proto.Message) (n int) {
 		n += proto.SizeVarint(14<<3 | proto.WireBytes)
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *Payload_Receipt:
+		s := proto.Size(x.Receipt)
+		n += proto.SizeVarint(15<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *Payload_Presence:
+		s := proto.Size(x.Presence)
+		n += proto.SizeVarint(16<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -399,46 +682,76 @@ func init() {
 	proto.RegisterType((*KickoutSession)(nil), "mqpb.KickoutSession")
 	proto.RegisterType((*SendOfflineToSession)(nil), "mqpb.SendOfflineToSession")
 	proto.RegisterType((*Notification)(nil), "mqpb.Notification")
+	proto.RegisterType((*Receipt)(nil), "mqpb.Receipt")
+	proto.RegisterType((*Presence)(nil), "mqpb.Presence")
 	proto.RegisterType((*Payload)(nil), "mqpb.Payload")
+	proto.RegisterEnum("mqpb.Receipt_Event", Receipt_Event_name, Receipt_Event_value)
+	proto.RegisterEnum("mqpb.Presence_Event", Presence_Event_name, Presence_Event_value)
 }
 
 func init() { proto.RegisterFile("github.com/molon/gomsg/internal/pb/mqpb/mq.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 556 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0xcf, 0x8f, 0xd2, 0x40,
-	0x14, 0x06, 0xcb, 0xee, 0xba, 0x53, 0x28, 0xeb, 0x04, 0xb5, 0x72, 0x59, 0x6c, 0x4c, 0xc4, 0xcb,
-	0xd4, 0xe0, 0x65, 0xe3, 0x65, 0x03, 0x5c, 0x48, 0x8c, 0xba, 0x29, 0x18, 0x8d, 0x07, 0x9b, 0xfe,
-	0x98, 0x76, 0x27, 0xb4, 0xf3, 0x4a, 0x67, 0x6a, 0xc2, 0x3f, 0xe5, 0xd9, 0x3f, 0xcf, 0x74, 0xa6,
-	0xe0, 0xe2, 0x92, 0xa8, 0x17, 0xca, 0xbc, 0xf7, 0xbd, 0xaf, 0x6f, 0xbe, 0xef, 0x4b, 0xd1, 0xeb,
-	0x94, 0xc9, 0xdb, 0x2a, 0x24, 0x11, 0xe4, 0x6e, 0x0e, 0x19, 0x70, 0x37, 0x85, 0x5c, 0xa4, 0x2e,
-	0xe3, 0x92, 0x96, 0x3c, 0xc8, 0xdc, 0x22, 0x74, 0xf3, 0x8d, 0xfa, 0x21, 0x45, 0x09, 0x12, 0x70,
-	0xa7, 0x3e, 0x0e, 0x9f, 0xa5, 0x00, 0x69, 0x46, 0x5d, 0x55, 0x0b, 0xab, 0xc4, 0x0d, 0xf8, 0x56,
-	0x03, 0x86, 0x97, 0x7f, 0xb6, 0x24, 0xcb, 0xa9, 0x90, 0x41, 0x5e, 0x34, 0x80, 0x7e, 0x2e, 0xd2,
-	0x9a, 0x51, 0xa4, 0x4d, 0xe1, 0x51, 0x51, 0x89, 0xdb, 0x22, 0x74, 0xeb, 0x47, 0x53, 0xc2, 0xb4,
-	0x2c, 0xa1, 0x2c, 0x42, 0x37, 0x82, 0x98, 0xea, 0x9a, 0xf3, 0xa3, 0x8d, 0x4e, 0x56, 0xf0, 0x89,
-	0xc5, 0xf8, 0x02, 0x19, 0x15, 0x8b, 0xed, 0xf6, 0xa8, 0x3d, 0x3e, 0xf7, 0xea, 0xbf, 0xf8, 0x1a,
-	0xf5, 0x8b, 0x2c, 0x90, 0x09, 0x94, 0xb9, 0x1f, 0x01, 0x4f, 0x58, 0x6a, 0x9b, 0xa3, 0xf6, 0xd8,
-	0x9c, 0x3c, 0x21, 0x9a, 0x9c, 0xdc, 0x34, 0xed, 0xb9, 0xea, 0x7a, 0x56, 0x71, 0x70, 0xc6, 0x0e,
-	0xea, 0xe4, 0x22, 0x15, 0xf6, 0xe3, 0x91, 0x31, 0x36, 0x27, 0x16, 0x51, 0x3b, 0x92, 0xf7, 0x54,
-	0x88, 0x20, 0xa5, 0x9e, 0xea, 0x61, 0x82, 0xce, 0x4a, 0x2a, 0x68, 0xf9, 0x9d, 0xda, 0x5f, 0x14,
-	0xf9, 0x80, 0xe8, 0xbb, 0x92, 0xdd, 0x5d, 0xc9, 0x94, 0x6f, 0xbd, 0x1d, 0xc8, 0xf9, 0x8c, 0xac,
-	0x77, 0x2c, 0x5a, 0x43, 0x25, 0x97, 0x54, 0x08, 0x06, 0xfc, 0xc8, 0xe2, 0x17, 0xc8, 0x10, 0x2c,
-	0xb6, 0x1f, 0xe8, 0x8a, 0x60, 0x31, 0x7e, 0x8e, 0x3a, 0xf5, 0xa5, 0x6d, 0x63, 0xd4, 0x1e, 0x5b,
-	0x93, 0x1e, 0x69, 0x94, 0x20, 0x73, 0x88, 0xa9, 0xa7, 0x5a, 0xce, 0x5b, 0x34, 0x58, 0x52, 0x1e,
-	0x7f, 0x4c, 0x92, 0x8c, 0x71, 0xba, 0x82, 0xff, 0xa0, 0x77, 0xbe, 0xa1, 0xee, 0x07, 0x90, 0x2c,
-	0x61, 0x51, 0x20, 0x8f, 0xcf, 0x0c, 0xd1, 0xc3, 0x9d, 0x38, 0xcd, 0xe0, 0xfe, 0x8c, 0x47, 0xc8,
-	0xc8, 0x45, 0xaa, 0x76, 0xbb, 0xaf, 0x52, 0xdd, 0x72, 0x7e, 0x1a, 0xe8, 0xec, 0x26, 0xd8, 0x66,
-	0x10, 0xe8, 0xb7, 0xd3, 0xcd, 0x8e, 0x5b, 0xd0, 0x0d, 0xbe, 0x42, 0xe7, 0xfb, 0x38, 0x28, 0x72,
-	0x73, 0x32, 0xbc, 0x27, 0xe2, 0x6a, 0x87, 0xf0, 0x7e, 0x83, 0xf1, 0x25, 0x32, 0x4b, 0x2a, 0xcb,
-	0xad, 0x1f, 0x41, 0xc5, 0xa5, 0xda, 0xc0, 0xf0, 0x90, 0x2a, 0xcd, 0xeb, 0x0a, 0x9e, 0xa1, 0x7e,
-	0x16, 0x08, 0xe9, 0x07, 0x52, 0xd2, 0xbc, 0xa8, 0x9f, 0x76, 0xe7, 0xaf, 0x2f, 0xe8, 0xd5, 0x23,
-	0x53, 0x3d, 0x31, 0x95, 0xf8, 0x05, 0x3a, 0x95, 0xe0, 0xd7, 0x7a, 0xe8, 0xf4, 0x98, 0xa4, 0x4e,
-	0x3b, 0x51, 0xa9, 0x5b, 0xb4, 0xbc, 0x13, 0xa9, 0xe2, 0x77, 0x8d, 0xfa, 0x6b, 0xed, 0xab, 0x2f,
-	0xb4, 0xf2, 0x76, 0xb7, 0xc9, 0x83, 0x82, 0x1f, 0x9a, 0xbe, 0x68, 0x79, 0xd6, 0xfa, 0x30, 0x06,
-	0x4b, 0xf4, 0x54, 0x50, 0x1e, 0xfb, 0xa0, 0x0d, 0xf4, 0x25, 0xec, 0x89, 0x7a, 0xcd, 0xca, 0x8a,
-	0xe8, 0x98, 0xc9, 0x8b, 0x96, 0x37, 0x10, 0xc7, 0xcc, 0xbf, 0x42, 0x5d, 0x7e, 0xc7, 0x58, 0xdb,
-	0x52, 0x4c, 0x58, 0x33, 0xdd, 0xb5, 0x7c, 0xd1, 0xf2, 0x0e, 0x90, 0xb3, 0x53, 0xd4, 0x99, 0x41,
-	0xbc, 0x9d, 0xbd, 0xfa, 0xfa, 0xf2, 0x1f, 0x3f, 0x07, 0xe1, 0xa9, 0xd2, 0xf2, 0xcd, 0xaf, 0x00,
-	0x00, 0x00, 0xff, 0xff, 0x45, 0x26, 0x48, 0x1e, 0x40, 0x04, 0x00, 0x00,
+	// 969 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x55, 0x5f, 0x6f, 0xe3, 0xc4,
+	0x17, 0xcd, 0x1f, 0x3b, 0x7f, 0x6e, 0xda, 0xd4, 0xbf, 0xd9, 0xfe, 0x76, 0xbd, 0x45, 0x62, 0x83,
+	0x85, 0x44, 0x8a, 0xc0, 0x86, 0xb2, 0x42, 0xd5, 0xbe, 0xa0, 0xb6, 0xf1, 0x2a, 0x51, 0xbb, 0x49,
+	0x98, 0xa6, 0xdd, 0x15, 0x2f, 0x96, 0x13, 0x4f, 0xbc, 0x56, 0x63, 0x8f, 0xe3, 0x99, 0x14, 0x85,
+	0xaf, 0x00, 0x5f, 0x05, 0xbe, 0x20, 0x2f, 0x68, 0x66, 0xec, 0xb6, 0x69, 0x23, 0x01, 0x12, 0x2f,
+	0x75, 0xef, 0x3d, 0xe7, 0xce, 0xcc, 0x3d, 0x73, 0xe7, 0x04, 0xbe, 0x09, 0x23, 0xfe, 0x71, 0x35,
+	0xb5, 0x67, 0x34, 0x76, 0x62, 0xba, 0xa0, 0x89, 0x13, 0xd2, 0x98, 0x85, 0x4e, 0x94, 0x70, 0x92,
+	0x25, 0xfe, 0xc2, 0x49, 0xa7, 0x4e, 0xbc, 0x94, 0x7f, 0xec, 0x34, 0xa3, 0x9c, 0x22, 0x4d, 0x84,
+	0x07, 0x2f, 0x43, 0x4a, 0xc3, 0x05, 0x71, 0x64, 0x6e, 0xba, 0x9a, 0x3b, 0x7e, 0xb2, 0x56, 0x84,
+	0x83, 0x57, 0x8f, 0x21, 0x1e, 0xc5, 0x84, 0x71, 0x3f, 0x4e, 0x73, 0xc2, 0xa7, 0x8f, 0x09, 0x3f,
+	0x67, 0x7e, 0x9a, 0x92, 0x8c, 0xe5, 0xf8, 0x5e, 0xcc, 0x42, 0xb1, 0x23, 0x0b, 0xf3, 0xc4, 0xff,
+	0xd2, 0x15, 0xfb, 0x98, 0x4e, 0x1d, 0xf1, 0xc9, 0x53, 0x88, 0x64, 0x19, 0xcd, 0xd2, 0xa9, 0x33,
+	0xa3, 0x01, 0xc9, 0x73, 0xcf, 0x96, 0x2b, 0x92, 0xad, 0xd3, 0xa9, 0x23, 0xbf, 0x2a, 0x69, 0xfd,
+	0x51, 0x06, 0x7d, 0x42, 0xaf, 0xa2, 0x00, 0x19, 0x50, 0x5d, 0x45, 0x81, 0x59, 0xee, 0x94, 0xbb,
+	0x4d, 0x2c, 0xfe, 0x45, 0x3f, 0xc0, 0x5e, 0xba, 0xf0, 0xf9, 0x9c, 0x66, 0xb1, 0x37, 0xa3, 0xc9,
+	0x3c, 0x0a, 0xcd, 0x56, 0xa7, 0xdc, 0x6d, 0x1d, 0x3d, 0xb7, 0xd5, 0x8e, 0xf6, 0x38, 0x87, 0xcf,
+	0x24, 0x8a, 0xdb, 0xe9, 0x46, 0x8c, 0x2c, 0xd0, 0x62, 0x16, 0x32, 0xf3, 0xff, 0x9d, 0x6a, 0xb7,
+	0x75, 0xd4, 0xb6, 0xe5, 0xc1, 0xed, 0x77, 0x84, 0x31, 0x3f, 0x24, 0x58, 0x62, 0xc8, 0x86, 0x7a,
+	0x46, 0x18, 0xc9, 0x6e, 0x89, 0xf9, 0x41, 0x2e, 0xbe, 0x6f, 0xab, 0xfe, 0xed, 0xa2, 0x7f, 0xfb,
+	0x24, 0x59, 0xe3, 0x82, 0x64, 0xbd, 0x87, 0xf6, 0x79, 0x34, 0xbb, 0xa1, 0x2b, 0x7e, 0x49, 0x18,
+	0x8b, 0x68, 0xb2, 0xe5, 0xe0, 0x06, 0x54, 0x59, 0x14, 0x98, 0x15, 0x95, 0x61, 0x51, 0x80, 0x3e,
+	0x03, 0x4d, 0x28, 0x61, 0x56, 0x3b, 0xe5, 0x6e, 0xfb, 0x68, 0xd7, 0xce, 0xe5, 0xb1, 0xcf, 0x68,
+	0x40, 0xb0, 0x84, 0xac, 0x37, 0xb0, 0x7f, 0x49, 0x92, 0x60, 0x34, 0x9f, 0x2f, 0xa2, 0x84, 0x4c,
+	0xe8, 0xbf, 0x58, 0xde, 0xfa, 0xbd, 0x0c, 0x3b, 0x43, 0xca, 0xa3, 0x79, 0x34, 0xf3, 0xf9, 0xf6,
+	0xa2, 0x03, 0x68, 0x14, 0xea, 0xe4, 0x95, 0x77, 0x31, 0xea, 0x40, 0x35, 0x66, 0xa1, 0x3c, 0xdc,
+	0x53, 0x99, 0x04, 0x24, 0xab, 0x33, 0x7a, 0x1b, 0x05, 0x24, 0x33, 0xb5, 0xbc, 0x3a, 0x8f, 0xd1,
+	0xb7, 0xa0, 0x4f, 0xfd, 0x20, 0x24, 0xa6, 0x2e, 0xeb, 0x3f, 0x79, 0xa2, 0xdf, 0x20, 0xe1, 0xdf,
+	0xbf, 0xbe, 0xf6, 0x17, 0x2b, 0x82, 0x15, 0xd3, 0xfa, 0xb5, 0x02, 0x75, 0x4c, 0x66, 0x24, 0x4a,
+	0x39, 0x3a, 0x04, 0x9d, 0xdc, 0x92, 0x84, 0xcb, 0xc3, 0xb6, 0x8f, 0x9e, 0xd9, 0x62, 0x80, 0xed,
+	0x1c, 0xb5, 0x5d, 0x01, 0x61, 0xc5, 0x28, 0xba, 0xaa, 0x6c, 0xef, 0xaa, 0xfa, 0xa8, 0xab, 0x5c,
+	0x26, 0xed, 0xfe, 0x16, 0x5e, 0x42, 0x23, 0x66, 0xa1, 0xc7, 0xc8, 0x92, 0x99, 0x7a, 0xa7, 0xda,
+	0x6d, 0xe2, 0x7a, 0xcc, 0xc2, 0x4b, 0xb2, 0x64, 0xd6, 0x2f, 0xa0, 0xcb, 0xad, 0x50, 0x0b, 0xea,
+	0x57, 0xc3, 0xf3, 0xe1, 0xe8, 0xfd, 0xd0, 0x28, 0xa1, 0x5d, 0x68, 0xf6, 0xdc, 0x8b, 0xc1, 0xb5,
+	0x8b, 0xdd, 0x9e, 0x51, 0x46, 0x4d, 0xd0, 0x4f, 0xce, 0xce, 0xdd, 0x9e, 0x51, 0x41, 0x08, 0xda,
+	0x97, 0x93, 0x11, 0x76, 0x7b, 0xde, 0xe8, 0xed, 0xdb, 0x8b, 0xc1, 0xd0, 0x35, 0xaa, 0xa2, 0xd4,
+	0xfd, 0x30, 0x1e, 0x08, 0xae, 0x26, 0x08, 0x3d, 0x3c, 0x1a, 0x8f, 0xdd, 0x9e, 0x37, 0x19, 0x79,
+	0xbd, 0x8b, 0x1f, 0x0d, 0x1d, 0x35, 0x40, 0xc3, 0xee, 0x49, 0xcf, 0xa8, 0x49, 0xea, 0xf5, 0xe0,
+	0x6c, 0xe2, 0xf6, 0x8c, 0xba, 0xf5, 0x5b, 0x05, 0x1a, 0x63, 0x31, 0x5f, 0xc9, 0x8c, 0xa0, 0x2f,
+	0x37, 0xe5, 0xd8, 0x57, 0x72, 0x14, 0xf0, 0x7f, 0xab, 0xc7, 0x0b, 0xa8, 0x4f, 0xa9, 0xcf, 0xbd,
+	0x28, 0x90, 0x77, 0xd7, 0xc4, 0x35, 0x11, 0x0e, 0x02, 0xf4, 0x1c, 0x6a, 0x19, 0xf1, 0x53, 0x12,
+	0x98, 0xb5, 0x4e, 0xb9, 0xdb, 0xc0, 0x79, 0x84, 0x5e, 0x43, 0x23, 0x26, 0xdc, 0x0f, 0x7c, 0xee,
+	0x9b, 0x75, 0x79, 0xdb, 0xa6, 0x9d, 0xbf, 0x6a, 0x3b, 0x9f, 0xd7, 0x77, 0x39, 0x8e, 0xef, 0x98,
+	0xd6, 0xd7, 0x5b, 0xb5, 0x05, 0xa8, 0x8d, 0x86, 0x52, 0xb9, 0xb2, 0x00, 0x0a, 0x19, 0x2b, 0xd6,
+	0x9f, 0x1a, 0xd4, 0xc7, 0xfe, 0x7a, 0x41, 0x7d, 0x35, 0xea, 0x64, 0x59, 0xcc, 0x31, 0x23, 0x4b,
+	0x74, 0x0c, 0xcd, 0x3b, 0xc3, 0x92, 0x9d, 0xb7, 0x8e, 0x0e, 0x9e, 0x4c, 0xdc, 0xa4, 0x60, 0xe0,
+	0x7b, 0x32, 0x7a, 0x05, 0xad, 0x8c, 0xf0, 0x6c, 0xed, 0xcd, 0xe8, 0x2a, 0xe1, 0x52, 0x9e, 0x2a,
+	0x06, 0x99, 0x3a, 0x13, 0x19, 0x74, 0x0a, 0x7b, 0x0b, 0x9f, 0x71, 0xcf, 0xe7, 0x9c, 0xc4, 0xa9,
+	0xf8, 0x9a, 0xda, 0xdf, 0x6e, 0xb0, 0x2b, 0x4a, 0x4e, 0x54, 0xc5, 0x09, 0x47, 0x36, 0xe8, 0x3c,
+	0xf3, 0x67, 0x44, 0xce, 0x97, 0x90, 0x47, 0x5d, 0x9f, 0x6a, 0xc7, 0x9e, 0x08, 0xc8, 0x4d, 0x78,
+	0xb6, 0xc6, 0x8a, 0x86, 0x3e, 0x87, 0x1a, 0xa7, 0x9e, 0xb8, 0x45, 0x65, 0x6d, 0x2d, 0x55, 0x20,
+	0x2d, 0xb1, 0x5f, 0xc2, 0x3a, 0xa7, 0x57, 0xca, 0x09, 0x6f, 0x94, 0xe9, 0x78, 0x4c, 0xc9, 0x6c,
+	0xee, 0xe4, 0x66, 0x25, 0xe9, 0x9b, 0x8e, 0xd4, 0x2f, 0xe1, 0xf6, 0xcd, 0x46, 0x06, 0x5d, 0xc2,
+	0x0b, 0x46, 0x92, 0xc0, 0xa3, 0xca, 0x5d, 0x3c, 0x4e, 0xef, 0x16, 0xda, 0xcd, 0x5b, 0x94, 0x0b,
+	0x6d, 0x73, 0xa0, 0x7e, 0x09, 0xef, 0xb3, 0x2d, 0x79, 0x74, 0x0c, 0x3b, 0xc9, 0x03, 0xd3, 0x31,
+	0xdb, 0x72, 0x25, 0xa4, 0x56, 0x7a, 0x68, 0x47, 0xfd, 0x12, 0xde, 0x60, 0xa2, 0x43, 0x61, 0xba,
+	0xf2, 0x81, 0x9b, 0x7b, 0xb2, 0x68, 0x77, 0xe3, 0xd5, 0xf7, 0x4b, 0xb8, 0xc0, 0xd1, 0x57, 0xc2,
+	0x79, 0xd4, 0xf0, 0x9b, 0x46, 0x61, 0x50, 0x0f, 0x9f, 0x44, 0xbf, 0x84, 0xef, 0x18, 0x07, 0xc7,
+	0x00, 0xf7, 0x1a, 0x8b, 0xe9, 0xb9, 0x21, 0xeb, 0x62, 0x7a, 0x6e, 0xc8, 0x1a, 0xed, 0x83, 0x7e,
+	0x2b, 0x8c, 0x28, 0x7f, 0x33, 0x2a, 0x78, 0x53, 0x39, 0x2e, 0x9f, 0xd6, 0x40, 0x3b, 0xa5, 0xc1,
+	0xfa, 0xf4, 0xf0, 0xa7, 0x2f, 0xfe, 0xe1, 0x6f, 0xee, 0xb4, 0x26, 0xc7, 0xe1, 0xbb, 0xbf, 0x06,
+	0x00, 0x71, 0x8b, 0xac, 0xdc, 0xa5, 0x07, 0x00, 0x00,
 }
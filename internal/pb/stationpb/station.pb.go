@@ -11,6 +11,9 @@ It has these top-level messages:
 	ConnectRequest
 	ConnectResponse
 	DisconnectRequest
+	ReadRequest
+	HeartbeatRequest
+	HeartbeatResponse
 */
 package stationpb
 
@@ -18,6 +21,7 @@ import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
 import google_protobuf "github.com/golang/protobuf/ptypes/empty"
+import google_protobuf1 "github.com/golang/protobuf/ptypes/duration"
 
 import (
 	context "golang.org/x/net/context"
@@ -40,6 +44,18 @@ type ConnectRequest struct {
 	BoatId string `protobuf:"bytes,1,opt,name=boat_id,json=boatId" json:"boat_id,omitempty"`
 	// 会话ID
 	Sid string `protobuf:"bytes,2,opt,name=sid" json:"sid,omitempty"`
+	// 以下为会话的附加信息，由boat从连接里获取，只用于排查和统计
+	// 客户端版本
+	ClientVersion string `protobuf:"bytes,3,opt,name=client_version,json=clientVersion" json:"client_version,omitempty"`
+	// 设备ID
+	DeviceId string `protobuf:"bytes,4,opt,name=device_id,json=deviceId" json:"device_id,omitempty"`
+	// 客户端IP
+	RemoteIp  string `protobuf:"bytes,5,opt,name=remote_ip,json=remoteIp" json:"remote_ip,omitempty"`
+	UserAgent string `protobuf:"bytes,6,opt,name=user_agent,json=userAgent" json:"user_agent,omitempty"`
+	// 客户端自行通过SyncRequest分页拉取离线消息，连接时不推送(推送成功的会被删除)
+	NoOfflinePush bool `protobuf:"varint,7,opt,name=no_offline_push,json=noOfflinePush" json:"no_offline_push,omitempty"`
+	// 所在boat租约的代数，清理租约到期的boat时据此保留其重新续约之后连接的会话
+	LeaseGen int64 `protobuf:"varint,8,opt,name=lease_gen,json=leaseGen" json:"lease_gen,omitempty"`
 }
 
 func (m *ConnectRequest) Reset()                    { *m = ConnectRequest{} }
@@ -61,6 +77,48 @@ func (m *ConnectRequest) GetSid() string {
 	return ""
 }
 
+func (m *ConnectRequest) GetClientVersion() string {
+	if m != nil {
+		return m.ClientVersion
+	}
+	return ""
+}
+
+func (m *ConnectRequest) GetDeviceId() string {
+	if m != nil {
+		return m.DeviceId
+	}
+	return ""
+}
+
+func (m *ConnectRequest) GetRemoteIp() string {
+	if m != nil {
+		return m.RemoteIp
+	}
+	return ""
+}
+
+func (m *ConnectRequest) GetUserAgent() string {
+	if m != nil {
+		return m.UserAgent
+	}
+	return ""
+}
+
+func (m *ConnectRequest) GetNoOfflinePush() bool {
+	if m != nil {
+		return m.NoOfflinePush
+	}
+	return false
+}
+
+func (m *ConnectRequest) GetLeaseGen() int64 {
+	if m != nil {
+		return m.LeaseGen
+	}
+	return 0
+}
+
 type ConnectResponse struct {
 	// 用户ID
 	Uid string `protobuf:"bytes,1,opt,name=uid" json:"uid,omitempty"`
@@ -94,6 +152,10 @@ type DisconnectRequest struct {
 	Sid string `protobuf:"bytes,2,opt,name=sid" json:"sid,omitempty"`
 	// 用户ID
 	Uid string `protobuf:"bytes,3,opt,name=uid" json:"uid,omitempty"`
+	// 平台名称
+	Platform string `protobuf:"bytes,4,opt,name=platform" json:"platform,omitempty"`
+	// 会话登记之后boat的租约丢失过，其登记可能已被清理并投递过下线事件
+	LeaseLost bool `protobuf:"varint,5,opt,name=lease_lost,json=leaseLost" json:"lease_lost,omitempty"`
 }
 
 func (m *DisconnectRequest) Reset()                    { *m = DisconnectRequest{} }
@@ -122,10 +184,123 @@ func (m *DisconnectRequest) GetUid() string {
 	return ""
 }
 
+func (m *DisconnectRequest) GetPlatform() string {
+	if m != nil {
+		return m.Platform
+	}
+	return ""
+}
+
+func (m *DisconnectRequest) GetLeaseLost() bool {
+	if m != nil {
+		return m.LeaseLost
+	}
+	return false
+}
+
+type ReadRequest struct {
+	// 会话ID
+	Sid string `protobuf:"bytes,1,opt,name=sid" json:"sid,omitempty"`
+	// 用户ID
+	Uid string `protobuf:"bytes,2,opt,name=uid" json:"uid,omitempty"`
+	// 平台名称
+	Platform string `protobuf:"bytes,3,opt,name=platform" json:"platform,omitempty"`
+	// 已读的消息seq列表
+	Seqs []string `protobuf:"bytes,4,rep,name=seqs" json:"seqs,omitempty"`
+}
+
+func (m *ReadRequest) Reset()                    { *m = ReadRequest{} }
+func (m *ReadRequest) String() string            { return proto.CompactTextString(m) }
+func (*ReadRequest) ProtoMessage()               {}
+func (*ReadRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *ReadRequest) GetSid() string {
+	if m != nil {
+		return m.Sid
+	}
+	return ""
+}
+
+func (m *ReadRequest) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *ReadRequest) GetPlatform() string {
+	if m != nil {
+		return m.Platform
+	}
+	return ""
+}
+
+func (m *ReadRequest) GetSeqs() []string {
+	if m != nil {
+		return m.Seqs
+	}
+	return nil
+}
+
+type HeartbeatRequest struct {
+	// boat服务ID
+	BoatId string `protobuf:"bytes,1,opt,name=boat_id,json=boatId" json:"boat_id,omitempty"`
+	// 租约时长，应该是心跳间隔的数倍
+	Ttl *google_protobuf1.Duration `protobuf:"bytes,2,opt,name=ttl" json:"ttl,omitempty"`
+}
+
+func (m *HeartbeatRequest) Reset()                    { *m = HeartbeatRequest{} }
+func (m *HeartbeatRequest) String() string            { return proto.CompactTextString(m) }
+func (*HeartbeatRequest) ProtoMessage()               {}
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *HeartbeatRequest) GetBoatId() string {
+	if m != nil {
+		return m.BoatId
+	}
+	return ""
+}
+
+func (m *HeartbeatRequest) GetTtl() *google_protobuf1.Duration {
+	if m != nil {
+		return m.Ttl
+	}
+	return nil
+}
+
+type HeartbeatResponse struct {
+	// 续约之前租约已不存在，首次心跳以外出现的话说明其会话登记已被清理
+	Lost bool `protobuf:"varint,1,opt,name=lost" json:"lost,omitempty"`
+	// 租约当前的代数，租约每次重新建立都会加一，之后连接的会话要带上
+	LeaseGen int64 `protobuf:"varint,2,opt,name=lease_gen,json=leaseGen" json:"lease_gen,omitempty"`
+}
+
+func (m *HeartbeatResponse) Reset()                    { *m = HeartbeatResponse{} }
+func (m *HeartbeatResponse) String() string            { return proto.CompactTextString(m) }
+func (*HeartbeatResponse) ProtoMessage()               {}
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *HeartbeatResponse) GetLost() bool {
+	if m != nil {
+		return m.Lost
+	}
+	return false
+}
+
+func (m *HeartbeatResponse) GetLeaseGen() int64 {
+	if m != nil {
+		return m.LeaseGen
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*ConnectRequest)(nil), "stationpb.ConnectRequest")
 	proto.RegisterType((*ConnectResponse)(nil), "stationpb.ConnectResponse")
 	proto.RegisterType((*DisconnectRequest)(nil), "stationpb.DisconnectRequest")
+	proto.RegisterType((*ReadRequest)(nil), "stationpb.ReadRequest")
+	proto.RegisterType((*HeartbeatRequest)(nil), "stationpb.HeartbeatRequest")
+	proto.RegisterType((*HeartbeatResponse)(nil), "stationpb.HeartbeatResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -145,6 +320,11 @@ type StationClient interface {
 	// boat服务在新会话断开之后应该调用此方法
 	// 内部会删除对应连接信息
 	Disconnect(ctx context.Context, in *DisconnectRequest, opts ...grpc.CallOption) (*google_protobuf.Empty, error)
+	// boat服务收到会话的已读回执后应该调用此方法
+	// 内部会将其投递到回执事件流里
+	Read(ctx context.Context, in *ReadRequest, opts ...grpc.CallOption) (*google_protobuf.Empty, error)
+	// boat服务应该定期调用此方法续约，租约到期后其会话登记会被清理
+	Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error)
 }
 
 type stationClient struct {
@@ -173,6 +353,24 @@ func (c *stationClient) Disconnect(ctx context.Context, in *DisconnectRequest, o
 	return out, nil
 }
 
+func (c *stationClient) Read(ctx context.Context, in *ReadRequest, opts ...grpc.CallOption) (*google_protobuf.Empty, error) {
+	out := new(google_protobuf.Empty)
+	err := grpc.Invoke(ctx, "/stationpb.Station/Read", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stationClient) Heartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*HeartbeatResponse, error) {
+	out := new(HeartbeatResponse)
+	err := grpc.Invoke(ctx, "/stationpb.Station/Heartbeat", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Station service
 
 type StationServer interface {
@@ -182,6 +380,11 @@ type StationServer interface {
 	// boat服务在新会话断开之后应该调用此方法
 	// 内部会删除对应连接信息
 	Disconnect(context.Context, *DisconnectRequest) (*google_protobuf.Empty, error)
+	// boat服务收到会话的已读回执后应该调用此方法
+	// 内部会将其投递到回执事件流里
+	Read(context.Context, *ReadRequest) (*google_protobuf.Empty, error)
+	// boat服务应该定期调用此方法续约，租约到期后其会话登记会被清理
+	Heartbeat(context.Context, *HeartbeatRequest) (*HeartbeatResponse, error)
 }
 
 func RegisterStationServer(s *grpc.Server, srv StationServer) {
@@ -224,6 +427,42 @@ func _Station_Disconnect_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Station_Read_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StationServer).Read(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stationpb.Station/Read",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StationServer).Read(ctx, req.(*ReadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Station_Heartbeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StationServer).Heartbeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/stationpb.Station/Heartbeat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StationServer).Heartbeat(ctx, req.(*HeartbeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Station_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "stationpb.Station",
 	HandlerType: (*StationServer)(nil),
@@ -236,6 +475,14 @@ var _Station_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Disconnect",
 			Handler:    _Station_Disconnect_Handler,
 		},
+		{
+			MethodName: "Read",
+			Handler:    _Station_Read_Handler,
+		},
+		{
+			MethodName: "Heartbeat",
+			Handler:    _Station_Heartbeat_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "github.com/molon/gomsg/internal/pb/stationpb/station.proto",
@@ -246,23 +493,39 @@ func init() {
 }
 
 var fileDescriptor0 = []byte{
-	// 273 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x51, 0x3f, 0x4f, 0xfb, 0x30,
-	0x10, 0x6d, 0x7e, 0x95, 0x9a, 0x5f, 0x6f, 0xe0, 0x8f, 0x07, 0x28, 0x81, 0x01, 0x65, 0x62, 0x40,
-	0xb6, 0x04, 0x1b, 0x0c, 0x48, 0xa5, 0x0c, 0x6c, 0xa8, 0x6c, 0x2c, 0x28, 0x7f, 0x5c, 0x63, 0x29,
-	0xf6, 0x99, 0xf8, 0x32, 0xf0, 0x39, 0xf8, 0xc2, 0xc8, 0x49, 0xeb, 0x82, 0x60, 0x81, 0xed, 0x2e,
-	0xef, 0xbd, 0xdc, 0x7b, 0xcf, 0x70, 0xa5, 0x34, 0xbd, 0x74, 0x25, 0xaf, 0xd0, 0x08, 0x83, 0x0d,
-	0x5a, 0xa1, 0xd0, 0x78, 0x25, 0xb4, 0x25, 0xd9, 0xda, 0xa2, 0x11, 0xae, 0x14, 0x9e, 0x0a, 0xd2,
-	0x68, 0xb7, 0x13, 0x77, 0x2d, 0x12, 0xb2, 0x69, 0x04, 0xb2, 0x63, 0x85, 0xa8, 0x1a, 0x29, 0x7a,
-	0xa0, 0xec, 0x56, 0x42, 0x1a, 0x47, 0x6f, 0x03, 0x2f, 0xbf, 0x86, 0x9d, 0x5b, 0xb4, 0x56, 0x56,
-	0xb4, 0x94, 0xaf, 0x9d, 0xf4, 0xc4, 0x0e, 0x21, 0x2d, 0xb1, 0xa0, 0x67, 0x5d, 0xcf, 0x92, 0xd3,
-	0xe4, 0x6c, 0xba, 0x9c, 0x84, 0xf5, 0xbe, 0x66, 0x7b, 0x30, 0xf6, 0xba, 0x9e, 0xfd, 0xeb, 0x3f,
-	0x86, 0x31, 0xbf, 0x81, 0xdd, 0x28, 0xf6, 0x0e, 0xad, 0x97, 0x81, 0xd4, 0x45, 0x65, 0x18, 0x59,
-	0x06, 0xff, 0x5d, 0x53, 0xd0, 0x0a, 0x5b, 0xb3, 0xd6, 0xc6, 0x3d, 0x7f, 0x80, 0xfd, 0x85, 0xf6,
-	0xd5, 0x5f, 0x0d, 0x6c, 0xae, 0x8d, 0xe3, 0xb5, 0x8b, 0xf7, 0x04, 0xd2, 0xc7, 0x21, 0x3a, 0x9b,
-	0x43, 0xba, 0xb6, 0xc7, 0x8e, 0x78, 0xec, 0x83, 0x7f, 0xcd, 0x9b, 0x65, 0x3f, 0x41, 0x43, 0x9a,
-	0x7c, 0xc4, 0x16, 0x00, 0x5b, 0x87, 0xec, 0xe4, 0x13, 0xf7, 0x9b, 0xf1, 0xec, 0x80, 0x0f, 0x4d,
-	0xf3, 0x4d, 0xd3, 0xfc, 0x2e, 0x34, 0x9d, 0x8f, 0xe6, 0xfc, 0xe9, 0xfc, 0x37, 0x6f, 0x59, 0x4e,
-	0xfa, 0x3f, 0x5c, 0x7e, 0x04, 0x00, 0x00, 0xff, 0xff, 0x0e, 0x58, 0x77, 0xbb, 0x02, 0x02, 0x00,
-	0x00,
+	// 539 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x54, 0x5d, 0x6b, 0xd4, 0x40,
+	0x14, 0x6d, 0x36, 0xb1, 0x9b, 0xdc, 0xd2, 0xaf, 0x79, 0xa8, 0x69, 0x5a, 0x65, 0x09, 0x28, 0x0b,
+	0x4a, 0x02, 0xf5, 0x45, 0x7c, 0x11, 0xeb, 0x8a, 0x2d, 0x08, 0x4a, 0x04, 0x11, 0x5f, 0x42, 0xb2,
+	0xb9, 0x9b, 0x06, 0x92, 0x99, 0x34, 0x33, 0x29, 0xf8, 0xe6, 0xbb, 0x3f, 0xc0, 0xbf, 0x2b, 0x33,
+	0x93, 0x4d, 0xb3, 0x1f, 0x28, 0xfa, 0x76, 0x73, 0xce, 0xcc, 0x9c, 0x39, 0xf7, 0xdc, 0x09, 0xbc,
+	0xca, 0x0b, 0x71, 0xd3, 0xa6, 0xc1, 0x9c, 0x55, 0x61, 0xc5, 0x4a, 0x46, 0xc3, 0x9c, 0x55, 0x3c,
+	0x0f, 0x0b, 0x2a, 0xb0, 0xa1, 0x49, 0x19, 0xd6, 0x69, 0xc8, 0x45, 0x22, 0x0a, 0x46, 0xef, 0xab,
+	0xa0, 0x6e, 0x98, 0x60, 0xc4, 0xe9, 0x09, 0xef, 0x2c, 0x67, 0x2c, 0x2f, 0x31, 0x54, 0x44, 0xda,
+	0x2e, 0x42, 0xac, 0x6a, 0xf1, 0x5d, 0xaf, 0xf3, 0x1e, 0xaf, 0x93, 0x59, 0xdb, 0x0c, 0xce, 0xf1,
+	0x7f, 0x8c, 0xe0, 0xe0, 0x2d, 0xa3, 0x14, 0xe7, 0x22, 0xc2, 0xdb, 0x16, 0xb9, 0x20, 0x0f, 0x61,
+	0x9c, 0xb2, 0x44, 0xc4, 0x45, 0xe6, 0x1a, 0x13, 0x63, 0xea, 0x44, 0xbb, 0xf2, 0xf3, 0x3a, 0x23,
+	0x47, 0x60, 0xf2, 0x22, 0x73, 0x47, 0x0a, 0x94, 0x25, 0x79, 0x02, 0x07, 0xf3, 0xb2, 0x40, 0x2a,
+	0xe2, 0x3b, 0x6c, 0x78, 0xc1, 0xa8, 0x6b, 0x2a, 0x72, 0x5f, 0xa3, 0x5f, 0x34, 0x48, 0xce, 0xc0,
+	0xc9, 0xf0, 0xae, 0x98, 0xa3, 0x3c, 0xd3, 0x52, 0x2b, 0x6c, 0x0d, 0x5c, 0x67, 0x92, 0x6c, 0xb0,
+	0x62, 0x02, 0xe3, 0xa2, 0x76, 0x1f, 0x68, 0x52, 0x03, 0xd7, 0x35, 0x79, 0x04, 0xd0, 0x72, 0x6c,
+	0xe2, 0x24, 0x47, 0x2a, 0xdc, 0x5d, 0xc5, 0x3a, 0x12, 0x79, 0x23, 0x01, 0xf2, 0x14, 0x0e, 0x29,
+	0x8b, 0xd9, 0x62, 0x51, 0x16, 0x14, 0xe3, 0xba, 0xe5, 0x37, 0xee, 0x78, 0x62, 0x4c, 0xed, 0x68,
+	0x9f, 0xb2, 0x8f, 0x1a, 0xfd, 0xd4, 0xf2, 0x1b, 0xa9, 0x51, 0x62, 0xc2, 0x31, 0xce, 0x91, 0xba,
+	0xf6, 0xc4, 0x98, 0x9a, 0x91, 0xad, 0x80, 0xf7, 0x48, 0xfd, 0xd7, 0x70, 0xd8, 0x77, 0x80, 0xd7,
+	0x8c, 0x72, 0x94, 0x4e, 0xdb, 0xde, 0xbe, 0x2c, 0x89, 0x07, 0x76, 0x5d, 0x26, 0x62, 0xc1, 0x9a,
+	0xaa, 0x6b, 0x40, 0xff, 0xed, 0xff, 0x34, 0xe0, 0x78, 0x56, 0xf0, 0xf9, 0x7f, 0xb7, 0xb1, 0x93,
+	0x33, 0xb7, 0xcb, 0x59, 0xab, 0x72, 0xb2, 0x27, 0xda, 0x4c, 0xc9, 0xb8, 0x50, 0x1d, 0xb3, 0x23,
+	0x6d, 0xef, 0x03, 0xe3, 0xc2, 0x4f, 0x60, 0x2f, 0xc2, 0x24, 0x5b, 0x5e, 0xa3, 0x53, 0x33, 0x36,
+	0xd4, 0x46, 0xdb, 0xd5, 0xcc, 0x35, 0x35, 0x02, 0x16, 0xc7, 0x5b, 0xee, 0x5a, 0x13, 0x73, 0xea,
+	0x44, 0xaa, 0xf6, 0xbf, 0xc2, 0xd1, 0x15, 0x26, 0x8d, 0x48, 0x31, 0xf9, 0xbb, 0xdd, 0x67, 0x60,
+	0x0a, 0x51, 0x2a, 0xb9, 0xbd, 0x8b, 0xd3, 0x40, 0xcf, 0x63, 0xb0, 0x9c, 0xc7, 0x60, 0xd6, 0xcd,
+	0x63, 0x24, 0x57, 0xf9, 0x33, 0x38, 0x1e, 0x9c, 0xdc, 0xa5, 0x41, 0xc0, 0x52, 0x56, 0x0d, 0x65,
+	0x55, 0xd5, 0xab, 0x89, 0x8e, 0x56, 0x13, 0xbd, 0xf8, 0x35, 0x82, 0xf1, 0x67, 0xfd, 0x3e, 0xc8,
+	0x25, 0x8c, 0xbb, 0x74, 0xc9, 0x69, 0xd0, 0x3f, 0x9a, 0x60, 0x75, 0xe6, 0x3d, 0x6f, 0x1b, 0xa5,
+	0xe5, 0xfd, 0x1d, 0x32, 0x03, 0xb8, 0xcf, 0x97, 0x9c, 0x0f, 0xd6, 0x6e, 0xc4, 0xee, 0x9d, 0x6c,
+	0x38, 0x7c, 0x27, 0x9f, 0xa3, 0xbf, 0x43, 0x5e, 0x82, 0x25, 0x83, 0x21, 0x27, 0x83, 0xfd, 0x83,
+	0xa4, 0xfe, 0xb0, 0xf3, 0x0a, 0x9c, 0xbe, 0x2b, 0xe4, 0x6c, 0xb0, 0x7d, 0x3d, 0x05, 0xef, 0x7c,
+	0x3b, 0xb9, 0x74, 0x72, 0x19, 0x7c, 0x7b, 0xfe, 0x2f, 0x3f, 0x9d, 0x74, 0x57, 0xdd, 0xe5, 0xc5,
+	0xef, 0x01, 0x00, 0x61, 0xbb, 0x22, 0x7b, 0xab, 0x04, 0x00, 0x00,
 }
@@ -0,0 +1,326 @@
+// 消息的审计轨迹，各组件在消息生命周期的各阶段记录事件，用于排查用户声称没收到消息之类的问题
+// 以 uid+seq 为粒度存储于redis，有保留时长和条数上限
+// 未开启时 *Store 为 nil，其方法都是安全的
+// 推送路径上的记录(pushed/acked等)经缓冲后批量写入，不拖慢推送；各组件的写入先后不定，查询时按事件时间排序
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/molon/gomsg/internal/pkg/redispool"
+	"github.com/molon/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+/*
+// 以 {uid1} 作为 HashTag，和会话、离线消息在同一slot，批量记录时按节点分组pipeline
+"msg/u:{uid1}/au:{seq1}": [
+    "{\"t\":\"...\",\"s\":\"accepted\"}",
+    "{\"t\":\"...\",\"s\":\"pushed\",\"p\":\"mobile\",\"sid\":\"sid1\",\"bid\":\"bid1\"}",
+]
+*/
+
+func auKey(uid string, seq string) string {
+	return fmt.Sprintf("msg/u:{%s}/au:%s", uid, seq)
+}
+
+// 消息生命周期的各阶段
+const (
+	// station接收推送请求并投递至mq
+	StageAccepted = "accepted"
+	// carrier开始消费，每次重试都会记录
+	StageConsumed = "consumed"
+	// carrier已推送给会话，需要ack的消息也已等到了ack
+	StagePushed = "pushed"
+	// boat收到了客户端的ack
+	StageAcked = "acked"
+	// 推送给会话失败，Detail为原因
+	StagePushFailed = "push_failed"
+	// 投递mq失败，推送请求返回错误，Detail为topic
+	StagePublishFailed = "publish_failed"
+	// 已存储为离线消息
+	StageStoredOffline = "stored_offline"
+	// 离线消息超出平台的最大数目被清理
+	StageEvicted = "evicted"
+	// 离线消息已下发给新连接的会话
+	StageReplayed = "replayed"
+	// 消息已过期被丢弃，或者离线消息已过期被清理
+	StageExpired = "expired"
+	// 达到最大重试次数被丢进死信队列
+	StageDeadLetter = "dead_letter"
+)
+
+type Event struct {
+	Time     time.Time `json:"t"`
+	Stage    string    `json:"s"`
+	Platform string    `json:"p,omitempty"`
+	Sid      string    `json:"sid,omitempty"`
+	Bid      string    `json:"bid,omitempty"`
+	Detail   string    `json:"d,omitempty"`
+}
+
+// 某用户若干消息的同一事件
+type Entry struct {
+	Uid   string
+	Seqs  []string
+	Event Event
+}
+
+const (
+	// 缓冲的记录数，满了的直接丢弃
+	bufferSize = 4096
+	// 攒够这么多条或者等待这么久就写入一次
+	flushCount    = 256
+	flushInterval = 100 * time.Millisecond
+	flushTimeout  = 5 * time.Second
+)
+
+type Store struct {
+	logger    *logrus.Entry
+	redisPool redispool.Pool
+	retention time.Duration
+	maxEvents int
+
+	// closed 之后不再接收，RecordAsync 可能和 Close 并发
+	mu      sync.RWMutex
+	closed  bool
+	bufC    chan Entry
+	closedC chan struct{}
+}
+
+// retention 为每条消息轨迹的保留时长(从最后一次记录算起)，maxEvents 为每条消息最多保留的事件数
+// 需调用 Close 写入缓冲里剩余的记录
+func NewStore(logger *logrus.Logger, redisPool redispool.Pool, retention time.Duration, maxEvents int) *Store {
+	ll := logger.WithFields(logrus.Fields{
+		"pkg": "audit",
+		"mod": "store",
+	})
+
+	s := &Store{
+		logger:    ll,
+		redisPool: redisPool,
+		retention: retention,
+		maxEvents: maxEvents,
+		bufC:      make(chan Entry, bufferSize),
+		closedC:   make(chan struct{}),
+	}
+	go s.flushLoop()
+	return s
+}
+
+// 为某用户的若干消息记录同一事件，Time为空则为当前时间，写入后才返回
+// 审计只是尽力而为，失败了打印日志即可，不能影响消息本身的处理
+func (s *Store) Record(ctx context.Context, uid string, seqs []string, ev Event) {
+	s.RecordBatch(ctx, []Entry{{Uid: uid, Seqs: seqs, Event: ev}})
+}
+
+// 一次写入多条记录，按redis节点分组pipeline
+func (s *Store) RecordBatch(ctx context.Context, entries []Entry) {
+	if s == nil {
+		return
+	}
+
+	if err := s.record(ctx, stamp(entries)); err != nil {
+		s.logger.Warnf("Record %d audit entries failed: %+v", len(entries), err)
+	}
+}
+
+// 放入缓冲稍后批量写入，不阻塞调用方，缓冲满了或者已Close则丢弃
+func (s *Store) RecordAsync(uid string, seqs []string, ev Event) {
+	if s == nil {
+		return
+	}
+
+	entries := stamp([]Entry{{Uid: uid, Seqs: seqs, Event: ev}})
+	if len(entries) <= 0 {
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.bufC <- entries[0]:
+	default:
+		s.logger.Warnf("Audit buffer is full, drop %s of %s", ev.Stage, uid)
+	}
+}
+
+// 写入缓冲里剩余的记录，之后的 RecordAsync 会被忽略
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.bufC)
+	}
+	s.mu.Unlock()
+
+	<-s.closedC
+	return nil
+}
+
+// 去掉无效的，并以当前时间填充未指定的事件时间
+func stamp(entries []Entry) []Entry {
+	now := time.Now()
+	ret := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if len(e.Uid) < 1 || len(e.Seqs) <= 0 {
+			continue
+		}
+		if e.Event.Time.IsZero() {
+			e.Event.Time = now
+		}
+		ret = append(ret, e)
+	}
+	return ret
+}
+
+func (s *Store) flushLoop() {
+	defer close(s.closedC)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	entries := []Entry{}
+	flush := func() {
+		if len(entries) <= 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		if err := s.record(ctx, entries); err != nil {
+			s.logger.Warnf("Flush %d audit entries failed: %+v", len(entries), err)
+		}
+		cancel()
+		entries = entries[:0]
+	}
+
+	for {
+		select {
+		case e, ok := <-s.bufC:
+			if !ok {
+				flush()
+				return
+			}
+			entries = append(entries, e)
+			if len(entries) >= flushCount {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (s *Store) record(ctx context.Context, entries []Entry) error {
+	if len(entries) <= 0 {
+		return nil
+	}
+
+	// 同一key的多个事件按顺序一次RPUSH
+	keyToVals := map[string][]interface{}{}
+	keys := []string{}
+	for _, e := range entries {
+		b, err := json.Marshal(&e.Event)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		for _, seq := range e.Seqs {
+			key := auKey(e.Uid, seq)
+			if _, ok := keyToVals[key]; !ok {
+				keys = append(keys, key)
+			}
+			keyToVals[key] = append(keyToVals[key], b)
+		}
+	}
+
+	// 集群下各key分布在不同节点，按节点分组pipeline
+	for _, group := range redispool.Partition(s.redisPool, keys) {
+		if err := s.pipeline(ctx, group, keyToVals); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) pipeline(ctx context.Context, keys []string, keyToVals map[string][]interface{}) error {
+	conn, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	for _, key := range keys {
+		if err := conn.Send("RPUSH", append([]interface{}{key}, keyToVals[key]...)...); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := conn.Send("LTRIM", key, -s.maxEvents, -1); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := conn.Send("PEXPIRE", key, int64(s.retention/time.Millisecond)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if err := conn.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// 把应答都读掉，返回第一个错误
+	var rerr error
+	for i := 0; i < 3*len(keys); i++ {
+		if _, err := conn.Receive(); err != nil && rerr == nil {
+			rerr = errors.WithStack(err)
+		}
+	}
+	return rerr
+}
+
+// 某用户某消息的完整轨迹，按事件时间排序，相同的按记录顺序
+func (s *Store) Timeline(ctx context.Context, uid string, seq string) ([]Event, error) {
+	if s == nil {
+		return nil, errors.Errorf("audit is disabled")
+	}
+
+	conn, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	bs, err := redis.ByteSlices(conn.Do("LRANGE", auKey(uid, seq), 0, -1))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	evs := make([]Event, 0, len(bs))
+	for _, b := range bs {
+		ev := Event{}
+		if err := json.Unmarshal(b, &ev); err != nil {
+			// 烂数据不应该出现，打印出来跳过即可
+			s.logger.Warnf("Unmarshal audit event of %s(%s) failed: %v", uid, seq, err)
+			continue
+		}
+		evs = append(evs, ev)
+	}
+
+	// 各组件各自写入，缓冲的会晚一些，记录顺序不一定是发生顺序
+	sort.SliceStable(evs, func(i, j int) bool {
+		return evs[i].Time.Before(evs[j].Time)
+	})
+
+	return evs, nil
+}
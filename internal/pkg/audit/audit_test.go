@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestStore(t *testing.T, maxEvents int) *Store {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis.Run: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() { pool.Close() })
+
+	s := NewStore(logrus.New(), pool, time.Hour, maxEvents)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func stages(t *testing.T, s *Store, uid string, seq string) []string {
+	evs, err := s.Timeline(context.Background(), uid, seq)
+	if err != nil {
+		t.Fatalf("Timeline: %+v", err)
+	}
+	ret := make([]string, len(evs))
+	for i, ev := range evs {
+		ret[i] = ev.Stage
+	}
+	return ret
+}
+
+func equalStages(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRecordBatch(t *testing.T) {
+	s := newTestStore(t, 2)
+
+	s.RecordBatch(context.Background(), []Entry{
+		{Uid: "u1", Seqs: []string{"s1", "s2"}, Event: Event{Stage: StageAccepted}},
+		{Uid: "u2", Seqs: []string{"s1"}, Event: Event{Stage: StageAccepted}},
+		{Uid: "u1", Seqs: []string{"s1"}, Event: Event{Stage: StageConsumed}},
+		{Uid: "u1", Seqs: []string{"s1"}, Event: Event{Stage: StagePushed}},
+	})
+
+	// 超出 maxEvents 的丢掉最早的
+	if got := stages(t, s, "u1", "s1"); !equalStages(got, StageConsumed, StagePushed) {
+		t.Fatalf("u1 s1: got %v", got)
+	}
+	if got := stages(t, s, "u1", "s2"); !equalStages(got, StageAccepted) {
+		t.Fatalf("u1 s2: got %v", got)
+	}
+	if got := stages(t, s, "u2", "s1"); !equalStages(got, StageAccepted) {
+		t.Fatalf("u2 s1: got %v", got)
+	}
+}
+
+// 缓冲的记录Close时写入，查询时按事件时间排序
+func TestRecordAsync(t *testing.T) {
+	s := newTestStore(t, 10)
+
+	now := time.Now()
+	s.RecordAsync("u1", []string{"s1"}, Event{Time: now.Add(time.Millisecond), Stage: StageAcked})
+	s.Record(context.Background(), "u1", []string{"s1"}, Event{Time: now, Stage: StagePushed})
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %+v", err)
+	}
+	if got := stages(t, s, "u1", "s1"); !equalStages(got, StagePushed, StageAcked) {
+		t.Fatalf("u1 s1: got %v", got)
+	}
+
+	// Close之后的忽略即可
+	s.RecordAsync("u1", []string{"s1"}, Event{Stage: StageAcked})
+}
+
+func TestNilStore(t *testing.T) {
+	var s *Store
+	s.Record(context.Background(), "u1", []string{"s1"}, Event{Stage: StageAccepted})
+	s.RecordAsync("u1", []string{"s1"}, Event{Stage: StageAccepted})
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %+v", err)
+	}
+}
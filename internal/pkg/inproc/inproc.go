@@ -0,0 +1,28 @@
+// 进程内直接调用gRPC服务实现时的一些工具，all-in-one 模式下使用
+package inproc
+
+import (
+	"google.golang.org/grpc/status"
+)
+
+// 和经过gRPC传输之后一样，只保留error里的gRPC status，褪去堆栈等包装信息
+// 这样调用方依然可以通过 status.Convert 拿到错误码和详情
+func StatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	for e := err; e != nil; {
+		if st, ok := status.FromError(e); ok {
+			return st.Err()
+		}
+
+		c, ok := e.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		e = c.Cause()
+	}
+
+	return status.Convert(err).Err()
+}
@@ -0,0 +1,15 @@
+// 各组件的prometheus指标共用的辅助，指标本身定义在各组件的 metrics.go 里
+package metrics
+
+// 结果标签 result 的取值
+const (
+	ResultOk    = "ok"
+	ResultError = "error"
+)
+
+func ResultOf(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultOk
+}
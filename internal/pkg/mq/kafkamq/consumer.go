@@ -0,0 +1,466 @@
+package kafkamq
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/sirupsen/logrus"
+
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/pkg/errors"
+)
+
+type consumer struct {
+	logger       *logrus.Logger
+	cg           sarama.ConsumerGroup
+	topic        string
+	drainTimeout time.Duration
+	ackTimeout   time.Duration
+	lagInterval  time.Duration
+
+	mu       sync.Mutex
+	trackers map[*partitionTracker]struct{}
+
+	msgC     chan mq.Delivery
+	stopC    chan struct{}
+	closedC  chan struct{}
+	stopOnce sync.Once
+}
+
+// 以sarama的消费组实现，offset在消息ack之后才会被标记提交，只会提交连续已ack的部分
+// 分区被收回(rebalance或者Stop)时会等待已交出的消息ack完毕，最多等待 drainTimeout，以减少重复消费
+// ackTimeout > 0 时交出后超过此时长还未ack的消息会被告警(日志和 ack_timeouts_total)，它会卡住所在分区的offset提交
+// 不会因此重新投递，否则第一次的处理还在进行中，两次处理会并发且乱序
+// lagInterval > 0 时会定期更新各分区的消费延迟(consumer_lag)并打印
+// Messages()不带缓冲，已交出的消息都在使用方手里，这样收回分区时才能等到它们ack
+// sarama v1.21 只支持 eager 的 range/roundrobin 分配，不支持 cooperative rebalancing，rebalance时所有分区都会被收回
+func NewConsumer(
+	logger *logrus.Logger,
+	brokers []string,
+	version string,
+	balanceStrategy string,
+	group string,
+	topic string,
+	drainTimeout time.Duration,
+	ackTimeout time.Duration,
+	lagInterval time.Duration,
+) (mq.Consumer, error) {
+	kc := sarama.NewConfig()
+	v, err := sarama.ParseKafkaVersion(version)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	kc.Version = v
+	kc.Consumer.Return.Errors = true
+	kc.Consumer.Offsets.Initial = sarama.OffsetOldest
+
+	switch balanceStrategy {
+	case "", "range":
+		kc.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRange
+	case "roundrobin":
+		kc.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
+	case "cooperative-sticky":
+		// 需要升级到支持 cooperative rebalancing 的客户端，不能悄悄退化为 eager 分配
+		return nil, errors.Errorf("balance strategy cooperative-sticky is not supported by sarama v1.21, use range or roundrobin")
+	default:
+		return nil, errors.Errorf("unknown balance strategy: %s", balanceStrategy)
+	}
+
+	// 收回分区前的等待不能超过rebalance的超时，否则会被踢出消费组
+	if drainTimeout >= kc.Consumer.Group.Rebalance.Timeout {
+		return nil, errors.Errorf("drain timeout must < %v", kc.Consumer.Group.Rebalance.Timeout)
+	}
+
+	cg, err := sarama.NewConsumerGroup(brokers, group, kc)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &consumer{
+		logger:       logger,
+		cg:           cg,
+		topic:        topic,
+		drainTimeout: drainTimeout,
+		ackTimeout:   ackTimeout,
+		lagInterval:  lagInterval,
+
+		trackers: map[*partitionTracker]struct{}{},
+
+		msgC:    make(chan mq.Delivery),
+		stopC:   make(chan struct{}),
+		closedC: make(chan struct{}),
+	}, nil
+}
+
+func (c *consumer) Start() error {
+	go func() {
+		for err := range c.cg.Errors() {
+			c.logger.Warnf("Kafka consumer group [%s]: %v", c.topic, err)
+		}
+	}()
+
+	go c.loop()
+
+	if c.lagInterval > 0 {
+		go c.lagLoop()
+	}
+
+	return nil
+}
+
+func (c *consumer) Messages() <-chan mq.Delivery {
+	return c.msgC
+}
+
+func (c *consumer) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stopC)
+	})
+}
+
+func (c *consumer) Closed() <-chan struct{} {
+	return c.closedC
+}
+
+func (c *consumer) loop() {
+	defer func() {
+		// 关闭时会提交已标记的offset，此时所有ConsumeClaim都已返回，不会再有人写msgC
+		if err := c.cg.Close(); err != nil {
+			c.logger.Warnf("Close kafka consumer group [%s] failed: %v", c.topic, err)
+		}
+		close(c.msgC)
+		close(c.closedC)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-c.stopC:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// 每次rebalance之后Consume都会返回，需要重新加入
+	for {
+		if err := c.cg.Consume(ctx, []string{c.topic}, c); err != nil {
+			c.logger.Warnf("Consume [%s] failed: %v", c.topic, err)
+
+			select {
+			case <-time.After(time.Second):
+			case <-c.stopC:
+				return
+			}
+		}
+
+		select {
+		case <-c.stopC:
+			return
+		default:
+		}
+	}
+}
+
+// 定期更新并打印各分区的消费延迟
+func (c *consumer) lagLoop() {
+	ticker := time.NewTicker(c.lagInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.mu.Lock()
+			for t := range c.trackers {
+				lag := t.lag()
+				lagGauge.WithLabelValues(t.topic, partitionLabel(t.partition)).Set(float64(lag))
+				c.logger.WithFields(logrus.Fields{
+					"topic":     t.topic,
+					"partition": t.partition,
+					"lag":       lag,
+					"inflight":  t.inflight(),
+				}).Infoln("Kafka consumer lag")
+			}
+			c.mu.Unlock()
+		case <-c.closedC:
+			return
+		}
+	}
+}
+
+func (c *consumer) Setup(sess sarama.ConsumerGroupSession) error {
+	c.logger.Infof("Kafka consumer group [%s] claims: %v", c.topic, sess.Claims())
+	return nil
+}
+
+func (c *consumer) Cleanup(sess sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (c *consumer) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	t := newPartitionTracker(sess, claim)
+
+	c.mu.Lock()
+	c.trackers[t] = struct{}{}
+	c.mu.Unlock()
+
+	defer func() {
+		c.drain(t)
+
+		c.mu.Lock()
+		delete(c.trackers, t)
+		c.mu.Unlock()
+
+		lagGauge.DeleteLabelValues(t.topic, partitionLabel(t.partition))
+	}()
+
+	for {
+		// 被nack的消息优先重新投递
+		if d, ok := t.popNacked(); ok {
+			if !c.deliver(sess, d) {
+				return nil
+			}
+			continue
+		}
+
+		select {
+		case m, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			if !c.deliver(sess, t.track(m)) {
+				return nil
+			}
+		case <-t.nackC:
+		case <-sess.Context().Done():
+			return nil
+		case <-c.stopC:
+			return nil
+		}
+	}
+}
+
+func (c *consumer) deliver(sess sarama.ConsumerGroupSession, d *delivery) bool {
+	select {
+	case c.msgC <- d:
+		if c.ackTimeout > 0 {
+			d.arm(c.logger, c.ackTimeout)
+		}
+		return true
+	case <-sess.Context().Done():
+		return false
+	case <-c.stopC:
+		return false
+	}
+}
+
+// 等待已交出的消息ack完毕再交还分区，超时的部分会被新的消费者重新消费
+func (c *consumer) drain(t *partitionTracker) {
+	defer t.release()
+
+	if t.inflight() == 0 {
+		return
+	}
+
+	timer := time.NewTimer(c.drainTimeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if t.inflight() == 0 {
+				return
+			}
+		case <-timer.C:
+			c.logger.Warnf("Drain %s/%d timeout, %d messages will be redelivered", t.topic, t.partition, t.inflight())
+			return
+		}
+	}
+}
+
+// 跟踪某个分区已交出消息的ack情况，只标记连续已ack的offset
+type partitionTracker struct {
+	sess      sarama.ConsumerGroupSession
+	claim     sarama.ConsumerGroupClaim
+	topic     string
+	partition int32
+
+	mu       sync.Mutex
+	offsets  []int64 // 已交出但还未能标记的offset，按顺序
+	acked    map[int64]bool
+	next     int64 // 下一个要标记的offset，即已标记的部分
+	released bool
+	nacked   []*delivery
+	nackC    chan struct{}
+}
+
+func newPartitionTracker(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) *partitionTracker {
+	return &partitionTracker{
+		sess:      sess,
+		claim:     claim,
+		topic:     claim.Topic(),
+		partition: claim.Partition(),
+		acked:     map[int64]bool{},
+		next:      claim.InitialOffset(),
+		nackC:     make(chan struct{}, 1),
+	}
+}
+
+func (t *partitionTracker) track(m *sarama.ConsumerMessage) *delivery {
+	t.mu.Lock()
+	t.offsets = append(t.offsets, m.Offset)
+	t.mu.Unlock()
+
+	return &delivery{t: t, m: m}
+}
+
+func (t *partitionTracker) ack(offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// 分区已交还，交由新的消费者重新消费
+	if t.released {
+		return
+	}
+
+	t.acked[offset] = true
+
+	advanced := false
+	for len(t.offsets) > 0 && t.acked[t.offsets[0]] {
+		delete(t.acked, t.offsets[0])
+		t.next = t.offsets[0] + 1
+		t.offsets = t.offsets[1:]
+		advanced = true
+	}
+
+	if advanced {
+		// 要标记的是下一个要消费的offset
+		t.sess.MarkOffset(t.topic, t.partition, t.next, "")
+	}
+}
+
+func (t *partitionTracker) nack(d *delivery) {
+	t.mu.Lock()
+	if t.released {
+		t.mu.Unlock()
+		return
+	}
+	t.nacked = append(t.nacked, d)
+	t.mu.Unlock()
+
+	select {
+	case t.nackC <- struct{}{}:
+	default:
+	}
+}
+
+func (t *partitionTracker) popNacked() (*delivery, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.nacked) == 0 {
+		return nil, false
+	}
+	d := t.nacked[0]
+	t.nacked = t.nacked[1:]
+	return d, true
+}
+
+// 在使用方手里还未ack的消息数，等待重新投递的不算
+func (t *partitionTracker) inflight() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.offsets) - len(t.nacked)
+}
+
+// 分区最新offset和已标记offset的差值
+func (t *partitionTracker) lag() int64 {
+	t.mu.Lock()
+	next := t.next
+	t.mu.Unlock()
+
+	lag := t.claim.HighWaterMarkOffset() - next
+	if lag < 0 {
+		return 0
+	}
+	return lag
+}
+
+func (t *partitionTracker) release() {
+	t.mu.Lock()
+	t.released = true
+	t.nacked = nil
+	t.mu.Unlock()
+}
+
+type delivery struct {
+	t    *partitionTracker
+	m    *sarama.ConsumerMessage
+	once sync.Once
+
+	mu    sync.Mutex
+	timer *time.Timer
+	done  bool
+}
+
+// 超时未ack只告警，之后的ack照常生效
+func (d *delivery) arm(logger *logrus.Logger, after time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.done {
+		return
+	}
+	d.timer = time.AfterFunc(after, func() {
+		ackTimeoutsCounter.WithLabelValues(d.m.Topic).Inc()
+		logger.Warnf("Message %s/%d/%d is not acked within %v, offset commit of the partition is held back", d.m.Topic, d.m.Partition, d.m.Offset, after)
+	})
+}
+
+func (d *delivery) stopTimer() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.done = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
+
+func (d *delivery) Topic() string {
+	return d.m.Topic
+}
+
+func (d *delivery) Key() []byte {
+	return d.m.Key
+}
+
+func (d *delivery) Value() []byte {
+	return d.m.Value
+}
+
+func (d *delivery) Ack() error {
+	d.once.Do(func() {
+		d.stopTimer()
+		d.t.ack(d.m.Offset)
+	})
+	return nil
+}
+
+// kafka无法单独重新投递某条消息，所以在本消费者里重新交出，分区被收回后则交由新的消费者
+func (d *delivery) Nack() error {
+	d.once.Do(func() {
+		d.stopTimer()
+		d.t.nack(&delivery{t: d.t, m: d.m})
+	})
+	return nil
+}
+
+func partitionLabel(partition int32) string {
+	return strconv.FormatInt(int64(partition), 10)
+}
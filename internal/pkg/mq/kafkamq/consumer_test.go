@@ -0,0 +1,254 @@
+package kafkamq
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/sirupsen/logrus"
+
+	"github.com/molon/gomsg/internal/pkg/mq"
+)
+
+// 只记录标记的offset，不连接broker
+type fakeSession struct {
+	ctx context.Context
+
+	mu     sync.Mutex
+	marked []int64
+}
+
+func (s *fakeSession) Claims() map[string][]int32 { return nil }
+func (s *fakeSession) MemberID() string           { return "m1" }
+func (s *fakeSession) GenerationID() int32        { return 1 }
+func (s *fakeSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {
+}
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {}
+func (s *fakeSession) Context() context.Context                                 { return s.ctx }
+
+func (s *fakeSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.marked = append(s.marked, offset)
+}
+
+// 最后标记的offset，-1表示没有标记过
+func (s *fakeSession) lastMarked() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.marked) == 0 {
+		return -1
+	}
+	return s.marked[len(s.marked)-1]
+}
+
+type fakeClaim struct {
+	msgC chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) Topic() string                            { return "msg" }
+func (c *fakeClaim) Partition() int32                         { return 0 }
+func (c *fakeClaim) InitialOffset() int64                     { return 10 }
+func (c *fakeClaim) HighWaterMarkOffset() int64               { return 20 }
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.msgC }
+
+func newFakeClaim(t *testing.T) (*fakeSession, *fakeClaim) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &fakeSession{ctx: ctx}, &fakeClaim{msgC: make(chan *sarama.ConsumerMessage, 10)}
+}
+
+func message(offset int64) *sarama.ConsumerMessage {
+	return &sarama.ConsumerMessage{Topic: "msg", Partition: 0, Offset: offset}
+}
+
+func newTestConsumer(drainTimeout time.Duration) *consumer {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return &consumer{
+		logger:       logger,
+		topic:        "msg",
+		drainTimeout: drainTimeout,
+		trackers:     map[*partitionTracker]struct{}{},
+		msgC:         make(chan mq.Delivery),
+		stopC:        make(chan struct{}),
+		closedC:      make(chan struct{}),
+	}
+}
+
+func receive(t *testing.T, c *consumer) *delivery {
+	select {
+	case d := <-c.Messages():
+		return d.(*delivery)
+	case <-time.After(time.Second):
+		t.Fatal("no message delivered")
+		return nil
+	}
+}
+
+// 只标记连续已ack的部分，标记的是下一个要消费的offset
+func TestTrackerMarkContiguous(t *testing.T) {
+	sess, claim := newFakeClaim(t)
+	tr := newPartitionTracker(sess, claim)
+
+	d10, d11, d12 := tr.track(message(10)), tr.track(message(11)), tr.track(message(12))
+
+	d11.Ack()
+	if got := sess.lastMarked(); got != -1 {
+		t.Fatalf("marked %d before offset 10 acked", got)
+	}
+	if got := tr.lag(); got != 10 {
+		t.Fatalf("lag: got %d, want 10", got)
+	}
+
+	d10.Ack()
+	if got := sess.lastMarked(); got != 12 {
+		t.Fatalf("marked: got %d, want 12", got)
+	}
+	if got := tr.inflight(); got != 1 {
+		t.Fatalf("inflight: got %d, want 1", got)
+	}
+
+	// 重复ack无效
+	d10.Ack()
+	d12.Ack()
+	if got := sess.lastMarked(); got != 13 {
+		t.Fatalf("marked: got %d, want 13", got)
+	}
+	if got := tr.lag(); got != 7 {
+		t.Fatalf("lag: got %d, want 7", got)
+	}
+}
+
+// 被nack的消息在本消费者里优先重新交出，ack之后才能越过它标记
+func TestNackRedeliver(t *testing.T) {
+	sess, claim := newFakeClaim(t)
+	c := newTestConsumer(time.Second)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.ConsumeClaim(sess, claim)
+	}()
+
+	claim.msgC <- message(10)
+	claim.msgC <- message(11)
+
+	d10 := receive(t, c)
+	d10.Nack()
+	// nack之后的ack无效
+	d10.Ack()
+
+	d11 := receive(t, c)
+	if d11.m.Offset != 11 {
+		t.Fatalf("got offset %d, want 11", d11.m.Offset)
+	}
+	d11.Ack()
+
+	redelivered := receive(t, c)
+	if redelivered.m.Offset != 10 {
+		t.Fatalf("redelivered offset %d, want 10", redelivered.m.Offset)
+	}
+	if got := sess.lastMarked(); got != -1 {
+		t.Fatalf("marked %d before the nacked one acked", got)
+	}
+
+	redelivered.Ack()
+	if got := sess.lastMarked(); got != 12 {
+		t.Fatalf("marked: got %d, want 12", got)
+	}
+
+	c.Stop()
+	<-done
+}
+
+// 收回分区时等待已交出的消息ack完毕
+func TestDrainOnRevoke(t *testing.T) {
+	sess, claim := newFakeClaim(t)
+	c := newTestConsumer(time.Second)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.ConsumeClaim(sess, claim)
+	}()
+
+	claim.msgC <- message(10)
+	d10 := receive(t, c)
+
+	c.Stop()
+	select {
+	case <-done:
+		t.Fatal("partition released before in-flight messages acked")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	d10.Ack()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("partition not released after in-flight messages acked")
+	}
+	if got := sess.lastMarked(); got != 11 {
+		t.Fatalf("marked: got %d, want 11", got)
+	}
+}
+
+// 等待超时就交还分区，之后的ack不再标记，交由新的消费者重新消费
+func TestDrainTimeout(t *testing.T) {
+	sess, claim := newFakeClaim(t)
+	c := newTestConsumer(50 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.ConsumeClaim(sess, claim)
+	}()
+
+	claim.msgC <- message(10)
+	d10 := receive(t, c)
+
+	c.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("drain not timed out")
+	}
+
+	d10.Ack()
+	if got := sess.lastMarked(); got != -1 {
+		t.Fatalf("marked %d after the partition released", got)
+	}
+}
+
+// 超时未ack只告警，不会重新交出，之后的ack照常生效
+func TestAckTimeoutNoRedeliver(t *testing.T) {
+	sess, claim := newFakeClaim(t)
+	c := newTestConsumer(time.Second)
+	c.ackTimeout = 20 * time.Millisecond
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.ConsumeClaim(sess, claim)
+	}()
+
+	claim.msgC <- message(10)
+	d10 := receive(t, c)
+
+	select {
+	case d := <-c.Messages():
+		t.Fatalf("offset %d redelivered after ack timeout", d.(*delivery).m.Offset)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	d10.Ack()
+	if got := sess.lastMarked(); got != 11 {
+		t.Fatalf("marked: got %d, want 11", got)
+	}
+
+	c.Stop()
+	<-done
+}
@@ -0,0 +1,23 @@
+package kafkamq
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// 分区被收回时删除，只反映本实例当前持有的分区
+	lagGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gomsg",
+		Subsystem: "kafka",
+		Name:      "consumer_lag",
+		Help:      "Difference between the high water mark and the marked offset of each claimed partition.",
+	}, []string{"topic", "partition"})
+
+	ackTimeoutsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gomsg",
+		Subsystem: "kafka",
+		Name:      "ack_timeouts_total",
+		Help:      "Messages not acked within kafka.ack-timeout, they hold back the offset commit of their partitions.",
+	}, []string{"topic"})
+)
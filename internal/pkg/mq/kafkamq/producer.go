@@ -0,0 +1,70 @@
+package kafkamq
+
+import (
+	"sort"
+
+	"github.com/Shopify/sarama"
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/pkg/errors"
+)
+
+type producer struct {
+	sp sarama.SyncProducer
+}
+
+func NewProducer(brokers []string) (mq.Producer, error) {
+	kc := sarama.NewConfig()
+	kc.Producer.RequiredAcks = sarama.WaitForAll
+	kc.Producer.Retry.Max = 10
+	kc.Producer.Return.Successes = true
+	sp, err := sarama.NewSyncProducer(brokers, kc)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &producer{sp: sp}, nil
+}
+
+func (p *producer) Publish(msgs ...*mq.Message) error {
+	pms := make([]*sarama.ProducerMessage, len(msgs))
+	for i, msg := range msgs {
+		pms[i] = &sarama.ProducerMessage{
+			Topic: msg.Topic,
+			Key:   sarama.StringEncoder(msg.Key),
+			Value: sarama.ByteEncoder(msg.Value),
+		}
+	}
+
+	if len(pms) == 1 {
+		if _, _, err := p.sp.SendMessage(pms[0]); err != nil {
+			return errors.WithStack(err)
+		}
+		return nil
+	}
+
+	if err := p.sp.SendMessages(pms); err != nil {
+		perrs, ok := err.(sarama.ProducerErrors)
+		if !ok {
+			return errors.WithStack(err)
+		}
+
+		idx := make(map[*sarama.ProducerMessage]int, len(pms))
+		for i, pm := range pms {
+			idx[pm] = i
+		}
+		failed := make([]int, 0, len(perrs))
+		for _, perr := range perrs {
+			failed = append(failed, idx[perr.Msg])
+		}
+		sort.Ints(failed)
+		return &mq.PublishError{
+			Failed: failed,
+			Err:    errors.WithStack(perrs[0].Err),
+		}
+	}
+	return nil
+}
+
+func (p *producer) Close() error {
+	return errors.WithStack(p.sp.Close())
+}
@@ -0,0 +1,141 @@
+// 进程内的消息队列实现，all-in-one 模式下使用，不持久化，进程退出即丢失
+package memmq
+
+import (
+	"sync"
+	"time"
+
+	"github.com/molon/gomsg/internal/pkg/mq"
+)
+
+// 进程内的broker，每个topic下每个消费组各有一份消息队列
+type Broker struct {
+	mu             sync.Mutex
+	redeliverAfter time.Duration
+	topics         map[string]*topic
+}
+
+type topic struct {
+	// 还没有任何消费组时发布的消息暂存于此，交给第一个消费组，和kafka从头消费保持一致
+	backlog []*mq.Message
+	groups  map[string]*queue
+}
+
+// 未ack的消息在 redeliverAfter 之后会被重新投递
+func NewBroker(redeliverAfter time.Duration) *Broker {
+	return &Broker{
+		redeliverAfter: redeliverAfter,
+		topics:         map[string]*topic{},
+	}
+}
+
+func (b *Broker) topic(name string) *topic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topic{
+			groups: map[string]*queue{},
+		}
+		b.topics[name] = t
+	}
+	return t
+}
+
+func (b *Broker) publish(msgs ...*mq.Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, m := range msgs {
+		t := b.topic(m.Topic)
+		if len(t.groups) == 0 {
+			t.backlog = append(t.backlog, m)
+			continue
+		}
+		for _, q := range t.groups {
+			q.push(m)
+		}
+	}
+}
+
+func (b *Broker) queue(topicName string, group string) *queue {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(topicName)
+	q, ok := t.groups[group]
+	if !ok {
+		q = newQueue()
+		q.push(t.backlog...)
+		t.backlog = nil
+		t.groups[group] = q
+	}
+	return q
+}
+
+// 此broker的生产者，Close不会有任何影响
+func (b *Broker) Producer() mq.Producer {
+	return &producer{b: b}
+}
+
+type producer struct {
+	b *Broker
+}
+
+func (p *producer) Publish(msgs ...*mq.Message) error {
+	p.b.publish(msgs...)
+	return nil
+}
+
+func (p *producer) Close() error {
+	return nil
+}
+
+// 某消费组的消息队列，同组的多个消费者共享
+type queue struct {
+	mu      sync.Mutex
+	msgs    []*mq.Message
+	notifyC chan struct{}
+}
+
+func newQueue() *queue {
+	return &queue{
+		notifyC: make(chan struct{}, 1),
+	}
+}
+
+func (q *queue) push(msgs ...*mq.Message) {
+	if len(msgs) == 0 {
+		return
+	}
+
+	q.mu.Lock()
+	q.msgs = append(q.msgs, msgs...)
+	q.mu.Unlock()
+
+	q.notify()
+}
+
+func (q *queue) pop() (*mq.Message, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.msgs) == 0 {
+		return nil, false
+	}
+
+	m := q.msgs[0]
+	q.msgs[0] = nil
+	q.msgs = q.msgs[1:]
+
+	// 还有剩余的话要继续唤醒，可能有其他消费者在等待
+	if len(q.msgs) > 0 {
+		q.notify()
+	}
+	return m, true
+}
+
+func (q *queue) notify() {
+	select {
+	case q.notifyC <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,146 @@
+package memmq
+
+import (
+	"sync"
+	"time"
+
+	"github.com/molon/gomsg/internal/pkg/mq"
+)
+
+type consumer struct {
+	q              *queue
+	redeliverAfter time.Duration
+
+	msgC     chan mq.Delivery
+	stopC    chan struct{}
+	closedC  chan struct{}
+	stopOnce sync.Once
+}
+
+// 以消费组的形式消费某个topic，同组的多个consumer竞争消费
+func (b *Broker) NewConsumer(group string, topic string, concurrency int) mq.Consumer {
+	return &consumer{
+		q:              b.queue(topic, group),
+		redeliverAfter: b.redeliverAfter,
+
+		msgC:    make(chan mq.Delivery, concurrency),
+		stopC:   make(chan struct{}),
+		closedC: make(chan struct{}),
+	}
+}
+
+func (c *consumer) Start() error {
+	go c.loop()
+	return nil
+}
+
+func (c *consumer) Messages() <-chan mq.Delivery {
+	return c.msgC
+}
+
+func (c *consumer) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stopC)
+	})
+}
+
+func (c *consumer) Closed() <-chan struct{} {
+	return c.closedC
+}
+
+func (c *consumer) loop() {
+	defer func() {
+		close(c.msgC)
+		close(c.closedC)
+	}()
+
+	for {
+		m, ok := c.q.pop()
+		if !ok {
+			select {
+			case <-c.q.notifyC:
+				continue
+			case <-c.stopC:
+				return
+			}
+		}
+
+		d := c.newDelivery(m)
+		select {
+		case c.msgC <- d:
+			// 交出去之后才开始计时，否则没交出去的也可能会被重新投递
+			d.arm(c.redeliverAfter)
+		case <-c.stopC:
+			// 还没交出去的放回队列
+			c.q.push(m)
+			return
+		}
+	}
+}
+
+func (c *consumer) newDelivery(m *mq.Message) *delivery {
+	return &delivery{
+		q:   c.q,
+		msg: m,
+	}
+}
+
+type delivery struct {
+	q       *queue
+	msg     *mq.Message
+	mu      sync.Mutex
+	timer   *time.Timer
+	stopped bool
+	once    sync.Once
+}
+
+// 超时未ack则重新投递，交给消费方之后调用，其可能已经ack了
+func (d *delivery) arm(after time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopped {
+		return
+	}
+	d.timer = time.AfterFunc(after, d.requeue)
+}
+
+func (d *delivery) stopTimer() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.stopped = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
+
+func (d *delivery) Topic() string {
+	return d.msg.Topic
+}
+
+func (d *delivery) Key() []byte {
+	return []byte(d.msg.Key)
+}
+
+func (d *delivery) Value() []byte {
+	return d.msg.Value
+}
+
+func (d *delivery) Ack() error {
+	d.once.Do(d.stopTimer)
+	return nil
+}
+
+func (d *delivery) Nack() error {
+	d.stopTimer()
+	d.requeue()
+	return nil
+}
+
+// 超时未ack或者nack时重新放回队列，仅会执行一次
+func (d *delivery) requeue() {
+	d.once.Do(func() {
+		d.q.push(d.msg)
+	})
+}
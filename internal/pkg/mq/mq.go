@@ -0,0 +1,50 @@
+// 消息队列的抽象，station和carrier只依赖于此
+// 各实现只需保证至少一次投递，重试和死信由使用方以重新发布到对应topic的方式实现
+package mq
+
+import "fmt"
+
+// 待发布的消息
+type Message struct {
+	Topic string
+	// 同一key的消息会尽量被同一个消费者按顺序消费，是否能保证视实现而定
+	Key   string
+	Value []byte
+}
+
+// 部分消息发布失败时返回，Failed 为失败的消息在参数里的下标(升序)，其余的都已发布成功
+// 返回其他错误则表示全部失败或者无法确定哪些成功了
+type PublishError struct {
+	Failed []int
+	Err    error
+}
+
+func (e *PublishError) Error() string {
+	return fmt.Sprintf("%d messages failed to publish: %v", len(e.Failed), e.Err)
+}
+
+// 生产者
+type Producer interface {
+	// 同步发布，全部成功才返回nil，能确定哪些失败的返回 *PublishError
+	Publish(msgs ...*Message) error
+	Close() error
+}
+
+// 消费到的消息，调用Ack确认消费完毕，否则会被重新投递
+type Delivery interface {
+	Topic() string
+	Key() []byte
+	Value() []byte
+	Ack() error
+	// 告知消费失败，尽快重新投递
+	Nack() error
+}
+
+// 消费者，以消费组的形式消费某个topic
+type Consumer interface {
+	Start() error
+	Messages() <-chan Delivery
+	// 停止消费，之后Messages()会被关闭
+	Stop()
+	Closed() <-chan struct{}
+}
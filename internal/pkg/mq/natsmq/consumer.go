@@ -0,0 +1,160 @@
+package natsmq
+
+import (
+	"sync"
+	"time"
+
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/pkg/errors"
+	nats "github.com/nats-io/nats.go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// 每次拉取的最长等待时间
+	fetchWait = time.Second
+)
+
+type consumer struct {
+	logger  *logrus.Logger
+	url     string
+	group   string
+	topic   string
+	batch   int
+	ackWait time.Duration
+	maxAge  time.Duration
+
+	nc  *nats.Conn
+	sub *nats.Subscription
+
+	msgC     chan mq.Delivery
+	stopC    chan struct{}
+	closedC  chan struct{}
+	stopOnce sync.Once
+}
+
+// 以 JetStream 的 durable pull consumer 实现，同一消费组共享同一个durable
+// 未ack的消息在 ackWait 之后会被重新投递
+func NewConsumer(logger *logrus.Logger, url string, group string, topic string, concurrency int, ackWait time.Duration, maxAge time.Duration) mq.Consumer {
+	return &consumer{
+		logger:  logger,
+		url:     url,
+		group:   group,
+		topic:   topic,
+		batch:   concurrency,
+		ackWait: ackWait,
+		maxAge:  maxAge,
+
+		msgC:    make(chan mq.Delivery),
+		stopC:   make(chan struct{}),
+		closedC: make(chan struct{}),
+	}
+}
+
+func (c *consumer) Start() error {
+	nc, err := nats.Connect(c.url)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		nc.Close()
+		return errors.WithStack(err)
+	}
+
+	if err := ensureStream(js, c.topic, c.maxAge); err != nil {
+		nc.Close()
+		return err
+	}
+
+	sub, err := js.PullSubscribe(c.topic, c.group,
+		nats.BindStream(streamName(c.topic)),
+		nats.ManualAck(),
+		nats.AckWait(c.ackWait),
+	)
+	if err != nil {
+		nc.Close()
+		return errors.WithStack(err)
+	}
+
+	c.nc, c.sub = nc, sub
+	go c.loop()
+	return nil
+}
+
+func (c *consumer) Messages() <-chan mq.Delivery {
+	return c.msgC
+}
+
+func (c *consumer) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stopC)
+	})
+}
+
+func (c *consumer) Closed() <-chan struct{} {
+	return c.closedC
+}
+
+func (c *consumer) loop() {
+	defer func() {
+		close(c.msgC)
+		c.nc.Close()
+		close(c.closedC)
+	}()
+
+	for {
+		select {
+		case <-c.stopC:
+			return
+		default:
+		}
+
+		msgs, err := c.sub.Fetch(c.batch, nats.MaxWait(fetchWait))
+		if err == nats.ErrTimeout {
+			continue
+		}
+		if err != nil {
+			c.logger.Warnf("Fetch from nats stream %s failed: %+v", c.topic, err)
+			select {
+			case <-c.stopC:
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		for _, m := range msgs {
+			select {
+			case c.msgC <- &delivery{m: m}:
+			case <-c.stopC:
+				return
+			}
+		}
+	}
+}
+
+type delivery struct {
+	m *nats.Msg
+}
+
+func (d *delivery) Topic() string {
+	return d.m.Subject
+}
+
+func (d *delivery) Key() []byte {
+	return []byte(d.m.Header.Get(keyHeader))
+}
+
+func (d *delivery) Value() []byte {
+	return d.m.Data
+}
+
+func (d *delivery) Ack() error {
+	return errors.WithStack(d.m.Ack())
+}
+
+func (d *delivery) Nack() error {
+	return errors.WithStack(d.m.Nak())
+}
@@ -0,0 +1,79 @@
+package natsmq
+
+import (
+	"sync"
+	"time"
+
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/pkg/errors"
+	nats "github.com/nats-io/nats.go"
+)
+
+type producer struct {
+	nc     *nats.Conn
+	js     nats.JetStreamContext
+	maxAge time.Duration
+
+	// 已确认存在的stream
+	streams sync.Map
+}
+
+func NewProducer(url string, maxAge time.Duration) (mq.Producer, error) {
+	nc, err := nats.Connect(url)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	js, err := nc.JetStream()
+	if err != nil {
+		nc.Close()
+		return nil, errors.WithStack(err)
+	}
+
+	return &producer{
+		nc:     nc,
+		js:     js,
+		maxAge: maxAge,
+	}, nil
+}
+
+func (p *producer) Publish(msgs ...*mq.Message) error {
+	for i, msg := range msgs {
+		if _, ok := p.streams.Load(msg.Topic); !ok {
+			if err := ensureStream(p.js, msg.Topic, p.maxAge); err != nil {
+				return failedFrom(i, len(msgs), err)
+			}
+			p.streams.Store(msg.Topic, struct{}{})
+		}
+
+		// JetStream没有分区的概念，key只是透传给消费者
+		m := nats.NewMsg(msg.Topic)
+		m.Header.Set(keyHeader, msg.Key)
+		m.Data = msg.Value
+		if _, err := p.js.PublishMsg(m); err != nil {
+			return failedFrom(i, len(msgs), errors.WithStack(err))
+		}
+	}
+	return nil
+}
+
+// 逐条发布的，第i条失败则其之前的都已成功
+func failedFrom(i int, n int, err error) error {
+	if i == 0 {
+		return err
+	}
+
+	failed := make([]int, 0, n-i)
+	for ; i < n; i++ {
+		failed = append(failed, i)
+	}
+	return &mq.PublishError{
+		Failed: failed,
+		Err:    err,
+	}
+}
+
+func (p *producer) Close() error {
+	p.nc.Close()
+	return nil
+}
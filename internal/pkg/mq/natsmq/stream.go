@@ -0,0 +1,38 @@
+package natsmq
+
+import (
+	"strings"
+	"time"
+
+	"github.com/molon/pkg/errors"
+	nats "github.com/nats-io/nats.go"
+)
+
+const (
+	// 消息头里存放key的字段
+	keyHeader = "Gomsg-Key"
+)
+
+// 每个topic对应一个同名subject的stream，stream名称不允许包含某些字符
+func streamName(topic string) string {
+	return strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_").Replace(topic)
+}
+
+// 若stream不存在则创建，maxAge为消息的保留时间
+func ensureStream(js nats.JetStreamContext, topic string, maxAge time.Duration) error {
+	name := streamName(topic)
+	if _, err := js.StreamInfo(name); err == nil {
+		return nil
+	} else if err != nats.ErrStreamNotFound {
+		return errors.WithStack(err)
+	}
+
+	if _, err := js.AddStream(&nats.StreamConfig{
+		Name:     name,
+		Subjects: []string{topic},
+		MaxAge:   maxAge,
+	}); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
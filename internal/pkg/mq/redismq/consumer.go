@@ -0,0 +1,342 @@
+package redismq
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/pkg/errors"
+	"github.com/rs/xid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// XREADGROUP 的阻塞时间
+	blockTime = time.Second
+)
+
+type consumer struct {
+	logger    *logrus.Logger
+	pool      *redis.Pool
+	group     string
+	topic     string
+	name      string
+	batch     int
+	claimIdle time.Duration
+
+	msgC     chan mq.Delivery
+	stopC    chan struct{}
+	closedC  chan struct{}
+	stopOnce sync.Once
+}
+
+// 以 redis stream 的消费组实现，每个consumer实例都是消费组里的一个独立消费者
+// 未ack的消息在 claimIdle 之后会被其他(或者自己)重新认领投递
+// 处理中的消息会定期以 XCLAIM 续期，所以处理时长超过 claimIdle 也不会被重复投递
+func NewConsumer(logger *logrus.Logger, pool *redis.Pool, group string, topic string, concurrency int, claimIdle time.Duration) mq.Consumer {
+	return &consumer{
+		logger:    logger,
+		pool:      pool,
+		group:     group,
+		topic:     topic,
+		name:      xid.New().String(),
+		batch:     concurrency,
+		claimIdle: claimIdle,
+
+		msgC:    make(chan mq.Delivery),
+		stopC:   make(chan struct{}),
+		closedC: make(chan struct{}),
+	}
+}
+
+func (c *consumer) Start() error {
+	conn := c.pool.Get()
+	defer conn.Close()
+
+	// 从头开始消费，和kafka的OffsetOldest保持一致
+	if _, err := conn.Do("XGROUP", "CREATE", c.topic, c.group, "0", "MKSTREAM"); err != nil {
+		if !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return errors.WithStack(err)
+		}
+	}
+
+	go c.loop()
+	return nil
+}
+
+func (c *consumer) Messages() <-chan mq.Delivery {
+	return c.msgC
+}
+
+func (c *consumer) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stopC)
+	})
+}
+
+func (c *consumer) Closed() <-chan struct{} {
+	return c.closedC
+}
+
+func (c *consumer) loop() {
+	defer func() {
+		close(c.msgC)
+		close(c.closedC)
+	}()
+
+	nextClaimAt := time.Now().Add(c.claimIdle)
+	for {
+		select {
+		case <-c.stopC:
+			return
+		default:
+		}
+
+		var (
+			ds  []*delivery
+			err error
+		)
+		if time.Now().After(nextClaimAt) {
+			nextClaimAt = time.Now().Add(c.claimIdle)
+			ds, err = c.claim()
+		} else {
+			ds, err = c.read()
+		}
+		if err != nil {
+			c.logger.Warnf("Consume redis stream %s failed: %+v", c.topic, err)
+			select {
+			case <-c.stopC:
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		for _, d := range ds {
+			select {
+			case c.msgC <- d:
+				d.keepalive()
+			case <-c.stopC:
+				return
+			}
+		}
+	}
+}
+
+// 读取新消息
+func (c *consumer) read() ([]*delivery, error) {
+	conn := c.pool.Get()
+	defer conn.Close()
+
+	// XREADGROUP GROUP group name COUNT batch BLOCK ms STREAMS topic >
+	reply, err := redis.Values(redis.DoWithTimeout(conn, blockTime+time.Second,
+		"XREADGROUP", "GROUP", c.group, c.name,
+		"COUNT", c.batch, "BLOCK", int64(blockTime/time.Millisecond),
+		"STREAMS", c.topic, ">"))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// [[topic, entries]]，只读取了一个stream
+	if len(reply) <= 0 {
+		return nil, nil
+	}
+	stream, err := redis.Values(reply[0], nil)
+	if err != nil || len(stream) != 2 {
+		return nil, errors.Errorf("unexpected XREADGROUP reply: %v", reply[0])
+	}
+	return c.parseEntries(stream[1])
+}
+
+// 认领空闲太久的未ack消息，一般是之前的消费者挂了或者消费失败了
+func (c *consumer) claim() ([]*delivery, error) {
+	conn := c.pool.Get()
+	defer conn.Close()
+
+	// XPENDING topic group - + count
+	pendings, err := redis.Values(conn.Do("XPENDING", c.topic, c.group, "-", "+", c.batch))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	minIdle := int64(c.claimIdle / time.Millisecond)
+	args := []interface{}{c.topic, c.group, c.name, minIdle}
+	for _, p := range pendings {
+		// [id, consumer, idle, deliveries]
+		pending, err := redis.Values(p, nil)
+		if err != nil || len(pending) < 3 {
+			return nil, errors.Errorf("unexpected XPENDING reply: %v", p)
+		}
+		idle, _ := redis.Int64(pending[2], nil)
+		if idle >= minIdle {
+			args = append(args, pending[0])
+		}
+	}
+	if len(args) <= 4 {
+		return nil, nil
+	}
+
+	// XCLAIM topic group name min-idle id...
+	reply, err := conn.Do("XCLAIM", args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return c.parseEntries(reply)
+}
+
+// [[id, [k, key, v, value]], ...]
+func (c *consumer) parseEntries(reply interface{}) ([]*delivery, error) {
+	entries, err := redis.Values(reply, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	ds := []*delivery{}
+	for _, e := range entries {
+		// 已经被裁剪掉的消息会返回nil
+		if e == nil {
+			continue
+		}
+
+		entry, err := redis.Values(e, nil)
+		if err != nil || len(entry) != 2 {
+			return nil, errors.Errorf("unexpected stream entry: %v", e)
+		}
+
+		id, _ := redis.String(entry[0], nil)
+		fields, err := redis.ByteSlices(entry[1], nil)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		d := &delivery{
+			c:  c,
+			id: id,
+		}
+		for i := 0; i+1 < len(fields); i += 2 {
+			switch string(fields[i]) {
+			case fieldKey:
+				d.key = fields[i+1]
+			case fieldValue:
+				d.value = fields[i+1]
+			}
+		}
+		ds = append(ds, d)
+	}
+	return ds, nil
+}
+
+type delivery struct {
+	c     *consumer
+	id    string
+	key   []byte
+	value []byte
+
+	mu    sync.Mutex
+	done  bool
+	timer *time.Timer
+}
+
+// 续期的间隔
+func (d *delivery) keepaliveInterval() time.Duration {
+	return d.c.claimIdle / 3
+}
+
+// 开始定期续期，直到 Ack/Nack 或者发现已被他人认领
+func (d *delivery) keepalive() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.done {
+		return
+	}
+	d.timer = time.AfterFunc(d.keepaliveInterval(), d.renew)
+}
+
+func (d *delivery) renew() {
+	d.mu.Lock()
+	if d.done {
+		d.mu.Unlock()
+		return
+	}
+	d.mu.Unlock()
+
+	owned, err := d.claim()
+	if err != nil {
+		// 续期失败也没关系，下次再试，最坏情况就是被重复投递
+		d.c.logger.Warnf("Renew %s of redis stream %s failed: %+v", d.id, d.c.topic, err)
+		owned = true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.done {
+		return
+	}
+	if !owned {
+		d.c.logger.Warnf("Message %s of redis stream %s has been claimed by others", d.id, d.c.topic)
+		d.done = true
+		return
+	}
+	d.timer = time.AfterFunc(d.keepaliveInterval(), d.renew)
+}
+
+// 以自己的名义重新认领以重置空闲时间，JUSTID 不会增加投递次数
+// 自上次续期以来若已被他人认领，其空闲时间必然小于半个续期间隔，此时返回false
+func (d *delivery) claim() (bool, error) {
+	conn := d.c.pool.Get()
+	defer conn.Close()
+
+	minIdle := int64(d.keepaliveInterval() / 2 / time.Millisecond)
+	ids, err := redis.Values(conn.Do("XCLAIM", d.c.topic, d.c.group, d.c.name, minIdle, d.id, "JUSTID"))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return len(ids) > 0, nil
+}
+
+// 停止续期
+func (d *delivery) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.done = true
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
+
+func (d *delivery) Topic() string {
+	return d.c.topic
+}
+
+func (d *delivery) Key() []byte {
+	return d.key
+}
+
+func (d *delivery) Value() []byte {
+	return d.value
+}
+
+func (d *delivery) Ack() error {
+	d.stop()
+
+	conn := d.c.pool.Get()
+	defer conn.Close()
+
+	if _, err := conn.Do("XACK", d.c.topic, d.c.group, d.id); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// 停止续期，等待空闲 claimIdle 之后被重新认领即可
+func (d *delivery) Nack() error {
+	d.stop()
+	return nil
+}
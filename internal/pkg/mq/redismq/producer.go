@@ -0,0 +1,73 @@
+package redismq
+
+import (
+	"github.com/gomodule/redigo/redis"
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/pkg/errors"
+)
+
+const (
+	fieldKey   = "k"
+	fieldValue = "v"
+)
+
+type producer struct {
+	pool   *redis.Pool
+	maxLen int64
+}
+
+// 每个topic对应一个stream，maxLen为stream保留的大概最大长度，超出的旧消息会被裁剪
+func NewProducer(pool *redis.Pool, maxLen int64) mq.Producer {
+	return &producer{
+		pool:   pool,
+		maxLen: maxLen,
+	}
+}
+
+func (p *producer) Publish(msgs ...*mq.Message) error {
+	if len(msgs) <= 0 {
+		return nil
+	}
+
+	conn := p.pool.Get()
+	defer conn.Close()
+
+	for _, msg := range msgs {
+		// XADD topic MAXLEN ~ maxLen * k key v value
+		if err := conn.Send("XADD", msg.Topic, "MAXLEN", "~", p.maxLen, "*",
+			fieldKey, msg.Key, fieldValue, msg.Value); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if err := conn.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// 单条命令的错误不影响其他的，连接出错的话就无法确定了
+	var failed []int
+	var ferr error
+	for i := range msgs {
+		if _, err := conn.Receive(); err != nil {
+			if _, ok := err.(redis.Error); !ok {
+				return errors.WithStack(err)
+			}
+			failed = append(failed, i)
+			if ferr == nil {
+				ferr = errors.WithStack(err)
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return &mq.PublishError{
+			Failed: failed,
+			Err:    ferr,
+		}
+	}
+	return nil
+}
+
+func (p *producer) Close() error {
+	return errors.WithStack(p.pool.Close())
+}
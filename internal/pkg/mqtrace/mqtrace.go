@@ -0,0 +1,45 @@
+// mq消息的trace传递，各mq实现不一定支持消息头，所以span上下文放在 mqpb.Payload 里
+// 生产时注入当前span的上下文，消费时以 FollowsFrom 延续，这样一次推送从gateway到boat都在同一trace里
+package mqtrace
+
+import (
+	"context"
+
+	"github.com/molon/gomsg/internal/pb/mqpb"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// 注入ctx里span的上下文，没有span则忽略
+func Inject(ctx context.Context, payload *mqpb.Payload) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+
+	carrier := opentracing.TextMapCarrier{}
+	if err := span.Tracer().Inject(span.Context(), opentracing.TextMap, carrier); err != nil {
+		return
+	}
+	payload.Trace = carrier
+}
+
+// 开启消费payload的span，payload里没有span上下文的话则为新的trace
+func StartSpan(ctx context.Context, operationName string, topic string, payload *mqpb.Payload) (opentracing.Span, context.Context) {
+	tracer := opentracing.GlobalTracer()
+
+	opts := []opentracing.StartSpanOption{ext.SpanKindConsumer}
+	if len(payload.GetTrace()) > 0 {
+		sc, err := tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier(payload.GetTrace()))
+		if err == nil {
+			opts = append(opts, opentracing.FollowsFrom(sc))
+		}
+	}
+
+	span := tracer.StartSpan(operationName, opts...)
+	ext.MessageBusDestination.Set(span, topic)
+	span.SetTag("payload.seq", payload.GetSeq())
+	span.SetTag("payload.retry_count", payload.GetRetryCount())
+
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
@@ -0,0 +1,365 @@
+package boltoffline
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/molon/gomsg/internal/pkg/offline"
+	"github.com/molon/gomsg/pb/msgpb"
+	"github.com/molon/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+func (s *Store) Write(ctx context.Context,
+	uid string, platform string,
+	msg *msgpb.Message,
+	sendTime time.Time, expire time.Duration,
+) error {
+	m, err := proto.Marshal(msg)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	seq := []byte(msg.GetSeq())
+	priority := offline.PriorityOf(msg)
+	ts := sendTime.Unix()
+	expAt := ts + int64(expire/time.Second)
+
+	// 消息自身的过期时间更早的话以其为准，已过期就没必要存储了
+	if msg.GetExpireAt() != nil {
+		msgExpAt := msg.GetExpireAt().GetSeconds()
+		if msgExpAt <= time.Now().Unix() {
+			return offline.ErrExpired
+		}
+		if msgExpAt < expAt {
+			expAt = msgExpAt
+		}
+	}
+
+	return errors.WithStack(s.db.Update(func(tx *bolt.Tx) error {
+		ub, err := tx.Bucket(usersBucket).CreateBucketIfNotExists(userKey(uid, platform))
+		if err != nil {
+			return err
+		}
+		tb, err := ub.CreateBucketIfNotExists(tsBucketOf(priority))
+		if err != nil {
+			return err
+		}
+		sb, err := ub.CreateBucketIfNotExists(seqBucket)
+		if err != nil {
+			return err
+		}
+
+		// 已经记录过了
+		if sb.Get(seq) != nil {
+			return nil
+		}
+
+		tsb := encodeInt64(ts)
+
+		// 同一折叠key的已有消息，发出时间更晚的话忽略此消息，否则之后替换掉
+		var replaced string
+		if collapseKey := []byte(msg.GetCollapseKey()); len(collapseKey) > 0 {
+			cb, err := ub.CreateBucketIfNotExists(collapseBucket)
+			if err != nil {
+				return err
+			}
+			if old := cb.Get(collapseKey); old != nil {
+				if v := sb.Get(old); v != nil {
+					if ots, _ := decodeSeqValue(v); bytes.Compare(ots, tsb) > 0 {
+						return nil
+					}
+					replaced = string(old)
+				}
+			}
+			if err := cb.Put(collapseKey, seq); err != nil {
+				return err
+			}
+		}
+
+		if err := sb.Put(seq, encodeSeqValue(tsb, priority)); err != nil {
+			return err
+		}
+		if err := tb.Put(tsKey(tsb, string(seq)), nil); err != nil {
+			return err
+		}
+
+		// 内容可能已经被其他映射写入过，增加引用计数即可
+		// 各平台的离线有效期不同，内容要保留到最晚的那个
+		msgs := tx.Bucket(msgsBucket)
+		if v := msgs.Get(seq); v != nil {
+			oExpAt, refs, body := decodeMsg(v)
+			if oExpAt > expAt {
+				expAt = oExpAt
+			}
+			if err := msgs.Put(seq, encodeMsg(expAt, refs+1, body)); err != nil {
+				return err
+			}
+		} else if err := msgs.Put(seq, encodeMsg(expAt, 1, m)); err != nil {
+			return err
+		}
+
+		if replaced != "" {
+			return removeSeqs(tx, uid, platform, []string{replaced})
+		}
+		return nil
+	}))
+}
+
+func (s *Store) Read(ctx context.Context, uid string, platform string, expire time.Duration, readCount int64) ([]*msgpb.Message, func(context.Context) error, error) {
+	now := time.Now()
+	expTs := encodeInt64(now.Add(-expire).Unix())
+
+	seqs := []string{}
+	msgs := []*msgpb.Message{}
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		ub := tx.Bucket(usersBucket).Bucket(userKey(uid, platform))
+		if ub == nil {
+			return nil
+		}
+		mb := tx.Bucket(msgsBucket)
+
+		// 高优先级的先读
+		for _, priority := range offline.Priorities {
+			tb := ub.Bucket(tsBucketOf(priority))
+			if tb == nil {
+				continue
+			}
+
+			c := tb.Cursor()
+			for k, _ := c.Seek(expTs); k != nil && int64(len(seqs)) < readCount; k, _ = c.Next() {
+				seq := string(k[8:])
+				seqs = append(seqs, seq)
+
+				// 内容已过期的跳过，但依然会被deleteFunc清理掉
+				pb, err := readMsg(mb, seq, now)
+				if err != nil {
+					return err
+				}
+				if pb == nil {
+					continue
+				}
+
+				msgs = append(msgs, pb)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	if len(seqs) <= 0 {
+		return nil, nil, nil
+	}
+
+	delete := func(ctx context.Context) error {
+		return s.Delete(ctx, uid, platform, seqs)
+	}
+	return msgs, delete, nil
+}
+
+func (s *Store) Scan(ctx context.Context, uid string, platform string, expire time.Duration, cursor string, limit int64) ([]*msgpb.Message, string, error) {
+	if limit <= 0 {
+		return nil, "", errors.Errorf("limit must > 0")
+	}
+
+	cursorTs, cursorSeq, err := offline.DecodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	start := encodeInt64(now.Add(-expire).Unix())
+	var after []byte
+	if cursorSeq != "" {
+		after = tsKey(encodeInt64(cursorTs), cursorSeq)
+		if bytes.Compare(after, start) > 0 {
+			start = after
+		}
+	}
+
+	var next string
+	msgs := []*msgpb.Message{}
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		ub := tx.Bucket(usersBucket).Bucket(userKey(uid, platform))
+		if ub == nil {
+			return nil
+		}
+		mb := tx.Bucket(msgsBucket)
+
+		var (
+			n    int64
+			last []byte
+		)
+		// 不区分优先级，按发出时间顺序
+		c := newMergedCursor(ub)
+		for k := c.Seek(start); k != nil; k = c.Next() {
+			// 游标自身不算
+			if after != nil && bytes.Equal(k, after) {
+				continue
+			}
+
+			// 还有更多
+			if n >= limit {
+				next = offline.EncodeCursor(decodeInt64(last[:8]), string(last[8:]))
+				break
+			}
+			n++
+			last = k
+
+			pb, err := readMsg(mb, string(k[8:]), now)
+			if err != nil {
+				return err
+			}
+			if pb != nil {
+				msgs = append(msgs, pb)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	return msgs, next, nil
+}
+
+func (s *Store) Count(ctx context.Context, uid string, platform string, expire time.Duration, byCategory bool) (int64, map[string]int64, error) {
+	now := time.Now()
+	expTs := encodeInt64(now.Add(-expire).Unix())
+
+	var n int64
+	msgs := []*msgpb.Message{}
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		ub := tx.Bucket(usersBucket).Bucket(userKey(uid, platform))
+		if ub == nil {
+			return nil
+		}
+		mb := tx.Bucket(msgsBucket)
+
+		c := newMergedCursor(ub)
+		for k := c.Seek(expTs); k != nil; k = c.Next() {
+			if !byCategory {
+				n++
+				continue
+			}
+
+			// 需要分组的话只能把内容都读出来
+			pb, err := readMsg(mb, string(k[8:]), now)
+			if err != nil {
+				return err
+			}
+			if pb != nil {
+				msgs = append(msgs, pb)
+			}
+		}
+		return nil
+	}); err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+
+	if !byCategory {
+		return n, nil, nil
+	}
+
+	total, categories := offline.CountByCategory(msgs)
+	return total, categories, nil
+}
+
+// 读取未过期的消息内容，不存在或者已过期则返回nil
+func readMsg(mb *bolt.Bucket, seq string, now time.Time) (*msgpb.Message, error) {
+	v := mb.Get([]byte(seq))
+	if v == nil {
+		return nil, nil
+	}
+	expAt, _, body := decodeMsg(v)
+	if expAt <= now.Unix() {
+		return nil, nil
+	}
+
+	// bolt里的数据只在事务内有效，需要拷贝出来
+	pb := &msgpb.Message{}
+	if err := proto.Unmarshal(append([]byte(nil), body...), pb); err != nil {
+		return nil, err
+	}
+
+	if pb.GetExpireAt() != nil && pb.GetExpireAt().GetSeconds() <= now.Unix() {
+		return nil, nil
+	}
+
+	return pb, nil
+}
+
+func (s *Store) Delete(ctx context.Context, uid string, platform string, seqs []string) error {
+	if len(seqs) <= 0 {
+		return errors.Errorf("seqs is empty")
+	}
+
+	return errors.WithStack(s.db.Update(func(tx *bolt.Tx) error {
+		return removeSeqs(tx, uid, platform, seqs)
+	}))
+}
+
+// 清理过期的以及超出最大数目的离线消息，返回各平台过期被清理的以及超出数目被清理的seq列表
+func (s *Store) Clean(ctx context.Context, uid string, expire time.Duration, platformToMaxOMCount map[string]int) (map[string][]string, map[string][]string, error) {
+	if len(platformToMaxOMCount) <= 0 {
+		return nil, nil, errors.Errorf("platformToMaxOMCount is empty")
+	}
+
+	expTs := encodeInt64(time.Now().Add(-expire).Unix())
+
+	platformToExpiredSeqs := map[string][]string{}
+	platformToEvictedSeqs := map[string][]string{}
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		for platform, maxOMCount := range platformToMaxOMCount {
+			ub := tx.Bucket(usersBucket).Bucket(userKey(uid, platform))
+			if ub == nil {
+				continue
+			}
+
+			// 各优先级按发出时间从旧到新，过期的都在前面
+			// 未过期的按优先级从低到高排列，这样超出数目时先清理低优先级的
+			expired := []string{}
+			valid := []string{}
+			for i := len(offline.Priorities) - 1; i >= 0; i-- {
+				tb := ub.Bucket(tsBucketOf(offline.Priorities[i]))
+				if tb == nil {
+					continue
+				}
+
+				c := tb.Cursor()
+				for k, _ := c.First(); k != nil; k, _ = c.Next() {
+					if bytes.Compare(k[:8], expTs) < 0 {
+						expired = append(expired, string(k[8:]))
+						continue
+					}
+					valid = append(valid, string(k[8:]))
+				}
+			}
+
+			// 未过期的里面只保留后maxOMCount个
+			var evicted []string
+			if maxOMCount >= 0 && len(valid) > maxOMCount {
+				evicted = valid[:len(valid)-maxOMCount]
+			}
+			removing := append(append([]string{}, expired...), evicted...)
+
+			if err := removeSeqs(tx, uid, platform, removing); err != nil {
+				return err
+			}
+
+			if len(expired) > 0 {
+				platformToExpiredSeqs[platform] = expired
+			}
+			if len(evicted) > 0 {
+				platformToEvictedSeqs[platform] = evicted
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	return platformToExpiredSeqs, platformToEvictedSeqs, nil
+}
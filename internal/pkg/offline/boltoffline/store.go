@@ -0,0 +1,224 @@
+// 以内嵌的bbolt实现的离线消息存储，无需外部依赖，适合单实例部署(例如 all-in-one 模式)
+// bbolt同一时间只能被一个进程打开，所以不能用于多个carrier实例
+package boltoffline
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/molon/gomsg/internal/pkg/offline"
+	"github.com/molon/gomsg/pb/msgpb"
+	"github.com/molon/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+/*
+// 离线消息内容，引用计数<=0时删除
+"msgs": {
+    "seq1": expire_at(8字节) + 引用计数(8字节) + 消息内容,
+}
+
+// 某用户在某平台的离线消息映射，key为 uid\x00platform
+"users": {
+    "uid1\x00platform1": {
+        "t":  { ts(8字节)+seq1: nil }, // NORMAL优先级的，按发出时间排序
+        "t1": { ts(8字节)+seq2: nil }, // 其他优先级的，t后跟优先级的值
+        "s":  { seq1: ts(8字节), seq2: ts(8字节)+优先级(4字节) }, // 根据seq查找，NORMAL的省略优先级
+        "c":  { collapse_key1: seq1 }, // 折叠key对应的seq，可能指向已被删除的seq
+    },
+}
+*/
+
+var (
+	msgsBucket     = []byte("msgs")
+	usersBucket    = []byte("users")
+	tsBucket       = []byte("t")
+	seqBucket      = []byte("s")
+	collapseBucket = []byte("c")
+)
+
+type Store struct {
+	db *bolt.DB
+}
+
+var _ offline.Store = (*Store)(nil)
+
+// 打开或者创建path对应的数据库文件
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(msgsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(usersBucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, errors.WithStack(err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return errors.WithStack(s.db.Close())
+}
+
+func userKey(uid string, platform string) []byte {
+	return []byte(uid + "\x00" + platform)
+}
+
+func encodeInt64(v int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(v))
+	return b
+}
+
+func decodeInt64(b []byte) int64 {
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+// 某优先级的映射bucket，NORMAL的沿用不区分优先级时的
+func tsBucketOf(priority msgpb.MessagePriority) []byte {
+	if priority == msgpb.MessagePriority_NORMAL {
+		return tsBucket
+	}
+	return []byte(fmt.Sprintf("t%d", priority))
+}
+
+// seq对应的 发出时间+优先级
+func encodeSeqValue(ts []byte, priority msgpb.MessagePriority) []byte {
+	if priority == msgpb.MessagePriority_NORMAL {
+		return ts
+	}
+	v := make([]byte, 12)
+	copy(v, ts)
+	binary.BigEndian.PutUint32(v[8:], uint32(priority))
+	return v
+}
+
+func decodeSeqValue(v []byte) (ts []byte, priority msgpb.MessagePriority) {
+	if len(v) < 12 {
+		return v[:8], msgpb.MessagePriority_NORMAL
+	}
+	return v[:8], msgpb.MessagePriority(binary.BigEndian.Uint32(v[8:12]))
+}
+
+func tsKey(ts []byte, seq string) []byte {
+	return append(append([]byte{}, ts...), seq...)
+}
+
+// 消息内容的编解码
+func encodeMsg(expAt int64, refs int64, body []byte) []byte {
+	v := make([]byte, 16+len(body))
+	binary.BigEndian.PutUint64(v, uint64(expAt))
+	binary.BigEndian.PutUint64(v[8:], uint64(refs))
+	copy(v[16:], body)
+	return v
+}
+
+func decodeMsg(v []byte) (expAt int64, refs int64, body []byte) {
+	return decodeInt64(v[:8]), decodeInt64(v[8:16]), v[16:]
+}
+
+// 删除某用户某平台的映射，并修正引用计数
+func removeSeqs(tx *bolt.Tx, uid string, platform string, seqs []string) error {
+	ub := tx.Bucket(usersBucket).Bucket(userKey(uid, platform))
+	if ub == nil {
+		return nil
+	}
+	sb := ub.Bucket(seqBucket)
+	msgs := tx.Bucket(msgsBucket)
+
+	for _, seq := range seqs {
+		v := sb.Get([]byte(seq))
+		if v == nil {
+			continue
+		}
+		ts, priority := decodeSeqValue(v)
+		if tb := ub.Bucket(tsBucketOf(priority)); tb != nil {
+			if err := tb.Delete(tsKey(ts, seq)); err != nil {
+				return err
+			}
+		}
+		if err := sb.Delete([]byte(seq)); err != nil {
+			return err
+		}
+
+		v = msgs.Get([]byte(seq))
+		if v == nil {
+			continue
+		}
+		expAt, refs, body := decodeMsg(v)
+		if refs <= 1 {
+			if err := msgs.Delete([]byte(seq)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := msgs.Put([]byte(seq), encodeMsg(expAt, refs-1, body)); err != nil {
+			return err
+		}
+	}
+
+	// 没有映射了就删掉，避免越积越多
+	if k, _ := sb.Cursor().First(); k == nil {
+		return tx.Bucket(usersBucket).DeleteBucket(userKey(uid, platform))
+	}
+
+	return nil
+}
+
+// 按 ts+seq 的顺序合并遍历某用户某平台各优先级的映射
+type mergedCursor struct {
+	cs   []*bolt.Cursor
+	keys [][]byte
+}
+
+func newMergedCursor(ub *bolt.Bucket) *mergedCursor {
+	mc := &mergedCursor{}
+	for _, priority := range offline.Priorities {
+		if tb := ub.Bucket(tsBucketOf(priority)); tb != nil {
+			mc.cs = append(mc.cs, tb.Cursor())
+		}
+	}
+	mc.keys = make([][]byte, len(mc.cs))
+	return mc
+}
+
+func (mc *mergedCursor) Seek(seek []byte) []byte {
+	for i, c := range mc.cs {
+		mc.keys[i], _ = c.Seek(seek)
+	}
+	return mc.current()
+}
+
+func (mc *mergedCursor) Next() []byte {
+	if i := mc.minIndex(); i >= 0 {
+		mc.keys[i], _ = mc.cs[i].Next()
+	}
+	return mc.current()
+}
+
+func (mc *mergedCursor) current() []byte {
+	if i := mc.minIndex(); i >= 0 {
+		return mc.keys[i]
+	}
+	return nil
+}
+
+func (mc *mergedCursor) minIndex() int {
+	idx := -1
+	for i, k := range mc.keys {
+		if k != nil && (idx < 0 || bytes.Compare(k, mc.keys[idx]) < 0) {
+			idx = i
+		}
+	}
+	return idx
+}
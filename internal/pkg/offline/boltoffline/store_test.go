@@ -0,0 +1,20 @@
+package boltoffline
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/molon/gomsg/internal/pkg/offline"
+	"github.com/molon/gomsg/internal/pkg/offline/offlinetest"
+)
+
+func TestStore(t *testing.T) {
+	offlinetest.Run(t, func(t *testing.T) offline.Store {
+		s, err := Open(filepath.Join(t.TempDir(), "offline.db"))
+		if err != nil {
+			t.Fatalf("Open: %+v", err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
@@ -1,180 +1,99 @@
+// 离线消息存储的抽象，carrier只依赖于此
+// 各实现需保证相同的语义，可用 offlinetest 包校验
 package offline
 
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/golang/protobuf/proto"
-	"github.com/gomodule/redigo/redis"
-	"github.com/molon/pkg/errors"
 	"github.com/molon/gomsg/pb/msgpb"
+	"github.com/molon/pkg/errors"
+	"google.golang.org/grpc/codes"
 )
 
-// 用户在某平台的离线消息映射记录
-func upomsKey(uid string, platform string) string {
-	return fmt.Sprintf("msg/u:%s/p:%s/oms", uid, platform)
-}
-
-// 离线消息内容
-func ommKey(seq string) string {
-	return fmt.Sprintf("msg/om:%s/m", seq)
+// 写入的消息自身已过期，没有被存储，调用方不应视其为已离线存储
+var ErrExpired = errors.Errorf("offline message is expired")
+
+type Store interface {
+	// 写入某用户某平台的一条离线消息，同一seq重复写入会被忽略
+	// 消息以 sendTime+expire 和消息自身过期时间中较早的为准过期，消息自身已过期则不写入并返回 ErrExpired
+	// 同一消息被多个用户或平台引用时内容只存一份
+	// 消息带有collapse_key时会替换此用户此平台同一key的离线消息，发出时间相同则后写入的为准
+	// 若已有的同一key的消息发出时间更晚，则忽略此消息
+	Write(ctx context.Context, uid string, platform string, msg *msgpb.Message, sendTime time.Time, expire time.Duration) error
+
+	// 按优先级从高到低、同一优先级按发出时间(相同则按seq)顺序读取发出时间在 now-expire 之后的最多readCount条离线消息
+	// 没有离线消息了则deleteFunc为nil，读取到的消息可能全部已过期，此时msgs为空但deleteFunc不为nil
+	// deleteFunc会删除本次读取到的所有消息(包括已过期而未返回的)
+	Read(ctx context.Context, uid string, platform string, expire time.Duration, readCount int64) (msgs []*msgpb.Message, deleteFunc func(context.Context) error, err error)
+
+	// 不区分优先级，按发出时间(相同则按seq)顺序读取cursor之后、发出时间在 now-expire 之后的最多limit条离线消息，不会删除
+	// cursor为空则从头读取，已过期的消息不返回但依然计入limit，没有更多了则next为空
+	Scan(ctx context.Context, uid string, platform string, expire time.Duration, cursor string, limit int64) (msgs []*msgpb.Message, next string, err error)
+
+	// 统计某用户某平台发出时间在 now-expire 之后的离线消息数目，可用于角标和未读数
+	// byCategory为false时只统计映射记录，开销小，但消息自身已过期而尚未被清理的也会计入
+	// byCategory为true时会读取消息内容，跳过已过期的，并按消息的category分组，total为各分组之和
+	Count(ctx context.Context, uid string, platform string, expire time.Duration, byCategory bool) (total int64, categories map[string]int64, err error)
+
+	// 删除某用户某平台的若干离线消息
+	Delete(ctx context.Context, uid string, platform string, seqs []string) error
+
+	// 清理发出时间在 now-expire 之前的，以及各平台超出最大数目(<0为不限)的离线消息
+	// 超出数目时先清理低优先级的，同一优先级先清理较旧的
+	// 返回各平台因过期被清理的以及因超出数目被清理的seq列表
+	Clean(ctx context.Context, uid string, expire time.Duration, platformToMaxOMCount map[string]int) (expired map[string][]string, evicted map[string][]string, err error)
 }
 
-// 离线消息引用计数
-func omnKey(seq string) string {
-	return fmt.Sprintf("msg/om:%s/n", seq)
+// 离线存储区分的各优先级，从高到低
+var Priorities = []msgpb.MessagePriority{
+	msgpb.MessagePriority_HIGH,
+	msgpb.MessagePriority_NORMAL,
 }
 
-func (s *Store) Delete(ctx context.Context, uid string, platform string, seqs []string) error {
-	if len(seqs) <= 0 {
-		return errors.Errorf("seqs is empty")
-	}
-
-	conn, err := s.redisPool.GetContext(ctx)
-	if err != nil {
-		return errors.WithStack(err)
-	}
-	defer conn.Close()
-
-	for _, seq := range seqs {
-		if err := deleteLua.SendHash(conn, upomsKey(uid, platform), ommKey(seq), omnKey(seq), seq); err != nil {
-			return errors.WithStack(err)
+// 消息的优先级，未知的值视为NORMAL
+func PriorityOf(msg *msgpb.Message) msgpb.MessagePriority {
+	p := msg.GetPriority()
+	for _, known := range Priorities {
+		if p == known {
+			return p
 		}
 	}
-
-	if err := conn.Flush(); err != nil {
-		return errors.WithStack(err)
-	}
-
-	for _ = range seqs {
-		if _, err := conn.Receive(); err != nil {
-			return errors.WithStack(err)
-		}
-	}
-
-	return nil
+	return msgpb.MessagePriority_NORMAL
 }
 
-func (s *Store) Clean(ctx context.Context, uid string, expire time.Duration, platformToMaxOMCount map[string]int) error {
-	if len(platformToMaxOMCount) <= 0 {
-		return errors.Errorf("platformToMaxOMCount is empty")
-	}
-
-	conn, err := s.redisPool.GetContext(ctx)
-	if err != nil {
-		return errors.WithStack(err)
-	}
-	defer conn.Close()
-
-	expTs := time.Now().Add(-expire).Unix()
-
-	for platform, maxOMCount := range platformToMaxOMCount {
-		if err := cleanLua.SendHash(conn, upomsKey(uid, platform), expTs, maxOMCount); err != nil {
-			return errors.WithStack(err)
-		}
-	}
-
-	if err := conn.Flush(); err != nil {
-		return errors.WithStack(err)
-	}
-
-	// 只需要关心执行次数即可
-	for _ = range platformToMaxOMCount {
-		if _, err := conn.Receive(); err != nil {
-			return errors.WithStack(err)
-		}
-	}
-
-	return nil
-}
-
-func (s *Store) Write(ctx context.Context,
-	uid string, platform string,
-	msg *msgpb.Message,
-	sendTime time.Time, expire time.Duration,
-) error {
-	conn, err := s.redisPool.GetContext(ctx)
-	if err != nil {
-		return errors.WithStack(err)
-	}
-	defer conn.Close()
-
-	/*
-		KEYS : msg/u:uid1/p:platform1/oms(某用户离线映射记录) msg/om:seq1/m(消息内容) msg/om:seq1/n(消息引用计数)
-		ARGV : seq1(消息标识) xxxx(消息内容) ts(消息发出时间) expire(多久过期) expireat(到期时间) expirets(已过期时间戳) max_offline_msg_count(最大离线映射数目)
-	*/
-	m, err := proto.Marshal(msg)
-	if err != nil {
-		return errors.WithStack(err)
-	}
-
-	seq := msg.GetSeq()
-	ts := sendTime.Unix()
-	exp := int64(expire / time.Second)
-	expAt := ts + exp
-
-	if _, err := writeLua.Do(conn,
-		upomsKey(uid, platform), ommKey(seq), omnKey(seq),
-		seq, m, ts, exp, expAt); err != nil {
-		return errors.WithStack(err)
-	}
-
-	return nil
+// 游标即为某条离线消息的位置，各实现共用此格式: 发出时间戳:seq
+func EncodeCursor(ts int64, seq string) string {
+	return fmt.Sprintf("%d:%s", ts, seq)
 }
 
-// 返回 seq:content deleteFunc error
-func (s *Store) Read(ctx context.Context, uid string, platform string, expire time.Duration, readCount int64) ([]*msgpb.Message, func(context.Context) error, error) {
-	conn, err := s.redisPool.GetContext(ctx)
-	if err != nil {
-		return nil, nil, errors.WithStack(err)
-	}
-	defer conn.Close()
-
-	/*
-		- 根据当前时间算出未过期消息时间戳 `expirets = now-expire`
-		- - `ZRANGEBYSCORE msg/u:uid1/p:platform1/oms expirets +inf LIMIT 0 50` 获取头部50个未过期消息
-		- - 执行`MGET msg/om:seq1/m msg/om:seq2/m`拿到所有消息内容
-	*/
-	expTs := time.Now().Add(-expire).Unix()
-
-	seqs, err := redis.Strings(
-		conn.Do("ZRANGEBYSCORE", upomsKey(uid, platform), expTs, "+inf", "LIMIT", "0", readCount),
-	)
-	if err != nil {
-		return nil, nil, errors.WithStack(err)
-	}
-
-	if len(seqs) <= 0 {
-		return nil, nil, nil
+// 空游标返回 0,""
+func DecodeCursor(cursor string) (ts int64, seq string, err error) {
+	if cursor == "" {
+		return 0, "", nil
 	}
 
-	ommKeys := make([]interface{}, len(seqs))
-	for i, seq := range seqs {
-		ommKeys[i] = ommKey(seq)
+	es := strings.SplitN(cursor, ":", 2)
+	if len(es) != 2 || es[1] == "" {
+		return 0, "", errors.Statusf(codes.InvalidArgument, "invalid cursor: %s", cursor)
 	}
 
-	ms, err := redis.ByteSlices(
-		conn.Do("MGET", ommKeys...),
-	)
+	ts, err = strconv.ParseInt(es[0], 10, 64)
 	if err != nil {
-		return nil, nil, errors.WithStack(err)
+		return 0, "", errors.Statusf(codes.InvalidArgument, "invalid cursor: %s", cursor)
 	}
 
-	msgs := []*msgpb.Message{}
-	for _, m := range ms {
-		if m != nil {
-			pb := &msgpb.Message{}
-			if err := proto.Unmarshal(m, pb); err != nil {
-				return nil, nil, errors.WithStack(err)
-			}
-
-			msgs = append(msgs, pb)
-		}
-	}
+	return ts, es[1], nil
+}
 
-	delete := func(ctx context.Context) error {
-		return s.Delete(ctx, uid, platform, seqs)
+// 按消息的category分组计数，未设置category的分组key为空字符串
+func CountByCategory(msgs []*msgpb.Message) (int64, map[string]int64) {
+	categories := map[string]int64{}
+	for _, msg := range msgs {
+		categories[msg.GetCategory()]++
 	}
-	return msgs, delete, nil
+	return int64(len(msgs)), categories
 }
@@ -0,0 +1,453 @@
+// 离线消息存储各实现共用的一致性校验，在各实现的测试里调用 Run 即可
+package offlinetest
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/molon/gomsg/internal/pkg/offline"
+	"github.com/molon/gomsg/pb/msgpb"
+	"github.com/rs/xid"
+)
+
+const expire = time.Hour
+
+// newStore 每次调用都应返回可用的存储，可以是同一个，各用例使用不同的uid互不影响
+func Run(t *testing.T, newStore func(t *testing.T) offline.Store) {
+	cases := []struct {
+		name string
+		f    func(t *testing.T, s offline.Store)
+	}{
+		{"ReadInOrder", testReadInOrder},
+		{"WriteIdempotent", testWriteIdempotent},
+		{"SharedContent", testSharedContent},
+		{"SharedContentExpire", testSharedContentExpire},
+		{"MsgExpired", testMsgExpired},
+		{"CleanExpired", testCleanExpired},
+		{"CleanMaxCount", testCleanMaxCount},
+		{"ScanPaging", testScanPaging},
+		{"Count", testCount},
+		{"Priority", testPriority},
+		{"Collapse", testCollapse},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			c.f(t, newStore(t))
+		})
+	}
+}
+
+func newMsg() *msgpb.Message {
+	return &msgpb.Message{
+		Seq: xid.New().String(),
+	}
+}
+
+func write(t *testing.T, s offline.Store, uid string, platform string, msg *msgpb.Message, sendTime time.Time) {
+	if err := s.Write(context.Background(), uid, platform, msg, sendTime, expire); err != nil {
+		t.Fatalf("Write: %+v", err)
+	}
+}
+
+// 读取一批，返回其seq列表以及deleteFunc
+func read(t *testing.T, s offline.Store, uid string, platform string, count int64) ([]string, func(context.Context) error) {
+	msgs, deleteFunc, err := s.Read(context.Background(), uid, platform, expire, count)
+	if err != nil {
+		t.Fatalf("Read: %+v", err)
+	}
+
+	seqs := []string{}
+	for _, msg := range msgs {
+		seqs = append(seqs, msg.GetSeq())
+	}
+	return seqs, deleteFunc
+}
+
+func assertSeqs(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("seqs: got %v, want %v", got, want)
+	}
+}
+
+func testReadInOrder(t *testing.T, s offline.Store) {
+	uid := xid.New().String()
+	now := time.Now()
+
+	// 发出时间靠前的先被读到，乱序写入
+	m1, m2, m3 := newMsg(), newMsg(), newMsg()
+	write(t, s, uid, "mobile", m3, now.Add(-time.Second))
+	write(t, s, uid, "mobile", m1, now.Add(-3*time.Second))
+	write(t, s, uid, "mobile", m2, now.Add(-2*time.Second))
+
+	seqs, deleteFunc := read(t, s, uid, "mobile", 2)
+	assertSeqs(t, seqs, m1.Seq, m2.Seq)
+	if err := deleteFunc(context.Background()); err != nil {
+		t.Fatalf("deleteFunc: %+v", err)
+	}
+
+	seqs, deleteFunc = read(t, s, uid, "mobile", 2)
+	assertSeqs(t, seqs, m3.Seq)
+	if err := deleteFunc(context.Background()); err != nil {
+		t.Fatalf("deleteFunc: %+v", err)
+	}
+
+	// 读完了deleteFunc为nil
+	seqs, deleteFunc = read(t, s, uid, "mobile", 2)
+	assertSeqs(t, seqs)
+	if deleteFunc != nil {
+		t.Fatalf("deleteFunc should be nil when there are no offline messages")
+	}
+
+	// 其他平台不受影响
+	seqs, deleteFunc = read(t, s, uid, "desktop", 2)
+	if len(seqs) > 0 || deleteFunc != nil {
+		t.Fatalf("desktop should have no offline messages")
+	}
+}
+
+func testWriteIdempotent(t *testing.T, s offline.Store) {
+	uid := xid.New().String()
+	now := time.Now()
+
+	m := newMsg()
+	write(t, s, uid, "mobile", m, now)
+	write(t, s, uid, "mobile", m, now)
+
+	seqs, _ := read(t, s, uid, "mobile", 10)
+	assertSeqs(t, seqs, m.Seq)
+}
+
+func testSharedContent(t *testing.T, s offline.Store) {
+	uid1, uid2 := xid.New().String(), xid.New().String()
+	now := time.Now()
+
+	// 同一消息被多个用户和平台引用，删除其中一个不影响其他
+	m := newMsg()
+	write(t, s, uid1, "mobile", m, now)
+	write(t, s, uid1, "desktop", m, now)
+	write(t, s, uid2, "mobile", m, now)
+
+	if err := s.Delete(context.Background(), uid1, "mobile", []string{m.Seq}); err != nil {
+		t.Fatalf("Delete: %+v", err)
+	}
+
+	seqs, _ := read(t, s, uid1, "mobile", 10)
+	assertSeqs(t, seqs)
+
+	seqs, _ = read(t, s, uid1, "desktop", 10)
+	assertSeqs(t, seqs, m.Seq)
+
+	if err := s.Delete(context.Background(), uid1, "desktop", []string{m.Seq}); err != nil {
+		t.Fatalf("Delete: %+v", err)
+	}
+
+	seqs, _ = read(t, s, uid2, "mobile", 10)
+	assertSeqs(t, seqs, m.Seq)
+}
+
+// 同一消息各平台的有效期不同，内容要保留到最晚的那个，不能由先写入的决定
+func testSharedContentExpire(t *testing.T, s offline.Store) {
+	uid := xid.New().String()
+	now := time.Now()
+
+	m := newMsg()
+	if err := s.Write(context.Background(), uid, "mobile", m, now.Add(-2*time.Second), time.Second); err != nil {
+		t.Fatalf("Write: %+v", err)
+	}
+	write(t, s, uid, "desktop", m, now)
+
+	seqs, _ := read(t, s, uid, "desktop", 10)
+	assertSeqs(t, seqs, m.Seq)
+}
+
+func testMsgExpired(t *testing.T, s offline.Store) {
+	uid := xid.New().String()
+	now := time.Now()
+
+	// 消息自身已过期则不会被写入
+	m := newMsg()
+	m.ExpireAt, _ = ptypes.TimestampProto(now.Add(-time.Second))
+	if err := s.Write(context.Background(), uid, "mobile", m, now, expire); err != offline.ErrExpired {
+		t.Fatalf("Write: got %v, want ErrExpired", err)
+	}
+
+	seqs, deleteFunc := read(t, s, uid, "mobile", 10)
+	assertSeqs(t, seqs)
+	if deleteFunc != nil {
+		t.Fatalf("expired message should not be written")
+	}
+}
+
+func testCleanExpired(t *testing.T, s offline.Store) {
+	uid := xid.New().String()
+	now := time.Now()
+
+	old, fresh := newMsg(), newMsg()
+	write(t, s, uid, "mobile", old, now.Add(-2*expire))
+	write(t, s, uid, "mobile", fresh, now)
+
+	// 过期的不会被读到
+	seqs, _ := read(t, s, uid, "mobile", 10)
+	assertSeqs(t, seqs, fresh.Seq)
+
+	expired, evicted, err := s.Clean(context.Background(), uid, expire, map[string]int{"mobile": -1, "desktop": -1})
+	if err != nil {
+		t.Fatalf("Clean: %+v", err)
+	}
+	want := map[string][]string{"mobile": {old.Seq}}
+	if !reflect.DeepEqual(expired, want) {
+		t.Fatalf("Clean: got %v, want %v", expired, want)
+	}
+	if len(evicted) > 0 {
+		t.Fatalf("Clean: got evicted %v, want none", evicted)
+	}
+
+	seqs, _ = read(t, s, uid, "mobile", 10)
+	assertSeqs(t, seqs, fresh.Seq)
+}
+
+func testCleanMaxCount(t *testing.T, s offline.Store) {
+	uid := xid.New().String()
+	now := time.Now()
+
+	seqs := []string{}
+	for i := 5; i > 0; i-- {
+		m := newMsg()
+		write(t, s, uid, "desktop", m, now.Add(-time.Duration(i)*time.Second))
+		seqs = append(seqs, m.Seq)
+	}
+
+	// 只保留最新的2个，超出数目被清理的不算过期
+	expired, evicted, err := s.Clean(context.Background(), uid, expire, map[string]int{"desktop": 2})
+	if err != nil {
+		t.Fatalf("Clean: %+v", err)
+	}
+	if len(expired) > 0 {
+		t.Fatalf("Clean: got expired %v, want none", expired)
+	}
+	gotEvicted := append([]string{}, evicted["desktop"]...)
+	sort.Strings(gotEvicted)
+	wantEvicted := append([]string{}, seqs[:3]...)
+	sort.Strings(wantEvicted)
+	assertSeqs(t, gotEvicted, wantEvicted...)
+
+	got, _ := read(t, s, uid, "desktop", 10)
+	sort.Strings(got)
+	want := append([]string{}, seqs[3:]...)
+	sort.Strings(want)
+	assertSeqs(t, got, want...)
+
+	// 0 表示全部清理
+	if _, _, err := s.Clean(context.Background(), uid, expire, map[string]int{"desktop": 0}); err != nil {
+		t.Fatalf("Clean: %+v", err)
+	}
+	_, deleteFunc := read(t, s, uid, "desktop", 10)
+	if deleteFunc != nil {
+		t.Fatalf("all offline messages should be cleaned")
+	}
+}
+
+func scan(t *testing.T, s offline.Store, uid string, platform string, cursor string, limit int64) ([]string, string) {
+	msgs, next, err := s.Scan(context.Background(), uid, platform, expire, cursor, limit)
+	if err != nil {
+		t.Fatalf("Scan: %+v", err)
+	}
+
+	seqs := []string{}
+	for _, msg := range msgs {
+		seqs = append(seqs, msg.GetSeq())
+	}
+	return seqs, next
+}
+
+func testScanPaging(t *testing.T, s offline.Store) {
+	uid := xid.New().String()
+	now := time.Now()
+
+	// 同一时间戳的按seq排序
+	seqs := []string{}
+	for i := 0; i < 5; i++ {
+		m := newMsg()
+		write(t, s, uid, "mobile", m, now.Add(-time.Duration(5-i/2)*time.Second))
+		seqs = append(seqs, m.Seq)
+	}
+
+	got, next := scan(t, s, uid, "mobile", "", 2)
+	assertSeqs(t, got, seqs[0], seqs[1])
+	if next == "" {
+		t.Fatalf("Scan: next should not be empty")
+	}
+
+	got, next = scan(t, s, uid, "mobile", next, 2)
+	assertSeqs(t, got, seqs[2], seqs[3])
+
+	// 游标对应的消息被删除了也不影响
+	if err := s.Delete(context.Background(), uid, "mobile", []string{seqs[3]}); err != nil {
+		t.Fatalf("Delete: %+v", err)
+	}
+
+	got, next = scan(t, s, uid, "mobile", next, 2)
+	assertSeqs(t, got, seqs[4])
+	if next != "" {
+		t.Fatalf("Scan: next should be empty at the end, got %s", next)
+	}
+
+	// Scan不会删除
+	got, _ = read(t, s, uid, "mobile", 10)
+	assertSeqs(t, got, seqs[0], seqs[1], seqs[2], seqs[4])
+
+	if _, _, err := s.Scan(context.Background(), uid, "mobile", expire, "bad", 2); err == nil {
+		t.Fatalf("Scan: invalid cursor should fail")
+	}
+}
+
+func testCount(t *testing.T, s offline.Store) {
+	uid := xid.New().String()
+	now := time.Now()
+
+	for _, category := range []string{"chat", "chat", "system", ""} {
+		m := newMsg()
+		m.Category = category
+		write(t, s, uid, "mobile", m, now)
+	}
+	// 发出时间已超出expire的不计入
+	write(t, s, uid, "mobile", newMsg(), now.Add(-2*expire))
+
+	total, categories, err := s.Count(context.Background(), uid, "mobile", expire, false)
+	if err != nil {
+		t.Fatalf("Count: %+v", err)
+	}
+	if total != 4 || categories != nil {
+		t.Fatalf("Count: got %d %v, want 4 nil", total, categories)
+	}
+
+	total, categories, err = s.Count(context.Background(), uid, "mobile", expire, true)
+	if err != nil {
+		t.Fatalf("Count: %+v", err)
+	}
+	want := map[string]int64{"chat": 2, "system": 1, "": 1}
+	if total != 4 || !reflect.DeepEqual(categories, want) {
+		t.Fatalf("Count: got %d %v, want 4 %v", total, categories, want)
+	}
+
+	// 没有离线消息的平台
+	total, _, err = s.Count(context.Background(), uid, "web", expire, true)
+	if err != nil {
+		t.Fatalf("Count: %+v", err)
+	}
+	if total != 0 {
+		t.Fatalf("Count: got %d, want 0", total)
+	}
+}
+
+func testPriority(t *testing.T, s offline.Store) {
+	uid := xid.New().String()
+	now := time.Now()
+
+	// 按发出时间: n1 h1 n2 h2
+	n1, h1, n2, h2 := newMsg(), newMsg(), newMsg(), newMsg()
+	h1.Priority = msgpb.MessagePriority_HIGH
+	h2.Priority = msgpb.MessagePriority_HIGH
+	for i, m := range []*msgpb.Message{n1, h1, n2, h2} {
+		write(t, s, uid, "mobile", m, now.Add(-time.Duration(4-i)*time.Second))
+	}
+
+	// 高优先级的先读
+	seqs, _ := read(t, s, uid, "mobile", 10)
+	assertSeqs(t, seqs, h1.Seq, h2.Seq, n1.Seq, n2.Seq)
+	seqs, _ = read(t, s, uid, "mobile", 3)
+	assertSeqs(t, seqs, h1.Seq, h2.Seq, n1.Seq)
+
+	// Scan不区分优先级
+	seqs, next := scan(t, s, uid, "mobile", "", 3)
+	assertSeqs(t, seqs, n1.Seq, h1.Seq, n2.Seq)
+	seqs, _ = scan(t, s, uid, "mobile", next, 3)
+	assertSeqs(t, seqs, h2.Seq)
+
+	total, _, err := s.Count(context.Background(), uid, "mobile", expire, false)
+	if err != nil {
+		t.Fatalf("Count: %+v", err)
+	}
+	if total != 4 {
+		t.Fatalf("Count: got %d, want 4", total)
+	}
+
+	// 超出数目时先清理低优先级的，即便它更新
+	if _, _, err := s.Clean(context.Background(), uid, expire, map[string]int{"mobile": 2}); err != nil {
+		t.Fatalf("Clean: %+v", err)
+	}
+	seqs, _ = read(t, s, uid, "mobile", 10)
+	assertSeqs(t, seqs, h1.Seq, h2.Seq)
+
+	if _, _, err := s.Clean(context.Background(), uid, expire, map[string]int{"mobile": 1}); err != nil {
+		t.Fatalf("Clean: %+v", err)
+	}
+	seqs, deleteFunc := read(t, s, uid, "mobile", 10)
+	assertSeqs(t, seqs, h2.Seq)
+
+	// 删除对各优先级都生效
+	if err := deleteFunc(context.Background()); err != nil {
+		t.Fatalf("Delete: %+v", err)
+	}
+	if _, deleteFunc := read(t, s, uid, "mobile", 10); deleteFunc != nil {
+		t.Fatalf("all offline messages should be deleted")
+	}
+}
+
+func testCollapse(t *testing.T, s offline.Store) {
+	uid, uid2 := xid.New().String(), xid.New().String()
+	now := time.Now()
+
+	newCollapseMsg := func() *msgpb.Message {
+		m := newMsg()
+		m.CollapseKey = "order:1"
+		return m
+	}
+
+	// c1被c2替换，plain不受影响
+	c1, plain, c2 := newCollapseMsg(), newMsg(), newCollapseMsg()
+	write(t, s, uid, "mobile", c1, now.Add(-3*time.Second))
+	// c1同时被另一用户引用，替换之后其内容依然可读
+	write(t, s, uid2, "mobile", c1, now.Add(-3*time.Second))
+	write(t, s, uid, "mobile", plain, now.Add(-2*time.Second))
+	write(t, s, uid, "mobile", c2, now.Add(-time.Second))
+
+	seqs, _ := read(t, s, uid, "mobile", 10)
+	assertSeqs(t, seqs, plain.Seq, c2.Seq)
+	seqs, _ = read(t, s, uid2, "mobile", 10)
+	assertSeqs(t, seqs, c1.Seq)
+
+	// 发出时间更早的同一key的消息被忽略
+	write(t, s, uid, "mobile", newCollapseMsg(), now.Add(-4*time.Second))
+	seqs, _ = read(t, s, uid, "mobile", 10)
+	assertSeqs(t, seqs, plain.Seq, c2.Seq)
+
+	// 重复写入被替换掉的消息也不会复活，因为已有的更新
+	write(t, s, uid, "mobile", c1, now.Add(-3*time.Second))
+	seqs, _ = read(t, s, uid, "mobile", 10)
+	assertSeqs(t, seqs, plain.Seq, c2.Seq)
+
+	// 不同平台互不影响
+	write(t, s, uid, "desktop", c1, now.Add(-3*time.Second))
+	seqs, _ = read(t, s, uid, "desktop", 10)
+	assertSeqs(t, seqs, c1.Seq)
+
+	// 已有的被删除之后，新的直接写入
+	if err := s.Delete(context.Background(), uid, "mobile", []string{c2.Seq}); err != nil {
+		t.Fatalf("Delete: %+v", err)
+	}
+	c3 := newCollapseMsg()
+	write(t, s, uid, "mobile", c3, now.Add(-4*time.Second))
+	seqs, _ = read(t, s, uid, "mobile", 10)
+	assertSeqs(t, seqs, c3.Seq, plain.Seq)
+}
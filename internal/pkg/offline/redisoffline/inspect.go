@@ -0,0 +1,475 @@
+package redisoffline
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gomodule/redigo/redis"
+	"github.com/molon/gomsg/internal/pkg/offline"
+	"github.com/molon/gomsg/internal/pkg/redispool"
+	"github.com/molon/gomsg/pb/msgpb"
+	"github.com/molon/pkg/errors"
+)
+
+// 以下用于排查和运维，直接读取redis里的原始结构，业务代码不要使用
+
+// 每次SCAN的建议数目
+const scanCount = 1000
+
+// 不被引用的内容，版本号在此时长内有变化的不删除
+// 写入时先加引用后写映射，刚加了引用的在统计时可能还没有映射
+const orphanGrace = time.Minute
+
+var (
+	/*
+		KEYS : msg/om:{seq1}/m(消息内容) msg/om:{seq1}/n(消息引用计数) msg/om:{seq1}/g(版本号)
+		ARGV : n(之前观察到的引用计数，不存在则为空) g(之前观察到的版本号，不存在则为空) now(当前毫秒时间戳) gen_expire(版本号的默认保留秒数)
+		版本号依然是观察到的值才删除，否则说明期间有写入或者释放，放弃
+		只比较引用计数的话，期间先加后减会回到原值，统计出的映射却已过时
+	*/
+	dropOrphanLua = redis.NewScript(3, bumpGenLua+`
+			local n = redis.call("GET", KEYS[2]) or ""
+			local g = redis.call("GET", KEYS[3]) or ""
+			if n ~= ARGV[1] or g ~= ARGV[2] then
+				return 0
+			end
+
+			bump_gen(1, 3, ARGV[3], ARGV[4])
+			redis.call("DEL", KEYS[1], KEYS[2])
+			return 1
+		`)
+
+	/*
+		KEYS : msg/om:{seq1}/m(消息内容) msg/om:{seq1}/n(消息引用计数) msg/om:{seq1}/g(版本号)
+		ARGV : n(之前观察到的引用计数，不存在则为空) g(之前观察到的版本号，不存在则为空) now(当前毫秒时间戳) gen_expire(版本号的默认保留秒数) actual(实际被映射引用的数目)
+		版本号依然是观察到的值才修正，过期时间和消息内容保持一致
+	*/
+	fixRefsLua = redis.NewScript(3, bumpGenLua+`
+			local n = redis.call("GET", KEYS[2]) or ""
+			local g = redis.call("GET", KEYS[3]) or ""
+			if n ~= ARGV[1] or g ~= ARGV[2] then
+				return 0
+			end
+
+			local pttl = redis.call("PTTL", KEYS[1])
+			if pttl == -2 then
+				return 0
+			end
+
+			bump_gen(1, 3, ARGV[3], ARGV[4])
+			redis.call("SET", KEYS[2], ARGV[5])
+			if pttl > 0 then
+				redis.call("PEXPIRE", KEYS[2], pttl)
+			end
+			return 1
+		`)
+)
+
+// 某用户某平台的一条离线映射记录
+type Entry struct {
+	Seq      string
+	SendTime time.Time
+	Priority msgpb.MessagePriority
+	// 消息内容，已不存在则为nil
+	Msg *msgpb.Message
+	// 引用计数，不存在则为0
+	Refs int64
+}
+
+// 某用户某平台的所有离线映射记录(包括已过期还没被清理的)，顺序和Read一致
+func (s *Store) Entries(ctx context.Context, uid string, platform string) ([]*Entry, error) {
+	entries := []*Entry{}
+	if err := func() error {
+		conn, err := s.redisPool.GetContext(ctx)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer conn.Close()
+
+		for _, priority := range offline.Priorities {
+			vals, err := redis.Strings(conn.Do("ZRANGE", upomsKey(uid, platform, priority), 0, -1, "WITHSCORES"))
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			for i := 0; i+1 < len(vals); i += 2 {
+				ts, err := strconv.ParseInt(vals[i+1], 10, 64)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				entries = append(entries, &Entry{
+					Seq:      vals[i],
+					SendTime: time.Unix(ts, 0),
+					Priority: priority,
+				})
+			}
+		}
+		return nil
+	}(); err != nil {
+		return nil, err
+	}
+
+	if len(entries) <= 0 {
+		return entries, nil
+	}
+
+	seqs := make([]string, len(entries))
+	for i, e := range entries {
+		seqs[i] = e.Seq
+	}
+
+	ms, err := s.getContents(ctx, seqs)
+	if err != nil {
+		return nil, err
+	}
+	ns, err := s.getBySeqs(ctx, seqs, omnKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if m, ok := ms[e.Seq]; ok {
+			e.Msg = &msgpb.Message{}
+			if err := proto.Unmarshal(m, e.Msg); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		if n, ok := ns[e.Seq]; ok {
+			e.Refs, err = redis.Int64(n, nil)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// 某条离线消息的内容和引用计数
+type Content struct {
+	Seq string
+	// 消息内容，不存在则为nil
+	Msg *msgpb.Message
+	// 引用计数，HasRefs为false表示不存在
+	Refs    int64
+	HasRefs bool
+	// 消息内容的剩余时间，和PTTL一致: -1表示不会过期，-2表示不存在
+	TTL time.Duration
+}
+
+func (s *Store) Lookup(ctx context.Context, seq string) (*Content, error) {
+	conn, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	// 内容和引用计数在同一slot，可以pipeline
+	if err := conn.Send("GET", ommKey(seq)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := conn.Send("GET", omnKey(seq)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := conn.Send("PTTL", ommKey(seq)); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	c := &Content{Seq: seq}
+
+	m, err := redis.Bytes(conn.Receive())
+	if err != nil && err != redis.ErrNil {
+		return nil, errors.WithStack(err)
+	}
+	if err == nil {
+		c.Msg = &msgpb.Message{}
+		if err := proto.Unmarshal(m, c.Msg); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	c.Refs, err = redis.Int64(conn.Receive())
+	if err != nil && err != redis.ErrNil {
+		return nil, errors.WithStack(err)
+	}
+	c.HasRefs = err == nil
+
+	pttl, err := redis.Int64(conn.Receive())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c.TTL = time.Duration(pttl)
+	if pttl > 0 {
+		c.TTL *= time.Millisecond
+	}
+
+	return c, nil
+}
+
+// 引用计数和实际被映射引用的数目不符的离线消息
+type Orphan struct {
+	Seq    string
+	HasMsg bool
+	// 记录的引用计数，HasRefs为false表示不存在
+	Refs    int64
+	HasRefs bool
+	// 实际被各用户映射记录引用的数目
+	Actual int64
+	// 记录引用计数时的版本号(不小于其最后一次改变的毫秒时间戳)，HasGen为false表示不存在
+	Gen    int64
+	HasGen bool
+}
+
+// 扫描所有节点，找出引用计数出错的离线消息，对大量数据会比较慢
+// 先记录版本号和引用计数再统计映射记录，期间有写入的话可能误报，但 RepairOrphan 会以记录的版本号做CAS
+func (s *Store) FindOrphans(ctx context.Context) ([]*Orphan, error) {
+	seqSet := map[string]bool{}
+	if err := s.scanKeys(ctx, "msg/om:{*}/*", func(key string) error {
+		if seq, ok := parseSeqKey(key); ok {
+			seqSet[seq] = true
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(seqSet) <= 0 {
+		return nil, nil
+	}
+
+	seqs := make([]string, 0, len(seqSet))
+	for seq := range seqSet {
+		seqs = append(seqs, seq)
+	}
+	sort.Strings(seqs)
+
+	// 版本号要最先读取，之后的任何改变都会使其变化
+	gs, err := s.getBySeqs(ctx, seqs, omgKey)
+	if err != nil {
+		return nil, err
+	}
+	ms, err := s.existsBySeqs(ctx, seqs)
+	if err != nil {
+		return nil, err
+	}
+	ns, err := s.getBySeqs(ctx, seqs, omnKey)
+	if err != nil {
+		return nil, err
+	}
+
+	actual := map[string]int64{}
+	if err := s.scanKeys(ctx, "msg/u:{*}/p:*/oms*", func(key string) error {
+		return s.countRefs(ctx, key, seqSet, actual)
+	}); err != nil {
+		return nil, err
+	}
+
+	orphans := []*Orphan{}
+	for _, seq := range seqs {
+		o := &Orphan{
+			Seq:    seq,
+			HasMsg: ms[seq],
+			Actual: actual[seq],
+		}
+		if n, ok := ns[seq]; ok {
+			o.HasRefs = true
+			o.Refs, err = redis.Int64(n, nil)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		if g, ok := gs[seq]; ok {
+			o.HasGen = true
+			o.Gen, err = redis.Int64(g, nil)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+
+		if o.HasMsg && o.HasRefs && o.Refs == o.Actual && o.Actual > 0 {
+			continue
+		}
+		// 内容已被正常删除，只剩下版本号
+		if !o.HasMsg && !o.HasRefs && o.Actual <= 0 {
+			continue
+		}
+		orphans = append(orphans, o)
+	}
+	return orphans, nil
+}
+
+// 安全地修复，返回所做的操作，统计之后版本号有变化的话放弃:
+//   - 已不被引用: 删除内容和引用计数，版本号在 orphanGrace 之内有变化的除外
+//   - 内容已不存在: 删除引用计数，映射记录会在读取时被跳过并清理
+//   - 引用计数偏小或者不存在: 修正为实际数目，否则之后的释放会过早删除内容
+//   - 引用计数偏大: 不做处理，最多是内容留存到其过期时间，改小的话有过早删除内容的风险
+func (s *Store) RepairOrphan(ctx context.Context, o *Orphan) (string, error) {
+	observedN, observedG := "", ""
+	if o.HasRefs {
+		observedN = strconv.FormatInt(o.Refs, 10)
+	}
+	if o.HasGen {
+		observedG = strconv.FormatInt(o.Gen, 10)
+	}
+
+	now := nowMillis()
+	var (
+		action string
+		script *redis.Script
+		args   = []interface{}{
+			ommKey(o.Seq), omnKey(o.Seq), omgKey(o.Seq),
+			observedN, observedG, now, int64(genExpire / time.Second),
+		}
+	)
+	switch {
+	case o.Actual <= 0 && o.HasGen && now-o.Gen < int64(orphanGrace/time.Millisecond):
+		return "skip recently changed", nil
+	case o.Actual <= 0:
+		action, script = "drop unreferenced content", dropOrphanLua
+	case !o.HasMsg:
+		action, script = "drop refcount of lost content", dropOrphanLua
+	case !o.HasRefs || o.Refs < o.Actual:
+		action, script = "fix refcount to "+strconv.FormatInt(o.Actual, 10), fixRefsLua
+		args = append(args, o.Actual)
+	default:
+		return "skip over-counted, kept until expired", nil
+	}
+
+	ok, err := redis.Bool(s.do(ctx, script, args...))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "skip changed meanwhile", nil
+	}
+	return action, nil
+}
+
+// 在每个节点上SCAN匹配的key
+func (s *Store) scanKeys(ctx context.Context, match string, f func(key string) error) error {
+	return redispool.ForEachNode(ctx, s.redisPool, func(conn redis.Conn) error {
+		cursor := "0"
+		for {
+			vals, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", match, "COUNT", scanCount))
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var keys []string
+			if _, err := redis.Scan(vals, &cursor, &keys); err != nil {
+				return errors.WithStack(err)
+			}
+
+			for _, key := range keys {
+				if err := f(key); err != nil {
+					return err
+				}
+			}
+
+			if cursor == "0" {
+				return nil
+			}
+		}
+	})
+}
+
+// 统计映射记录对seqs里各seq的引用
+func (s *Store) countRefs(ctx context.Context, key string, seqs map[string]bool, actual map[string]int64) error {
+	conn, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	cursor := "0"
+	for {
+		vals, err := redis.Values(conn.Do("ZSCAN", key, cursor, "COUNT", scanCount))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		var members []string
+		if _, err := redis.Scan(vals, &cursor, &members); err != nil {
+			return errors.WithStack(err)
+		}
+
+		// member score 交替
+		for i := 0; i < len(members); i += 2 {
+			if seqs[members[i]] {
+				actual[members[i]]++
+			}
+		}
+
+		if cursor == "0" {
+			return nil
+		}
+	}
+}
+
+// 各seq的消息内容是否存在
+func (s *Store) existsBySeqs(ctx context.Context, seqs []string) (map[string]bool, error) {
+	ret := map[string]bool{}
+	for _, group := range redispool.Partition(s.redisPool, toKeys(seqs, ommKey)) {
+		if err := func() error {
+			conn, err := s.redisPool.GetContext(ctx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer conn.Close()
+
+			for _, key := range group {
+				if err := conn.Send("EXISTS", key); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+
+			if err := conn.Flush(); err != nil {
+				return errors.WithStack(err)
+			}
+
+			for _, key := range group {
+				exists, err := redis.Bool(conn.Receive())
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				if exists {
+					seq, _ := parseSeqKey(key)
+					ret[seq] = true
+				}
+			}
+			return nil
+		}(); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func toKeys(seqs []string, keyOf func(seq string) string) []string {
+	keys := make([]string, len(seqs))
+	for i, seq := range seqs {
+		keys[i] = keyOf(seq)
+	}
+	return keys
+}
+
+// 从 msg/om:{seq1}/m 或 msg/om:{seq1}/n 里解析出seq
+func parseSeqKey(key string) (string, bool) {
+	if !strings.HasPrefix(key, "msg/om:{") {
+		return "", false
+	}
+	key = strings.TrimPrefix(key, "msg/om:{")
+
+	end := strings.LastIndex(key, "}/")
+	if end <= 0 {
+		return "", false
+	}
+	return key[:end], true
+}
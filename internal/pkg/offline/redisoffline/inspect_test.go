@@ -0,0 +1,184 @@
+package redisoffline
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/molon/gomsg/internal/pkg/offline"
+	"github.com/molon/gomsg/pb/msgpb"
+)
+
+func newInspectStore(t *testing.T) (*Store, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis.Run: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() { pool.Close() })
+
+	s, err := InitStore(context.Background(), pool)
+	if err != nil {
+		t.Fatalf("InitStore: %+v", err)
+	}
+	return s, mr
+}
+
+// 写入一条离线消息后丢掉其映射，使内容成为不被引用的孤儿，并把版本号改到 orphanGrace 之前
+func writeOrphan(t *testing.T, s *Store, mr *miniredis.Miniredis, seq string) {
+	msg := &msgpb.Message{Seq: seq}
+	if err := s.Write(context.Background(), "u1", "mobile", msg, time.Now(), time.Hour); err != nil {
+		t.Fatalf("Write: %+v", err)
+	}
+	mr.ZRem(upomsKey("u1", "mobile", offline.PriorityOf(msg)), seq)
+
+	old := nowMillis() - int64(2*orphanGrace/time.Millisecond)
+	if err := mr.Set(omgKey(seq), strconv.FormatInt(old, 10)); err != nil {
+		t.Fatalf("Set gen: %v", err)
+	}
+}
+
+func findOrphan(t *testing.T, s *Store, seq string) *Orphan {
+	orphans, err := s.FindOrphans(context.Background())
+	if err != nil {
+		t.Fatalf("FindOrphans: %+v", err)
+	}
+	for _, o := range orphans {
+		if o.Seq == seq {
+			return o
+		}
+	}
+	t.Fatalf("FindOrphans: %s not found in %v", seq, orphans)
+	return nil
+}
+
+func TestRepairOrphanDrop(t *testing.T) {
+	s, mr := newInspectStore(t)
+	writeOrphan(t, s, mr, "s1")
+
+	o := findOrphan(t, s, "s1")
+	if o.Actual != 0 || o.Refs != 1 || !o.HasGen {
+		t.Fatalf("orphan: got %+v", o)
+	}
+
+	action, err := s.RepairOrphan(context.Background(), o)
+	if err != nil {
+		t.Fatalf("RepairOrphan: %+v", err)
+	}
+	if action != "drop unreferenced content" {
+		t.Fatalf("RepairOrphan: got %q", action)
+	}
+	if mr.Exists(ommKey("s1")) || mr.Exists(omnKey("s1")) {
+		t.Fatalf("content and refcount should be dropped")
+	}
+	// 版本号保留，之后的写入依然只增
+	if !mr.Exists(omgKey("s1")) {
+		t.Fatalf("gen should be kept")
+	}
+
+	orphans, err := s.FindOrphans(context.Background())
+	if err != nil {
+		t.Fatalf("FindOrphans: %+v", err)
+	}
+	if len(orphans) != 0 {
+		t.Fatalf("FindOrphans after repair: got %v", orphans)
+	}
+}
+
+// 统计之后引用计数先加后减回到原值，映射却已变化，不能删除
+func TestRepairOrphanABA(t *testing.T) {
+	s, mr := newInspectStore(t)
+	writeOrphan(t, s, mr, "s1")
+
+	o := findOrphan(t, s, "s1")
+
+	msg := &msgpb.Message{Seq: "s1"}
+	if err := s.Write(context.Background(), "u2", "mobile", msg, time.Now(), time.Hour); err != nil {
+		t.Fatalf("Write: %+v", err)
+	}
+	if _, err := s.release(context.Background(), []string{"s1"}); err != nil {
+		t.Fatalf("release: %+v", err)
+	}
+	if n, _ := mr.Get(omnKey("s1")); n != strconv.FormatInt(o.Refs, 10) {
+		t.Fatalf("refcount: got %s, want %d", n, o.Refs)
+	}
+
+	action, err := s.RepairOrphan(context.Background(), o)
+	if err != nil {
+		t.Fatalf("RepairOrphan: %+v", err)
+	}
+	if action != "skip changed meanwhile" {
+		t.Fatalf("RepairOrphan: got %q", action)
+	}
+	if !mr.Exists(ommKey("s1")) {
+		t.Fatalf("content referenced by u2 should be kept")
+	}
+}
+
+// 刚加了引用还没写映射的，统计时看起来不被引用
+func TestRepairOrphanRecentlyChanged(t *testing.T) {
+	s, mr := newInspectStore(t)
+
+	msg := &msgpb.Message{Seq: "s1"}
+	if _, err := s.do(context.Background(), retainLua, ommKey("s1"), omnKey("s1"), omgKey("s1"),
+		"content", time.Now().Add(time.Hour).Unix(), nowMillis(), int64(genExpire/time.Second)); err != nil {
+		t.Fatalf("retain: %+v", err)
+	}
+
+	o := findOrphan(t, s, "s1")
+	action, err := s.RepairOrphan(context.Background(), o)
+	if err != nil {
+		t.Fatalf("RepairOrphan: %+v", err)
+	}
+	if action != "skip recently changed" {
+		t.Fatalf("RepairOrphan: got %q", action)
+	}
+
+	if err := s.Write(context.Background(), "u1", "mobile", msg, time.Now(), time.Hour); err != nil {
+		t.Fatalf("Write: %+v", err)
+	}
+	if !mr.Exists(ommKey("s1")) {
+		t.Fatalf("content should be kept")
+	}
+}
+
+func TestRepairOrphanFixRefs(t *testing.T) {
+	s, mr := newInspectStore(t)
+
+	for _, uid := range []string{"u1", "u2"} {
+		if err := s.Write(context.Background(), uid, "mobile", &msgpb.Message{Seq: "s1"}, time.Now(), time.Hour); err != nil {
+			t.Fatalf("Write: %+v", err)
+		}
+	}
+	if err := mr.Set(omnKey("s1"), "1"); err != nil {
+		t.Fatalf("Set refcount: %v", err)
+	}
+
+	o := findOrphan(t, s, "s1")
+	if o.Actual != 2 || o.Refs != 1 {
+		t.Fatalf("orphan: got %+v", o)
+	}
+
+	action, err := s.RepairOrphan(context.Background(), o)
+	if err != nil {
+		t.Fatalf("RepairOrphan: %+v", err)
+	}
+	if action != "fix refcount to 2" {
+		t.Fatalf("RepairOrphan: got %q", action)
+	}
+	if n, _ := mr.Get(omnKey("s1")); n != "2" {
+		t.Fatalf("refcount: got %s, want 2", n)
+	}
+	if ttl := mr.TTL(omnKey("s1")); ttl <= 0 {
+		t.Fatalf("refcount ttl: got %v", ttl)
+	}
+}
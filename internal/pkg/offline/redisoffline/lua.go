@@ -0,0 +1,254 @@
+package redisoffline
+
+import "github.com/gomodule/redigo/redis"
+
+// 为了兼容redis集群，每个脚本只操作同一slot的key:
+// 用户各优先级的映射记录都以 {uid} 为hashtag，消息内容和引用计数以 {seq} 为hashtag
+// 映射和内容分两步操作，先加引用后写映射，先删映射后减引用，中间失败的话最多是内容多留存一段时间，到期后会被redis自动删除
+// 删除映射的脚本会把需要归还引用的seq连同 msg/u:{uid1}/orl 里之前没能归还的一并取出返回，由外部执行releaseLua，没能执行的再记回去
+
+// 取出待归还引用的seq追加到releases里，orl为 msg/u:{uid1}/orl 所在的KEYS下标
+const popReleasesLua = `
+			local function pop_releases(orl, releases)
+				local pending = redis.call("SMEMBERS", KEYS[orl])
+				if #pending > 0 then
+					redis.call("DEL", KEYS[orl])
+					for _, seq in ipairs(pending) do
+						table.insert(releases, seq)
+					end
+				end
+				return releases
+			end
+`
+
+// 消息内容的版本号增加到不小于当前毫秒时间戳，m、g为内容和版本号所在的KEYS下标，now为当前毫秒时间戳
+// 每次改变引用计数都要执行，版本号只增不减，内容被删除之后也保留至过期，排查修复时以其做CAS
+// 版本号还没有过期时间的，取内容的剩余时间，内容已不存在则为gen_expire秒
+const bumpGenLua = `
+			local function bump_gen(m, g, now, gen_expire)
+				local cur = tonumber(redis.call("GET", KEYS[g]) or "0")
+				local d = 1
+				if tonumber(now) > cur then
+					d = tonumber(now) - cur
+				end
+				redis.call("INCRBY", KEYS[g], d)
+
+				if redis.call("PTTL", KEYS[g]) == -1 then
+					local pttl = redis.call("PTTL", KEYS[m])
+					if pttl > 0 then
+						redis.call("PEXPIRE", KEYS[g], pttl)
+					else
+						redis.call("EXPIRE", KEYS[g], gen_expire)
+					end
+				end
+			end
+`
+
+var (
+	/*
+		- 若带有折叠key，`HGET msg/u:{uid1}/p:platform1/ock key` 找到已有的同一key的seq，及其在各优先级映射记录里的分数
+		- - 已有的发出时间更晚的话，直接忽略此消息
+		- `ZADD msg/u:{uid1}/p:platform1/oms NX ts seq1`
+		- 1. 如果返回0，说明已经记录过了，外部需要归还之前为其增加的引用
+		- 2. 如果返回1，执行`EXPIRE msg/u:{uid1}/p:platform1/oms expire`，因为如果过了这个时间没有更新 EXPIRE 的话，肯定消息全特么都过期了，防止用户一直没操作而产生的过多的脏数据
+		- - 若带有折叠key，`HSET msg/u:{uid1}/p:platform1/ock key seq1`，并`ZREM`掉已有的同一key的seq，由外部对其执行releaseLua
+	*/
+
+	/*
+		KEYS : msg/u:{uid1}/p:platform1/oms(此消息优先级的离线映射记录) msg/u:{uid1}/p:platform1/ock(折叠key对应的seq) msg/u:{uid1}/orl(待归还引用的seq)
+		       msg/u:{uid1}/p:platform1/oms:high msg/u:{uid1}/p:platform1/oms ...(各优先级的离线映射记录)
+		ARGV : seq1(消息标识) ts(消息发出时间) expire(多久过期) key(折叠key，可为空)
+		返回 : [1表示新增了映射, 需要归还引用的seq列表(被替换掉的以及之前没能归还的)]
+	*/
+	writeLua = redis.NewScript(-1, popReleasesLua+`
+			local old, old_key
+			if ARGV[4] ~= "" then
+				-- HGET msg/u:{uid1}/p:platform1/ock key
+				old = redis.call("HGET", KEYS[2], ARGV[4])
+				if old and old ~= ARGV[1] then
+					for i = 4, #KEYS do
+						local score = redis.call("ZSCORE", KEYS[i], old)
+						if score then
+							-- 已有的更新，忽略此消息
+							if tonumber(score) > tonumber(ARGV[2]) then
+								return {0, pop_releases(3, {})}
+							end
+							old_key = KEYS[i]
+							break
+						end
+					end
+				end
+			end
+
+			-- ZADD msg/u:{uid1}/p:platform1/oms NX
+			if redis.call("ZADD", KEYS[1], "NX", ARGV[2], ARGV[1]) == 0 then
+				return {0, pop_releases(3, {})}
+			end
+
+			-- EXPIRE msg/u:{uid1}/p:platform1/oms expire
+			redis.call("EXPIRE", KEYS[1], ARGV[3])
+
+			if ARGV[4] == "" then
+				return {1, pop_releases(3, {})}
+			end
+
+			-- HSET msg/u:{uid1}/p:platform1/ock key seq1
+			redis.call("HSET", KEYS[2], ARGV[4], ARGV[1])
+			redis.call("EXPIRE", KEYS[2], ARGV[3])
+
+			-- ZREM msg/u:{uid1}/p:platform1/oms old
+			if old_key then
+				redis.call("ZREM", old_key, old)
+				return {1, pop_releases(3, {old})}
+			end
+
+			return {1, pop_releases(3, {})}
+		`)
+
+	/*
+		- `INCR msg/om:{seq1}/n`
+		- 如果返回1，说明是刚刚创建的，所以要执行`SET msg/om:{seq1}/m "xxxx" NX`
+		- 各平台的离线有效期不同，内容和引用计数要保留到最晚的那个，所以只在expireat比现有的过期时间晚时才执行`EXPIREAT`
+	*/
+
+	/*
+		KEYS : msg/om:{seq1}/m(消息内容) msg/om:{seq1}/n(消息引用计数) msg/om:{seq1}/g(版本号)
+		ARGV : xxxx(消息内容) expireat(到期时间) now(当前毫秒时间戳) gen_expire(版本号的默认保留秒数)
+	*/
+	retainLua = redis.NewScript(3, bumpGenLua+`
+			-- INCR msg/om:{seq1}/n
+			if redis.call("INCR", KEYS[2]) == 1 then
+				-- SET msg/om:{seq1}/m xxxx NX
+				redis.call("SET", KEYS[1], ARGV[1], "NX")
+			end
+
+			-- 没有过期时间或者比expireat早的才延长，TTL是向下取整的秒数，算出来的只会偏早
+			local expat = tonumber(ARGV[2])
+			local now = math.floor(tonumber(ARGV[3]) / 1000)
+			for i = 1, 2 do
+				local ttl = redis.call("TTL", KEYS[i])
+				if ttl < 0 or now + ttl < expat then
+					-- EXPIREAT msg/om:{seq1}/m expireat
+					redis.call("EXPIREAT", KEYS[i], expat)
+				end
+			end
+
+			bump_gen(1, 3, ARGV[3], ARGV[4])
+			return nil
+		`)
+
+	/*
+		- 对每个seq在各优先级的映射记录里执行 `ZREM msg/u:{uid1}/p:platform1/oms seq1`，返回1的需要再执行releaseLua
+	*/
+
+	/*
+		KEYS : msg/u:{uid1}/orl(待归还引用的seq) msg/u:{uid1}/p:platform1/oms:high msg/u:{uid1}/p:platform1/oms(某用户各优先级的离线映射记录)
+		ARGV : seq1 seq2 ...(消息标识)
+		返回 : 需要归还引用的seq列表(确实被删除的以及之前没能归还的)
+	*/
+	deleteLua = redis.NewScript(-1, popReleasesLua+`
+			local removed = {}
+			for i, seq in ipairs(ARGV) do
+				for j = 2, #KEYS do
+					-- ZREM msg/u:{uid1}/p:platform1/oms seq1
+					if redis.call("ZREM", KEYS[j], seq) == 1 then
+						table.insert(removed, seq)
+						break
+					end
+				end
+			end
+
+			return pop_releases(1, removed)
+		`)
+
+	/*
+		- `DECR msg/om:{seq1}/n`
+		- 上一步若返回<=0，则执行`DEL msg/om:{seq1}/m msg/om:{seq1}/n`
+	*/
+
+	/*
+		KEYS : msg/om:{seq1}/m(消息内容) msg/om:{seq1}/n(消息引用计数) msg/om:{seq1}/g(版本号)
+		ARGV : now(当前毫秒时间戳) gen_expire(版本号的默认保留秒数)
+	*/
+	releaseLua = redis.NewScript(3, bumpGenLua+`
+			bump_gen(1, 3, ARGV[1], ARGV[2])
+
+			-- DECR msg/om:{seq1}/n
+			if redis.call("DECR", KEYS[2]) > 0 then
+				return nil
+			end
+
+			-- DEL msg/om:{seq1}/m msg/om:{seq1}/n
+			redis.call("DEL", KEYS[1], KEYS[2])
+
+			return nil
+		`)
+
+	/*
+		- 一般在写入一批离线消息成功之后就要执行
+		- 对各优先级的映射记录执行一发`ZREMRANGEBYSCORE msg/u:{uid1}/p:platform1/oms -inf (expirets` 删除过期元素，减少下面的开支
+		- 而因为最大离线映射数做的清理，就需要修正引用计数了
+		- 各优先级的总数超出 max_offline_msg_count 时，从最低优先级开始，每个优先级里从最旧的开始清理
+		- - `ZRANGE msg/u:{uid1}/p:platform1/oms 0 n-1` 查出来要清理的数据列表
+		- - `ZREMRANGEBYRANK msg/u:{uid1}/p:platform1/oms 0 n-1`
+		- 返回此列表，由外部对其挨个执行releaseLua
+	*/
+
+	/*
+		KEYS : msg/u:{uid1}/orl(待归还引用的seq) msg/u:{uid1}/p:platform1/oms:high msg/u:{uid1}/p:platform1/oms(某用户各优先级的离线映射记录，从高到低)
+		ARGV : expirets(已过期时间戳) max_offline_msg_count(最大离线映射数目)
+		返回 : [过期被清理的seq列表, 超出数目被清理的seq列表, 需要归还引用的seq列表(超出数目被清理的以及之前没能归还的)]
+	*/
+	cleanLua = redis.NewScript(-1, popReleasesLua+`
+			local expired = {}
+			local counts = {}
+			local total = 0
+			for i = 2, #KEYS do
+				local key = KEYS[i]
+				-- ZRANGEBYSCORE msg/u:{uid1}/p:platform1/oms -inf (expirets
+				local seqs = redis.call("ZRANGEBYSCORE", key, "-inf", "("..ARGV[1])
+
+				-- ZREMRANGEBYSCORE msg/u:{uid1}/p:platform1/oms -inf (expirets
+				if #seqs > 0 then
+					redis.call("ZREMRANGEBYSCORE", key, "-inf", "("..ARGV[1])
+					for _, seq in ipairs(seqs) do
+						table.insert(expired, seq)
+					end
+				end
+
+				counts[i] = redis.call("ZCARD", key)
+				total = total + counts[i]
+			end
+
+			local trimmed = {}
+			local max_count = tonumber(ARGV[2])
+			if max_count>=0 then
+				local overflow = total - max_count
+				for i = #KEYS, 2, -1 do
+					if overflow <= 0 then
+						break
+					end
+
+					local n = math.min(overflow, counts[i])
+					if n > 0 then
+						-- ZRANGE msg/u:{uid1}/p:platform1/oms 0 n-1
+						local seqs = redis.call("ZRANGE", KEYS[i], 0, n-1)
+
+						-- ZREMRANGEBYRANK msg/u:{uid1}/p:platform1/oms 0 n-1
+						redis.call("ZREMRANGEBYRANK", KEYS[i], 0, n-1)
+
+						for _, seq in ipairs(seqs) do
+							table.insert(trimmed, seq)
+						end
+						overflow = overflow - n
+					end
+				end
+			end
+
+			local releases = {}
+			for _, seq in ipairs(trimmed) do
+				table.insert(releases, seq)
+			end
+			return {expired, trimmed, pop_releases(1, releases)}
+		`)
+)
@@ -0,0 +1,202 @@
+package redisoffline
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/molon/pkg/errors"
+)
+
+// 以下用于从key还没有 {hashtag} 的旧版本升级，升级之后执行一次即可
+// 旧key: msg/u:uid1/... msg/om:seq1/...  新key: msg/u:{uid1}/... msg/om:{seq1}/...
+// 除了离线消息，会话、uid_seq等用户维度的key也一并迁移
+
+var (
+	/*
+		KEYS : 新key
+		ARGV : 旧key的值
+		只有旧值更大才覆盖，用于uid_seq这种只增的计数，以INCRBY补上差值从而保留剩余时间
+	*/
+	setMaxLua = redis.NewScript(1, `
+			local cur = tonumber(redis.call("GET", KEYS[1]) or "0")
+			local old = tonumber(ARGV[1])
+			if old > cur then
+				redis.call("INCRBY", KEYS[1], old - cur)
+			end
+			return nil
+		`)
+)
+
+// 旧key对应的新key，已经是新格式或者不是gomsg的key返回false
+func migratedKey(key string) (string, bool) {
+	for _, prefix := range []string{"msg/u:", "msg/om:"} {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		rest := strings.TrimPrefix(key, prefix)
+		if strings.HasPrefix(rest, "{") {
+			return "", false
+		}
+		idx := strings.Index(rest, "/")
+		if idx <= 0 {
+			return "", false
+		}
+		return prefix + "{" + rest[:idx] + "}" + rest[idx:], true
+	}
+	return "", false
+}
+
+// 将旧版本的key迁移为当前格式，f为每个要迁移的key的回调，dryRun时只回调不迁移，返回迁移的key数目
+// 按类型合并到新key: zset/hash 只补充不存在的成员，引用计数相加，uid_seq取较大的，其他字符串保留新的
+// 新key不存在的就相当于直接搬过去，剩余时间取两者较长的
+// 可以在服务运行时执行，期间旧key不会再被写入
+func (s *Store) MigrateLegacyKeys(ctx context.Context, dryRun bool, f func(oldKey string, newKey string)) (int, error) {
+	n := 0
+	for _, match := range []string{"msg/u:*", "msg/om:*"} {
+		// SCAN的连接只在某个节点上，迁移需要另外取连接
+		if err := s.scanKeys(ctx, match, func(key string) error {
+			newKey, ok := migratedKey(key)
+			if !ok {
+				return nil
+			}
+
+			if f != nil {
+				f(key, newKey)
+			}
+			if dryRun {
+				n++
+				return nil
+			}
+
+			if err := s.migrateKey(ctx, key, newKey); err != nil {
+				return err
+			}
+			n++
+			return nil
+		}); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) migrateKey(ctx context.Context, oldKey string, newKey string) error {
+	// 新旧key不一定在同一slot，分开两个连接
+	src, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer src.Close()
+
+	dst, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer dst.Close()
+
+	typ, err := redis.String(src.Do("TYPE", oldKey))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if typ == "none" {
+		return nil
+	}
+
+	pttl, err := redis.Int64(src.Do("PTTL", oldKey))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if pttl == -2 {
+		return nil
+	}
+
+	existed, err := redis.Bool(dst.Do("EXISTS", newKey))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := s.mergeKey(src, dst, typ, oldKey, newKey); err != nil {
+		return err
+	}
+	if err := extendTTL(dst, newKey, pttl, existed); err != nil {
+		return err
+	}
+
+	if _, err := src.Do("DEL", oldKey); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (s *Store) mergeKey(src redis.Conn, dst redis.Conn, typ string, oldKey string, newKey string) error {
+	switch typ {
+	case "zset":
+		vals, err := redis.Strings(src.Do("ZRANGE", oldKey, 0, -1, "WITHSCORES"))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		for i := 0; i+1 < len(vals); i += 2 {
+			if _, err := dst.Do("ZADD", newKey, "NX", vals[i+1], vals[i]); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	case "hash":
+		vals, err := redis.Strings(src.Do("HGETALL", oldKey))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		for i := 0; i+1 < len(vals); i += 2 {
+			if _, err := dst.Do("HSETNX", newKey, vals[i], vals[i+1]); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	case "string":
+		val, err := redis.String(src.Do("GET", oldKey))
+		if err == redis.ErrNil {
+			return nil
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		switch {
+		case strings.HasPrefix(oldKey, "msg/om:") && strings.HasSuffix(oldKey, "/n"):
+			// 新旧映射各自的引用都要算上
+			if _, err := dst.Do("INCRBY", newKey, val); err != nil {
+				return errors.WithStack(err)
+			}
+		case strings.HasSuffix(oldKey, "/seq"):
+			if _, err := setMaxLua.Do(dst, newKey, val); err != nil {
+				return errors.WithStack(err)
+			}
+		default:
+			if _, err := dst.Do("SET", newKey, val, "NX"); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	default:
+		return errors.Errorf("unexpected type %s of %s", typ, oldKey)
+	}
+	return nil
+}
+
+// 合并之后新key的剩余时间取两者较长的，之前就存在且不过期的保持不过期
+func extendTTL(conn redis.Conn, key string, pttl int64, existed bool) error {
+	if pttl <= 0 {
+		return nil
+	}
+
+	cur, err := redis.Int64(conn.Do("PTTL", key))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if (existed && cur < 0) || cur >= pttl {
+		return nil
+	}
+
+	if _, err := conn.Do("PEXPIRE", key, pttl); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
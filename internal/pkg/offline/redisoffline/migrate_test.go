@@ -0,0 +1,73 @@
+package redisoffline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestMigrateLegacyKeys(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis.Run: %v", err)
+	}
+	defer mr.Close()
+
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	defer pool.Close()
+
+	s, err := InitStore(context.Background(), pool)
+	if err != nil {
+		t.Fatalf("InitStore: %+v", err)
+	}
+
+	// 旧版本写入的
+	mr.ZAdd("msg/u:u1/p:mobile/oms", 100, "s1")
+	mr.SetTTL("msg/u:u1/p:mobile/oms", time.Hour)
+	mr.Set("msg/om:s1/m", "content")
+	mr.Set("msg/om:s1/n", "2")
+	mr.Set("msg/u:u1/seq", "10")
+	// 升级之后已有写入的
+	mr.ZAdd("msg/u:{u1}/p:mobile/oms", 200, "s2")
+	mr.SetTTL("msg/u:{u1}/p:mobile/oms", time.Minute)
+	mr.Set("msg/om:{s1}/n", "1")
+	mr.Set("msg/u:{u1}/seq", "3")
+
+	n, err := s.MigrateLegacyKeys(context.Background(), false, nil)
+	if err != nil {
+		t.Fatalf("MigrateLegacyKeys: %+v", err)
+	}
+	if n != 4 {
+		t.Fatalf("MigrateLegacyKeys: got %d keys, want 4", n)
+	}
+
+	for _, key := range []string{"msg/u:u1/p:mobile/oms", "msg/om:s1/m", "msg/om:s1/n", "msg/u:u1/seq"} {
+		if mr.Exists(key) {
+			t.Fatalf("legacy key %s should be removed", key)
+		}
+	}
+
+	members, _ := mr.ZMembers("msg/u:{u1}/p:mobile/oms")
+	if len(members) != 2 {
+		t.Fatalf("mapping: got %v, want s1 and s2", members)
+	}
+	if ttl := mr.TTL("msg/u:{u1}/p:mobile/oms"); ttl <= time.Minute {
+		t.Fatalf("mapping should take the longer ttl, got %v", ttl)
+	}
+	if v, _ := mr.Get("msg/om:{s1}/m"); v != "content" {
+		t.Fatalf("content: got %q", v)
+	}
+	if v, _ := mr.Get("msg/om:{s1}/n"); v != "3" {
+		t.Fatalf("refcount: got %q, want 3", v)
+	}
+	if v, _ := mr.Get("msg/u:{u1}/seq"); v != "10" {
+		t.Fatalf("uid_seq: got %q, want 10", v)
+	}
+}
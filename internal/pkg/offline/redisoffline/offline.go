@@ -0,0 +1,733 @@
+package redisoffline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/gomodule/redigo/redis"
+	"github.com/molon/gomsg/internal/pkg/offline"
+	"github.com/molon/gomsg/internal/pkg/redispool"
+	"github.com/molon/pkg/errors"
+	"github.com/molon/gomsg/pb/msgpb"
+)
+
+// 用户在某平台某优先级的离线消息映射记录，NORMAL的沿用不区分优先级时的key
+func upomsKey(uid string, platform string, priority msgpb.MessagePriority) string {
+	if priority == msgpb.MessagePriority_NORMAL {
+		return fmt.Sprintf("msg/u:{%s}/p:%s/oms", uid, platform)
+	}
+	return fmt.Sprintf("msg/u:{%s}/p:%s/oms:%s", uid, platform, strings.ToLower(priority.String()))
+}
+
+// 用户在某平台的离线消息折叠key对应的seq
+func upockKey(uid string, platform string) string {
+	return fmt.Sprintf("msg/u:{%s}/p:%s/ock", uid, platform)
+}
+
+// 离线消息内容的版本号，引用计数每次改变都会增加，值不小于最后一次改变时的毫秒时间戳
+func omgKey(seq string) string {
+	return fmt.Sprintf("msg/om:{%s}/g", seq)
+}
+
+// 内容已不存在时版本号的保留时长，过期之后同一seq又被写入的话，版本号从当前时间重新开始
+const genExpire = 7 * 24 * time.Hour
+
+// 当前毫秒时间戳，用于增加版本号
+func nowMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// 用户待归还引用的seq，归还失败的记在这里，下次删除映射时一并归还
+func uorlKey(uid string) string {
+	return fmt.Sprintf("msg/u:{%s}/orl", uid)
+}
+
+// 待归还引用的seq的保留时长，超过的话最多是内容留存到其过期时间
+const orlExpire = 7 * 24 * time.Hour
+
+// 用户在某平台各优先级的离线消息映射记录，从高到低
+func upomsKeys(uid string, platform string) []string {
+	keys := make([]string, len(offline.Priorities))
+	for i, priority := range offline.Priorities {
+		keys[i] = upomsKey(uid, platform, priority)
+	}
+	return keys
+}
+
+// 不定key数目的脚本参数: key数目 keys... args...
+func keysAndArgs(keys []string, args ...interface{}) []interface{} {
+	ret := make([]interface{}, 0, 1+len(keys)+len(args))
+	ret = append(ret, len(keys))
+	for _, key := range keys {
+		ret = append(ret, key)
+	}
+	return append(ret, args...)
+}
+
+// 离线消息内容
+func ommKey(seq string) string {
+	return fmt.Sprintf("msg/om:{%s}/m", seq)
+}
+
+// 离线消息引用计数
+func omnKey(seq string) string {
+	return fmt.Sprintf("msg/om:{%s}/n", seq)
+}
+
+func (s *Store) Delete(ctx context.Context, uid string, platform string, seqs []string) error {
+	if len(seqs) <= 0 {
+		return errors.Errorf("seqs is empty")
+	}
+
+	conn, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	args := make([]interface{}, len(seqs))
+	for i, seq := range seqs {
+		args[i] = seq
+	}
+
+	keys := append([]string{uorlKey(uid)}, upomsKeys(uid, platform)...)
+	releases, err := redis.Strings(deleteLua.Do(conn, keysAndArgs(keys, args...)...))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return s.releaseFor(ctx, uid, releases)
+}
+
+// 归还某用户的映射对消息内容的引用，确定没有执行的记回 msg/u:{uid}/orl，下次删除映射时再归还
+func (s *Store) releaseFor(ctx context.Context, uid string, seqs []string) error {
+	unsent, err := s.release(ctx, seqs)
+	if err == nil {
+		return nil
+	}
+	if len(unsent) <= 0 {
+		return err
+	}
+
+	if derr := s.deferRelease(ctx, uid, unsent); derr != nil {
+		return errors.Errorf("release %v failed: %+v, and defer them failed: %+v", unsent, err, derr)
+	}
+	// 都记下来了的话稍后会再归还
+	if len(unsent) == len(seqs) {
+		return nil
+	}
+	return err
+}
+
+func (s *Store) deferRelease(ctx context.Context, uid string, seqs []string) error {
+	conn, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	args := make([]interface{}, 0, 1+len(seqs))
+	args = append(args, uorlKey(uid))
+	for _, seq := range seqs {
+		args = append(args, seq)
+	}
+
+	if err := conn.Send("MULTI"); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := conn.Send("SADD", args...); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := conn.Send("EXPIRE", uorlKey(uid), int64(orlExpire/time.Second)); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// 减少消息内容的引用计数，不再被引用的删除
+// 内容按seq分布在不同slot，所以按节点分组pipeline执行
+// 出错时返回确定没有执行的seq，可以稍后再归还；已发出但结果未知的不在其中，宁可内容多留存也不能多归还
+func (s *Store) release(ctx context.Context, seqs []string) ([]string, error) {
+	if len(seqs) <= 0 {
+		return nil, nil
+	}
+
+	keyToSeq := map[string]string{}
+	keys := make([]string, len(seqs))
+	for i, seq := range seqs {
+		keys[i] = ommKey(seq)
+		keyToSeq[keys[i]] = seq
+	}
+
+	var (
+		unsent   []string
+		firstErr error
+	)
+	for _, group := range redispool.Partition(s.redisPool, keys) {
+		groupUnsent, err := s.releaseGroup(ctx, group, keyToSeq)
+		unsent = append(unsent, groupUnsent...)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return unsent, firstErr
+}
+
+// 在同一节点上pipeline归还引用，返回确定没有执行的seq
+func (s *Store) releaseGroup(ctx context.Context, group []string, keyToSeq map[string]string) ([]string, error) {
+	seqsOf := func(keys []string) []string {
+		seqs := make([]string, len(keys))
+		for i, key := range keys {
+			seqs[i] = keyToSeq[key]
+		}
+		return seqs
+	}
+
+	conn, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return seqsOf(group), errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	now, gexp := nowMillis(), int64(genExpire/time.Second)
+	for _, key := range group {
+		seq := keyToSeq[key]
+		if err := releaseLua.SendHash(conn, key, omnKey(seq), omgKey(seq), now, gexp); err != nil {
+			return seqsOf(group), errors.WithStack(err)
+		}
+	}
+
+	if err := conn.Flush(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// 节点上没有脚本的(故障转移或者迁移slot之后)并没有执行，之后以 EVAL 兜底重新执行
+	// 服务端返回的其他错误也说明没有执行，网络错误则无法确定，之后的结果也都无法确定
+	var (
+		noScripts []string
+		failed    []string
+		firstErr  error
+	)
+	for _, key := range group {
+		_, err := conn.Receive()
+		if err == nil {
+			continue
+		}
+		if redispool.IsNoScript(err) {
+			noScripts = append(noScripts, key)
+			continue
+		}
+		if _, ok := err.(redis.Error); !ok {
+			return seqsOf(append(failed, noScripts...)), errors.WithStack(err)
+		}
+		failed = append(failed, key)
+		if firstErr == nil {
+			firstErr = errors.WithStack(err)
+		}
+	}
+
+	for i, key := range noScripts {
+		seq := keyToSeq[key]
+		if _, err := releaseLua.Do(conn, key, omnKey(seq), omgKey(seq), now, gexp); err != nil {
+			if _, ok := err.(redis.Error); ok {
+				return seqsOf(append(failed, noScripts[i:]...)), errors.WithStack(err)
+			}
+			return seqsOf(append(failed, noScripts[i+1:]...)), errors.WithStack(err)
+		}
+	}
+
+	return seqsOf(failed), firstErr
+}
+
+// 清理过期的以及超出最大数目的离线消息，返回各平台过期被清理的以及超出数目被清理的seq列表
+func (s *Store) Clean(ctx context.Context, uid string, expire time.Duration, platformToMaxOMCount map[string]int) (map[string][]string, map[string][]string, error) {
+	if len(platformToMaxOMCount) <= 0 {
+		return nil, nil, errors.Errorf("platformToMaxOMCount is empty")
+	}
+
+	conn, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	expTs := time.Now().Add(-expire).Unix()
+
+	// 同一用户的映射记录都在同一slot，可以pipeline
+	platforms := make([]string, 0, len(platformToMaxOMCount))
+	platformToArgs := map[string][]interface{}{}
+	for platform, maxOMCount := range platformToMaxOMCount {
+		keys := append([]string{uorlKey(uid)}, upomsKeys(uid, platform)...)
+		args := keysAndArgs(keys, expTs, maxOMCount)
+		if err := cleanLua.SendHash(conn, args...); err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		platforms = append(platforms, platform)
+		platformToArgs[platform] = args
+	}
+
+	if err := conn.Flush(); err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	replies := make([]interface{}, len(platforms))
+	noScripts := []int{}
+	for i := range platforms {
+		reply, err := conn.Receive()
+		if redispool.IsNoScript(err) {
+			noScripts = append(noScripts, i)
+			continue
+		}
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		replies[i] = reply
+	}
+	// 节点上没有脚本的并没有执行，以 EVAL 兜底重新执行
+	for _, i := range noScripts {
+		reply, err := cleanLua.Do(conn, platformToArgs[platforms[i]]...)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		replies[i] = reply
+	}
+
+	platformToExpiredSeqs := map[string][]string{}
+	platformToEvictedSeqs := map[string][]string{}
+	releases := []string{}
+	for i, platform := range platforms {
+		vals, err := redis.Values(replies[i], nil)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+
+		if len(vals) != 3 {
+			return nil, nil, errors.Errorf("unexpected clean result: %v", vals)
+		}
+		expiredSeqs, err := redis.Strings(vals[0], nil)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		trimmedSeqs, err := redis.Strings(vals[1], nil)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		releaseSeqs, err := redis.Strings(vals[2], nil)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+
+		if len(expiredSeqs) > 0 {
+			platformToExpiredSeqs[platform] = expiredSeqs
+		}
+		if len(trimmedSeqs) > 0 {
+			platformToEvictedSeqs[platform] = trimmedSeqs
+		}
+		releases = append(releases, releaseSeqs...)
+	}
+
+	// 过期的内容会被redis自动删除，只需修正超出数目被清理的(以及之前没能归还的)
+	if err := s.releaseFor(ctx, uid, releases); err != nil {
+		return nil, nil, err
+	}
+
+	return platformToExpiredSeqs, platformToEvictedSeqs, nil
+}
+
+func (s *Store) Write(ctx context.Context,
+	uid string, platform string,
+	msg *msgpb.Message,
+	sendTime time.Time, expire time.Duration,
+) error {
+	m, err := proto.Marshal(msg)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	seq := msg.GetSeq()
+	ts := sendTime.Unix()
+	exp := int64(expire / time.Second)
+	expAt := ts + exp
+
+	// 消息自身的过期时间更早的话以其为准，已过期就没必要存储了
+	if msg.GetExpireAt() != nil {
+		msgExpAt := msg.GetExpireAt().GetSeconds()
+		if msgExpAt <= time.Now().Unix() {
+			return offline.ErrExpired
+		}
+		if msgExpAt < expAt {
+			expAt = msgExpAt
+		}
+	}
+
+	// 先写内容和引用计数，这样映射写入之后内容一定存在，和映射不一定在同一slot所以分开执行
+	if _, err := s.do(ctx, retainLua, ommKey(seq), omnKey(seq), omgKey(seq), m, expAt, nowMillis(), int64(genExpire/time.Second)); err != nil {
+		return err
+	}
+
+	// 再写映射，失败的话无法确定是否已写入，不能归还引用，最多是内容多留存到其过期
+	keys := append([]string{upomsKey(uid, platform, offline.PriorityOf(msg)), upockKey(uid, platform), uorlKey(uid)}, upomsKeys(uid, platform)...)
+	reply, err := s.do(ctx, writeLua, keysAndArgs(keys, seq, ts, exp, msg.GetCollapseKey())...)
+	if err != nil {
+		return err
+	}
+	var (
+		added    int
+		releases []string
+	)
+	vals, err := redis.Values(reply, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := redis.Scan(vals, &added, &releases); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// 已经记录过了或者被更新的消息折叠掉了，归还刚刚增加的引用
+	if added != 1 {
+		releases = append(releases, seq)
+	}
+
+	// 被折叠替换掉的消息不再被此映射引用，连同之前没能归还的一并归还
+	return s.releaseFor(ctx, uid, releases)
+}
+
+func (s *Store) do(ctx context.Context, script *redis.Script, keysAndArgs ...interface{}) (interface{}, error) {
+	conn, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	reply, err := script.Do(conn, keysAndArgs...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return reply, nil
+}
+
+// 返回 seq:content deleteFunc error
+// 没有离线消息了则deleteFunc为nil，读取到的消息可能全部已过期，此时msgs为空但deleteFunc不为nil
+func (s *Store) Read(ctx context.Context, uid string, platform string, expire time.Duration, readCount int64) ([]*msgpb.Message, func(context.Context) error, error) {
+	/*
+		- 根据当前时间算出未过期消息时间戳 `expirets = now-expire`
+		- - 按优先级从高到低 `ZRANGEBYSCORE msg/u:{uid1}/p:platform1/oms expirets +inf LIMIT 0 50` 获取头部50个未过期消息
+		- - 按节点分组pipeline执行`GET msg/om:{seq1}/m`拿到所有消息内容
+	*/
+	expTs := time.Now().Add(-expire).Unix()
+
+	seqs, err := s.rangeSeqs(ctx, uid, platform, expTs, readCount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(seqs) <= 0 {
+		return nil, nil, nil
+	}
+
+	ms, err := s.getContents(ctx, seqs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 消息自身已过期的跳过，但依然会被deleteFunc清理掉
+	msgs, err := decodeContents(seqs, ms)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	delete := func(ctx context.Context) error {
+		return s.Delete(ctx, uid, platform, seqs)
+	}
+	return msgs, delete, nil
+}
+
+// 按优先级从高到低获取最多readCount个未过期的seq，readCount<0则不限
+func (s *Store) rangeSeqs(ctx context.Context, uid string, platform string, expTs int64, readCount int64) ([]string, error) {
+	conn, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	seqs := []string{}
+	for _, key := range upomsKeys(uid, platform) {
+		count := readCount
+		if readCount >= 0 {
+			count = readCount - int64(len(seqs))
+			if count <= 0 {
+				break
+			}
+		}
+
+		ss, err := redis.Strings(
+			conn.Do("ZRANGEBYSCORE", key, expTs, "+inf", "LIMIT", "0", count),
+		)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		seqs = append(seqs, ss...)
+	}
+	return seqs, nil
+}
+
+// 获取消息内容，不存在的不会出现在结果里
+func (s *Store) getContents(ctx context.Context, seqs []string) (map[string][]byte, error) {
+	return s.getBySeqs(ctx, seqs, ommKey)
+}
+
+// 对各seq的keyOf(seq)执行GET，不存在的不会出现在结果里
+// 集群下不同slot的key不能MGET，所以按节点分组pipeline执行GET
+func (s *Store) getBySeqs(ctx context.Context, seqs []string, keyOf func(seq string) string) (map[string][]byte, error) {
+	keyToSeq := map[string]string{}
+	keys := make([]string, len(seqs))
+	for i, seq := range seqs {
+		keys[i] = keyOf(seq)
+		keyToSeq[keys[i]] = seq
+	}
+
+	ret := map[string][]byte{}
+	for _, group := range redispool.Partition(s.redisPool, keys) {
+		if err := func() error {
+			conn, err := s.redisPool.GetContext(ctx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer conn.Close()
+
+			for _, key := range group {
+				if err := conn.Send("GET", key); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+
+			if err := conn.Flush(); err != nil {
+				return errors.WithStack(err)
+			}
+
+			for _, key := range group {
+				m, err := redis.Bytes(conn.Receive())
+				if err == redis.ErrNil {
+					continue
+				}
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				ret[keyToSeq[key]] = m
+			}
+			return nil
+		}(); err != nil {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+// 按seqs的顺序解析消息内容，不存在或者已过期的跳过
+func decodeContents(seqs []string, ms map[string][]byte) ([]*msgpb.Message, error) {
+	now := time.Now().Unix()
+	msgs := []*msgpb.Message{}
+	for _, seq := range seqs {
+		m := ms[seq]
+		if m != nil {
+			pb := &msgpb.Message{}
+			if err := proto.Unmarshal(m, pb); err != nil {
+				return nil, errors.WithStack(err)
+			}
+
+			if pb.GetExpireAt() != nil && pb.GetExpireAt().GetSeconds() <= now {
+				continue
+			}
+
+			msgs = append(msgs, pb)
+		}
+	}
+	return msgs, nil
+}
+
+func (s *Store) Scan(ctx context.Context, uid string, platform string, expire time.Duration, cursor string, limit int64) ([]*msgpb.Message, string, error) {
+	if limit <= 0 {
+		return nil, "", errors.Errorf("limit must > 0")
+	}
+
+	cursorTs, cursorSeq, err := offline.DecodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	expTs := time.Now().Add(-expire).Unix()
+
+	// 多取一条用以判断是否还有更多
+	seqs, tss, err := s.scanSeqs(ctx, uid, platform, expTs, cursorTs, cursorSeq, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if int64(len(seqs)) > limit {
+		seqs, tss = seqs[:limit], tss[:limit]
+		next = offline.EncodeCursor(tss[limit-1], seqs[limit-1])
+	}
+
+	if len(seqs) <= 0 {
+		return nil, "", nil
+	}
+
+	ms, err := s.getContents(ctx, seqs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	msgs, err := decodeContents(seqs, ms)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return msgs, next, nil
+}
+
+// 返回游标之后的最多count个seq及其发出时间，不区分优先级
+func (s *Store) scanSeqs(ctx context.Context, uid string, platform string, expTs int64, cursorTs int64, cursorSeq string, count int64) ([]string, []int64, error) {
+	conn, err := s.redisPool.GetContext(ctx)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	// 各优先级分别取count个，合并之后再取前count个
+	type entry struct {
+		seq string
+		ts  int64
+	}
+	entries := []entry{}
+	for _, key := range upomsKeys(uid, platform) {
+		seqs, tss, err := scanKeySeqs(conn, key, expTs, cursorTs, cursorSeq, count)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range seqs {
+			entries = append(entries, entry{seq: seqs[i], ts: tss[i]})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].ts != entries[j].ts {
+			return entries[i].ts < entries[j].ts
+		}
+		return entries[i].seq < entries[j].seq
+	})
+	if int64(len(entries)) > count {
+		entries = entries[:count]
+	}
+
+	seqs := make([]string, len(entries))
+	tss := make([]int64, len(entries))
+	for i, e := range entries {
+		seqs[i], tss[i] = e.seq, e.ts
+	}
+	return seqs, tss, nil
+}
+
+// 返回某个映射记录里游标之后的最多count个seq及其发出时间
+// zset里相同分数的按member字典序排列，和游标的顺序一致
+func scanKeySeqs(conn redis.Conn, key string, expTs int64, cursorTs int64, cursorSeq string, count int64) ([]string, []int64, error) {
+	seqs := []string{}
+	tss := []int64{}
+
+	min := fmt.Sprint(expTs)
+	if cursorSeq != "" && cursorTs >= expTs {
+		// 和游标同一时间戳的，只要seq更大的
+		sameTs, err := redis.Strings(conn.Do("ZRANGEBYSCORE", key, cursorTs, cursorTs))
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		for _, seq := range sameTs {
+			if seq > cursorSeq && int64(len(seqs)) < count {
+				seqs = append(seqs, seq)
+				tss = append(tss, cursorTs)
+			}
+		}
+
+		min = fmt.Sprintf("(%d", cursorTs)
+	}
+
+	if int64(len(seqs)) >= count {
+		return seqs, tss, nil
+	}
+
+	vals, err := redis.Values(
+		conn.Do("ZRANGEBYSCORE", key, min, "+inf", "WITHSCORES", "LIMIT", "0", count-int64(len(seqs))),
+	)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	for len(vals) > 0 {
+		var (
+			seq string
+			ts  int64
+		)
+		vals, err = redis.Scan(vals, &seq, &ts)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		seqs = append(seqs, seq)
+		tss = append(tss, ts)
+	}
+
+	return seqs, tss, nil
+}
+
+func (s *Store) Count(ctx context.Context, uid string, platform string, expire time.Duration, byCategory bool) (int64, map[string]int64, error) {
+	expTs := time.Now().Add(-expire).Unix()
+
+	if !byCategory {
+		conn, err := s.redisPool.GetContext(ctx)
+		if err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		defer conn.Close()
+
+		var total int64
+		for _, key := range upomsKeys(uid, platform) {
+			n, err := redis.Int64(conn.Do("ZCOUNT", key, expTs, "+inf"))
+			if err != nil {
+				return 0, nil, errors.WithStack(err)
+			}
+			total += n
+		}
+		return total, nil, nil
+	}
+
+	// 需要分组的话只能把内容都读出来，LIMIT 0 -1 即为不限
+	seqs, err := s.rangeSeqs(ctx, uid, platform, expTs, -1)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if len(seqs) <= 0 {
+		return 0, map[string]int64{}, nil
+	}
+
+	ms, err := s.getContents(ctx, seqs)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	msgs, err := decodeContents(seqs, ms)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	total, categories := offline.CountByCategory(msgs)
+	return total, categories, nil
+}
@@ -0,0 +1,34 @@
+package redisoffline
+
+import (
+	"context"
+
+	"github.com/molon/gomsg/internal/pkg/offline"
+	"github.com/molon/gomsg/internal/pkg/redispool"
+)
+
+// 以redis实现的离线消息存储，结构见项目Readme
+type Store struct {
+	redisPool redispool.Pool
+}
+
+var _ offline.Store = (*Store)(nil)
+
+func InitStore(
+	ctx context.Context,
+	redisPool redispool.Pool,
+) (*Store, error) {
+	s := &Store{
+		redisPool: redisPool,
+	}
+
+	if err := s.init(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// 初始化需要用到的lua脚本
+func (s *Store) init(ctx context.Context) error {
+	return redispool.LoadScripts(ctx, s.redisPool, writeLua, retainLua, deleteLua, releaseLua, cleanLua)
+}
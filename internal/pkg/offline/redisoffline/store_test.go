@@ -0,0 +1,69 @@
+package redisoffline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/molon/gomsg/internal/pkg/offline"
+	"github.com/molon/gomsg/internal/pkg/offline/offlinetest"
+	"github.com/molon/gomsg/pb/msgpb"
+)
+
+func TestStore(t *testing.T) {
+	offlinetest.Run(t, func(t *testing.T) offline.Store {
+		mr, err := miniredis.Run()
+		if err != nil {
+			t.Fatalf("miniredis.Run: %v", err)
+		}
+		t.Cleanup(mr.Close)
+
+		pool := &redis.Pool{
+			Dial: func() (redis.Conn, error) {
+				return redis.Dial("tcp", mr.Addr())
+			},
+		}
+		t.Cleanup(func() { pool.Close() })
+
+		s, err := InitStore(context.Background(), pool)
+		if err != nil {
+			t.Fatalf("InitStore: %+v", err)
+		}
+		return s
+	})
+}
+
+// 内容和引用计数的过期时间只延长不缩短
+func TestRetainExtendsExpire(t *testing.T) {
+	s, mr := newInspectStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	msg := &msgpb.Message{Seq: "s1"}
+	for _, w := range []struct {
+		uid      string
+		platform string
+		expire   time.Duration
+	}{
+		{"u1", "mobile", time.Hour},
+		{"u1", "desktop", 2 * time.Hour},
+		{"u2", "mobile", 30 * time.Minute},
+	} {
+		if err := s.Write(ctx, w.uid, w.platform, msg, now, w.expire); err != nil {
+			t.Fatalf("Write: %+v", err)
+		}
+	}
+
+	for _, key := range []string{ommKey("s1"), omnKey("s1")} {
+		if ttl := mr.TTL(key); ttl < 2*time.Hour-time.Minute || ttl > 2*time.Hour {
+			t.Fatalf("TTL of %s: got %v, want about 2h", key, ttl)
+		}
+	}
+
+	mr.FastForward(90 * time.Minute)
+	if !mr.Exists(ommKey("s1")) {
+		t.Fatal("content expired with the earlier platform")
+	}
+}
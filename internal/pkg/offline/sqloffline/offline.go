@@ -0,0 +1,326 @@
+package sqloffline
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/molon/gomsg/internal/pkg/offline"
+	"github.com/molon/gomsg/pb/msgpb"
+	"github.com/molon/pkg/errors"
+)
+
+func (s *Store) Write(ctx context.Context,
+	uid string, platform string,
+	msg *msgpb.Message,
+	sendTime time.Time, expire time.Duration,
+) error {
+	m, err := proto.Marshal(msg)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	seq := msg.GetSeq()
+	ts := sendTime.Unix()
+	expAt := ts + int64(expire/time.Second)
+
+	// 消息自身的过期时间更早的话以其为准，已过期就没必要存储了
+	if msg.GetExpireAt() != nil {
+		msgExpAt := msg.GetExpireAt().GetSeconds()
+		if msgExpAt <= time.Now().Unix() {
+			return offline.ErrExpired
+		}
+		if msgExpAt < expAt {
+			expAt = msgExpAt
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer tx.Rollback()
+
+	// 先锁住内容行，避免其被并发的删除当作无引用而删掉
+	if err := s.lockMsgs(ctx, tx, []interface{}{seq}); err != nil {
+		return err
+	}
+
+	var replaced string
+	if collapseKey := msg.GetCollapseKey(); collapseKey != "" {
+		oseq, ok, err := s.upsertCollapsed(ctx, tx, uid, platform, seq, ts, int32(offline.PriorityOf(msg)), collapseKey)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		replaced = oseq
+	} else {
+		// 已经记录过了就什么都不用做
+		res, err := tx.ExecContext(ctx, s.insertIgnore(mapTable, "uid", "platform", "seq", "ts", "priority", "collapse_key"),
+			uid, platform, seq, ts, int32(offline.PriorityOf(msg)), "")
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if n == 0 {
+			return nil
+		}
+	}
+
+	// 内容可能已经被其他映射写入过，各平台的离线有效期不同，内容要保留到最晚的那个
+	if _, err := tx.ExecContext(ctx, s.insertIgnore(msgTable, "seq", "body", "expire_at"), seq, m, expAt); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := tx.ExecContext(ctx, s.rebind(fmt.Sprintf(
+		"UPDATE %s SET expire_at = ? WHERE seq = ? AND expire_at < ?", msgTable,
+	)), expAt, seq, expAt); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// 被折叠替换掉的，映射已经指向新消息，只需删除不再被引用的内容
+	if replaced != "" {
+		seqArgs := []interface{}{replaced}
+		if err := s.lockMsgs(ctx, tx, seqArgs); err != nil {
+			return err
+		}
+		if err := s.removeUnreferenced(ctx, tx, seqArgs); err != nil {
+			return err
+		}
+	}
+
+	return errors.WithStack(tx.Commit())
+}
+
+func (s *Store) Read(ctx context.Context, uid string, platform string, expire time.Duration, readCount int64) ([]*msgpb.Message, func(context.Context) error, error) {
+	now := time.Now()
+	expTs := now.Add(-expire).Unix()
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(fmt.Sprintf(
+		"SELECT m.seq, m.ts, b.body, b.expire_at FROM %s m LEFT JOIN %s b ON b.seq = m.seq "+
+			"WHERE m.uid = ? AND m.platform = ? AND m.ts >= ? ORDER BY m.priority DESC, m.ts, m.seq LIMIT ?",
+		mapTable, msgTable,
+	)), uid, platform, expTs, readCount)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	seqs, _, msgs, err := scanRows(rows, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(seqs) <= 0 {
+		return nil, nil, nil
+	}
+
+	delete := func(ctx context.Context) error {
+		return s.Delete(ctx, uid, platform, seqs)
+	}
+	return msgs, delete, nil
+}
+
+func (s *Store) Scan(ctx context.Context, uid string, platform string, expire time.Duration, cursor string, limit int64) ([]*msgpb.Message, string, error) {
+	if limit <= 0 {
+		return nil, "", errors.Errorf("limit must > 0")
+	}
+
+	cursorTs, cursorSeq, err := offline.DecodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	expTs := now.Add(-expire).Unix()
+
+	query := "SELECT m.seq, m.ts, b.body, b.expire_at FROM %s m LEFT JOIN %s b ON b.seq = m.seq " +
+		"WHERE m.uid = ? AND m.platform = ? AND m.ts >= ?"
+	args := []interface{}{uid, platform, expTs}
+	if cursorSeq != "" {
+		query += " AND (m.ts > ? OR (m.ts = ? AND m.seq > ?))"
+		args = append(args, cursorTs, cursorTs, cursorSeq)
+	}
+	query += " ORDER BY m.ts, m.seq LIMIT ?"
+	// 多取一条用以判断是否还有更多
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(fmt.Sprintf(query, mapTable, msgTable)), args...)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	seqs, tss, msgs, err := scanRows(rows, now)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if int64(len(seqs)) > limit {
+		next = offline.EncodeCursor(tss[limit-1], seqs[limit-1])
+		// 多取的那条不返回
+		if len(msgs) > 0 && msgs[len(msgs)-1].GetSeq() == seqs[limit] {
+			msgs = msgs[:len(msgs)-1]
+		}
+	}
+
+	return msgs, next, nil
+}
+
+func (s *Store) Count(ctx context.Context, uid string, platform string, expire time.Duration, byCategory bool) (int64, map[string]int64, error) {
+	now := time.Now()
+	expTs := now.Add(-expire).Unix()
+
+	if !byCategory {
+		var n int64
+		if err := s.db.QueryRowContext(ctx, s.rebind(fmt.Sprintf(
+			"SELECT COUNT(*) FROM %s WHERE uid = ? AND platform = ? AND ts >= ?",
+			mapTable,
+		)), uid, platform, expTs).Scan(&n); err != nil {
+			return 0, nil, errors.WithStack(err)
+		}
+		return n, nil, nil
+	}
+
+	// 需要分组的话只能把内容都读出来
+	rows, err := s.db.QueryContext(ctx, s.rebind(fmt.Sprintf(
+		"SELECT m.seq, m.ts, b.body, b.expire_at FROM %s m LEFT JOIN %s b ON b.seq = m.seq "+
+			"WHERE m.uid = ? AND m.platform = ? AND m.ts >= ?",
+		mapTable, msgTable,
+	)), uid, platform, expTs)
+	if err != nil {
+		return 0, nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	_, _, msgs, err := scanRows(rows, now)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	total, categories := offline.CountByCategory(msgs)
+	return total, categories, nil
+}
+
+// 读取 seq,ts,body,expire_at 的结果，返回所有seq及其发出时间，以及其中未过期的消息
+func scanRows(rows *sql.Rows, now time.Time) ([]string, []int64, []*msgpb.Message, error) {
+	seqs := []string{}
+	tss := []int64{}
+	msgs := []*msgpb.Message{}
+	for rows.Next() {
+		var (
+			seq   string
+			ts    int64
+			body  []byte
+			expAt sql.NullInt64
+		)
+		if err := rows.Scan(&seq, &ts, &body, &expAt); err != nil {
+			return nil, nil, nil, errors.WithStack(err)
+		}
+		seqs = append(seqs, seq)
+		tss = append(tss, ts)
+
+		// 内容已过期的跳过
+		if body == nil || !expAt.Valid || expAt.Int64 <= now.Unix() {
+			continue
+		}
+
+		pb := &msgpb.Message{}
+		if err := proto.Unmarshal(body, pb); err != nil {
+			return nil, nil, nil, errors.WithStack(err)
+		}
+
+		if pb.GetExpireAt() != nil && pb.GetExpireAt().GetSeconds() <= now.Unix() {
+			continue
+		}
+
+		msgs = append(msgs, pb)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, nil, errors.WithStack(err)
+	}
+
+	return seqs, tss, msgs, nil
+}
+
+func (s *Store) Delete(ctx context.Context, uid string, platform string, seqs []string) error {
+	if len(seqs) <= 0 {
+		return errors.Errorf("seqs is empty")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer tx.Rollback()
+
+	if err := s.removeSeqs(ctx, tx, uid, platform, seqs); err != nil {
+		return err
+	}
+
+	return errors.WithStack(tx.Commit())
+}
+
+// 清理过期的以及超出最大数目的离线消息，返回各平台过期被清理的以及超出数目被清理的seq列表
+func (s *Store) Clean(ctx context.Context, uid string, expire time.Duration, platformToMaxOMCount map[string]int) (map[string][]string, map[string][]string, error) {
+	if len(platformToMaxOMCount) <= 0 {
+		return nil, nil, errors.Errorf("platformToMaxOMCount is empty")
+	}
+
+	expTs := time.Now().Add(-expire).Unix()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	defer tx.Rollback()
+
+	platformToExpiredSeqs := map[string][]string{}
+	platformToEvictedSeqs := map[string][]string{}
+	for platform, maxOMCount := range platformToMaxOMCount {
+		expired, err := querySeqs(ctx, tx, s.rebind(fmt.Sprintf(
+			"SELECT seq FROM %s WHERE uid = ? AND platform = ? AND ts < ? ORDER BY ts, seq",
+			mapTable,
+		)), uid, platform, expTs)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// 未过期的里面只保留maxOMCount个，优先保留高优先级的，同一优先级保留较新的
+		var overflowed []string
+		if maxOMCount >= 0 {
+			overflowed, err = querySeqs(ctx, tx, s.rebind(fmt.Sprintf(
+				"SELECT seq FROM %s WHERE uid = ? AND platform = ? AND ts >= ? ORDER BY priority DESC, ts DESC, seq DESC LIMIT ? OFFSET ?",
+				mapTable,
+			)), uid, platform, expTs, math.MaxInt32, maxOMCount)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if err := s.removeSeqs(ctx, tx, uid, platform, append(append([]string{}, overflowed...), expired...)); err != nil {
+			return nil, nil, err
+		}
+
+		if len(expired) > 0 {
+			platformToExpiredSeqs[platform] = expired
+		}
+		if len(overflowed) > 0 {
+			platformToEvictedSeqs[platform] = overflowed
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	return platformToExpiredSeqs, platformToEvictedSeqs, nil
+}
@@ -0,0 +1,427 @@
+// 以SQL数据库实现的离线消息存储，支持 postgres/mysql/sqlite3
+// 适合离线消息保存较久、不希望全部放在redis内存里的场景
+package sqloffline
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/molon/gomsg/internal/pkg/offline"
+	"github.com/molon/pkg/errors"
+)
+
+/*
+// 某用户在某平台的离线消息映射，ts为消息发出时间，priority为消息优先级，collapse_key为消息折叠key
+// (uid, platform, collapse_key) 对非空的折叠key唯一
+gomsg_offline_map (uid, platform, seq, ts, priority, collapse_key)
+
+// 离线消息内容，不再被映射引用时删除
+gomsg_offline_msg (seq, body, expire_at)
+*/
+
+const (
+	mapTable = "gomsg_offline_map"
+	msgTable = "gomsg_offline_msg"
+
+	// 单条语句里IN的最大参数数目
+	maxInArgs = 500
+)
+
+var schemas = map[string][]string{
+	"postgres": {
+		`CREATE TABLE IF NOT EXISTS gomsg_offline_map (
+			uid VARCHAR(128) NOT NULL,
+			platform VARCHAR(64) NOT NULL,
+			seq VARCHAR(64) NOT NULL,
+			ts BIGINT NOT NULL,
+			priority INTEGER NOT NULL DEFAULT 0,
+			collapse_key VARCHAR(128) NOT NULL DEFAULT '',
+			PRIMARY KEY (uid, platform, seq)
+		)`,
+		`CREATE INDEX IF NOT EXISTS gomsg_offline_map_ts ON gomsg_offline_map (uid, platform, ts, seq)`,
+		`CREATE INDEX IF NOT EXISTS gomsg_offline_map_seq ON gomsg_offline_map (seq)`,
+		`CREATE TABLE IF NOT EXISTS gomsg_offline_msg (
+			seq VARCHAR(64) NOT NULL PRIMARY KEY,
+			body BYTEA NOT NULL,
+			expire_at BIGINT NOT NULL
+		)`,
+	},
+	"mysql": {
+		`CREATE TABLE IF NOT EXISTS gomsg_offline_map (
+			uid VARCHAR(128) NOT NULL,
+			platform VARCHAR(64) NOT NULL,
+			seq VARCHAR(64) NOT NULL,
+			ts BIGINT NOT NULL,
+			priority INT NOT NULL DEFAULT 0,
+			collapse_key VARCHAR(128) NOT NULL DEFAULT '',
+			PRIMARY KEY (uid, platform, seq),
+			INDEX gomsg_offline_map_ts (uid, platform, ts, seq),
+			INDEX gomsg_offline_map_seq (seq)
+		)`,
+		`CREATE TABLE IF NOT EXISTS gomsg_offline_msg (
+			seq VARCHAR(64) NOT NULL PRIMARY KEY,
+			body MEDIUMBLOB NOT NULL,
+			expire_at BIGINT NOT NULL
+		)`,
+	},
+	"sqlite3": {
+		`CREATE TABLE IF NOT EXISTS gomsg_offline_map (
+			uid TEXT NOT NULL,
+			platform TEXT NOT NULL,
+			seq TEXT NOT NULL,
+			ts INTEGER NOT NULL,
+			priority INTEGER NOT NULL DEFAULT 0,
+			collapse_key TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (uid, platform, seq)
+		)`,
+		`CREATE INDEX IF NOT EXISTS gomsg_offline_map_ts ON gomsg_offline_map (uid, platform, ts, seq)`,
+		`CREATE INDEX IF NOT EXISTS gomsg_offline_map_seq ON gomsg_offline_map (seq)`,
+		`CREATE TABLE IF NOT EXISTS gomsg_offline_msg (
+			seq TEXT NOT NULL PRIMARY KEY,
+			body BLOB NOT NULL,
+			expire_at INTEGER NOT NULL
+		)`,
+	},
+}
+
+type Store struct {
+	db      *sql.DB
+	dialect string
+}
+
+var _ offline.Store = (*Store)(nil)
+
+// dialect 可选 postgres/mysql/sqlite3，需要和db的驱动对应，表不存在则会创建
+func InitStore(ctx context.Context, db *sql.DB, dialect string) (*Store, error) {
+	stmts, ok := schemas[dialect]
+	if !ok {
+		return nil, errors.Errorf("unknown sql dialect: %s", dialect)
+	}
+
+	for _, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	// 之前创建的映射表没有这些列，补上
+	for _, col := range addedMapColumns {
+		if err := addColumn(ctx, db, mapTable, col[0], col[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ensureCollapseIndex(ctx, db, dialect); err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		db:      db,
+		dialect: dialect,
+	}, nil
+}
+
+const collapseIndex = "gomsg_offline_map_collapse"
+
+// 同一用户同一平台同一折叠key只能有一条映射，没有折叠key的不受限
+// mysql不支持部分索引，借助空折叠key为NULL的生成列
+var collapseIndexStmts = map[string][]string{
+	"postgres": {
+		`CREATE UNIQUE INDEX IF NOT EXISTS gomsg_offline_map_collapse ON gomsg_offline_map (uid, platform, collapse_key) WHERE collapse_key <> ''`,
+	},
+	"mysql": {
+		`CREATE UNIQUE INDEX gomsg_offline_map_collapse ON gomsg_offline_map (uid, platform, collapse_uk)`,
+	},
+	"sqlite3": {
+		`CREATE UNIQUE INDEX IF NOT EXISTS gomsg_offline_map_collapse ON gomsg_offline_map (uid, platform, collapse_key) WHERE collapse_key <> ''`,
+	},
+}
+
+// 删除同一折叠key里除了最新的之外的映射，之前没有唯一索引时并发写入可能留下这种数据
+var collapseDedupStmts = map[string]string{
+	"postgres": `DELETE FROM gomsg_offline_map a WHERE a.collapse_key <> '' AND EXISTS (
+		SELECT 1 FROM gomsg_offline_map b WHERE b.uid = a.uid AND b.platform = a.platform AND b.collapse_key = a.collapse_key
+		AND (b.ts > a.ts OR (b.ts = a.ts AND b.seq > a.seq))
+	)`,
+	"mysql": `DELETE a FROM gomsg_offline_map a JOIN gomsg_offline_map b
+		ON b.uid = a.uid AND b.platform = a.platform AND b.collapse_key = a.collapse_key
+		AND (b.ts > a.ts OR (b.ts = a.ts AND b.seq > a.seq))
+		WHERE a.collapse_key <> ''`,
+	"sqlite3": `DELETE FROM gomsg_offline_map WHERE collapse_key <> '' AND EXISTS (
+		SELECT 1 FROM gomsg_offline_map b WHERE b.uid = gomsg_offline_map.uid AND b.platform = gomsg_offline_map.platform
+		AND b.collapse_key = gomsg_offline_map.collapse_key
+		AND (b.ts > gomsg_offline_map.ts OR (b.ts = gomsg_offline_map.ts AND b.seq > gomsg_offline_map.seq))
+	)`,
+}
+
+// 折叠key的唯一索引不存在则创建，创建前先清理重复的映射以及因此不再被引用的内容
+func ensureCollapseIndex(ctx context.Context, db *sql.DB, dialect string) error {
+	exists, err := indexExists(ctx, db, dialect, mapTable, collapseIndex)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	if dialect == "mysql" {
+		if err := addColumn(ctx, db, mapTable, "collapse_uk", "VARCHAR(128) AS (NULLIF(collapse_key, '')) STORED"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := db.ExecContext(ctx, collapseDedupStmts[dialect]); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(
+		"DELETE FROM %s WHERE NOT EXISTS (SELECT 1 FROM %s WHERE %s.seq = %s.seq)",
+		msgTable, mapTable, mapTable, msgTable,
+	)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, stmt := range collapseIndexStmts[dialect] {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			// 多个实例同时启动时可能已经被其他实例创建了
+			if exists, _ := indexExists(ctx, db, dialect, mapTable, collapseIndex); exists {
+				return nil
+			}
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func indexExists(ctx context.Context, db *sql.DB, dialect string, table string, index string) (bool, error) {
+	var query string
+	switch dialect {
+	case "postgres":
+		query = "SELECT COUNT(*) FROM pg_indexes WHERE tablename = $1 AND indexname = $2"
+	case "mysql":
+		query = "SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?"
+	default:
+		query = "SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND name = ?"
+	}
+
+	var n int
+	if err := db.QueryRowContext(ctx, query, table, index).Scan(&n); err != nil {
+		return false, errors.WithStack(err)
+	}
+	return n > 0, nil
+}
+
+// 映射表后来加上的列: 列名 定义
+var addedMapColumns = [][2]string{
+	{"priority", "INTEGER NOT NULL DEFAULT 0"},
+	{"collapse_key", "VARCHAR(128) NOT NULL DEFAULT ''"},
+}
+
+// 列不存在则添加
+// 各数据库对 ADD COLUMN IF NOT EXISTS 的支持不一，所以先查询一下
+func addColumn(ctx context.Context, db *sql.DB, table string, column string, definition string) error {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE 1 = 0", column, table))
+	if err == nil {
+		rows.Close()
+		return nil
+	}
+
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(
+		"ALTER TABLE %s ADD COLUMN %s %s", table, column, definition,
+	)); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// 已存在则忽略的插入语句
+func (s *Store) insertIgnore(table string, columns ...string) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	values := fmt.Sprintf("%s (%s) VALUES (%s)", table, strings.Join(columns, ", "), placeholders)
+
+	switch s.dialect {
+	case "postgres":
+		return s.rebind("INSERT INTO " + values + " ON CONFLICT DO NOTHING")
+	case "mysql":
+		return "INSERT IGNORE INTO " + values
+	default:
+		return "INSERT OR IGNORE INTO " + values
+	}
+}
+
+// postgres的占位符为 $n
+func (s *Store) rebind(query string) string {
+	if s.dialect != "postgres" {
+		return query
+	}
+
+	var (
+		b strings.Builder
+		n int
+	)
+	for _, c := range query {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func inPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// 在事务里锁住若干消息内容的行，直到事务结束
+// 写入映射和删除内容都要先锁住内容行，否则并发时删除方看不到未提交的新映射，会删掉仍被引用的内容
+// sqlite3 的写事务本身就是串行的，也不支持 FOR UPDATE
+func (s *Store) lockMsgs(ctx context.Context, tx *sql.Tx, seqArgs []interface{}) error {
+	if s.dialect == "sqlite3" {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, s.rebind(fmt.Sprintf(
+		"SELECT seq FROM %s WHERE seq IN (%s) ORDER BY seq FOR UPDATE",
+		msgTable, inPlaceholders(len(seqArgs)),
+	)), seqArgs...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	rows.Close()
+	return errors.WithStack(rows.Err())
+}
+
+// 在事务里删除某用户某平台的若干映射，并删除不再被引用的消息内容
+func (s *Store) removeSeqs(ctx context.Context, tx *sql.Tx, uid string, platform string, seqs []string) error {
+	// 排序后按顺序加锁，减少并发删除时的死锁
+	seqs = append([]string(nil), seqs...)
+	sort.Strings(seqs)
+
+	for len(seqs) > 0 {
+		n := len(seqs)
+		if n > maxInArgs {
+			n = maxInArgs
+		}
+		chunk := seqs[:n]
+		seqs = seqs[n:]
+
+		args := []interface{}{uid, platform}
+		seqArgs := make([]interface{}, len(chunk))
+		for i, seq := range chunk {
+			seqArgs[i] = seq
+		}
+		args = append(args, seqArgs...)
+
+		if err := s.lockMsgs(ctx, tx, seqArgs); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, s.rebind(fmt.Sprintf(
+			"DELETE FROM %s WHERE uid = ? AND platform = ? AND seq IN (%s)",
+			mapTable, inPlaceholders(len(chunk)),
+		)), args...); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if err := s.removeUnreferenced(ctx, tx, seqArgs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 在事务里删除若干不再被映射引用的消息内容，调用前需已锁住内容行
+func (s *Store) removeUnreferenced(ctx context.Context, tx *sql.Tx, seqArgs []interface{}) error {
+	if _, err := tx.ExecContext(ctx, s.rebind(fmt.Sprintf(
+		"DELETE FROM %s WHERE seq IN (%s) AND NOT EXISTS (SELECT 1 FROM %s WHERE %s.seq = %s.seq)",
+		msgTable, inPlaceholders(len(seqArgs)), mapTable, mapTable, msgTable,
+	)), seqArgs...); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// 写入带折叠key的映射，由 (uid, platform, collapse_key) 的唯一索引保证同一折叠key只有一条
+// 已有的发出时间不比此消息晚则将其替换为此消息，返回被替换的seq，ok为false表示已有相同或者更新的消息，不需要写入
+func (s *Store) upsertCollapsed(ctx context.Context, tx *sql.Tx,
+	uid string, platform string, seq string, ts int64, priority int32, collapseKey string,
+) (string, bool, error) {
+	// 冲突的映射在查询之前可能被并发删除，重试几次
+	for i := 0; i < 3; i++ {
+		res, err := tx.ExecContext(ctx, s.insertIgnore(mapTable, "uid", "platform", "seq", "ts", "priority", "collapse_key"),
+			uid, platform, seq, ts, priority, collapseKey)
+		if err != nil {
+			return "", false, errors.WithStack(err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return "", false, errors.WithStack(err)
+		}
+		if n > 0 {
+			return "", true, nil
+		}
+
+		// 冲突的是此消息自身或者同一折叠key的其他消息，锁住再比较
+		var (
+			oseq string
+			ots  int64
+		)
+		err = tx.QueryRowContext(ctx, s.rebind(fmt.Sprintf(
+			"SELECT seq, ts FROM %s WHERE uid = ? AND platform = ? AND collapse_key = ?%s",
+			mapTable, s.forUpdate(),
+		)), uid, platform, collapseKey).Scan(&oseq, &ots)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return "", false, errors.WithStack(err)
+		}
+		if oseq == seq || ots > ts {
+			return "", false, nil
+		}
+
+		if _, err := tx.ExecContext(ctx, s.rebind(fmt.Sprintf(
+			"UPDATE %s SET seq = ?, ts = ?, priority = ? WHERE uid = ? AND platform = ? AND seq = ?",
+			mapTable,
+		)), seq, ts, priority, uid, platform, oseq); err != nil {
+			return "", false, errors.WithStack(err)
+		}
+		return oseq, true, nil
+	}
+
+	return "", false, errors.Errorf("write collapsed offline message %s of %s(%s) conflicted too many times", seq, uid, platform)
+}
+
+func (s *Store) forUpdate() string {
+	if s.dialect == "sqlite3" {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+func querySeqs(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	seqs := []string{}
+	for rows.Next() {
+		var seq string
+		if err := rows.Scan(&seq); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		seqs = append(seqs, seq)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return seqs, nil
+}
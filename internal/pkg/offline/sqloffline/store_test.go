@@ -0,0 +1,105 @@
+package sqloffline
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/molon/gomsg/internal/pkg/offline"
+	"github.com/molon/gomsg/internal/pkg/offline/offlinetest"
+	"github.com/molon/gomsg/pb/msgpb"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	// 内存数据库每个连接各自独立，只能用一个连接
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestStore(t *testing.T) {
+	offlinetest.Run(t, func(t *testing.T) offline.Store {
+		s, err := InitStore(context.Background(), openDB(t), "sqlite3")
+		if err != nil {
+			t.Fatalf("InitStore: %+v", err)
+		}
+		return s
+	})
+}
+
+// 没有唯一索引时留下的重复折叠映射，升级时只保留最新的，之后也不会再重复
+func TestCollapseIndex(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	for _, stmt := range schemas["sqlite3"] {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("create table: %v", err)
+		}
+	}
+	for _, row := range []struct {
+		seq string
+		ts  int64
+	}{{"s1", 1}, {"s2", 3}, {"s3", 2}} {
+		if _, err := db.Exec("INSERT INTO gomsg_offline_map (uid, platform, seq, ts, collapse_key) VALUES ('u1', 'mobile', ?, ?, 'k1')", row.seq, row.ts); err != nil {
+			t.Fatalf("insert map: %v", err)
+		}
+		if _, err := db.Exec("INSERT INTO gomsg_offline_msg (seq, body, expire_at) VALUES (?, '', ?)", row.seq, time.Now().Add(time.Hour).Unix()); err != nil {
+			t.Fatalf("insert msg: %v", err)
+		}
+	}
+
+	s, err := InitStore(ctx, db, "sqlite3")
+	if err != nil {
+		t.Fatalf("InitStore: %+v", err)
+	}
+
+	seqsOf := func(query string) []string {
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatalf("query: %v", err)
+		}
+		defer rows.Close()
+		seqs := []string{}
+		for rows.Next() {
+			var seq string
+			if err := rows.Scan(&seq); err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+			seqs = append(seqs, seq)
+		}
+		return seqs
+	}
+	check := func(want string) {
+		t.Helper()
+		if seqs := seqsOf("SELECT seq FROM gomsg_offline_map"); len(seqs) != 1 || seqs[0] != want {
+			t.Fatalf("map seqs = %v, want [%s]", seqs, want)
+		}
+		if seqs := seqsOf("SELECT seq FROM gomsg_offline_msg"); len(seqs) != 1 || seqs[0] != want {
+			t.Fatalf("msg seqs = %v, want [%s]", seqs, want)
+		}
+	}
+	check("s2")
+
+	if _, err := db.Exec("INSERT INTO gomsg_offline_map (uid, platform, seq, ts, collapse_key) VALUES ('u1', 'mobile', 's4', 4, 'k1')"); err == nil {
+		t.Fatalf("duplicate collapse key should be rejected by the unique index")
+	}
+
+	// 更旧的被忽略，更新的替换掉已有的
+	write := func(seq string, ts int64) {
+		msg := &msgpb.Message{Seq: seq, CollapseKey: "k1"}
+		if err := s.Write(ctx, "u1", "mobile", msg, time.Unix(ts, 0), time.Hour); err != nil {
+			t.Fatalf("Write %s: %+v", seq, err)
+		}
+	}
+	write("s5", 2)
+	check("s2")
+	write("s6", 5)
+	check("s6")
+}
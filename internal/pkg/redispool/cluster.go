@@ -0,0 +1,427 @@
+package redispool
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/molon/pkg/errors"
+)
+
+// 单条命令最多跟随的重定向次数
+const maxRedirects = 3
+
+// 简单的redis集群客户端，按key所在slot路由到对应的主节点，遇到MOVED/ASK时重定向
+// 每个节点一个 *redis.Pool，由newPool创建
+type Cluster struct {
+	startupAddrs []string
+	newPool      func(addr string) *redis.Pool
+
+	mu    sync.RWMutex
+	slots [hashSlots]string
+	pools map[string]*redis.Pool
+
+	refreshing int32
+}
+
+// addrs 为部分集群节点地址即可，会从中获取完整的slot分布
+func NewCluster(ctx context.Context, addrs []string, newPool func(addr string) *redis.Pool) (*Cluster, error) {
+	if len(addrs) <= 0 {
+		return nil, errors.Errorf("cluster addrs is empty")
+	}
+
+	c := &Cluster{
+		startupAddrs: addrs,
+		newPool:      newPool,
+		pools:        map[string]*redis.Pool{},
+	}
+
+	if err := c.Refresh(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// 从已知节点里获取最新的slot分布，有一个成功即可
+func (c *Cluster) Refresh(ctx context.Context) error {
+	c.mu.RLock()
+	addrs := append([]string{}, c.startupAddrs...)
+	for addr := range c.pools {
+		addrs = append(addrs, addr)
+	}
+	c.mu.RUnlock()
+
+	var lastErr error
+	for _, addr := range addrs {
+		slots, err := c.fetchSlots(ctx, addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		c.mu.Lock()
+		c.slots = *slots
+		c.mu.Unlock()
+		return nil
+	}
+
+	return lastErr
+}
+
+func (c *Cluster) fetchSlots(ctx context.Context, addr string) (*[hashSlots]string, error) {
+	conn, err := c.getPool(addr).GetContext(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	vals, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	slots := &[hashSlots]string{}
+	for _, val := range vals {
+		// [start, end, [host, port, id], 从节点...]
+		rng, err := redis.Values(val, nil)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		var (
+			start, end int
+			master     []interface{}
+		)
+		if _, err := redis.Scan(rng, &start, &end, &master); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		var (
+			host string
+			port int
+		)
+		if _, err := redis.Scan(master, &host, &port); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		// 为空表示和当前节点的host一致
+		if host == "" {
+			host, _, _ = net.SplitHostPort(addr)
+		}
+		node := net.JoinHostPort(host, strconv.Itoa(port))
+
+		for slot := start; slot <= end && slot < hashSlots; slot++ {
+			slots[slot] = node
+		}
+	}
+
+	return slots, nil
+}
+
+// 后台刷新slot分布，同一时间只有一个在进行
+func (c *Cluster) refreshAsync() {
+	if !atomic.CompareAndSwapInt32(&c.refreshing, 0, 1) {
+		return
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&c.refreshing, 0)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		c.Refresh(ctx)
+	}()
+}
+
+func (c *Cluster) addrOf(slot int) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if addr := c.slots[slot]; addr != "" {
+		return addr
+	}
+	// 还不知道的话随便找一个，会被重定向
+	return c.startupAddrs[0]
+}
+
+// 当前已知的所有主节点
+func (c *Cluster) masterAddrs() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	addrs := []string{}
+	seen := map[string]bool{}
+	for _, addr := range c.slots {
+		if addr != "" && !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+func (c *Cluster) setSlot(slot int, addr string) {
+	c.mu.Lock()
+	c.slots[slot] = addr
+	c.mu.Unlock()
+}
+
+func (c *Cluster) getPool(addr string) *redis.Pool {
+	c.mu.RLock()
+	p, ok := c.pools[addr]
+	c.mu.RUnlock()
+	if ok {
+		return p
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p, ok := c.pools[addr]; ok {
+		return p
+	}
+	p = c.newPool(addr)
+	c.pools[addr] = p
+	return p
+}
+
+// 返回的连接在第一条带key的命令执行时才会绑定到对应节点
+func (c *Cluster) GetContext(ctx context.Context) (redis.Conn, error) {
+	return &clusterConn{
+		cluster: c,
+		ctx:     ctx,
+	}, nil
+}
+
+func (c *Cluster) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for addr, p := range c.pools {
+		if e := p.Close(); e != nil && err == nil {
+			err = errors.WithStack(e)
+		}
+		delete(c.pools, addr)
+	}
+	return err
+}
+
+type command struct {
+	name string
+	args []interface{}
+}
+
+// 第一条带key的命令决定连接的节点，之后的命令都在此节点执行
+// 绑定之前发送的不带key的命令(例如MULTI)会暂存，绑定之后再发送
+type clusterConn struct {
+	cluster *Cluster
+	ctx     context.Context
+
+	conn      redis.Conn
+	pending   []command
+	pipelined bool
+}
+
+func (cc *clusterConn) bind(addr string) error {
+	conn, err := cc.cluster.getPool(addr).GetContext(cc.ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	cc.conn = conn
+
+	for _, cmd := range cc.pending {
+		if err := conn.Send(cmd.name, cmd.args...); err != nil {
+			return err
+		}
+		cc.pipelined = true
+	}
+	cc.pending = nil
+
+	return nil
+}
+
+func (cc *clusterConn) bindFor(name string, args []interface{}) error {
+	if cc.conn != nil {
+		return nil
+	}
+
+	slot := 0
+	if key, ok := commandKey(name, args); ok {
+		slot = Slot(key)
+	}
+	return cc.bind(cc.cluster.addrOf(slot))
+}
+
+func (cc *clusterConn) Do(name string, args ...interface{}) (interface{}, error) {
+	if err := cc.bindFor(name, args); err != nil {
+		return nil, err
+	}
+
+	reply, err := cc.conn.Do(name, args...)
+
+	// pipeline的结果混在一起，没法安全重试，只修正slot分布
+	if cc.pipelined || name == "" {
+		cc.pipelined = false
+		cc.checkRedirect(err)
+		return reply, err
+	}
+
+	for i := 0; i < maxRedirects; i++ {
+		kind, slot, addr, ok := parseRedirect(err)
+		if !ok {
+			break
+		}
+
+		// ASK 只对这一条命令生效
+		if kind == "ASK" {
+			reply, err = cc.askDo(addr, name, args)
+			continue
+		}
+
+		cc.cluster.setSlot(slot, addr)
+		cc.cluster.refreshAsync()
+
+		cc.conn.Close()
+		cc.conn = nil
+		if err := cc.bind(addr); err != nil {
+			return nil, err
+		}
+		reply, err = cc.conn.Do(name, args...)
+	}
+
+	return reply, err
+}
+
+func (cc *clusterConn) askDo(addr string, name string, args []interface{}) (interface{}, error) {
+	conn, err := cc.cluster.getPool(addr).GetContext(cc.ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Do("ASKING"); err != nil {
+		return nil, err
+	}
+	return conn.Do(name, args...)
+}
+
+func (cc *clusterConn) Send(name string, args ...interface{}) error {
+	if cc.conn == nil {
+		key, ok := commandKey(name, args)
+		if !ok {
+			cc.pending = append(cc.pending, command{name: name, args: args})
+			return nil
+		}
+		if err := cc.bind(cc.cluster.addrOf(Slot(key))); err != nil {
+			return err
+		}
+	}
+
+	cc.pipelined = true
+	return cc.conn.Send(name, args...)
+}
+
+func (cc *clusterConn) Flush() error {
+	if cc.conn == nil {
+		if len(cc.pending) <= 0 {
+			return nil
+		}
+		if err := cc.bind(cc.cluster.addrOf(0)); err != nil {
+			return err
+		}
+	}
+	return cc.conn.Flush()
+}
+
+func (cc *clusterConn) Receive() (interface{}, error) {
+	if cc.conn == nil {
+		return nil, errors.Errorf("no pending replies")
+	}
+
+	reply, err := cc.conn.Receive()
+	cc.checkRedirect(err)
+	return reply, err
+}
+
+func (cc *clusterConn) Close() error {
+	if cc.conn == nil {
+		return nil
+	}
+	return cc.conn.Close()
+}
+
+func (cc *clusterConn) Err() error {
+	if cc.conn == nil {
+		return nil
+	}
+	return cc.conn.Err()
+}
+
+func (cc *clusterConn) checkRedirect(err error) {
+	if kind, slot, addr, ok := parseRedirect(err); ok && kind == "MOVED" {
+		cc.cluster.setSlot(slot, addr)
+		cc.cluster.refreshAsync()
+	}
+}
+
+// MOVED/ASK 错误的格式为 `MOVED 3999 127.0.0.1:6381`
+func parseRedirect(err error) (kind string, slot int, addr string, ok bool) {
+	rerr, isRedisErr := err.(redis.Error)
+	if !isRedisErr {
+		return "", 0, "", false
+	}
+
+	fields := strings.Fields(string(rerr))
+	if len(fields) != 3 || (fields[0] != "MOVED" && fields[0] != "ASK") {
+		return "", 0, "", false
+	}
+
+	slot, e := strconv.Atoi(fields[1])
+	if e != nil || slot < 0 || slot >= hashSlots {
+		return "", 0, "", false
+	}
+
+	return fields[0], slot, fields[2], true
+}
+
+// 命令用于路由的key，不带key的命令返回false
+func commandKey(name string, args []interface{}) (string, bool) {
+	switch strings.ToUpper(name) {
+	case "", "MULTI", "EXEC", "DISCARD", "PING", "ECHO", "INFO", "SCRIPT", "CLUSTER", "ASKING":
+		return "", false
+	case "EVAL", "EVALSHA":
+		// EVALSHA sha numkeys key...
+		if len(args) < 3 {
+			return "", false
+		}
+		n, err := strconv.Atoi(argString(args[1]))
+		if err != nil || n <= 0 {
+			return "", false
+		}
+		return argString(args[2]), true
+	default:
+		if len(args) < 1 {
+			return "", false
+		}
+		return argString(args[0]), true
+	}
+}
+
+func argString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,96 @@
+// redis连接的来源，单机为 *redis.Pool，集群为 *Cluster
+// 业务代码只依赖 Pool 接口，需要pipeline多个key时通过 Partition 分组
+package redispool
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/molon/pkg/errors"
+)
+
+type Pool interface {
+	GetContext(ctx context.Context) (redis.Conn, error)
+	Close() error
+}
+
+var (
+	_ Pool = (*redis.Pool)(nil)
+	_ Pool = (*Cluster)(nil)
+)
+
+// 将keys按所在节点分组，保持原有顺序，同一组的命令可以在同一连接里pipeline
+// 单机时所有key都在同一组
+// 注意同一组里的key不一定在同一slot，涉及多个key的单条命令或者lua脚本依然需要用 {hashtag} 保证
+func Partition(p Pool, keys []string) [][]string {
+	if len(keys) <= 0 {
+		return nil
+	}
+
+	c, ok := p.(*Cluster)
+	if !ok {
+		return [][]string{keys}
+	}
+
+	groups := [][]string{}
+	addrToIdx := map[string]int{}
+	for _, key := range keys {
+		addr := c.addrOf(Slot(key))
+		idx, ok := addrToIdx[addr]
+		if !ok {
+			idx = len(groups)
+			addrToIdx[addr] = idx
+			groups = append(groups, nil)
+		}
+		groups[idx] = append(groups[idx], key)
+	}
+	return groups
+}
+
+// 预先加载lua脚本，这样之后在pipeline里可以直接用 SendHash
+// 集群时需要加载到每个节点，之后新加入的节点没有的话，SendHash 的结果需要以 IsNoScript 判断并重新执行
+func LoadScripts(ctx context.Context, p Pool, scripts ...*redis.Script) error {
+	return ForEachNode(ctx, p, func(conn redis.Conn) error {
+		for _, script := range scripts {
+			if err := script.Load(conn); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return nil
+	})
+}
+
+// 在每个节点(集群时为每个主节点)的连接上执行f，例如SCAN这种只针对单个节点的命令
+func ForEachNode(ctx context.Context, p Pool, f func(conn redis.Conn) error) error {
+	c, ok := p.(*Cluster)
+	if !ok {
+		conn, err := p.GetContext(ctx)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer conn.Close()
+		return f(conn)
+	}
+
+	for _, addr := range c.masterAddrs() {
+		if err := func() error {
+			conn, err := c.getPool(addr).GetContext(ctx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer conn.Close()
+			return f(conn)
+		}(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 是否为节点上没有此脚本的错误，故障转移或者迁移slot之后新的节点可能还没加载
+// 此时命令并没有执行，可以用 Script.Do 重新执行，其会以 EVAL 兜底
+func IsNoScript(err error) bool {
+	e, ok := err.(redis.Error)
+	return ok && strings.HasPrefix(string(e), "NOSCRIPT")
+}
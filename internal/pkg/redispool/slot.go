@@ -0,0 +1,32 @@
+package redispool
+
+import "strings"
+
+const hashSlots = 16384
+
+// 计算key所在的slot，规则和redis集群一致:
+// key里存在非空的 {hashtag} 时只对hashtag计算，这样同一hashtag的key一定在同一slot
+func Slot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key)) % hashSlots
+}
+
+// CRC16-CCITT(XMODEM)
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
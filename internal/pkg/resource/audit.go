@@ -0,0 +1,25 @@
+package resource
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/molon/gomsg/internal/pkg/audit"
+	"github.com/molon/gomsg/internal/pkg/redispool"
+)
+
+// 根据 audit.enabled 创建消息审计轨迹存储，未开启时返回nil
+func NewAuditStore(logger *logrus.Logger, redisPool redispool.Pool) *audit.Store {
+	if !viper.GetBool("audit.enabled") {
+		return nil
+	}
+
+	retention := viper.GetDuration("audit.retention")
+	maxEvents := viper.GetInt("audit.max-events")
+	if retention <= 0 || maxEvents <= 0 {
+		logger.Fatalln("audit.retention and audit.max-events must be greater than 0")
+	}
+
+	logger.Infof("Init audit store, retention: %s, max events: %d", retention, maxEvents)
+	return audit.NewStore(logger, redisPool, retention, maxEvents)
+}
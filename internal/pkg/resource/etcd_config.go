@@ -0,0 +1,188 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/molon/pkg/errors"
+)
+
+// 以etcd某个key的值覆盖本地配置(flag/环境变量/配置文件)，并可监听其变更
+// key为空则表示不启用，此时只使用本地配置
+type EtcdConfig struct {
+	logger *logrus.Logger
+	cli    *etcd.Client
+	key    string
+	format string
+
+	// 启动时的本地配置，etcd中的值被删除后即回退到此
+	base map[string]interface{}
+
+	mu    sync.Mutex
+	value []byte
+	// 当前值的ModRevision，key不存在时为0，只有它变化才认为配置变更了
+	rev int64
+	// 已经看到的etcd整体revision，watch从其之后开始
+	watchRev int64
+}
+
+func NewEtcdConfig(ctx context.Context, logger *logrus.Logger, cli *etcd.Client) *EtcdConfig {
+	ec := &EtcdConfig{
+		logger: logger,
+		cli:    cli,
+		key:    viper.GetString("config.etcd-key"),
+		format: viper.GetString("config.etcd-format"),
+		base:   viper.AllSettings(),
+	}
+
+	if len(ec.key) < 1 {
+		return ec
+	}
+
+	rev, watchRev, value, err := ec.get(ctx)
+	if err != nil {
+		logger.Fatalf("Get config from etcd key %s failed: %+v", ec.key, err)
+	}
+	ec.rev, ec.watchRev, ec.value = rev, watchRev, value
+
+	logger.Infof("Load config from etcd key %s at revision %d", ec.key, rev)
+
+	return ec
+}
+
+// 返回key的ModRevision(不存在则为0)、etcd整体的revision以及key的值
+func (ec *EtcdConfig) get(ctx context.Context) (int64, int64, []byte, error) {
+	resp, err := ec.cli.Get(ctx, ec.key)
+	if err != nil {
+		return 0, 0, nil, errors.WithStack(err)
+	}
+
+	if len(resp.Kvs) <= 0 {
+		return 0, resp.Header.Revision, nil, nil
+	}
+
+	return resp.Kvs[0].ModRevision, resp.Header.Revision, resp.Kvs[0].Value, nil
+}
+
+// 返回本地配置与etcd中的值合并后的结果
+func (ec *EtcdConfig) Viper() (*viper.Viper, error) {
+	ec.mu.Lock()
+	value := ec.value
+	ec.mu.Unlock()
+
+	return ec.merge(value)
+}
+
+func (ec *EtcdConfig) merge(value []byte) (*viper.Viper, error) {
+	v := viper.New()
+	if err := v.MergeConfigMap(ec.base); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if len(value) > 0 {
+		v.SetConfigType(ec.format)
+		if err := v.MergeConfig(bytes.NewReader(value)); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return v, nil
+}
+
+// 监听etcd中值的变更，每次变更都会以合并后的结果回调onChange，直到ctx结束
+// 值无法解析的变更会被忽略，保持之前的配置
+func (ec *EtcdConfig) Watch(ctx context.Context, onChange func(v *viper.Viper)) {
+	if len(ec.key) < 1 {
+		return
+	}
+
+	go func() {
+		for {
+			ec.watch(ctx, onChange)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+
+			// watch中断后可能错过了变更，重新获取一次
+			rev, watchRev, value, err := ec.get(ctx)
+			if err != nil {
+				ec.logger.Warnf("Get config from etcd key %s failed: %+v", ec.key, err)
+				continue
+			}
+			ec.setWatchRev(watchRev)
+			ec.apply(rev, value, onChange)
+		}
+	}()
+}
+
+func (ec *EtcdConfig) watch(ctx context.Context, onChange func(v *viper.Viper)) {
+	ec.mu.Lock()
+	rev := ec.watchRev
+	ec.mu.Unlock()
+
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wc := ec.cli.Watch(wctx, ec.key, etcd.WithRev(rev+1))
+	for resp := range wc {
+		if err := resp.Err(); err != nil {
+			ec.logger.Warnf("Watch config at etcd key %s failed: %+v", ec.key, err)
+			return
+		}
+
+		for _, ev := range resp.Events {
+			// 删除即回退到本地配置
+			if ev.Type != etcd.EventTypePut {
+				ec.apply(0, nil, onChange)
+				continue
+			}
+			ec.apply(ev.Kv.ModRevision, ev.Kv.Value, onChange)
+		}
+		ec.setWatchRev(resp.Header.Revision)
+	}
+}
+
+func (ec *EtcdConfig) setWatchRev(rev int64) {
+	ec.mu.Lock()
+	if rev > ec.watchRev {
+		ec.watchRev = rev
+	}
+	ec.mu.Unlock()
+}
+
+// rev为0表示key已被删除
+func (ec *EtcdConfig) apply(rev int64, value []byte, onChange func(v *viper.Viper)) {
+	ec.mu.Lock()
+	if (rev == 0 && ec.rev == 0) || (rev > 0 && rev <= ec.rev) {
+		ec.mu.Unlock()
+		return
+	}
+	ec.rev = rev
+	ec.mu.Unlock()
+
+	v, err := ec.merge(value)
+	if err != nil {
+		ec.logger.Errorf("Parse config from etcd key %s at revision %d failed, ignored: %+v", ec.key, rev, err)
+		return
+	}
+
+	ec.mu.Lock()
+	ec.value = value
+	ec.mu.Unlock()
+
+	if rev == 0 {
+		ec.logger.Infof("Config at etcd key %s deleted, fall back to local config", ec.key)
+	} else {
+		ec.logger.Infof("Config at etcd key %s changed at revision %d", ec.key, rev)
+	}
+	onChange(v)
+}
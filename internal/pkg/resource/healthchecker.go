@@ -8,6 +8,7 @@ import (
 
 	"github.com/molon/pkg/server"
 	"github.com/molon/pkg/server/health"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
@@ -29,6 +30,8 @@ func NewHealthChecker(logger *logrus.Logger, checks map[string]health.Check) (*s
 			w.WriteHeader(200)
 			w.Write([]byte("pong"))
 		})),
+		// 各服务的指标都注册在默认的registry里
+		server.WithHTTPHandler(viper.GetString("health.metrics"), promhttp.Handler()),
 	)
 	if err != nil {
 		logger.Fatalln(err)
@@ -0,0 +1,80 @@
+package resource
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/molon/gomsg/internal/pkg/mq"
+	"github.com/molon/gomsg/internal/pkg/mq/kafkamq"
+	"github.com/molon/gomsg/internal/pkg/mq/natsmq"
+	"github.com/molon/gomsg/internal/pkg/mq/redismq"
+)
+
+// 根据 mq.driver 创建生产者，可选 kafka/redis/nats
+func NewMQProducer(logger *logrus.Logger) mq.Producer {
+	driver := viper.GetString("mq.driver")
+	switch driver {
+	case "kafka":
+		p, err := kafkamq.NewProducer(viper.GetStringSlice("kafka.brokers"))
+		if err != nil {
+			logger.Fatalln("Create kafka producer failed:", err)
+		}
+		logger.Infof("Create producer at kafka brokers %v", viper.GetStringSlice("kafka.brokers"))
+		return p
+	case "redis":
+		addr := mqRedisAddr()
+		logger.Infof("Create producer at redis %s", addr)
+		return redismq.NewProducer(newRedisPool(addr), viper.GetInt64("mq.redis.max-len"))
+	case "nats":
+		p, err := natsmq.NewProducer(viper.GetString("mq.nats.url"), viper.GetDuration("mq.nats.max-age"))
+		if err != nil {
+			logger.Fatalln("Create nats producer failed:", err)
+		}
+		logger.Infof("Create producer at nats %s", viper.GetString("mq.nats.url"))
+		return p
+	}
+
+	logger.Fatalf("Unknown mq.driver: %s", driver)
+	return nil
+}
+
+// 根据 mq.driver 创建并启动消费者，消费组为 consumer.group
+func StartMQConsumer(logger *logrus.Logger, topic string, concurrency int) mq.Consumer {
+	var (
+		driver = viper.GetString("mq.driver")
+		group  = viper.GetString("consumer.group")
+		c      mq.Consumer
+		err    error
+	)
+
+	switch driver {
+	case "kafka":
+		c, err = kafkamq.NewConsumer(logger, viper.GetStringSlice("kafka.brokers"), viper.GetString("kafka.version"),
+			viper.GetString("kafka.balance-strategy"), group, topic,
+			viper.GetDuration("kafka.drain-timeout"), viper.GetDuration("kafka.ack-timeout"), viper.GetDuration("kafka.lag-interval"))
+		if err != nil {
+			logger.Fatalln("Create consumer failed:", err)
+		}
+	case "redis":
+		c = redismq.NewConsumer(logger, newRedisPool(mqRedisAddr()), group, topic, concurrency, viper.GetDuration("mq.redis.claim-idle"))
+	case "nats":
+		c = natsmq.NewConsumer(logger, viper.GetString("mq.nats.url"), group, topic, concurrency,
+			viper.GetDuration("mq.nats.ack-wait"), viper.GetDuration("mq.nats.max-age"))
+	default:
+		logger.Fatalf("Unknown mq.driver: %s", driver)
+	}
+
+	if err := c.Start(); err != nil {
+		logger.Fatalln("Start consumer failed:", err)
+	}
+
+	logger.Infof("Start consume [%s] with %s driver", topic, driver)
+
+	return c
+}
+
+func mqRedisAddr() string {
+	return fmt.Sprintf("%s:%d", viper.GetString("mq.redis.address"), viper.GetInt("mq.redis.port"))
+}
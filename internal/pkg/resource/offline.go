@@ -0,0 +1,64 @@
+package resource
+
+import (
+	"context"
+	"database/sql"
+	"io"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/molon/gomsg/internal/pkg/offline"
+	"github.com/molon/gomsg/internal/pkg/offline/boltoffline"
+	"github.com/molon/gomsg/internal/pkg/offline/redisoffline"
+	"github.com/molon/gomsg/internal/pkg/offline/sqloffline"
+	"github.com/molon/gomsg/internal/pkg/redispool"
+
+	// sql驱动
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+// 根据 offline.driver 创建离线消息存储，可选 redis/sql/bolt
+// redis 使用传入的redisPool，由调用方负责关闭
+func NewOfflineStore(ctx context.Context, logger *logrus.Logger, redisPool redispool.Pool) (offline.Store, io.Closer) {
+	driver := viper.GetString("offline.driver")
+	switch driver {
+	case "redis":
+		s, err := redisoffline.InitStore(ctx, redisPool)
+		if err != nil {
+			logger.Fatalln("Init redis offline store failed:", err)
+		}
+		logger.Infof("Init offline store with redis")
+		return s, closerFunc(func() error { return nil })
+	case "sql":
+		dialect := viper.GetString("offline.sql.dialect")
+		db, err := sql.Open(dialect, viper.GetString("offline.sql.dsn"))
+		if err != nil {
+			logger.Fatalln("Open offline database failed:", err)
+		}
+		s, err := sqloffline.InitStore(ctx, db, dialect)
+		if err != nil {
+			logger.Fatalln("Init sql offline store failed:", err)
+		}
+		logger.Infof("Init offline store with %s", dialect)
+		return s, db
+	case "bolt":
+		s, err := boltoffline.Open(viper.GetString("offline.bolt.path"))
+		if err != nil {
+			logger.Fatalln("Open bolt offline store failed:", err)
+		}
+		logger.Infof("Init offline store with bolt at %s", viper.GetString("offline.bolt.path"))
+		return s, s
+	}
+
+	logger.Fatalf("Unknown offline.driver: %s", driver)
+	return nil, nil
+}
@@ -0,0 +1,94 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/sirupsen/logrus"
+
+	etcd "github.com/coreos/etcd/clientv3"
+	"github.com/molon/pkg/errors"
+)
+
+// station和carrier分开部署，各自的priority-topic无法在配置里互相校验
+// carrier运行期间在etcd里登记其消费的priority topic，station启动时据此检查 producer.priority-topic 是否有人消费
+// gomsg/priority-topic/<topic>/<lease>: ""
+const priorityTopicKeyPrefix = "gomsg/priority-topic/"
+
+type priorityTopicRegister struct {
+	logger *logrus.Logger
+	cli    *etcd.Client
+	lease  etcd.LeaseID
+	cancel context.CancelFunc
+}
+
+// 登记carrier消费的 consumer.priority-topic，随租约存活，Close时撤销
+func RegisterPriorityTopic(ctx context.Context, logger *logrus.Logger, cli *etcd.Client, topic string, ttl int64) io.Closer {
+	lease, err := cli.Grant(ctx, ttl)
+	if err != nil {
+		logger.Fatalf("Grant lease for priority topic %s failed: %+v", topic, err)
+	}
+
+	key := fmt.Sprintf("%s%s/%x", priorityTopicKeyPrefix, topic, lease.ID)
+	if _, err := cli.Put(ctx, key, "", etcd.WithLease(lease.ID)); err != nil {
+		logger.Fatalf("Register priority topic %s failed: %+v", topic, err)
+	}
+
+	kctx, cancel := context.WithCancel(ctx)
+	kaC, err := cli.KeepAlive(kctx, lease.ID)
+	if err != nil {
+		cancel()
+		logger.Fatalf("Keep alive lease of priority topic %s failed: %+v", topic, err)
+	}
+	// 必须把应答读掉，否则KeepAlive的channel会堵塞
+	go func() {
+		for range kaC {
+		}
+	}()
+
+	logger.Infof("Register priority topic %s", topic)
+
+	return &priorityTopicRegister{
+		logger: logger,
+		cli:    cli,
+		lease:  lease.ID,
+		cancel: cancel,
+	}
+}
+
+func (r *priorityTopicRegister) Close() error {
+	r.cancel()
+	if _, err := r.cli.Revoke(context.Background(), r.lease); err != nil {
+		r.logger.Warnf("Revoke lease of priority topic failed: %+v", err)
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// station启动时检查 producer.priority-topic 是否有carrier在消费
+// 还没有carrier登记任何priority topic时(例如先于carrier启动)只警告，有登记却都不是此topic则返回错误
+func CheckPriorityTopic(ctx context.Context, logger *logrus.Logger, cli *etcd.Client, topic string) error {
+	if len(topic) < 1 {
+		return nil
+	}
+
+	resp, err := cli.Get(ctx, priorityTopicKeyPrefix+topic+"/", etcd.WithPrefix(), etcd.WithCountOnly())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if resp.Count > 0 {
+		return nil
+	}
+
+	resp, err = cli.Get(ctx, priorityTopicKeyPrefix, etcd.WithPrefix(), etcd.WithCountOnly())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if resp.Count > 0 {
+		return errors.Errorf("producer.priority-topic %s is not consumed by any carrier, check consumer.priority-topic of carrier", topic)
+	}
+
+	logger.Warnf("No carrier consuming priority topic is registered, make sure consumer.priority-topic of carrier is %s", topic)
+	return nil
+}
@@ -1,20 +1,42 @@
 package resource
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
+	"github.com/molon/gomsg/internal/pkg/redispool"
 	"github.com/molon/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
-func NewRedisPool(logger *logrus.Logger) *redis.Pool {
+// redis.cluster 为true时以 redis.cluster-addrs 连接redis集群，否则连接单机 redis.address:redis.port
+func NewRedisPool(logger *logrus.Logger) redispool.Pool {
+	if viper.GetBool("redis.cluster") {
+		addrs := viper.GetStringSlice("redis.cluster-addrs")
+
+		logger.Infof("Init redis cluster at %v", addrs)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		c, err := redispool.NewCluster(ctx, addrs, newRedisPool)
+		if err != nil {
+			logger.Fatalln("Init redis cluster failed:", err)
+		}
+		return c
+	}
+
 	addr := fmt.Sprintf("%s:%d", viper.GetString("redis.address"), viper.GetInt("redis.port"))
 
 	logger.Infof("Init redis pool at %s", addr)
 
+	return newRedisPool(addr)
+}
+
+func newRedisPool(addr string) *redis.Pool {
 	return &redis.Pool{
 		MaxIdle:     1024,
 		MaxActive:   10240,
@@ -0,0 +1,390 @@
+package sessionstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/molon/pkg/errors"
+)
+
+/*
+// boat的租约，分数为到期时间(ms)，boat定期心跳续约
+"msg/{boats}/leases": [
+    "bid1",
+    "bid2",
+]
+
+// 租约已到期正在清理其会话的boat，分数为下次可被认领的时间(ms)，这样清理者挂掉之后也能被重新认领
+// 和租约同一hashtag，这样才能原子地从租约里转移过来
+"msg/{boats}/reaping": [
+    "bid3",
+]
+
+// boat租约的代数，租约每次重新建立(首次或者被认领清理之后)都会加一，会话登记时记下当时的代数
+"msg/{boats}/gens": {
+    "bid1": "1",
+    "bid3": "2",
+}
+
+// 认领清理时租约的代数，只清理不大于此代数的会话
+// 心跳慢了的boat在清理期间重新续约的话代数会增加，之后新连接的会话不会被误删
+"msg/{boats}/reaping-gens": {
+    "bid3": "2",
+}
+
+// boat上的会话，反向索引，以 {bid1} 为hashtag，和会话记录不在同一slot，所以在写入会话之前写入
+"msg/b:{bid1}/ss": {
+    "sid1": "uid1",
+    "sid2": "uid2",
+}
+*/
+
+const (
+	boatLeasesKey      = "msg/{boats}/leases"
+	boatReapingKey     = "msg/{boats}/reaping"
+	boatGensKey        = "msg/{boats}/gens"
+	boatReapingGensKey = "msg/{boats}/reaping-gens"
+)
+
+func bssKey(bid string) string {
+	return fmt.Sprintf("msg/b:{%s}/ss", bid)
+}
+
+// 每批清理的会话数目
+const reapBatchCount = 500
+
+var (
+	/*
+		KEYS : msg/{boats}/leases msg/{boats}/gens
+		ARGV : expire_at bid
+		返回 : [续约之前租约是否已不存在, 当前代数]，租约是新建立的则代数加一
+	*/
+	renewLeaseLua = redis.NewScript(2, `
+local added = redis.call('ZADD', KEYS[1], ARGV[1], ARGV[2])
+local gen
+if added == 1 then
+	gen = redis.call('HINCRBY', KEYS[2], ARGV[2], 1)
+else
+	gen = tonumber(redis.call('HGET', KEYS[2], ARGV[2]) or '0')
+end
+return {added, gen}
+`)
+
+	/*
+		KEYS : msg/{boats}/leases msg/{boats}/reaping msg/{boats}/gens msg/{boats}/reaping-gens
+		ARGV : now count retry_until
+		返回 : 认领到的bid列表，先认领之前没清理完的，再认领租约刚到期的，其分数都被推迟至retry_until
+		租约刚到期的记下其当前代数，之前没清理完的沿用之前记下的
+	*/
+	claimExpiredBoatsLua = redis.NewScript(4, `
+local bids = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, bid in ipairs(bids) do
+	redis.call('ZADD', KEYS[2], ARGV[3], bid)
+end
+
+local left = tonumber(ARGV[2]) - #bids
+if left > 0 then
+	local expired = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, left)
+	for _, bid in ipairs(expired) do
+		redis.call('ZREM', KEYS[1], bid)
+		redis.call('ZADD', KEYS[2], ARGV[3], bid)
+		redis.call('HSET', KEYS[4], bid, redis.call('HGET', KEYS[3], bid) or '0')
+		table.insert(bids, bid)
+	end
+end
+return bids
+`)
+
+	/*
+		KEYS : msg/u:{uid1}/ss msg/u:{uid1}/sm
+		ARGV : bid gen sid1 sid2 ...
+		只删除依然登记在此boat上且代数不大于gen的会话，连同其附加信息，gen<0则不限代数，会话详情的两种格式见 session.go
+		返回 : [[sid1, platform1, 附加信息1, sid2, platform2, 附加信息2 ...], [sid3 ...]]
+		       确实被删除的会话(附加信息不存在则为空)，以及因代数更新而保留的会话
+	*/
+	deleteBoatSessionsLua = redis.NewScript(2, `
+local function parse(detail)
+	if string.sub(detail, 1, 1) == '{' then
+		local ok, d = pcall(cjson.decode, detail)
+		if ok and type(d) == 'table' and type(d.p) == 'string' and type(d.b) == 'string' then
+			local g = 0
+			if type(d.g) == 'number' then
+				g = d.g
+			end
+			return d.p, d.b, g
+		end
+		return nil, nil, 0
+	end
+	local platform, bid = string.match(detail, '^([^-]+)-([^-]+)$')
+	return platform, bid, 0
+end
+
+local max_gen = tonumber(ARGV[2])
+local removed = {}
+local kept = {}
+for i = 3, #ARGV do
+	local detail = redis.call('HGET', KEYS[1], ARGV[i])
+	if detail then
+		local platform, bid, gen = parse(detail)
+		if bid == ARGV[1] then
+			if max_gen >= 0 and gen > max_gen then
+				table.insert(kept, ARGV[i])
+			else
+				local metadata = redis.call('HGET', KEYS[2], ARGV[i]) or ''
+				redis.call('HDEL', KEYS[1], ARGV[i])
+				redis.call('HDEL', KEYS[2], ARGV[i])
+				table.insert(removed, ARGV[i])
+				table.insert(removed, platform)
+				table.insert(removed, metadata)
+			end
+		end
+	end
+end
+return {removed, kept}
+`)
+
+	/*
+		KEYS : msg/{boats}/leases msg/{boats}/reaping msg/{boats}/gens msg/{boats}/reaping-gens
+		ARGV : bid gen
+		清理完毕，若期间没有被重新认领(记下的代数未变)则从待清理列表里移除，租约也没有重新建立的话一并删除其代数
+	*/
+	finishReapLua = redis.NewScript(4, `
+local gen = redis.call('HGET', KEYS[4], ARGV[1]) or ''
+if gen ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[4], ARGV[1])
+if not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	redis.call('HDEL', KEYS[3], ARGV[1])
+end
+return 1
+`)
+)
+
+// 续约，返回续约之前租约是否已不存在(首次或者已被认领清理)，以及租约当前的代数
+func (ss *Store) RenewLease(ctx context.Context, bid string, ttl time.Duration) (bool, int64, error) {
+	if len(bid) < 1 {
+		return false, 0, errors.Errorf("bid is empty")
+	}
+
+	vals, err := redis.Int64s(ss.doScript(ctx, renewLeaseLua,
+		boatLeasesKey, boatGensKey,
+		nowMs()+int64(ttl/time.Millisecond), bid,
+	))
+	if err != nil {
+		return false, 0, err
+	}
+	if len(vals) != 2 {
+		return false, 0, errors.Errorf("unexpected renew lease reply: %v", vals)
+	}
+	return vals[0] > 0, vals[1], nil
+}
+
+// 认领最多count个租约已到期的boat，认领后 retryAfter 时间内不会被再次认领，需对其调用 ReapBoat
+func (ss *Store) ClaimExpiredBoats(ctx context.Context, count int, retryAfter time.Duration) ([]string, error) {
+	now := nowMs()
+	bids, err := redis.Strings(ss.doScript(ctx, claimExpiredBoatsLua,
+		boatLeasesKey, boatReapingKey, boatGensKey, boatReapingGensKey,
+		now, count, now+int64(retryAfter/time.Millisecond),
+	))
+	if err != nil {
+		return nil, err
+	}
+	return bids, nil
+}
+
+// 分批删除某boat上代数不大于认领时的会话，每批删除之后以确实被删除的会话回调f，全部完成后将其从待清理列表里移除
+// 中途失败(包括f返回错误)的话等认领过期后会被重新清理，已被删除的会话不会再次回调
+func (ss *Store) ReapBoat(ctx context.Context, bid string, f func(sesses []Session) error) error {
+	// 升级之前认领的没有记下代数，不限代数
+	reply, err := ss.do(ctx, "HGET", boatReapingGensKey, bid)
+	if err != nil {
+		return err
+	}
+	genStr, gen := "", int64(-1)
+	if reply != nil {
+		if genStr, err = redis.String(reply, nil); err != nil {
+			return errors.WithStack(err)
+		}
+		if gen, err = strconv.ParseInt(genStr, 10, 64); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	// 删除的同时遍历，HSCAN可能会重复返回，但不会遗漏
+	cursor := "0"
+	for {
+		vals, err := redis.Values(ss.do(ctx, "HSCAN", bssKey(bid), cursor, "COUNT", reapBatchCount))
+		if err != nil {
+			return err
+		}
+
+		var kvs []string
+		if _, err := redis.Scan(vals, &cursor, &kvs); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if len(kvs) > 0 {
+			sesses, kept, err := ss.deleteBoatSessions(ctx, bid, gen, kvs)
+			if err != nil {
+				return err
+			}
+
+			if len(sesses) > 0 {
+				if err := f(sesses); err != nil {
+					return err
+				}
+			}
+
+			// 代数更新的会话是boat重新续约之后连接的，保留其登记，其余的已被删除或者本就不在了
+			if err := ss.unindexStale(ctx, bid, kvs, kept); err != nil {
+				return err
+			}
+		}
+
+		if cursor == "0" {
+			break
+		}
+	}
+
+	if _, err := ss.doScript(ctx, finishReapLua,
+		boatLeasesKey, boatReapingKey, boatGensKey, boatReapingGensKey,
+		bid, genStr,
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// kvs 为 sid,uid 交替的列表，返回确实被删除的会话以及因代数更新而保留的sid
+func (ss *Store) deleteBoatSessions(ctx context.Context, bid string, gen int64, kvs []string) ([]Session, map[string]bool, error) {
+	uids := []string{}
+	uidToSids := map[string][]interface{}{}
+	for i := 0; i+1 < len(kvs); i += 2 {
+		uid := kvs[i+1]
+		if _, ok := uidToSids[uid]; !ok {
+			uids = append(uids, uid)
+		}
+		uidToSids[uid] = append(uidToSids[uid], kvs[i])
+	}
+
+	sesses := []Session{}
+	kept := map[string]bool{}
+	for _, uid := range uids {
+		args := append([]interface{}{ussKey(uid), usmKey(uid), bid, gen}, uidToSids[uid]...)
+		vals, err := redis.Values(ss.doScript(ctx, deleteBoatSessionsLua, args...))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(vals) != 2 {
+			return nil, nil, errors.Errorf("unexpected delete boat sessions reply: %v", vals)
+		}
+		removed, err := redis.Strings(vals[0], nil)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		keptSids, err := redis.Strings(vals[1], nil)
+		if err != nil {
+			return nil, nil, errors.WithStack(err)
+		}
+		for _, sid := range keptSids {
+			kept[sid] = true
+		}
+
+		for i := 0; i+2 < len(removed); i += 3 {
+			sess := Session{
+				Sid:      removed[i],
+				Uid:      uid,
+				Bid:      bid,
+				Platform: removed[i+1],
+			}
+			if len(removed[i+2]) > 0 {
+				md := &Metadata{}
+				if err := json.Unmarshal([]byte(removed[i+2]), md); err != nil {
+					ss.logger.Warnf("Unmarshal metadata of session(%s) failed: %v", sess.Sid, err)
+				} else {
+					sess.Metadata = md
+				}
+			}
+			sesses = append(sesses, sess)
+		}
+	}
+
+	return sesses, kept, nil
+}
+
+// 从boat的反向索引里删除kvs里除了kept之外的sid
+func (ss *Store) unindexStale(ctx context.Context, bid string, kvs []string, kept map[string]bool) error {
+	args := []interface{}{bssKey(bid)}
+	for i := 0; i+1 < len(kvs); i += 2 {
+		if !kept[kvs[i]] {
+			args = append(args, kvs[i])
+		}
+	}
+	if len(args) <= 1 {
+		return nil
+	}
+
+	_, err := ss.do(ctx, "HDEL", args...)
+	return err
+}
+
+// 登记会话所在的boat，需在写入会话之前执行
+func (ss *Store) indexBoatSession(ctx context.Context, sess Session) error {
+	_, err := ss.do(ctx, "HSET", bssKey(sess.Bid), sess.Sid, sess.Uid)
+	return err
+}
+
+// 删除会话所在boat的登记，需在删除会话之后执行
+func (ss *Store) unindexBoatSessions(ctx context.Context, sesses []Session) error {
+	bids := []string{}
+	bidToSids := map[string][]interface{}{}
+	for _, sess := range sesses {
+		if _, ok := bidToSids[sess.Bid]; !ok {
+			bids = append(bids, sess.Bid)
+		}
+		bidToSids[sess.Bid] = append(bidToSids[sess.Bid], sess.Sid)
+	}
+
+	for _, bid := range bids {
+		if _, err := ss.do(ctx, "HDEL", append([]interface{}{bssKey(bid)}, bidToSids[bid]...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 单条命令，集群时每次都要按key重新选择节点，所以涉及不同slot的命令不要共用连接
+func (ss *Store) do(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	conn, err := ss.redisPool.GetContext(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	reply, err := conn.Do(commandName, args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return reply, nil
+}
+
+func (ss *Store) doScript(ctx context.Context, script *redis.Script, keysAndArgs ...interface{}) (interface{}, error) {
+	conn, err := ss.redisPool.GetContext(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	reply, err := script.Do(conn, keysAndArgs...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return reply, nil
+}
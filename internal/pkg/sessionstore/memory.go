@@ -0,0 +1,416 @@
+package sessionstore
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/molon/pkg/errors"
+)
+
+// 进程内的实现，语义和 Store 一致，只能用于单进程(单元测试或者all-in-one)
+type MemoryStore struct {
+	mu sync.Mutex
+
+	// uid -> sid -> 会话，附加信息一起存着，读取时按需去掉
+	sessions map[string]map[string]Session
+	// bid -> sid -> uid，清理租约到期的boat时不需要遍历所有用户
+	boatSessions map[string]map[string]string
+
+	// uid -> 待投递任务，按写入顺序
+	outbox map[string][]*memOutbox
+
+	// bid -> 租约到期时间
+	leases map[string]time.Time
+	// bid -> 下次可被认领的时间
+	reaping map[string]time.Time
+	// bid -> 租约的代数
+	gens map[string]int64
+	// bid -> 认领清理时租约的代数
+	reapingGens map[string]int64
+
+	// uid -> 用户序号
+	uidSeqs map[string]int64
+	// uid -> seq -> 处理中的有序消息
+	pendings map[string]map[string]*memPending
+}
+
+type memPending struct {
+	oseq     int64
+	deadline time.Time
+}
+
+type memOutbox struct {
+	msg OutboxMessage
+	// 下次可被认领的时间
+	claimableAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions:     map[string]map[string]Session{},
+		boatSessions: map[string]map[string]string{},
+		outbox:       map[string][]*memOutbox{},
+		leases:       map[string]time.Time{},
+		reaping:      map[string]time.Time{},
+		gens:         map[string]int64{},
+		reapingGens:  map[string]int64{},
+		uidSeqs:      map[string]int64{},
+		pendings:     map[string]map[string]*memPending{},
+	}
+}
+
+func (ms *MemoryStore) SetSession(ctx context.Context, sess Session) error {
+	return ms.SetSessionWithOutbox(ctx, sess, nil)
+}
+
+func (ms *MemoryStore) SetSessionWithOutbox(ctx context.Context, sess Session, outbox []*OutboxMessage) error {
+	if !sess.Valid() {
+		return errors.Errorf("session is not valid")
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	sesses, ok := ms.sessions[sess.Uid]
+	if !ok {
+		sesses = map[string]Session{}
+		ms.sessions[sess.Uid] = sesses
+	}
+	if _, ok := sesses[sess.Sid]; !ok {
+		sesses[sess.Sid] = sess
+
+		sids, ok := ms.boatSessions[sess.Bid]
+		if !ok {
+			sids = map[string]string{}
+			ms.boatSessions[sess.Bid] = sids
+		}
+		sids[sess.Sid] = sess.Uid
+	}
+
+	now := time.Now()
+	for _, m := range outbox {
+		mo := &memOutbox{msg: *m, claimableAt: now}
+		mo.msg.Uid = sess.Uid
+		ms.outbox[sess.Uid] = append(ms.outbox[sess.Uid], mo)
+	}
+
+	return nil
+}
+
+func (ms *MemoryStore) AddOutbox(ctx context.Context, uid string, outbox []*OutboxMessage) error {
+	if len(uid) < 1 {
+		return errors.WithStack(ErrNoUid)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	for _, m := range outbox {
+		mo := &memOutbox{msg: *m, claimableAt: now}
+		mo.msg.Uid = uid
+		ms.outbox[uid] = append(ms.outbox[uid], mo)
+	}
+
+	return nil
+}
+
+func (ms *MemoryStore) DeleteSessions(ctx context.Context, uid string, sids []string) error {
+	if len(uid) < 1 {
+		return errors.WithStack(ErrNoUid)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.deleteSessions(uid, sids)
+	return nil
+}
+
+func (ms *MemoryStore) deleteSessions(uid string, sids []string) {
+	sesses := ms.sessions[uid]
+	for _, sid := range sids {
+		sess, ok := sesses[sid]
+		if !ok {
+			continue
+		}
+		delete(sesses, sid)
+
+		if bsids := ms.boatSessions[sess.Bid]; bsids != nil {
+			delete(bsids, sid)
+			if len(bsids) <= 0 {
+				delete(ms.boatSessions, sess.Bid)
+			}
+		}
+	}
+	if len(sesses) <= 0 {
+		delete(ms.sessions, uid)
+	}
+}
+
+func (ms *MemoryStore) GetSidToSession(ctx context.Context, uid string, opts ...GetOption) (map[string]Session, error) {
+	if len(uid) < 1 {
+		return nil, errors.WithStack(ErrNoUid)
+	}
+
+	ops := &getOptions{}
+	for _, opt := range opts {
+		opt(ops)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	all := ms.sessions[uid]
+	sesses := map[string]Session{}
+	add := func(sess Session) {
+		if !ops.withMetadata {
+			sess.Metadata = nil
+		}
+		sesses[sess.Sid] = sess
+	}
+
+	if len(ops.sids) < 1 {
+		for _, sess := range all {
+			add(sess)
+		}
+	} else {
+		for _, sid := range ops.sids {
+			if sess, ok := all[sid]; ok {
+				add(sess)
+			}
+		}
+	}
+
+	return sesses, nil
+}
+
+func (ms *MemoryStore) GetPlatformToSessions(ctx context.Context, uid string, opts ...GetOption) (map[string][]Session, error) {
+	sesses, err := ms.GetSidToSession(ctx, uid, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return groupByPlatform(sesses), nil
+}
+
+func (ms *MemoryStore) GetSessions(ctx context.Context, uid string, opts ...GetOption) ([]Session, error) {
+	sesses, err := ms.GetSidToSession(ctx, uid, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return sortSessions(sesses), nil
+}
+
+func (ms *MemoryStore) ClaimOutbox(ctx context.Context, count int, lease time.Duration) ([]*OutboxMessage, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	msgs := []*OutboxMessage{}
+	for _, mos := range ms.outbox {
+		for _, mo := range mos {
+			if len(msgs) >= count {
+				return msgs, nil
+			}
+			if mo.claimableAt.After(now) {
+				continue
+			}
+
+			mo.claimableAt = now.Add(lease)
+			m := mo.msg
+			msgs = append(msgs, &m)
+		}
+	}
+
+	return msgs, nil
+}
+
+func (ms *MemoryStore) DeleteOutbox(ctx context.Context, msgs []*OutboxMessage) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for _, m := range msgs {
+		mos := ms.outbox[m.Uid]
+		for i, mo := range mos {
+			if mo.msg.Id == m.Id {
+				mos = append(mos[:i], mos[i+1:]...)
+				break
+			}
+		}
+
+		if len(mos) > 0 {
+			ms.outbox[m.Uid] = mos
+		} else {
+			delete(ms.outbox, m.Uid)
+		}
+	}
+
+	return nil
+}
+
+func (ms *MemoryStore) RenewLease(ctx context.Context, bid string, ttl time.Duration) (bool, int64, error) {
+	if len(bid) < 1 {
+		return false, 0, errors.Errorf("bid is empty")
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	_, ok := ms.leases[bid]
+	ms.leases[bid] = time.Now().Add(ttl)
+	if !ok {
+		ms.gens[bid]++
+	}
+	return !ok, ms.gens[bid], nil
+}
+
+func (ms *MemoryStore) ClaimExpiredBoats(ctx context.Context, count int, retryAfter time.Duration) ([]string, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	retryAt := now.Add(retryAfter)
+
+	// 先认领之前没清理完的，再认领租约刚到期的
+	bids := []string{}
+	for bid, at := range ms.reaping {
+		if len(bids) >= count {
+			return bids, nil
+		}
+		if at.After(now) {
+			continue
+		}
+		ms.reaping[bid] = retryAt
+		bids = append(bids, bid)
+	}
+
+	for bid, at := range ms.leases {
+		if len(bids) >= count {
+			break
+		}
+		if at.After(now) {
+			continue
+		}
+		delete(ms.leases, bid)
+		ms.reaping[bid] = retryAt
+		ms.reapingGens[bid] = ms.gens[bid]
+		bids = append(bids, bid)
+	}
+
+	return bids, nil
+}
+
+func (ms *MemoryStore) ReapBoat(ctx context.Context, bid string, f func(sesses []Session) error) error {
+	// 按反向索引逐个用户删除并回调，回调时不持有锁
+	ms.mu.Lock()
+	gen := ms.reapingGens[bid]
+	uids := map[string]struct{}{}
+	for _, uid := range ms.boatSessions[bid] {
+		uids[uid] = struct{}{}
+	}
+	ms.mu.Unlock()
+
+	for uid := range uids {
+		ms.mu.Lock()
+		sesses := []Session{}
+		sids := []string{}
+		for sid, sess := range ms.sessions[uid] {
+			if sess.Bid == bid && sess.LeaseGen <= gen {
+				sesses = append(sesses, sess)
+				sids = append(sids, sid)
+			}
+		}
+		ms.deleteSessions(uid, sids)
+		ms.mu.Unlock()
+
+		if len(sesses) > 0 {
+			if err := f(sesses); err != nil {
+				return err
+			}
+		}
+	}
+
+	ms.mu.Lock()
+	// 期间被重新认领的话由新的认领者移除
+	if ms.reapingGens[bid] == gen {
+		delete(ms.reaping, bid)
+		delete(ms.reapingGens, bid)
+		if _, ok := ms.leases[bid]; !ok {
+			delete(ms.gens, bid)
+		}
+	}
+	ms.mu.Unlock()
+
+	return nil
+}
+
+func (ms *MemoryStore) IncrUidSeqs(ctx context.Context, uids []string, count int64) (map[string]int64, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ret := map[string]int64{}
+	for _, uid := range uids {
+		ms.uidSeqs[uid] += count
+		ret[uid] = ms.uidSeqs[uid]
+	}
+	return ret, nil
+}
+
+func (ms *MemoryStore) RollbackUidSeq(ctx context.Context, uid string, last int64, count int64) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.uidSeqs[uid] != last {
+		return false, nil
+	}
+	ms.uidSeqs[uid] -= count
+	return true, nil
+}
+
+func (ms *MemoryStore) IsBlocked(ctx context.Context, uid string, seq string, oseq int64) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	pendings := ms.pendings[uid]
+	blocked := false
+	for pseq, p := range pendings {
+		if !p.deadline.After(now) {
+			delete(pendings, pseq)
+			continue
+		}
+		if pseq != seq && p.oseq < oseq {
+			blocked = true
+		}
+	}
+	if len(pendings) <= 0 {
+		delete(ms.pendings, uid)
+	}
+	return blocked, nil
+}
+
+func (ms *MemoryStore) MarkPending(ctx context.Context, uid string, seq string, oseq int64, ttl time.Duration) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	pendings, ok := ms.pendings[uid]
+	if !ok {
+		pendings = map[string]*memPending{}
+		ms.pendings[uid] = pendings
+	}
+	pendings[seq] = &memPending{oseq: oseq, deadline: time.Now().Add(ttl)}
+	return nil
+}
+
+func (ms *MemoryStore) UnmarkPending(ctx context.Context, uid string, seq string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	pendings := ms.pendings[uid]
+	delete(pendings, seq)
+	if len(pendings) <= 0 {
+		delete(ms.pendings, uid)
+	}
+	return nil
+}
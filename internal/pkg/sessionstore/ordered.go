@@ -0,0 +1,179 @@
+package sessionstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/molon/gomsg/internal/pkg/redispool"
+	"github.com/molon/pkg/errors"
+)
+
+/*
+// 有序投递用到的用户维度的数据，和会话同一slot
+
+// 用户维度的消息序号，由station申请，只增不减，所以不设置过期
+"msg/u:{uid1}/seq": 10
+
+// 某用户处于重试中或者等待中的ToUid消息，由carrier标记，分数为其顺序号
+// 只要其中存在比自身顺序号小的消息，自身就需要等待
+"msg/u:{uid1}/pts": [
+    "payload_seq1",
+    "payload_seq2",
+]
+
+// 同样的成员，分数为各自的阻塞截止时间(ms)，每次标记时更新
+// 到期的视为已丢失(例如投递死信队列失败或者解除标记失败)，不再阻塞后续消息
+"msg/u:{uid1}/ptd": [
+    "payload_seq1",
+    "payload_seq2",
+]
+*/
+
+func useqKey(uid string) string {
+	return fmt.Sprintf("msg/u:{%s}/seq", uid)
+}
+
+func uptsKey(uid string) string {
+	return fmt.Sprintf("msg/u:{%s}/pts", uid)
+}
+
+func uptdKey(uid string) string {
+	return fmt.Sprintf("msg/u:{%s}/ptd", uid)
+}
+
+var (
+	/*
+		KEYS : msg/u:{uid1}/seq
+		ARGV : last count
+		只有期间没有别人再申请才能归还，否则会和之后的序号冲突
+	*/
+	rollbackUidSeqLua = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	redis.call('DECRBY', KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+	/*
+		KEYS : msg/u:{uid1}/pts msg/u:{uid1}/ptd
+		ARGV : now seq oseq
+		先清理到期的，再看最早的那个是否在自身之前，返回1表示需要等待
+	*/
+	isBlockedLua = redis.NewScript(2, `
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, seq in ipairs(expired) do
+	redis.call('ZREM', KEYS[1], seq)
+	redis.call('ZREM', KEYS[2], seq)
+end
+local first = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if first[2] and first[1] ~= ARGV[2] and tonumber(first[2]) < tonumber(ARGV[3]) then
+	return 1
+end
+return 0
+`)
+
+	/*
+		KEYS : msg/u:{uid1}/pts msg/u:{uid1}/ptd
+		ARGV : now seq oseq ttl(ms)
+		成员都有各自的截止时间，key的过期只是为了最终回收，所以只延长不缩短，否则会带走其他还未到期的成员
+	*/
+	markPendingLua = redis.NewScript(2, `
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[2])
+redis.call('ZADD', KEYS[2], tonumber(ARGV[1]) + tonumber(ARGV[4]), ARGV[2])
+for _, key in ipairs(KEYS) do
+	if redis.call('PTTL', key) < tonumber(ARGV[4]) then
+		redis.call('PEXPIRE', key, ARGV[4])
+	end
+end
+return 1
+`)
+)
+
+// 为每个用户申请count个连续的序号，返回每个用户申请到的最后一个序号
+func (ss *Store) IncrUidSeqs(ctx context.Context, uids []string, count int64) (map[string]int64, error) {
+	keyToUid := map[string]string{}
+	keys := make([]string, len(uids))
+	for i, uid := range uids {
+		keys[i] = useqKey(uid)
+		keyToUid[keys[i]] = uid
+	}
+
+	// 集群下各用户的key分布在不同节点，按节点分组pipeline
+	ret := map[string]int64{}
+	for _, group := range redispool.Partition(ss.redisPool, keys) {
+		if err := func() error {
+			conn, err := ss.redisPool.GetContext(ctx)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer conn.Close()
+
+			for _, key := range group {
+				if err := conn.Send("INCRBY", key, count); err != nil {
+					return errors.WithStack(err)
+				}
+			}
+
+			if err := conn.Flush(); err != nil {
+				return errors.WithStack(err)
+			}
+
+			for _, key := range group {
+				seq, err := redis.Int64(conn.Receive())
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				ret[keyToUid[key]] = seq
+			}
+			return nil
+		}(); err != nil {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+// 归还某用户最后申请的count个序号，返回是否归还成功
+func (ss *Store) RollbackUidSeq(ctx context.Context, uid string, last int64, count int64) (bool, error) {
+	ok, err := redis.Bool(ss.doScript(ctx, rollbackUidSeqLua, useqKey(uid), last, count))
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// 检查此消息是否需要等待此用户之前的消息处理完毕
+func (ss *Store) IsBlocked(ctx context.Context, uid string, seq string, oseq int64) (bool, error) {
+	blocked, err := redis.Bool(ss.doScript(ctx, isBlockedLua, uptsKey(uid), uptdKey(uid), nowMs(), seq, oseq))
+	if err != nil {
+		return false, err
+	}
+	return blocked, nil
+}
+
+// 标记此消息处于重试中或者等待中，ttl 之内没有再次标记就不再阻塞后续消息
+func (ss *Store) MarkPending(ctx context.Context, uid string, seq string, oseq int64, ttl time.Duration) error {
+	_, err := ss.doScript(ctx, markPendingLua, uptsKey(uid), uptdKey(uid), nowMs(), seq, oseq, int64(ttl/time.Millisecond))
+	return err
+}
+
+func (ss *Store) UnmarkPending(ctx context.Context, uid string, seq string) error {
+	conn, err := ss.redisPool.GetContext(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	conn.Send("MULTI")
+	conn.Send("ZREM", uptsKey(uid), seq)
+	conn.Send("ZREM", uptdKey(uid), seq)
+	if _, err := conn.Do("EXEC"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package sessionstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestStore(t *testing.T) (*Store, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("miniredis.Run: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	t.Cleanup(func() { pool.Close() })
+
+	return NewStore(logrus.New(), pool), mr
+}
+
+// 两种实现需要表现一致
+// miniredis的key不会自行过期，sleep 会让其时间一并前进
+func forEachStore(t *testing.T, f func(t *testing.T, ss StationStore, sleep func(d time.Duration))) {
+	t.Run("memory", func(t *testing.T) {
+		f(t, NewMemoryStore(), time.Sleep)
+	})
+	t.Run("redis", func(t *testing.T) {
+		ss, mr := newTestStore(t)
+		f(t, ss, func(d time.Duration) {
+			time.Sleep(d)
+			mr.FastForward(d)
+		})
+	})
+}
+
+func incrUidSeq(t *testing.T, ss StationStore, uid string, count int64) int64 {
+	ret, err := ss.IncrUidSeqs(context.Background(), []string{uid}, count)
+	if err != nil {
+		t.Fatalf("IncrUidSeqs: %+v", err)
+	}
+	return ret[uid]
+}
+
+func isBlocked(t *testing.T, ss StationStore, uid string, seq string, oseq int64) bool {
+	blocked, err := ss.IsBlocked(context.Background(), uid, seq, oseq)
+	if err != nil {
+		t.Fatalf("IsBlocked: %+v", err)
+	}
+	return blocked
+}
+
+func markPending(t *testing.T, ss StationStore, uid string, seq string, oseq int64, ttl time.Duration) {
+	if err := ss.MarkPending(context.Background(), uid, seq, oseq, ttl); err != nil {
+		t.Fatalf("MarkPending: %+v", err)
+	}
+}
+
+func TestIncrUidSeqs(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ss StationStore, _ func(time.Duration)) {
+		ret, err := ss.IncrUidSeqs(context.Background(), []string{"u1", "u2"}, 3)
+		if err != nil {
+			t.Fatalf("IncrUidSeqs: %+v", err)
+		}
+		if ret["u1"] != 3 || ret["u2"] != 3 {
+			t.Fatalf("IncrUidSeqs: got %v, want 3 for each", ret)
+		}
+		if got := incrUidSeq(t, ss, "u1", 2); got != 5 {
+			t.Fatalf("IncrUidSeqs again: got %d, want 5", got)
+		}
+	})
+}
+
+func TestRollbackUidSeq(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ss StationStore, _ func(time.Duration)) {
+		ctx := context.Background()
+
+		last := incrUidSeq(t, ss, "u1", 2)
+		if ok, err := ss.RollbackUidSeq(ctx, "u1", last, 2); err != nil || !ok {
+			t.Fatalf("RollbackUidSeq: got %v %v, want true", ok, err)
+		}
+		if got := incrUidSeq(t, ss, "u1", 1); got != 1 {
+			t.Fatalf("IncrUidSeqs after rollback: got %d, want 1", got)
+		}
+
+		// 期间有人再申请的话不能归还，否则会和之后的序号冲突
+		last = incrUidSeq(t, ss, "u1", 2)
+		incrUidSeq(t, ss, "u1", 1)
+		if ok, err := ss.RollbackUidSeq(ctx, "u1", last, 2); err != nil || ok {
+			t.Fatalf("RollbackUidSeq after a later allocation: got %v %v, want false", ok, err)
+		}
+		if got := incrUidSeq(t, ss, "u1", 1); got != 5 {
+			t.Fatalf("IncrUidSeqs: got %d, want 5", got)
+		}
+	})
+}
+
+func TestPending(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ss StationStore, _ func(time.Duration)) {
+		if isBlocked(t, ss, "u1", "p2", 2) {
+			t.Fatal("blocked without pendings")
+		}
+
+		markPending(t, ss, "u1", "p1", 1, time.Hour)
+		if isBlocked(t, ss, "u1", "p1", 1) {
+			t.Fatal("p1 blocked by itself")
+		}
+		if !isBlocked(t, ss, "u1", "p2", 2) {
+			t.Fatal("p2 not blocked by p1")
+		}
+		if isBlocked(t, ss, "u2", "p2", 2) {
+			t.Fatal("u2 blocked by pendings of u1")
+		}
+
+		// 后面的等待中也不影响前面的
+		markPending(t, ss, "u1", "p2", 2, time.Hour)
+		if isBlocked(t, ss, "u1", "p1", 1) {
+			t.Fatal("p1 blocked by the later p2")
+		}
+
+		if err := ss.UnmarkPending(context.Background(), "u1", "p1"); err != nil {
+			t.Fatalf("UnmarkPending: %+v", err)
+		}
+		if isBlocked(t, ss, "u1", "p2", 2) {
+			t.Fatal("p2 blocked after p1 unmarked")
+		}
+		if !isBlocked(t, ss, "u1", "p3", 3) {
+			t.Fatal("p3 not blocked by p2")
+		}
+	})
+}
+
+// 各自的截止时间到了就不再阻塞，不影响其他还未到期的
+func TestPendingExpire(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ss StationStore, sleep func(time.Duration)) {
+		markPending(t, ss, "u1", "p1", 1, 50*time.Millisecond)
+		markPending(t, ss, "u1", "p2", 2, time.Hour)
+		if !isBlocked(t, ss, "u1", "p2", 2) {
+			t.Fatal("p2 not blocked by p1")
+		}
+
+		sleep(100 * time.Millisecond)
+		if isBlocked(t, ss, "u1", "p2", 2) {
+			t.Fatal("p2 blocked by the expired p1")
+		}
+		if !isBlocked(t, ss, "u1", "p3", 3) {
+			t.Fatal("p3 not blocked by p2")
+		}
+
+		// 再次标记会续期
+		markPending(t, ss, "u2", "p1", 1, 50*time.Millisecond)
+		sleep(30 * time.Millisecond)
+		markPending(t, ss, "u2", "p1", 1, time.Hour)
+		sleep(30 * time.Millisecond)
+		if !isBlocked(t, ss, "u2", "p2", 2) {
+			t.Fatal("p2 of u2 not blocked by the renewed p1")
+		}
+
+		// 截止时间短的不能带走其他还未到期的
+		markPending(t, ss, "u3", "p1", 1, time.Hour)
+		markPending(t, ss, "u3", "p2", 2, 50*time.Millisecond)
+		sleep(100 * time.Millisecond)
+		if !isBlocked(t, ss, "u3", "p3", 3) {
+			t.Fatal("p3 of u3 not blocked by p1")
+		}
+	})
+}
@@ -0,0 +1,409 @@
+package sessionstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/molon/pkg/errors"
+)
+
+/*
+// 和会话写入在同一事务里的待投递任务，由relay投递成功后删除
+// 为了兼容redis集群，和会话一样以 {uid} 为hashtag，这样才能在同一事务里写入
+// 分数为下次可被认领的时间(ms)，被认领后会推迟，这样投递失败或者认领者挂掉之后都能被重新认领
+"msg/u:{uid1}/ob": [
+    "id1",
+    "id2",
+]
+
+"msg/u:{uid1}/ob/m": {
+    "id1": "{...}",
+    "id2": "{...}",
+}
+
+// 存在待投递任务的用户索引，分数为其下次可被认领的时间(ms)
+// 写入任务的前后各更新一次: 之前是为了写入后挂掉也能被找到，之后是为了不被正在认领此用户的relay误删
+"msg/{outbox}/us": [
+    "uid1",
+    "uid2",
+]
+*/
+
+const outboxUidsKey = "msg/{outbox}/us"
+
+func uobKey(uid string) string {
+	return fmt.Sprintf("msg/u:{%s}/ob", uid)
+}
+
+func uobmKey(uid string) string {
+	return fmt.Sprintf("msg/u:{%s}/ob/m", uid)
+}
+
+var (
+	/*
+		KEYS : msg/{outbox}/us
+		ARGV : now count lease_until
+		返回 : 到期的uid列表，其分数被推迟至lease_until
+	*/
+	claimOutboxUidsLua = redis.NewScript(1, `
+local uids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, uid in ipairs(uids) do
+	redis.call('ZADD', KEYS[1], ARGV[3], uid)
+end
+return uids
+`)
+
+	/*
+		KEYS : msg/{outbox}/us
+		ARGV : uid lease_until next
+		分数依然是认领时设置的lease_until才更新，否则说明期间有新任务写入，保持不动
+		next为空表示此用户已经没有任务了
+	*/
+	settleOutboxUidLua = redis.NewScript(1, `
+local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not score or tonumber(score) ~= tonumber(ARGV[2]) then
+	return 0
+end
+if ARGV[3] == '' then
+	redis.call('ZREM', KEYS[1], ARGV[1])
+else
+	redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+end
+return 1
+`)
+
+	/*
+		KEYS : msg/u:{uid1}/ob msg/u:{uid1}/ob/m
+		ARGV : now count lease_until
+		返回 : [next, id1, 内容1, id2, 内容2...]，next为剩余任务最早可被认领的时间，没有剩余则为空
+	*/
+	claimOutboxLua = redis.NewScript(2, `
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+local msgs = {''}
+for _, id in ipairs(ids) do
+	local m = redis.call('HGET', KEYS[2], id)
+	if m then
+		redis.call('ZADD', KEYS[1], ARGV[3], id)
+		table.insert(msgs, id)
+		table.insert(msgs, m)
+	else
+		redis.call('ZREM', KEYS[1], id)
+	end
+end
+local first = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if first[2] then
+	msgs[1] = first[2]
+end
+return msgs
+`)
+)
+
+// 待投递至mq的任务
+type OutboxMessage struct {
+	Id    string `json:"id"`
+	Topic string `json:"topic"`
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+
+	// 所属用户，认领时填充
+	Uid string `json:"-"`
+}
+
+// 写入会话，同时原子写入待投递的任务
+func (ss *Store) SetSessionWithOutbox(ctx context.Context, sess Session, outbox []*OutboxMessage) error {
+	if !sess.Valid() {
+		return errors.Errorf("session is not valid")
+	}
+
+	if err := ss.indexBoatSession(ctx, sess); err != nil {
+		return err
+	}
+
+	if len(outbox) > 0 {
+		if err := ss.markOutboxUid(ctx, sess.Uid); err != nil {
+			return err
+		}
+	}
+
+	if err := func() error {
+		conn, err := ss.redisPool.GetContext(ctx)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer conn.Close()
+
+		if err := conn.Send("MULTI"); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := ss.sendSession(conn, sess); err != nil {
+			return err
+		}
+		if err := sendOutbox(conn, sess.Uid, outbox); err != nil {
+			return err
+		}
+		if _, err := conn.Do("EXEC"); err != nil {
+			return errors.WithStack(err)
+		}
+		return nil
+	}(); err != nil {
+		return err
+	}
+
+	if len(outbox) > 0 {
+		if err := ss.markOutboxUid(ctx, sess.Uid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 单独写入某用户的待投递任务，用于直接投递失败之后稍后重试
+func (ss *Store) AddOutbox(ctx context.Context, uid string, outbox []*OutboxMessage) error {
+	if len(uid) < 1 {
+		return errors.WithStack(ErrNoUid)
+	}
+	if len(outbox) <= 0 {
+		return nil
+	}
+
+	// 和 SetSessionWithOutbox 一样，写入前后各标记一次
+	if err := ss.markOutboxUid(ctx, uid); err != nil {
+		return err
+	}
+
+	if err := func() error {
+		conn, err := ss.redisPool.GetContext(ctx)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer conn.Close()
+
+		if err := conn.Send("MULTI"); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := sendOutbox(conn, uid, outbox); err != nil {
+			return err
+		}
+		if _, err := conn.Do("EXEC"); err != nil {
+			return errors.WithStack(err)
+		}
+		return nil
+	}(); err != nil {
+		return err
+	}
+
+	return ss.markOutboxUid(ctx, uid)
+}
+
+func sendOutbox(conn redis.Conn, uid string, outbox []*OutboxMessage) error {
+	now := nowMs()
+	for _, m := range outbox {
+		b, err := json.Marshal(m)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if err := conn.Send("HSET", uobmKey(uid), m.Id, b); err != nil {
+			return errors.WithStack(err)
+		}
+		if err := conn.Send("ZADD", uobKey(uid), now, m.Id); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// 标记此用户有待投递的任务，立即可被认领
+func (ss *Store) markOutboxUid(ctx context.Context, uid string) error {
+	conn, err := ss.redisPool.GetContext(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Do("ZADD", outboxUidsKey, nowMs(), uid); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// 认领最多count个到期的任务，认领后 lease 时间内不会被再次认领
+func (ss *Store) ClaimOutbox(ctx context.Context, count int, lease time.Duration) ([]*OutboxMessage, error) {
+	now := nowMs()
+	leaseUntil := now + int64(lease/time.Millisecond)
+
+	uids, err := ss.claimOutboxUids(ctx, now, count, leaseUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := []*OutboxMessage{}
+	for i, uid := range uids {
+		// 已经够数了，剩下的用户放回去等下次
+		if len(msgs) >= count {
+			for _, uid := range uids[i:] {
+				if err := ss.settleOutboxUid(ctx, uid, leaseUntil, now); err != nil {
+					ss.logger.Warnf("Settle outbox uid(%s) failed: %+v", uid, err)
+				}
+			}
+			break
+		}
+
+		ms, next, err := ss.claimUidOutbox(ctx, uid, now, count-len(msgs), leaseUntil)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, ms...)
+
+		if err := ss.settleOutboxUid(ctx, uid, leaseUntil, next); err != nil {
+			ss.logger.Warnf("Settle outbox uid(%s) failed: %+v", uid, err)
+		}
+	}
+
+	return msgs, nil
+}
+
+func (ss *Store) claimOutboxUids(ctx context.Context, now int64, count int, leaseUntil int64) ([]string, error) {
+	conn, err := ss.redisPool.GetContext(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	uids, err := redis.Strings(claimOutboxUidsLua.Do(conn, outboxUidsKey, now, count, leaseUntil))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return uids, nil
+}
+
+// next<=0 表示此用户已经没有任务了
+func (ss *Store) settleOutboxUid(ctx context.Context, uid string, leaseUntil int64, next int64) error {
+	conn, err := ss.redisPool.GetContext(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	var nextArg interface{} = ""
+	if next > 0 {
+		nextArg = next
+	}
+
+	if _, err := settleOutboxUidLua.Do(conn, outboxUidsKey, uid, leaseUntil, nextArg); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// 返回认领到的任务以及剩余任务最早可被认领的时间，没有剩余则为0
+func (ss *Store) claimUidOutbox(ctx context.Context, uid string, now int64, count int, leaseUntil int64) ([]*OutboxMessage, int64, error) {
+	conn, err := ss.redisPool.GetContext(ctx)
+	if err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	bs, err := redis.ByteSlices(claimOutboxLua.Do(conn,
+		uobKey(uid), uobmKey(uid),
+		now, count, leaseUntil,
+	))
+	if err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+	if len(bs) <= 0 {
+		return nil, 0, errors.Errorf("unexpected claim outbox result")
+	}
+
+	var next int64
+	if len(bs[0]) > 0 {
+		next, err = redis.Int64(bs[0], nil)
+		if err != nil {
+			return nil, 0, errors.WithStack(err)
+		}
+	}
+
+	// 之后是 id,内容 交替的列表
+	msgs := make([]*OutboxMessage, 0, len(bs)/2)
+	badIds := []string{}
+	for i := 1; i+1 < len(bs); i += 2 {
+		m := &OutboxMessage{}
+		if err := json.Unmarshal(bs[i+1], m); err != nil {
+			// 烂数据不应该出现，打印出来顺便删除
+			ss.logger.Warnf("Unmarshal outbox message(%s) failed: %v", bs[i], err)
+			badIds = append(badIds, string(bs[i]))
+			continue
+		}
+		m.Uid = uid
+		msgs = append(msgs, m)
+	}
+
+	if len(badIds) > 0 {
+		if err := ss.deleteUidOutbox(ctx, uid, badIds); err != nil {
+			ss.logger.Warnf("Delete outbox failed: %+v", err)
+		}
+	}
+
+	return msgs, next, nil
+}
+
+// 投递成功之后删除
+func (ss *Store) DeleteOutbox(ctx context.Context, msgs []*OutboxMessage) error {
+	uids := []string{}
+	uidToIds := map[string][]string{}
+	for _, m := range msgs {
+		if _, ok := uidToIds[m.Uid]; !ok {
+			uids = append(uids, m.Uid)
+		}
+		uidToIds[m.Uid] = append(uidToIds[m.Uid], m.Id)
+	}
+
+	for _, uid := range uids {
+		if err := ss.deleteUidOutbox(ctx, uid, uidToIds[uid]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ss *Store) deleteUidOutbox(ctx context.Context, uid string, ids []string) error {
+	if len(ids) <= 0 {
+		return nil
+	}
+
+	conn, err := ss.redisPool.GetContext(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	zArgs := []interface{}{uobKey(uid)}
+	hArgs := []interface{}{uobmKey(uid)}
+	for _, id := range ids {
+		zArgs = append(zArgs, id)
+		hArgs = append(hArgs, id)
+	}
+
+	if err := conn.Send("MULTI"); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := conn.Send("ZREM", zArgs...); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := conn.Send("HDEL", hArgs...); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func nowMs() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,148 @@
+package sessionstore
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+)
+
+func addOutbox(t *testing.T, ss StationStore, uid string, ids ...string) {
+	msgs := []*OutboxMessage{}
+	for _, id := range ids {
+		msgs = append(msgs, &OutboxMessage{Id: id, Topic: "msg", Key: uid, Value: []byte(id)})
+	}
+	if err := ss.AddOutbox(context.Background(), uid, msgs); err != nil {
+		t.Fatalf("AddOutbox: %+v", err)
+	}
+}
+
+// 返回认领到的 uid/id 列表，已排序
+func claimOutbox(t *testing.T, ss StationStore, count int, lease time.Duration) ([]string, []*OutboxMessage) {
+	msgs, err := ss.ClaimOutbox(context.Background(), count, lease)
+	if err != nil {
+		t.Fatalf("ClaimOutbox: %+v", err)
+	}
+	ret := []string{}
+	for _, m := range msgs {
+		if string(m.Value) != m.Id {
+			t.Fatalf("ClaimOutbox: got value %q of %s", m.Value, m.Id)
+		}
+		ret = append(ret, m.Uid+"/"+m.Id)
+	}
+	sort.Strings(ret)
+	return ret, msgs
+}
+
+func equalStrings(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestClaimOutbox(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ss StationStore, _ func(time.Duration)) {
+		addOutbox(t, ss, "u1", "a", "b")
+		addOutbox(t, ss, "u2", "c")
+
+		got, msgs := claimOutbox(t, ss, 10, time.Hour)
+		if !equalStrings(got, "u1/a", "u1/b", "u2/c") {
+			t.Fatalf("ClaimOutbox: got %v", got)
+		}
+
+		// 认领期间不会被再次认领
+		if got, _ := claimOutbox(t, ss, 10, time.Hour); len(got) != 0 {
+			t.Fatalf("ClaimOutbox within lease: got %v", got)
+		}
+
+		if err := ss.DeleteOutbox(context.Background(), msgs); err != nil {
+			t.Fatalf("DeleteOutbox: %+v", err)
+		}
+	})
+}
+
+// 超出数目的剩余任务下次还能立即认领
+func TestClaimOutboxCount(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ss StationStore, _ func(time.Duration)) {
+		addOutbox(t, ss, "u1", "a", "b")
+		addOutbox(t, ss, "u2", "c")
+
+		first, _ := claimOutbox(t, ss, 2, time.Hour)
+		if len(first) != 2 {
+			t.Fatalf("ClaimOutbox: got %v, want 2", first)
+		}
+		second, _ := claimOutbox(t, ss, 2, time.Hour)
+		if len(second) != 1 {
+			t.Fatalf("ClaimOutbox the rest: got %v, want 1", second)
+		}
+
+		all := append(first, second...)
+		sort.Strings(all)
+		if !equalStrings(all, "u1/a", "u1/b", "u2/c") {
+			t.Fatalf("ClaimOutbox: got %v", all)
+		}
+	})
+}
+
+// 投递之后没来得及删除就挂掉的，认领过期后会被重新认领，删除之后就不会了
+func TestReclaimOutbox(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ss StationStore, sleep func(time.Duration)) {
+		addOutbox(t, ss, "u1", "a")
+
+		if got, _ := claimOutbox(t, ss, 10, 50*time.Millisecond); !equalStrings(got, "u1/a") {
+			t.Fatalf("ClaimOutbox: got %v", got)
+		}
+
+		sleep(100 * time.Millisecond)
+		got, msgs := claimOutbox(t, ss, 10, 50*time.Millisecond)
+		if !equalStrings(got, "u1/a") {
+			t.Fatalf("ClaimOutbox after lease: got %v", got)
+		}
+		if err := ss.DeleteOutbox(context.Background(), msgs); err != nil {
+			t.Fatalf("DeleteOutbox: %+v", err)
+		}
+
+		sleep(100 * time.Millisecond)
+		if got, _ := claimOutbox(t, ss, 10, time.Hour); len(got) != 0 {
+			t.Fatalf("ClaimOutbox after delete: got %v", got)
+		}
+	})
+}
+
+// 认领期间新写入的任务不用等之前的认领过期
+func TestClaimOutboxWrittenDuringLease(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ss StationStore, _ func(time.Duration)) {
+		addOutbox(t, ss, "u1", "a")
+		if got, _ := claimOutbox(t, ss, 10, time.Hour); !equalStrings(got, "u1/a") {
+			t.Fatalf("ClaimOutbox: got %v", got)
+		}
+
+		addOutbox(t, ss, "u1", "b")
+		if got, _ := claimOutbox(t, ss, 10, time.Hour); !equalStrings(got, "u1/b") {
+			t.Fatalf("ClaimOutbox: got %v, want u1/b", got)
+		}
+	})
+}
+
+// 和会话一起写入的任务
+func TestSetSessionWithOutbox(t *testing.T) {
+	forEachStore(t, func(t *testing.T, ss StationStore, _ func(time.Duration)) {
+		sess := Session{Sid: "s1", Uid: "u1", Bid: "b1", Platform: "mobile", LeaseGen: 1}
+		err := ss.SetSessionWithOutbox(context.Background(), sess, []*OutboxMessage{
+			{Id: "a", Topic: "msg", Key: "u1", Value: []byte("a")},
+		})
+		if err != nil {
+			t.Fatalf("SetSessionWithOutbox: %+v", err)
+		}
+
+		if got, _ := claimOutbox(t, ss, 10, time.Hour); !equalStrings(got, "u1/a") {
+			t.Fatalf("ClaimOutbox: got %v", got)
+		}
+	})
+}
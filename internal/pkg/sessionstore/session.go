@@ -1,16 +1,97 @@
 package sessionstore
 
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/molon/pkg/errors"
+)
+
 type Session struct {
 	Sid      string // session_id
 	Uid      string // user_id
 	Bid      string // boat_id
 	Platform string
+	// 登记时所在boat租约的代数，清理租约到期的boat时只清理不大于认领时代数的会话
+	LeaseGen int64
+
+	// 附加信息，读取时需指定 WithMetadata 才有，可能为nil
+	Metadata *Metadata
+}
+
+// 会话的附加信息，连接建立时记录，只用于排查和统计，不参与投递
+type Metadata struct {
+	ConnectedAt   time.Time         `json:"connected_at"`
+	ClientVersion string            `json:"client_version,omitempty"`
+	DeviceId      string            `json:"device_id,omitempty"`
+	RemoteIp      string            `json:"remote_ip,omitempty"`
+	UserAgent     string            `json:"user_agent,omitempty"`
+	Claims        map[string]string `json:"claims,omitempty"`
 }
 
 func (sess *Session) Valid() bool {
 	return sess.Sid != "" && sess.Uid != "" && sess.Bid != "" && sess.Platform != ""
 }
 
+/*
+存储于redis里的会话详情，json编码，例如 {"v":1,"p":"apple-watch","b":"bid1"}
+新增字段加上omitempty即可，旧版本读取时会忽略，不兼容的变更才需要升级版本
+v、p、b 的含义不能再变，lease.go 里的lua脚本也依赖它们
+
+之前的版本为 "platform-bid"，平台或者boat名称里有'-'的话就无法解析，不再写入
+旧key迁移(offlinectl migrate-keys)过来的依然兼容读取，其租约代数视为0
+*/
+type detail struct {
+	Version  int    `json:"v"`
+	Platform string `json:"p"`
+	Bid      string `json:"b"`
+	LeaseGen int64  `json:"g,omitempty"`
+}
+
+const detailVersion = 1
+
+// 比当前版本新的详情，由升级后的实例写入，不能当作烂数据删除
+var errNewerDetail = errors.Errorf("newer session detail")
+
+func (sess *Session) detail() (string, error) {
+	b, err := json.Marshal(&detail{
+		Version:  detailVersion,
+		Platform: sess.Platform,
+		Bid:      sess.Bid,
+		LeaseGen: sess.LeaseGen,
+	})
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return string(b), nil
+}
+
+// 解析会话详情，填充 Platform 和 Bid
+func (sess *Session) parseDetail(s string) error {
+	if !strings.HasPrefix(s, "{") {
+		es := strings.Split(s, "-")
+		if len(es) != 2 || es[0] == "" || es[1] == "" {
+			return errors.Errorf("invalid legacy session detail: %s", s)
+		}
+		sess.Platform, sess.Bid = es[0], es[1]
+		return nil
+	}
+
+	d := &detail{}
+	if err := json.Unmarshal([]byte(s), d); err != nil {
+		return errors.WithStack(err)
+	}
+	if d.Version > detailVersion {
+		return errNewerDetail
+	}
+	if d.Platform == "" || d.Bid == "" {
+		return errors.Errorf("invalid session detail: %s", s)
+	}
+	sess.Platform, sess.Bid, sess.LeaseGen = d.Platform, d.Bid, d.LeaseGen
+	return nil
+}
+
 type SessionSlice []Session
 
 func (p SessionSlice) Len() int           { return len(p) }
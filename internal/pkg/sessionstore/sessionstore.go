@@ -0,0 +1,118 @@
+// 会话存储的抽象，carrier只依赖于 SessionStore，station还需要outbox、boat租约和用户序号
+// Store 为redis实现，MemoryStore 为进程内实现，可用于单元测试
+package sessionstore
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+type SessionStore interface {
+	// 写入会话，同一sid已存在则忽略
+	SetSession(ctx context.Context, sess Session) error
+
+	// 删除某用户的若干会话，连同其附加信息
+	DeleteSessions(ctx context.Context, uid string, sids []string) error
+
+	// 获取某用户的会话，map 形式 SidToSession
+	GetSidToSession(ctx context.Context, uid string, opts ...GetOption) (map[string]Session, error)
+
+	// 获取某用户的会话，按平台分组，各组内按sid倒序
+	GetPlatformToSessions(ctx context.Context, uid string, opts ...GetOption) (map[string][]Session, error)
+
+	// 获取某用户的会话，按sid倒序
+	GetSessions(ctx context.Context, uid string, opts ...GetOption) ([]Session, error)
+
+	// 有序模式下，检查此消息是否需要等待此用户之前顺序号更小的消息处理完毕
+	IsBlocked(ctx context.Context, uid string, seq string, oseq int64) (bool, error)
+
+	// 标记此消息处于重试中或者等待中，ttl 之内没有再次标记就不再阻塞后续消息
+	MarkPending(ctx context.Context, uid string, seq string, oseq int64, ttl time.Duration) error
+
+	// 处理完毕或者进了死信队列之后解除标记
+	UnmarkPending(ctx context.Context, uid string, seq string) error
+}
+
+type StationStore interface {
+	SessionStore
+
+	// 写入会话，同时原子写入待投递的任务
+	SetSessionWithOutbox(ctx context.Context, sess Session, outbox []*OutboxMessage) error
+
+	// 单独写入某用户的待投递任务，用于直接投递失败之后稍后重试
+	AddOutbox(ctx context.Context, uid string, outbox []*OutboxMessage) error
+
+	// 认领最多count个到期的任务，认领后 lease 时间内不会被再次认领
+	ClaimOutbox(ctx context.Context, count int, lease time.Duration) ([]*OutboxMessage, error)
+
+	// 投递成功之后删除
+	DeleteOutbox(ctx context.Context, msgs []*OutboxMessage) error
+
+	// 续约，返回续约之前租约是否已不存在(首次或者已被认领清理)，以及租约当前的代数，租约每次重新建立代数都会加一
+	RenewLease(ctx context.Context, bid string, ttl time.Duration) (bool, int64, error)
+
+	// 认领最多count个租约已到期的boat，认领后 retryAfter 时间内不会被再次认领，需对其调用 ReapBoat
+	// 租约刚到期的会记下其当时的代数
+	ClaimExpiredBoats(ctx context.Context, count int, retryAfter time.Duration) ([]string, error)
+
+	// 分批删除某boat上代数不大于认领时的会话，每批删除之后以确实被删除的会话回调f，全部完成后将其从待清理列表里移除
+	ReapBoat(ctx context.Context, bid string, f func(sesses []Session) error) error
+
+	// 有序模式下为每个用户申请count个连续的序号，返回每个用户申请到的最后一个序号
+	IncrUidSeqs(ctx context.Context, uids []string, count int64) (map[string]int64, error)
+
+	// 归还某用户最后申请的count个序号，期间有别人申请过则不归还，返回是否归还成功
+	RollbackUidSeq(ctx context.Context, uid string, last int64, count int64) (bool, error)
+}
+
+var (
+	_ StationStore = (*Store)(nil)
+	_ StationStore = (*MemoryStore)(nil)
+)
+
+type getOptions struct {
+	sids         []string
+	withMetadata bool
+}
+
+type GetOption func(*getOptions)
+
+func WithSids(sids []string) GetOption {
+	return func(opts *getOptions) {
+		opts.sids = sids
+	}
+}
+
+// 同时读取会话的附加信息
+func WithMetadata() GetOption {
+	return func(opts *getOptions) {
+		opts.withMetadata = true
+	}
+}
+
+func groupByPlatform(sesses map[string]Session) map[string][]Session {
+	platformToSessions := map[string][]Session{}
+	for _, sess := range sesses {
+		platformToSessions[sess.Platform] = append(platformToSessions[sess.Platform], sess)
+	}
+
+	// 会话列表里按sid倒序，这样是为了让最晚建立的会话成为第一个会话，晚为大
+	for platform := range platformToSessions {
+		sort.Sort(sort.Reverse(SessionSlice(platformToSessions[platform])))
+	}
+
+	return platformToSessions
+}
+
+func sortSessions(sesses map[string]Session) []Session {
+	sessions := make([]Session, 0, len(sesses))
+	for _, sess := range sesses {
+		sessions = append(sessions, sess)
+	}
+
+	// 会话列表里按sid倒序，这样是为了让最晚建立的会话成为第一个会话，晚为大
+	sort.Sort(sort.Reverse(SessionSlice(sessions)))
+
+	return sessions
+}
@@ -2,11 +2,11 @@ package sessionstore
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"sort"
-	"strings"
 
 	"github.com/gomodule/redigo/redis"
+	"github.com/molon/gomsg/internal/pkg/redispool"
 	"github.com/molon/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc/codes"
@@ -14,27 +14,41 @@ import (
 )
 
 /*
-// 这个结构后续还是可以根据 u:uid1 作为 HashTag 支持集群访问的
-"msg/u:uid1/ss": {
-    "sid1":"platform1-bid1",
+// 以 {uid1} 作为 HashTag 支持集群访问，同一用户的所有key都在同一slot
+// 会话详情的编码见 session.go 里的 detail，旧格式为 "platform-bid"
+"msg/u:{uid1}/ss": {
+    "sid1":"{\"v\":1,\"p\":\"platform1\",\"b\":\"bid1\"}",
     "sid2":"platform3-bid1",
 }
+
+// 会话的附加信息(json)，和会话同时写入和删除
+"msg/u:{uid1}/sm": {
+    "sid1":"{...}",
+    "sid2":"{...}",
+}
+
+// 另外各boat上的会话有反向索引，用于boat租约到期后批量清理，见 lease.go
+// 有序投递用到的用户序号和处理中标记见 ordered.go
 */
 
 var ErrNoUid = status.Errorf(codes.InvalidArgument, "uid is required")
 
 func ussKey(uid string) string {
-	return fmt.Sprintf("msg/u:%s/ss", uid)
+	return fmt.Sprintf("msg/u:{%s}/ss", uid)
+}
+
+func usmKey(uid string) string {
+	return fmt.Sprintf("msg/u:{%s}/sm", uid)
 }
 
 type Store struct {
 	logger    *logrus.Entry
-	redisPool *redis.Pool
+	redisPool redispool.Pool
 }
 
 func NewStore(
 	logger *logrus.Logger,
-	redisPool *redis.Pool,
+	redisPool redispool.Pool,
 ) *Store {
 	ll := logger.WithFields(logrus.Fields{
 		"pkg": "sessionstore",
@@ -52,17 +66,52 @@ func (ss *Store) SetSession(ctx context.Context, sess Session) error {
 		return errors.Errorf("session is not valid")
 	}
 
+	if err := ss.indexBoatSession(ctx, sess); err != nil {
+		return err
+	}
+
 	conn, err := ss.redisPool.GetContext(ctx)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 	defer conn.Close()
 
+	if err := conn.Send("MULTI"); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ss.sendSession(conn, sess); err != nil {
+		return err
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// 会话和其附加信息，需在事务里发送
+func (ss *Store) sendSession(conn redis.Conn, sess Session) error {
+	detail, err := sess.detail()
+	if err != nil {
+		return err
+	}
+
 	// 存入即可，奇怪的是 redis 不支持 HSET k hk hv NX 命令
-	if _, err := conn.Do("HSETNX", ussKey(sess.Uid), sess.Sid, fmt.Sprintf("%s-%s", sess.Platform, sess.Bid)); err != nil {
+	if err := conn.Send("HSETNX", ussKey(sess.Uid), sess.Sid, detail); err != nil {
 		return errors.WithStack(err)
 	}
 
+	if sess.Metadata == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(sess.Metadata)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := conn.Send("HSETNX", usmKey(sess.Uid), sess.Sid, b); err != nil {
+		return errors.WithStack(err)
+	}
 	return nil
 }
 
@@ -75,7 +124,25 @@ func (ss *Store) DeleteSessions(ctx context.Context, uid string, sids []string)
 		return nil
 	}
 
-	// 建立连接
+	// 先查出所在的boat，删除之后再清理其反向索引
+	sidToSession, err := ss.GetSidToSession(ctx, uid, WithSids(sids))
+	if err != nil {
+		return err
+	}
+
+	if err := ss.deleteSessions(ctx, uid, sids); err != nil {
+		return err
+	}
+
+	sesses := make([]Session, 0, len(sidToSession))
+	for _, sess := range sidToSession {
+		sesses = append(sesses, sess)
+	}
+	return ss.unindexBoatSessions(ctx, sesses)
+}
+
+// 同时删除会话和其附加信息
+func (ss *Store) deleteSessions(ctx context.Context, uid string, sids []string) error {
 	conn, err := ss.redisPool.GetContext(ctx)
 	if err != nil {
 		return errors.WithStack(err)
@@ -84,11 +151,22 @@ func (ss *Store) DeleteSessions(ctx context.Context, uid string, sids []string)
 
 	// 整理删除语句结构
 	dArgs := []interface{}{ussKey(uid)}
+	mArgs := []interface{}{usmKey(uid)}
 	for _, sid := range sids {
 		dArgs = append(dArgs, sid)
+		mArgs = append(mArgs, sid)
 	}
 
-	if _, err := conn.Do("HDEL", dArgs...); err != nil {
+	if err := conn.Send("MULTI"); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := conn.Send("HDEL", dArgs...); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := conn.Send("HDEL", mArgs...); err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
 		return errors.WithStack(err)
 	}
 
@@ -149,75 +227,89 @@ func (ss *Store) GetSidToSession(ctx context.Context, uid string, opts ...GetOpt
 
 	dArgs := []interface{}{ussKey}
 	for sid, detail := range sidToDetail {
-		es := strings.Split(detail, "-")
-		if len(es) != 2 { // 烂数据就直接忽略且顺便删除
+		sess := Session{
+			Sid: sid,
+			Uid: uid,
+		}
+		if err := sess.parseDetail(detail); err != nil {
+			// 升级后的实例写入的，忽略即可，不能删除
+			if err == errNewerDetail {
+				continue
+			}
+			// 烂数据就直接忽略且顺便删除
+			ss.logger.Warnf("Drop bad session(%s) of %s: %v", sid, uid, err)
 			dArgs = append(dArgs, sid)
 			continue
 		}
 
-		sesses[sid] = Session{
-			Sid:      sid,
-			Uid:      uid,
-			Platform: es[0],
-			Bid:      es[1],
-		}
+		sesses[sid] = sess
 	}
 
 	if len(dArgs) > 1 {
 		if _, err := conn.Do("HDEL", dArgs...); err != nil {
 			return nil, errors.WithStack(err)
 		}
+		dArgs[0] = usmKey(uid)
+		if _, err := conn.Do("HDEL", dArgs...); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	if ops.withMetadata && len(sesses) > 0 {
+		if err := ss.fillMetadata(conn, uid, sesses); err != nil {
+			return nil, err
+		}
 	}
 
 	return sesses, nil
 }
 
-// 获取session信息，map 形式 PlatformToSessions
-func (ss *Store) GetPlatformToSessions(ctx context.Context, uid string, opts ...GetOption) (map[string][]Session, error) {
-	sesses, err := ss.GetSidToSession(ctx, uid, opts...)
-	if err != nil {
-		return nil, errors.WithStack(err)
+func (ss *Store) fillMetadata(conn redis.Conn, uid string, sesses map[string]Session) error {
+	args := []interface{}{usmKey(uid)}
+	sids := make([]string, 0, len(sesses))
+	for sid := range sesses {
+		args = append(args, sid)
+		sids = append(sids, sid)
 	}
 
-	platformToSessions := map[string][]Session{}
-	for _, sess := range sesses {
-		platformToSessions[sess.Platform] = append(platformToSessions[sess.Platform], sess)
+	bs, err := redis.ByteSlices(conn.Do("HMGET", args...))
+	if err != nil {
+		return errors.WithStack(err)
 	}
 
-	// 会话列表里按sid倒序，这样是为了让最晚建立的会话成为第一个会话，晚为大
-	for platform, _ := range platformToSessions {
-		sort.Sort(sort.Reverse(SessionSlice(platformToSessions[platform])))
-	}
+	for i, sid := range sids {
+		if len(bs[i]) <= 0 {
+			continue
+		}
+
+		md := &Metadata{}
+		if err := json.Unmarshal(bs[i], md); err != nil {
+			// 附加信息不影响投递，打印出来即可
+			ss.logger.Warnf("Unmarshal metadata of session(%s) failed: %v", sid, err)
+			continue
+		}
 
-	return platformToSessions, nil
+		sess := sesses[sid]
+		sess.Metadata = md
+		sesses[sid] = sess
+	}
+	return nil
 }
 
-// 获取session信息
-func (ss *Store) GetSessions(ctx context.Context, uid string, opts ...GetOption) ([]Session, error) {
+// 获取session信息，map 形式 PlatformToSessions
+func (ss *Store) GetPlatformToSessions(ctx context.Context, uid string, opts ...GetOption) (map[string][]Session, error) {
 	sesses, err := ss.GetSidToSession(ctx, uid, opts...)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-
-	sessions := []Session{}
-	for _, sess := range sesses {
-		sessions = append(sessions, sess)
-	}
-
-	// 会话列表里按sid倒序，这样是为了让最晚建立的会话成为第一个会话，晚为大
-	sort.Sort(sort.Reverse(SessionSlice(sessions)))
-
-	return sessions, nil
+	return groupByPlatform(sesses), nil
 }
 
-type getOptions struct {
-	sids []string
-}
-
-type GetOption func(*getOptions)
-
-func WithSids(sids []string) GetOption {
-	return func(opts *getOptions) {
-		opts.sids = sids
+// 获取session信息
+func (ss *Store) GetSessions(ctx context.Context, uid string, opts ...GetOption) ([]Session, error) {
+	sesses, err := ss.GetSidToSession(ctx, uid, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
 	}
+	return sortSessions(sesses), nil
 }
@@ -38,6 +38,8 @@ type AuthResponse struct {
 	Uid string `protobuf:"bytes,1,opt,name=uid" json:"uid,omitempty"`
 	// 平台 mobile/desktop 或者 其他
 	Platform string `protobuf:"bytes,2,opt,name=platform" json:"platform,omitempty"`
+	// 鉴权得到的其他信息，例如租户/角色，会记录在会话的附加信息里
+	Claims map[string]string `protobuf:"bytes,3,rep,name=claims" json:"claims,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 }
 
 func (m *AuthResponse) Reset()                    { *m = AuthResponse{} }
@@ -59,6 +61,13 @@ func (m *AuthResponse) GetPlatform() string {
 	return ""
 }
 
+func (m *AuthResponse) GetClaims() map[string]string {
+	if m != nil {
+		return m.Claims
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*AuthResponse)(nil), "authpb.AuthResponse")
 }
@@ -140,17 +149,21 @@ var _Auth_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("github.com/molon/gomsg/pb/authpb/auth.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 182 bytes of a gzipped FileDescriptorProto
+	// 246 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xd2, 0x4e, 0xcf, 0x2c, 0xc9,
 	0x28, 0x4d, 0xd2, 0x4b, 0xce, 0xcf, 0xd5, 0xcf, 0xcd, 0xcf, 0xc9, 0xcf, 0xd3, 0x4f, 0xcf, 0xcf,
 	0x2d, 0x4e, 0xd7, 0x2f, 0x48, 0xd2, 0x4f, 0x2c, 0x2d, 0xc9, 0x80, 0x52, 0x7a, 0x05, 0x45, 0xf9,
 	0x25, 0xf9, 0x42, 0x6c, 0x10, 0x21, 0x29, 0xe9, 0xf4, 0xfc, 0xfc, 0xf4, 0x9c, 0x54, 0x7d, 0xb0,
-	0x68, 0x52, 0x69, 0x9a, 0x7e, 0x6a, 0x6e, 0x41, 0x49, 0x25, 0x44, 0x91, 0x92, 0x0d, 0x17, 0x8f,
-	0x63, 0x69, 0x49, 0x46, 0x50, 0x6a, 0x71, 0x41, 0x7e, 0x5e, 0x71, 0xaa, 0x90, 0x00, 0x17, 0x73,
-	0x69, 0x66, 0x8a, 0x04, 0xa3, 0x02, 0xa3, 0x06, 0x67, 0x10, 0x88, 0x29, 0x24, 0xc5, 0xc5, 0x51,
-	0x90, 0x93, 0x58, 0x92, 0x96, 0x5f, 0x94, 0x2b, 0xc1, 0x04, 0x16, 0x86, 0xf3, 0x8d, 0xec, 0xb8,
-	0x58, 0x40, 0xba, 0x85, 0xcc, 0xa0, 0xb4, 0x98, 0x1e, 0xc4, 0x2e, 0x3d, 0x98, 0x5d, 0x7a, 0xae,
-	0x20, 0xbb, 0xa4, 0x44, 0xf4, 0x20, 0x6e, 0xd1, 0x43, 0xb6, 0x4b, 0x89, 0xc1, 0x49, 0x29, 0x4a,
-	0x81, 0x90, 0x8f, 0x92, 0xd8, 0xc0, 0x66, 0x19, 0x03, 0x02, 0x00, 0x00, 0xff, 0xff, 0xe2, 0x38,
-	0x06, 0xe2, 0xfc, 0x00, 0x00, 0x00,
+	0x68, 0x52, 0x69, 0x9a, 0x7e, 0x6a, 0x6e, 0x41, 0x49, 0x25, 0x44, 0x91, 0xd2, 0x46, 0x46, 0x2e,
+	0x1e, 0xc7, 0xd2, 0x92, 0x8c, 0xa0, 0xd4, 0xe2, 0x82, 0xfc, 0xbc, 0xe2, 0x54, 0x21, 0x01, 0x2e,
+	0xe6, 0xd2, 0xcc, 0x14, 0x09, 0x46, 0x05, 0x46, 0x0d, 0xce, 0x20, 0x10, 0x53, 0x48, 0x8a, 0x8b,
+	0xa3, 0x20, 0x27, 0xb1, 0x24, 0x2d, 0xbf, 0x28, 0x57, 0x82, 0x09, 0x2c, 0x0c, 0xe7, 0x0b, 0x59,
+	0x70, 0xb1, 0x25, 0xe7, 0x24, 0x66, 0xe6, 0x16, 0x4b, 0x30, 0x2b, 0x30, 0x6b, 0x70, 0x1b, 0x29,
+	0xe8, 0x41, 0x2c, 0xd5, 0x43, 0x36, 0x53, 0xcf, 0x19, 0xac, 0xc4, 0x35, 0xaf, 0xa4, 0xa8, 0x32,
+	0x08, 0xaa, 0x5e, 0xca, 0x92, 0x8b, 0x1b, 0x49, 0x18, 0x64, 0x6d, 0x76, 0x6a, 0x25, 0xcc, 0xda,
+	0xec, 0xd4, 0x4a, 0x21, 0x11, 0x2e, 0xd6, 0xb2, 0xc4, 0x9c, 0xd2, 0x54, 0xa8, 0x9d, 0x10, 0x8e,
+	0x15, 0x93, 0x05, 0xa3, 0x91, 0x1d, 0x17, 0x0b, 0xc8, 0x78, 0x21, 0x33, 0x28, 0x2d, 0xa6, 0x07,
+	0xf1, 0xa1, 0x1e, 0xcc, 0x87, 0x7a, 0xae, 0x20, 0x1f, 0x4a, 0x89, 0x60, 0x73, 0x8c, 0x12, 0x83,
+	0x93, 0x52, 0x94, 0x02, 0xa1, 0x70, 0x4c, 0x62, 0x03, 0x9b, 0x65, 0x0c, 0x18, 0x00, 0x6e, 0x91,
+	0x9e, 0x3b, 0x72, 0x01, 0x00, 0x00,
 }
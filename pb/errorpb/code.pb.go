@@ -42,6 +42,12 @@ const (
 	Code_SESSION_NOT_FOUND Code = 4
 	// 相同平台的重复会话
 	Code_NEW_SESSION_ON_SAME_PLATFORM Code = 5
+	// 拉取离线消息时的游标不合法
+	Code_INVALID_CURSOR Code = 6
+	// 所在boat的租约曾过期，会话登记已被清理，需要重新连接
+	Code_SESSION_EXPIRED Code = 7
+	// 进行中的请求太多，稍后重试
+	Code_TOO_MANY_REQUESTS Code = 8
 )
 
 var Code_name = map[int32]string{
@@ -51,6 +57,9 @@ var Code_name = map[int32]string{
 	3: "TOO_MANY_MSGS_TO_BE_SENT",
 	4: "SESSION_NOT_FOUND",
 	5: "NEW_SESSION_ON_SAME_PLATFORM",
+	6: "INVALID_CURSOR",
+	7: "SESSION_EXPIRED",
+	8: "TOO_MANY_REQUESTS",
 }
 var Code_value = map[string]int32{
 	"NONE":                         0,
@@ -59,6 +68,9 @@ var Code_value = map[string]int32{
 	"TOO_MANY_MSGS_TO_BE_SENT":     3,
 	"SESSION_NOT_FOUND":            4,
 	"NEW_SESSION_ON_SAME_PLATFORM": 5,
+	"INVALID_CURSOR":               6,
+	"SESSION_EXPIRED":              7,
+	"TOO_MANY_REQUESTS":            8,
 }
 
 func (x Code) String() string {
@@ -90,20 +102,23 @@ func init() {
 func init() { proto.RegisterFile("github.com/molon/gomsg/pb/errorpb/code.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 236 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x8f, 0x41, 0x4b, 0xc3, 0x40,
-	0x10, 0x46, 0x8d, 0xc6, 0x54, 0x46, 0x94, 0x75, 0x41, 0xe8, 0xa1, 0x87, 0x56, 0x2f, 0xa2, 0x92,
-	0x05, 0xfd, 0x05, 0x69, 0xbb, 0x15, 0xa9, 0x99, 0x91, 0xee, 0x96, 0xa2, 0x97, 0xc1, 0xb4, 0x21,
-	0x16, 0x1a, 0x27, 0xc4, 0x78, 0xf7, 0xa7, 0x4b, 0x4b, 0x3c, 0xf7, 0xf8, 0x7d, 0xef, 0x5d, 0x1e,
-	0xdc, 0x17, 0xeb, 0xe6, 0xf3, 0x27, 0x8b, 0x97, 0x52, 0x9a, 0x52, 0x36, 0xf2, 0x65, 0x0a, 0x29,
-	0xbf, 0x0b, 0x53, 0x65, 0x26, 0xaf, 0x6b, 0xa9, 0xab, 0xcc, 0x2c, 0x65, 0x95, 0xc7, 0x55, 0x2d,
-	0x8d, 0xe8, 0x4e, 0xfb, 0x5d, 0xdd, 0x41, 0x34, 0xce, 0x9b, 0x8f, 0xf5, 0x46, 0x0f, 0x20, 0xdc,
-	0x0a, 0xdd, 0xa0, 0x1f, 0xdc, 0x9c, 0x3f, 0x9c, 0xc5, 0xad, 0x11, 0x8f, 0x64, 0x95, 0xcf, 0x76,
-	0xe8, 0xf6, 0x37, 0x80, 0x70, 0x3b, 0xf5, 0x09, 0x84, 0x48, 0x68, 0xd5, 0x81, 0x3e, 0x85, 0xce,
-	0x1c, 0xa7, 0x48, 0x0b, 0x54, 0x81, 0x06, 0x88, 0x90, 0x38, 0x19, 0x4d, 0xd5, 0xa1, 0xee, 0x41,
-	0xd7, 0x13, 0x71, 0x9a, 0xe0, 0x1b, 0xa7, 0xee, 0xc9, 0xb1, 0x27, 0x1e, 0x5a, 0x76, 0x16, 0xbd,
-	0x3a, 0xd2, 0x97, 0x70, 0xe1, 0xac, 0x73, 0xcf, 0x84, 0x8c, 0xe4, 0x79, 0x42, 0x73, 0x1c, 0xab,
-	0x50, 0xf7, 0xa1, 0x87, 0x76, 0xc1, 0xff, 0x88, 0x90, 0x5d, 0x92, 0x5a, 0x7e, 0x7d, 0x49, 0xfc,
-	0x84, 0x66, 0xa9, 0x3a, 0x1e, 0x5e, 0xbf, 0x0f, 0xf6, 0x86, 0x66, 0xd1, 0x2e, 0xf2, 0xf1, 0x2f,
-	0x00, 0x00, 0xff, 0xff, 0xa5, 0x65, 0xc2, 0xfe, 0x14, 0x01, 0x00, 0x00,
+	// 276 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x90, 0x41, 0x4f, 0xf2, 0x40,
+	0x10, 0x86, 0xbf, 0x7e, 0xd6, 0x42, 0xc6, 0x88, 0xeb, 0x1a, 0x13, 0x0e, 0x1c, 0x40, 0x2f, 0x46,
+	0x4d, 0x9b, 0xe8, 0x2f, 0x28, 0x74, 0x31, 0x0d, 0x74, 0x06, 0x77, 0xb7, 0xa2, 0x5e, 0x26, 0x16,
+	0x1a, 0x24, 0x01, 0x97, 0x54, 0xfc, 0x7f, 0xfe, 0x34, 0x03, 0x01, 0xaf, 0x1e, 0x67, 0xde, 0xf7,
+	0xcd, 0x93, 0x3c, 0x70, 0x3b, 0x9b, 0xaf, 0xdf, 0xbf, 0x8a, 0x70, 0xe2, 0x96, 0xd1, 0xd2, 0x2d,
+	0xdc, 0x47, 0x34, 0x73, 0xcb, 0xcf, 0x59, 0xb4, 0x2a, 0xa2, 0xb2, 0xaa, 0x5c, 0xb5, 0x2a, 0xa2,
+	0x89, 0x9b, 0x96, 0xe1, 0xaa, 0x72, 0x6b, 0x27, 0x6b, 0xbb, 0xdf, 0xc5, 0x0d, 0x04, 0x49, 0xb9,
+	0x7e, 0x9b, 0x2f, 0x64, 0x07, 0xfc, 0x4d, 0xa1, 0xe9, 0xb5, 0xbd, 0xab, 0xc6, 0xdd, 0x71, 0xb8,
+	0x6b, 0x84, 0x3d, 0x37, 0x2d, 0xf5, 0x36, 0xba, 0xfe, 0xf6, 0xc0, 0xdf, 0x9c, 0xb2, 0x0e, 0x3e,
+	0x12, 0x2a, 0xf1, 0x4f, 0x1e, 0x41, 0x2d, 0xc7, 0x01, 0xd2, 0x18, 0x85, 0x27, 0x01, 0x02, 0x24,
+	0x8e, 0x7b, 0x03, 0xf1, 0x5f, 0xb6, 0xa0, 0x69, 0x89, 0x38, 0x8b, 0xf1, 0x85, 0x33, 0xf3, 0x60,
+	0xd8, 0x12, 0x77, 0x15, 0x1b, 0x85, 0x56, 0x1c, 0xc8, 0x73, 0x38, 0x35, 0xca, 0x98, 0x94, 0x90,
+	0x91, 0x2c, 0xf7, 0x29, 0xc7, 0x44, 0xf8, 0xb2, 0x0d, 0x2d, 0x54, 0x63, 0xde, 0x47, 0x84, 0x6c,
+	0xe2, 0x4c, 0xf1, 0x68, 0x18, 0xdb, 0x3e, 0xe9, 0x4c, 0x1c, 0x4a, 0x09, 0x8d, 0x14, 0x9f, 0xe2,
+	0x61, 0x9a, 0x70, 0x2f, 0xd7, 0x86, 0xb4, 0x08, 0xe4, 0x19, 0x9c, 0xec, 0x17, 0xea, 0x79, 0x94,
+	0x6a, 0x95, 0x88, 0xda, 0x86, 0xf0, 0xcb, 0xd7, 0xea, 0x31, 0x57, 0xc6, 0x1a, 0x51, 0xef, 0x5e,
+	0xbe, 0x76, 0xfe, 0x14, 0x55, 0x04, 0x5b, 0x49, 0xf7, 0x3f, 0x03, 0x00, 0x0e, 0xa5, 0x1e, 0xdb,
+	0x54, 0x01, 0x00, 0x00,
 }
@@ -9,9 +9,12 @@ It is generated from these files:
 
 It has these top-level messages:
 	Ack
+	Read
 	Ping
 	Pong
 	SubRoomRequest
+	SyncRequest
+	SyncResponse
 	CommonResponse
 	ClientPayload
 	ServerPayload
@@ -24,6 +27,7 @@ import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
 import google_protobuf "github.com/golang/protobuf/ptypes/any"
+import google_protobuf1 "github.com/golang/protobuf/ptypes/timestamp"
 import errorpb "github.com/molon/gomsg/pb/errorpb"
 
 import (
@@ -73,6 +77,29 @@ func (x MessageOption) String() string {
 }
 func (MessageOption) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
+// 消息优先级
+// 离线存储超出最大数目时先清理低优先级的，连接建立时先下发高优先级的离线消息
+type MessagePriority int32
+
+const (
+	MessagePriority_NORMAL MessagePriority = 0
+	MessagePriority_HIGH   MessagePriority = 1
+)
+
+var MessagePriority_name = map[int32]string{
+	0: "NORMAL",
+	1: "HIGH",
+}
+var MessagePriority_value = map[string]int32{
+	"NORMAL": 0,
+	"HIGH":   1,
+}
+
+func (x MessagePriority) String() string {
+	return proto.EnumName(MessagePriority_name, int32(x))
+}
+func (MessagePriority) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
 // Ack反馈消息
 type Ack struct {
 	Seq string `protobuf:"bytes,1,opt,name=seq" json:"seq,omitempty"`
@@ -90,6 +117,24 @@ func (m *Ack) GetSeq() string {
 	return ""
 }
 
+// 已读回执，会被投递到回执事件流里
+type Read struct {
+	// 已读的消息seq列表
+	Seqs []string `protobuf:"bytes,1,rep,name=seqs" json:"seqs,omitempty"`
+}
+
+func (m *Read) Reset()                    { *m = Read{} }
+func (m *Read) String() string            { return proto.CompactTextString(m) }
+func (*Read) ProtoMessage()               {}
+func (*Read) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *Read) GetSeqs() []string {
+	if m != nil {
+		return m.Seqs
+	}
+	return nil
+}
+
 // 心跳，这个是由于一些语言的gRPC skd不支持客户端的keepalive
 // 所以客户端检测服务端存活就需要自行ping，服务端这里简单的返回pong而已
 type Ping struct {
@@ -98,7 +143,7 @@ type Ping struct {
 func (m *Ping) Reset()                    { *m = Ping{} }
 func (m *Ping) String() string            { return proto.CompactTextString(m) }
 func (*Ping) ProtoMessage()               {}
-func (*Ping) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*Ping) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
 
 type Pong struct {
 	Code errorpb.Code `protobuf:"varint,1,opt,name=code,enum=errorpb.Code" json:"code,omitempty"`
@@ -108,7 +153,7 @@ type Pong struct {
 func (m *Pong) Reset()                    { *m = Pong{} }
 func (m *Pong) String() string            { return proto.CompactTextString(m) }
 func (*Pong) ProtoMessage()               {}
-func (*Pong) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*Pong) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
 func (m *Pong) GetCode() errorpb.Code {
 	if m != nil {
@@ -133,7 +178,7 @@ type SubRoomRequest struct {
 func (m *SubRoomRequest) Reset()                    { *m = SubRoomRequest{} }
 func (m *SubRoomRequest) String() string            { return proto.CompactTextString(m) }
 func (*SubRoomRequest) ProtoMessage()               {}
-func (*SubRoomRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*SubRoomRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
 
 func (m *SubRoomRequest) GetRoom() string {
 	if m != nil {
@@ -149,6 +194,85 @@ func (m *SubRoomRequest) GetParams() *google_protobuf.Any {
 	return nil
 }
 
+// 分页拉取离线消息，按发出时间顺序，拉取不会删除离线消息
+type SyncRequest struct {
+	// 上一页反馈的next_cursor，为空则从最早的离线消息开始
+	Cursor string `protobuf:"bytes,1,opt,name=cursor" json:"cursor,omitempty"`
+	// 本页最多多少条，<=0则使用服务端默认值，超过服务端上限则按上限
+	Limit int32 `protobuf:"varint,2,opt,name=limit" json:"limit,omitempty"`
+}
+
+func (m *SyncRequest) Reset()                    { *m = SyncRequest{} }
+func (m *SyncRequest) String() string            { return proto.CompactTextString(m) }
+func (*SyncRequest) ProtoMessage()               {}
+func (*SyncRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *SyncRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+func (m *SyncRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+// 拉取离线消息的反馈
+type SyncResponse struct {
+	// 对应请求的seq
+	Seq  string       `protobuf:"bytes,1,opt,name=seq" json:"seq,omitempty"`
+	Code errorpb.Code `protobuf:"varint,2,opt,name=code,enum=errorpb.Code" json:"code,omitempty"`
+	Msg  string       `protobuf:"bytes,3,opt,name=msg" json:"msg,omitempty"`
+	// 本页的消息，已过期的不会返回，所以条数可能少于limit
+	Msgs []*Message `protobuf:"bytes,4,rep,name=msgs" json:"msgs,omitempty"`
+	// 下一页的游标，为空表示没有更多了
+	NextCursor string `protobuf:"bytes,5,opt,name=next_cursor,json=nextCursor" json:"next_cursor,omitempty"`
+}
+
+func (m *SyncResponse) Reset()                    { *m = SyncResponse{} }
+func (m *SyncResponse) String() string            { return proto.CompactTextString(m) }
+func (*SyncResponse) ProtoMessage()               {}
+func (*SyncResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *SyncResponse) GetSeq() string {
+	if m != nil {
+		return m.Seq
+	}
+	return ""
+}
+
+func (m *SyncResponse) GetCode() errorpb.Code {
+	if m != nil {
+		return m.Code
+	}
+	return errorpb.Code_NONE
+}
+
+func (m *SyncResponse) GetMsg() string {
+	if m != nil {
+		return m.Msg
+	}
+	return ""
+}
+
+func (m *SyncResponse) GetMsgs() []*Message {
+	if m != nil {
+		return m.Msgs
+	}
+	return nil
+}
+
+func (m *SyncResponse) GetNextCursor() string {
+	if m != nil {
+		return m.NextCursor
+	}
+	return ""
+}
+
 // 通用反馈，跟对一些后缀为Request的使用
 type CommonResponse struct {
 	Seq  string       `protobuf:"bytes,1,opt,name=seq" json:"seq,omitempty"`
@@ -159,7 +283,7 @@ type CommonResponse struct {
 func (m *CommonResponse) Reset()                    { *m = CommonResponse{} }
 func (m *CommonResponse) String() string            { return proto.CompactTextString(m) }
 func (*CommonResponse) ProtoMessage()               {}
-func (*CommonResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+func (*CommonResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
 
 func (m *CommonResponse) GetSeq() string {
 	if m != nil {
@@ -190,13 +314,15 @@ type ClientPayload struct {
 	//	*ClientPayload_Ack
 	//	*ClientPayload_Ping
 	//	*ClientPayload_Sub
+	//	*ClientPayload_Read
+	//	*ClientPayload_Sync
 	Body isClientPayload_Body `protobuf_oneof:"Body"`
 }
 
 func (m *ClientPayload) Reset()                    { *m = ClientPayload{} }
 func (m *ClientPayload) String() string            { return proto.CompactTextString(m) }
 func (*ClientPayload) ProtoMessage()               {}
-func (*ClientPayload) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+func (*ClientPayload) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
 
 type isClientPayload_Body interface{ isClientPayload_Body() }
 
@@ -209,10 +335,18 @@ type ClientPayload_Ping struct {
 type ClientPayload_Sub struct {
 	Sub *SubRoomRequest `protobuf:"bytes,13,opt,name=sub,oneof"`
 }
+type ClientPayload_Read struct {
+	Read *Read `protobuf:"bytes,14,opt,name=read,oneof"`
+}
+type ClientPayload_Sync struct {
+	Sync *SyncRequest `protobuf:"bytes,15,opt,name=sync,oneof"`
+}
 
 func (*ClientPayload_Ack) isClientPayload_Body()  {}
 func (*ClientPayload_Ping) isClientPayload_Body() {}
 func (*ClientPayload_Sub) isClientPayload_Body()  {}
+func (*ClientPayload_Read) isClientPayload_Body() {}
+func (*ClientPayload_Sync) isClientPayload_Body() {}
 
 func (m *ClientPayload) GetBody() isClientPayload_Body {
 	if m != nil {
@@ -249,12 +383,28 @@ func (m *ClientPayload) GetSub() *SubRoomRequest {
 	return nil
 }
 
+func (m *ClientPayload) GetRead() *Read {
+	if x, ok := m.GetBody().(*ClientPayload_Read); ok {
+		return x.Read
+	}
+	return nil
+}
+
+func (m *ClientPayload) GetSync() *SyncRequest {
+	if x, ok := m.GetBody().(*ClientPayload_Sync); ok {
+		return x.Sync
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*ClientPayload) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _ClientPayload_OneofMarshaler, _ClientPayload_OneofUnmarshaler, _ClientPayload_OneofSizer, []interface{}{
 		(*ClientPayload_Ack)(nil),
 		(*ClientPayload_Ping)(nil),
 		(*ClientPayload_Sub)(nil),
+		(*ClientPayload_Read)(nil),
+		(*ClientPayload_Sync)(nil),
 	}
 }
 
@@ -277,6 +427,16 @@ func _ClientPayload_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Sub); err != nil {
 			return err
 		}
+	case *ClientPayload_Read:
+		b.EncodeVarint(14<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Read); err != nil {
+			return err
+		}
+	case *ClientPayload_Sync:
+		b.EncodeVarint(15<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Sync); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("ClientPayload.Body has unexpected type %T", x)
@@ -311,6 +471,22 @@ func _ClientPayload_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.
 		err := b.DecodeMessage(msg)
 		m.Body = &ClientPayload_Sub{msg}
 		return true, err
+	case 14: // Body.read
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Read)
+		err := b.DecodeMessage(msg)
+		m.Body = &ClientPayload_Read{msg}
+		return true, err
+	case 15: // Body.sync
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(SyncRequest)
+		err := b.DecodeMessage(msg)
+		m.Body = &ClientPayload_Sync{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -335,6 +511,16 @@ func _ClientPayload_OneofSizer(msg proto.Message) (n int) {
 		n += proto.SizeVarint(13<<3 | proto.WireBytes)
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *ClientPayload_Read:
+		s := proto.Size(x.Read)
+		n += proto.SizeVarint(14<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ClientPayload_Sync:
+		s := proto.Size(x.Sync)
+		n += proto.SizeVarint(15<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -352,13 +538,14 @@ type ServerPayload struct {
 	//	*ServerPayload_Pong
 	//	*ServerPayload_MsgsWrapper
 	//	*ServerPayload_SubResp
+	//	*ServerPayload_SyncResp
 	Body isServerPayload_Body `protobuf_oneof:"Body"`
 }
 
 func (m *ServerPayload) Reset()                    { *m = ServerPayload{} }
 func (m *ServerPayload) String() string            { return proto.CompactTextString(m) }
 func (*ServerPayload) ProtoMessage()               {}
-func (*ServerPayload) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+func (*ServerPayload) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
 
 type isServerPayload_Body interface{ isServerPayload_Body() }
 
@@ -371,10 +558,14 @@ type ServerPayload_MsgsWrapper struct {
 type ServerPayload_SubResp struct {
 	SubResp *CommonResponse `protobuf:"bytes,13,opt,name=sub_resp,json=subResp,oneof"`
 }
+type ServerPayload_SyncResp struct {
+	SyncResp *SyncResponse `protobuf:"bytes,14,opt,name=sync_resp,json=syncResp,oneof"`
+}
 
 func (*ServerPayload_Pong) isServerPayload_Body()        {}
 func (*ServerPayload_MsgsWrapper) isServerPayload_Body() {}
 func (*ServerPayload_SubResp) isServerPayload_Body()     {}
+func (*ServerPayload_SyncResp) isServerPayload_Body()    {}
 
 func (m *ServerPayload) GetBody() isServerPayload_Body {
 	if m != nil {
@@ -418,12 +609,20 @@ func (m *ServerPayload) GetSubResp() *CommonResponse {
 	return nil
 }
 
+func (m *ServerPayload) GetSyncResp() *SyncResponse {
+	if x, ok := m.GetBody().(*ServerPayload_SyncResp); ok {
+		return x.SyncResp
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*ServerPayload) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _ServerPayload_OneofMarshaler, _ServerPayload_OneofUnmarshaler, _ServerPayload_OneofSizer, []interface{}{
 		(*ServerPayload_Pong)(nil),
 		(*ServerPayload_MsgsWrapper)(nil),
 		(*ServerPayload_SubResp)(nil),
+		(*ServerPayload_SyncResp)(nil),
 	}
 }
 
@@ -446,6 +645,11 @@ func _ServerPayload_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.SubResp); err != nil {
 			return err
 		}
+	case *ServerPayload_SyncResp:
+		b.EncodeVarint(14<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.SyncResp); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("ServerPayload.Body has unexpected type %T", x)
@@ -480,6 +684,14 @@ func _ServerPayload_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.
 		err := b.DecodeMessage(msg)
 		m.Body = &ServerPayload_SubResp{msg}
 		return true, err
+	case 14: // Body.sync_resp
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(SyncResponse)
+		err := b.DecodeMessage(msg)
+		m.Body = &ServerPayload_SyncResp{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -504,6 +716,11 @@ func _ServerPayload_OneofSizer(msg proto.Message) (n int) {
 		n += proto.SizeVarint(13<<3 | proto.WireBytes)
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *ServerPayload_SyncResp:
+		s := proto.Size(x.SyncResp)
+		n += proto.SizeVarint(14<<3 | proto.WireBytes)
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -519,12 +736,25 @@ type Message struct {
 	Options MessageOption `protobuf:"varint,2,opt,name=options,enum=msgpb.MessageOption" json:"options,omitempty"`
 	// 消息体
 	Body *google_protobuf.Any `protobuf:"bytes,3,opt,name=body" json:"body,omitempty"`
+	// 用户维度单调递增的序号，仅在有序投递模式下有值
+	// 客户端可依此检测消息是否有缺失，注意若推送时指定了平台，其他平台会看到不连续的序号
+	UidSeq int64 `protobuf:"varint,4,opt,name=uid_seq,json=uidSeq" json:"uid_seq,omitempty"`
+	// 过期时间，为空则不过期，过期的消息不会再投递也不会存储为离线消息
+	// 客户端收到已过期的消息也应该丢弃
+	ExpireAt *google_protobuf1.Timestamp `protobuf:"bytes,5,opt,name=expire_at,json=expireAt" json:"expire_at,omitempty"`
+	// 业务自定义的分类，例如 chat/system，未读数可按此分组统计
+	Category string `protobuf:"bytes,6,opt,name=category" json:"category,omitempty"`
+	// 优先级
+	Priority MessagePriority `protobuf:"varint,7,opt,name=priority,enum=msgpb.MessagePriority" json:"priority,omitempty"`
+	// 折叠key，例如 "order:123:status"，为空则不折叠
+	// 同一用户同一平台的离线消息里同一key只保留发出时间最新的那条，适合只关心最新状态的消息
+	CollapseKey string `protobuf:"bytes,8,opt,name=collapse_key,json=collapseKey" json:"collapse_key,omitempty"`
 }
 
 func (m *Message) Reset()                    { *m = Message{} }
 func (m *Message) String() string            { return proto.CompactTextString(m) }
 func (*Message) ProtoMessage()               {}
-func (*Message) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+func (*Message) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
 
 func (m *Message) GetSeq() string {
 	if m != nil {
@@ -547,6 +777,41 @@ func (m *Message) GetBody() *google_protobuf.Any {
 	return nil
 }
 
+func (m *Message) GetUidSeq() int64 {
+	if m != nil {
+		return m.UidSeq
+	}
+	return 0
+}
+
+func (m *Message) GetExpireAt() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.ExpireAt
+	}
+	return nil
+}
+
+func (m *Message) GetCategory() string {
+	if m != nil {
+		return m.Category
+	}
+	return ""
+}
+
+func (m *Message) GetPriority() MessagePriority {
+	if m != nil {
+		return m.Priority
+	}
+	return MessagePriority_NORMAL
+}
+
+func (m *Message) GetCollapseKey() string {
+	if m != nil {
+		return m.CollapseKey
+	}
+	return ""
+}
+
 // 消息列表wrapper
 type MessagesWrapper struct {
 	Msgs []*Message `protobuf:"bytes,1,rep,name=msgs" json:"msgs,omitempty"`
@@ -555,7 +820,7 @@ type MessagesWrapper struct {
 func (m *MessagesWrapper) Reset()                    { *m = MessagesWrapper{} }
 func (m *MessagesWrapper) String() string            { return proto.CompactTextString(m) }
 func (*MessagesWrapper) ProtoMessage()               {}
-func (*MessagesWrapper) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+func (*MessagesWrapper) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
 
 func (m *MessagesWrapper) GetMsgs() []*Message {
 	if m != nil {
@@ -566,15 +831,19 @@ func (m *MessagesWrapper) GetMsgs() []*Message {
 
 func init() {
 	proto.RegisterType((*Ack)(nil), "msgpb.Ack")
+	proto.RegisterType((*Read)(nil), "msgpb.Read")
 	proto.RegisterType((*Ping)(nil), "msgpb.Ping")
 	proto.RegisterType((*Pong)(nil), "msgpb.Pong")
 	proto.RegisterType((*SubRoomRequest)(nil), "msgpb.SubRoomRequest")
+	proto.RegisterType((*SyncRequest)(nil), "msgpb.SyncRequest")
+	proto.RegisterType((*SyncResponse)(nil), "msgpb.SyncResponse")
 	proto.RegisterType((*CommonResponse)(nil), "msgpb.CommonResponse")
 	proto.RegisterType((*ClientPayload)(nil), "msgpb.ClientPayload")
 	proto.RegisterType((*ServerPayload)(nil), "msgpb.ServerPayload")
 	proto.RegisterType((*Message)(nil), "msgpb.Message")
 	proto.RegisterType((*MessagesWrapper)(nil), "msgpb.MessagesWrapper")
 	proto.RegisterEnum("msgpb.MessageOption", MessageOption_name, MessageOption_value)
+	proto.RegisterEnum("msgpb.MessagePriority", MessagePriority_name, MessagePriority_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -686,42 +955,59 @@ var _Msg_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("github.com/molon/gomsg/pb/msgpb/msg.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 588 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x54, 0x4d, 0x6f, 0xd3, 0x40,
-	0x10, 0x8d, 0x6b, 0x93, 0x84, 0xc9, 0x07, 0x61, 0xd5, 0x42, 0xda, 0x03, 0xb4, 0x3e, 0xa5, 0x08,
-	0xd9, 0x10, 0xc4, 0x85, 0x9e, 0xdc, 0x90, 0x2a, 0x11, 0x6d, 0x12, 0x6d, 0x2b, 0x2a, 0x71, 0x89,
-	0xec, 0x64, 0x59, 0xa2, 0xc6, 0x3b, 0xae, 0x37, 0x06, 0xe5, 0x97, 0xf0, 0xd7, 0xf8, 0x39, 0x68,
-	0xd7, 0x9b, 0x20, 0x97, 0x56, 0xbd, 0x58, 0x3b, 0x33, 0x6f, 0xdf, 0xbc, 0x37, 0xb3, 0x32, 0x1c,
-	0xf3, 0xc5, 0xea, 0x47, 0x16, 0x79, 0x33, 0x8c, 0xfd, 0x18, 0x97, 0x28, 0x7c, 0x8e, 0xb1, 0xe4,
-	0x7e, 0x12, 0xf9, 0xb1, 0xe4, 0xf9, 0xd7, 0x4b, 0x52, 0x5c, 0x21, 0x79, 0xa2, 0x13, 0x07, 0xfb,
-	0x1c, 0x91, 0x2f, 0x99, 0xaf, 0x93, 0x51, 0xf6, 0xdd, 0x0f, 0xc5, 0x3a, 0x47, 0x1c, 0x10, 0x96,
-	0xa6, 0x98, 0x26, 0x91, 0x3f, 0xc3, 0x39, 0xcb, 0x73, 0xee, 0x4b, 0xb0, 0x83, 0xd9, 0x0d, 0x69,
-	0x81, 0x2d, 0xd9, 0x6d, 0xdb, 0x3a, 0xb4, 0x3a, 0x4f, 0xa9, 0x3a, 0xba, 0x65, 0x70, 0x26, 0x0b,
-	0xc1, 0xdd, 0x13, 0x70, 0x26, 0x28, 0x38, 0x39, 0x02, 0x47, 0x5d, 0xd3, 0x90, 0x66, 0xb7, 0xe1,
-	0x19, 0x2e, 0xaf, 0x87, 0x73, 0x46, 0x75, 0x49, 0x91, 0xc4, 0x92, 0xb7, 0x77, 0x72, 0x92, 0x58,
-	0x72, 0x97, 0x42, 0xf3, 0x32, 0x8b, 0x28, 0x62, 0x4c, 0xd9, 0x6d, 0xc6, 0xe4, 0x8a, 0x10, 0x70,
-	0x52, 0xc4, 0xd8, 0x74, 0xd2, 0x67, 0xf2, 0x16, 0xca, 0x49, 0x98, 0x86, 0xb1, 0xd4, 0x57, 0x6b,
-	0xdd, 0x5d, 0x2f, 0xf7, 0xe0, 0x6d, 0x3c, 0x78, 0x81, 0x58, 0x53, 0x83, 0x71, 0xaf, 0xa1, 0xd9,
-	0xc3, 0x38, 0x46, 0x41, 0x99, 0x4c, 0x50, 0x48, 0xf6, 0xbf, 0xf8, 0xad, 0xd8, 0x9d, 0x47, 0xc5,
-	0xda, 0xff, 0xc4, 0xfe, 0xb6, 0xa0, 0xd1, 0x5b, 0x2e, 0x98, 0x58, 0x4d, 0xc2, 0xf5, 0x12, 0xc3,
-	0xf9, 0x3d, 0xc4, 0xaf, 0xc0, 0x0e, 0x67, 0x37, 0xed, 0x9a, 0xd6, 0x09, 0x9e, 0x1e, 0xb9, 0x17,
-	0xcc, 0x6e, 0x06, 0x25, 0xaa, 0x0a, 0xaa, 0x71, 0xb2, 0x10, 0xbc, 0x5d, 0xd7, 0x80, 0x9a, 0x01,
-	0xa8, 0x41, 0x0e, 0x4a, 0x54, 0x97, 0xc8, 0x31, 0xd8, 0x32, 0x8b, 0xda, 0x0d, 0x8d, 0xd8, 0x33,
-	0x88, 0xe2, 0x94, 0x14, 0x9b, 0xcc, 0xa2, 0xd3, 0x32, 0x38, 0xa7, 0x38, 0x5f, 0xbb, 0x7f, 0x2c,
-	0x68, 0x5c, 0xb2, 0xf4, 0x27, 0x4b, 0x1f, 0x56, 0xb6, 0x0f, 0x55, 0xc1, 0xd8, 0x7c, 0xaa, 0xe4,
-	0x29, 0xdb, 0x55, 0x5a, 0x51, 0x71, 0x60, 0x44, 0xa1, 0xe0, 0x46, 0xf5, 0x56, 0x14, 0x1a, 0x51,
-	0x6a, 0xbb, 0x27, 0x50, 0x8f, 0x25, 0x97, 0xd3, 0x5f, 0x69, 0x98, 0x24, 0x2c, 0x35, 0xfa, 0x5f,
-	0x18, 0xe8, 0x05, 0x93, 0x32, 0xe4, 0x4c, 0x5e, 0xe7, 0xd5, 0x41, 0x89, 0xd6, 0x14, 0xda, 0x84,
-	0xa4, 0x0b, 0x55, 0x99, 0x45, 0xd3, 0x94, 0xc9, 0xe4, 0x8e, 0xad, 0xe2, 0xa2, 0x06, 0x25, 0x5a,
-	0x91, 0x59, 0xa4, 0xc2, 0xad, 0xb5, 0x0c, 0x2a, 0x86, 0xfd, 0x1e, 0x4f, 0x1e, 0x54, 0x30, 0x59,
-	0x2d, 0x50, 0x48, 0xb3, 0xc9, 0xdd, 0xa2, 0xa0, 0xb1, 0x2e, 0xd2, 0x0d, 0x88, 0x74, 0xc0, 0x89,
-	0x70, 0xbe, 0xd6, 0x4b, 0x7d, 0xe8, 0x19, 0x69, 0x84, 0xfb, 0x11, 0x9e, 0xdd, 0x31, 0x45, 0x5c,
-	0x70, 0x94, 0xa9, 0xb6, 0x75, 0x68, 0x77, 0x6a, 0xdd, 0x66, 0xb1, 0x13, 0xd5, 0xb5, 0x37, 0x13,
-	0x68, 0x14, 0x5a, 0x93, 0x2a, 0x38, 0xa3, 0xf1, 0xa8, 0xdf, 0x2a, 0x91, 0x3a, 0x54, 0x47, 0xfd,
-	0xfe, 0xe7, 0x69, 0xd0, 0xfb, 0xd2, 0xb2, 0x48, 0x0b, 0xea, 0x3a, 0x1a, 0x9f, 0x9d, 0x9d, 0x0f,
-	0x47, 0xfd, 0xd6, 0x0e, 0xd9, 0x83, 0xe7, 0x3a, 0x33, 0x1a, 0x5f, 0x0d, 0xcf, 0x86, 0xbd, 0xe0,
-	0x6a, 0x38, 0x1e, 0xb5, 0x9c, 0x6e, 0x00, 0xf6, 0x85, 0xe4, 0xe4, 0x13, 0x94, 0xcf, 0x11, 0x93,
-	0xaf, 0xef, 0xc9, 0xc6, 0x62, 0xe1, 0x25, 0x1e, 0x6c, 0xb2, 0x85, 0x57, 0xe0, 0x96, 0x3a, 0xd6,
-	0x3b, 0xeb, 0xf4, 0xe8, 0xdb, 0xeb, 0x47, 0xfe, 0x12, 0x51, 0x59, 0x8f, 0xe0, 0xc3, 0xdf, 0x00,
-	0x00, 0x00, 0xff, 0xff, 0x6f, 0xad, 0x5a, 0xa0, 0x4f, 0x04, 0x00, 0x00,
+	// 858 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x55, 0xdd, 0x6e, 0xe3, 0x44,
+	0x14, 0x8e, 0x63, 0xd7, 0x71, 0x8f, 0x93, 0x34, 0x0c, 0xdd, 0x5d, 0x6f, 0x2e, 0x68, 0xeb, 0x1b,
+	0xb2, 0x2b, 0xe4, 0x40, 0x10, 0x42, 0xa2, 0x57, 0x6e, 0x68, 0x49, 0xb4, 0x6d, 0x12, 0x4d, 0x2b,
+	0x56, 0xe2, 0x26, 0xf2, 0xcf, 0x60, 0xac, 0xc6, 0x1e, 0x67, 0xc6, 0x86, 0xf5, 0x3b, 0xf0, 0x04,
+	0x3c, 0x0a, 0xcf, 0xc3, 0x83, 0xa0, 0x19, 0x3b, 0x41, 0xce, 0xee, 0xd2, 0x9b, 0xbd, 0xa9, 0x7c,
+	0xce, 0xf9, 0xe6, 0x3b, 0xf3, 0x9d, 0x6f, 0x4e, 0x03, 0xaf, 0xa2, 0x38, 0xff, 0xad, 0xf0, 0x9d,
+	0x80, 0x26, 0xe3, 0x84, 0x6e, 0x68, 0x3a, 0x8e, 0x68, 0xc2, 0xa3, 0x71, 0xe6, 0x8f, 0x13, 0x1e,
+	0x55, 0x7f, 0x9d, 0x8c, 0xd1, 0x9c, 0xa2, 0x23, 0x99, 0x18, 0xbe, 0x8c, 0x28, 0x8d, 0x36, 0x64,
+	0x2c, 0x93, 0x7e, 0xf1, 0xeb, 0xd8, 0x4b, 0xcb, 0x0a, 0x31, 0x3c, 0x3b, 0x2c, 0xe5, 0x71, 0x42,
+	0x78, 0xee, 0x25, 0x59, 0x0d, 0x40, 0x84, 0x31, 0xca, 0x32, 0x7f, 0x1c, 0xd0, 0x90, 0x54, 0x39,
+	0xfb, 0x05, 0xa8, 0x6e, 0xf0, 0x88, 0x06, 0xa0, 0x72, 0xb2, 0xb5, 0x94, 0x73, 0x65, 0x74, 0x8c,
+	0xc5, 0xa7, 0x3d, 0x04, 0x0d, 0x13, 0x2f, 0x44, 0x08, 0x34, 0x4e, 0xb6, 0xdc, 0x52, 0xce, 0xd5,
+	0xd1, 0x31, 0x96, 0xdf, 0xb6, 0x0e, 0xda, 0x2a, 0x4e, 0x23, 0xfb, 0x12, 0xb4, 0x15, 0x4d, 0x23,
+	0x74, 0x01, 0x9a, 0xa0, 0x94, 0xc7, 0xfb, 0x93, 0x9e, 0x53, 0xf7, 0x71, 0xa6, 0x34, 0x24, 0x58,
+	0x96, 0x44, 0x83, 0x84, 0x47, 0x56, 0xbb, 0x6a, 0x90, 0xf0, 0xc8, 0xc6, 0xd0, 0xbf, 0x2f, 0x7c,
+	0x4c, 0x69, 0x82, 0xc9, 0xb6, 0x20, 0x3c, 0x17, 0xad, 0x18, 0xa5, 0x49, 0x7d, 0x0b, 0xf9, 0x8d,
+	0xbe, 0x02, 0x3d, 0xf3, 0x98, 0x97, 0x70, 0x79, 0xd4, 0x9c, 0x9c, 0x3a, 0x95, 0x4a, 0x67, 0xa7,
+	0xd2, 0x71, 0xd3, 0x12, 0xd7, 0x18, 0xfb, 0x12, 0xcc, 0xfb, 0x32, 0x0d, 0x76, 0x84, 0xcf, 0x41,
+	0x0f, 0x0a, 0xc6, 0x29, 0xab, 0x29, 0xeb, 0x08, 0x9d, 0xc2, 0xd1, 0x26, 0x4e, 0xe2, 0x5c, 0x72,
+	0x1e, 0xe1, 0x2a, 0xb0, 0xff, 0x52, 0xa0, 0x5b, 0x9d, 0xe6, 0x19, 0x4d, 0x39, 0x79, 0x7f, 0x28,
+	0x7b, 0xa1, 0xed, 0x27, 0x85, 0xaa, 0x7b, 0xa1, 0xc8, 0x06, 0x2d, 0xe1, 0x11, 0xb7, 0xb4, 0x73,
+	0x75, 0x64, 0x4e, 0xfa, 0x8e, 0x34, 0xd2, 0xb9, 0x23, 0x9c, 0x7b, 0x11, 0xc1, 0xb2, 0x86, 0xce,
+	0xc0, 0x4c, 0xc9, 0xbb, 0x7c, 0x5d, 0x5f, 0xf7, 0x48, 0x9e, 0x06, 0x91, 0x9a, 0xca, 0x8c, 0xfd,
+	0x16, 0xfa, 0x53, 0x9a, 0x24, 0x34, 0xfd, 0xc4, 0xb7, 0xb3, 0xff, 0x51, 0xa0, 0x37, 0xdd, 0xc4,
+	0x24, 0xcd, 0x57, 0x5e, 0xb9, 0xa1, 0x5e, 0xf8, 0x01, 0xe2, 0x2f, 0x40, 0xf5, 0x82, 0x47, 0xcb,
+	0x94, 0x0e, 0x40, 0x2d, 0xc0, 0x0d, 0x1e, 0x67, 0x2d, 0x2c, 0x0a, 0xa2, 0x71, 0x16, 0xa7, 0x91,
+	0xd5, 0x95, 0x00, 0xb3, 0x06, 0x88, 0x27, 0x32, 0x6b, 0x61, 0x59, 0x42, 0xaf, 0x40, 0xe5, 0x85,
+	0x6f, 0xf5, 0x24, 0xe2, 0x59, 0x8d, 0x68, 0xfa, 0x2f, 0xd8, 0x78, 0xe1, 0x0b, 0x36, 0x46, 0xbc,
+	0xd0, 0xea, 0x37, 0xd8, 0xc4, 0x63, 0x14, 0x6c, 0xa2, 0x84, 0x46, 0xa0, 0xf1, 0x32, 0x0d, 0xac,
+	0x13, 0x09, 0x41, 0x3b, 0xba, 0xff, 0xac, 0x17, 0x48, 0x81, 0xb8, 0xd2, 0x41, 0xbb, 0xa2, 0x61,
+	0x69, 0xff, 0xd9, 0x86, 0xde, 0x3d, 0x61, 0xbf, 0x13, 0xf6, 0x71, 0x99, 0x2f, 0xc1, 0x48, 0x09,
+	0x09, 0xd7, 0x42, 0xab, 0x98, 0xa1, 0x81, 0x3b, 0x22, 0x76, 0x6b, 0x85, 0x34, 0x8d, 0x2c, 0xb3,
+	0x71, 0x27, 0xf1, 0xf8, 0xa5, 0x42, 0xb1, 0x04, 0x97, 0xd0, 0x15, 0x56, 0xae, 0xff, 0x60, 0x5e,
+	0x96, 0x11, 0x56, 0x0f, 0xe3, 0x79, 0xd3, 0x6e, 0xfe, 0xb6, 0xaa, 0xce, 0x5a, 0xd8, 0x14, 0xe8,
+	0x3a, 0x44, 0x13, 0x30, 0x78, 0xe1, 0xaf, 0x19, 0xe1, 0xd9, 0xc1, 0x8c, 0x9a, 0xae, 0xcf, 0x5a,
+	0xb8, 0xc3, 0x0b, 0x5f, 0x84, 0x68, 0x02, 0xc7, 0x42, 0x62, 0x75, 0xa8, 0x1a, 0xd6, 0xe7, 0x8d,
+	0x49, 0xec, 0x8f, 0x18, 0xbc, 0x8e, 0xf7, 0xe3, 0xf8, 0xbb, 0x0d, 0x9d, 0xfa, 0x4a, 0x1f, 0x18,
+	0x84, 0x03, 0x1d, 0x9a, 0xe5, 0x31, 0x4d, 0x79, 0xfd, 0x96, 0x4e, 0x9b, 0x2a, 0x96, 0xb2, 0x88,
+	0x77, 0x20, 0x61, 0x87, 0x4f, 0xc3, 0xd2, 0x52, 0xff, 0x67, 0x45, 0x25, 0x02, 0xbd, 0x80, 0x4e,
+	0x11, 0x87, 0x6b, 0xd1, 0x4f, 0x3b, 0x57, 0x46, 0x2a, 0xd6, 0x8b, 0x38, 0xbc, 0x27, 0x5b, 0xf4,
+	0x3d, 0x1c, 0x93, 0x77, 0x59, 0xcc, 0xc8, 0xda, 0xcb, 0xe5, 0xf3, 0x37, 0x27, 0xc3, 0xf7, 0x78,
+	0x1e, 0x76, 0xff, 0xd0, 0xb0, 0x51, 0x81, 0xdd, 0x1c, 0x0d, 0xc1, 0x08, 0xbc, 0x9c, 0x44, 0x94,
+	0x95, 0x96, 0x2e, 0x25, 0xec, 0x63, 0x31, 0xd5, 0x8c, 0xc5, 0x94, 0xc5, 0x79, 0x69, 0x75, 0xa4,
+	0x90, 0x03, 0x3b, 0x56, 0x75, 0x15, 0xef, 0x71, 0xe8, 0x02, 0xba, 0x01, 0xdd, 0x6c, 0xbc, 0x8c,
+	0x93, 0xf5, 0x23, 0x29, 0x2d, 0x43, 0x72, 0x9a, 0xbb, 0xdc, 0x1b, 0x52, 0xda, 0xdf, 0xc1, 0xc9,
+	0x81, 0x9d, 0xfb, 0x1d, 0x57, 0x3e, 0xbe, 0xe3, 0xaf, 0x57, 0xd0, 0x6b, 0xcc, 0x0f, 0x19, 0xa0,
+	0x2d, 0x96, 0x8b, 0xeb, 0x41, 0x0b, 0x75, 0xc1, 0x58, 0x5c, 0x5f, 0xff, 0xb8, 0x76, 0xa7, 0x6f,
+	0x06, 0x0a, 0x1a, 0x40, 0x57, 0x46, 0xcb, 0x9b, 0x9b, 0xdb, 0xf9, 0xe2, 0x7a, 0xd0, 0x46, 0xcf,
+	0xe0, 0x33, 0x99, 0x59, 0x2c, 0x1f, 0xe6, 0x37, 0xf3, 0xa9, 0xfb, 0x30, 0x5f, 0x2e, 0x06, 0xda,
+	0xeb, 0x2f, 0xe1, 0xe4, 0x40, 0x08, 0x02, 0xd0, 0x17, 0x4b, 0x7c, 0xe7, 0xde, 0x0e, 0x5a, 0x82,
+	0x7f, 0x36, 0xff, 0x69, 0x36, 0x50, 0x26, 0x2e, 0xa8, 0x77, 0x3c, 0x42, 0x3f, 0x80, 0x7e, 0x4b,
+	0x69, 0xf6, 0xf3, 0x37, 0x68, 0x67, 0x68, 0x63, 0xf3, 0x87, 0xbb, 0x6c, 0x63, 0x51, 0xec, 0xd6,
+	0x48, 0xf9, 0x5a, 0xb9, 0xba, 0xf8, 0xe5, 0xec, 0x89, 0x1f, 0x2b, 0x5f, 0x97, 0x46, 0x7d, 0xfb,
+	0xef, 0x00, 0x36, 0x65, 0x9a, 0x84, 0xd6, 0x06, 0x00, 0x00,
 }
@@ -18,6 +18,7 @@ import fmt "fmt"
 import math "math"
 import google_protobuf "github.com/golang/protobuf/ptypes/empty"
 import google_protobuf1 "github.com/golang/protobuf/ptypes/any"
+import google_protobuf2 "github.com/golang/protobuf/ptypes/duration"
 import _ "google.golang.org/genproto/googleapis/api/annotations"
 import msgpb "github.com/molon/gomsg/pb/msgpb"
 
@@ -77,6 +78,14 @@ type PushRequest struct {
 	MsgOptions msgpb.MessageOption `protobuf:"varint,22,opt,name=msg_options,json=msgOptions,enum=msgpb.MessageOption" json:"msg_options,omitempty"`
 	// 针对某用户单独设置消息特性
 	ExclusiveMsgOptions map[string]msgpb.MessageOption `protobuf:"bytes,23,rep,name=exclusive_msg_options,json=exclusiveMsgOptions" json:"exclusive_msg_options,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value,enum=msgpb.MessageOption"`
+	// 消息存活时间，为空则不过期，例如正在输入、来电响铃等时效性很强的消息
+	MsgTtl *google_protobuf2.Duration `protobuf:"bytes,24,opt,name=msg_ttl,json=msgTtl" json:"msg_ttl,omitempty"`
+	// 消息分类，未读数可按此分组统计
+	MsgCategory string `protobuf:"bytes,25,opt,name=msg_category,json=msgCategory" json:"msg_category,omitempty"`
+	// 消息优先级，高优先级的推送任务可由独立的topic投递，不受普通消息积压的影响
+	MsgPriority msgpb.MessagePriority `protobuf:"varint,26,opt,name=msg_priority,json=msgPriority,enum=msgpb.MessagePriority" json:"msg_priority,omitempty"`
+	// 消息折叠key，离线存储时同一key只保留最新的一条
+	MsgCollapseKey string `protobuf:"bytes,27,opt,name=msg_collapse_key,json=msgCollapseKey" json:"msg_collapse_key,omitempty"`
 	// 保留给一些特殊业务使用的项目
 	Reserve *google_protobuf1.Any `protobuf:"bytes,88,opt,name=reserve" json:"reserve,omitempty"`
 }
@@ -128,6 +137,34 @@ func (m *PushRequest) GetExclusiveMsgOptions() map[string]msgpb.MessageOption {
 	return nil
 }
 
+func (m *PushRequest) GetMsgTtl() *google_protobuf2.Duration {
+	if m != nil {
+		return m.MsgTtl
+	}
+	return nil
+}
+
+func (m *PushRequest) GetMsgCategory() string {
+	if m != nil {
+		return m.MsgCategory
+	}
+	return ""
+}
+
+func (m *PushRequest) GetMsgPriority() msgpb.MessagePriority {
+	if m != nil {
+		return m.MsgPriority
+	}
+	return msgpb.MessagePriority_NORMAL
+}
+
+func (m *PushRequest) GetMsgCollapseKey() string {
+	if m != nil {
+		return m.MsgCollapseKey
+	}
+	return ""
+}
+
 func (m *PushRequest) GetReserve() *google_protobuf1.Any {
 	if m != nil {
 		return m.Reserve
@@ -215,36 +252,42 @@ var _Push_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("github.com/molon/gomsg/pb/pushpb/push.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 487 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x93, 0xd1, 0x6e, 0xd3, 0x30,
-	0x14, 0x86, 0x95, 0x76, 0x1d, 0xea, 0x09, 0xea, 0x8a, 0xb7, 0x75, 0x59, 0xe8, 0x45, 0x94, 0xab,
-	0x68, 0x9b, 0x1c, 0xd4, 0x09, 0x09, 0xed, 0x06, 0x31, 0xd4, 0xcb, 0x8a, 0x29, 0x57, 0x08, 0x90,
-	0x4a, 0xd2, 0xb9, 0x6e, 0x44, 0x12, 0x9b, 0xd8, 0x2e, 0xf4, 0x96, 0x27, 0x40, 0xe2, 0xd1, 0x78,
-	0x05, 0x1e, 0x04, 0xc5, 0x49, 0x96, 0x75, 0x6b, 0xbb, 0xab, 0x58, 0xe7, 0xff, 0xfd, 0xe5, 0x9c,
-	0xff, 0xc8, 0x70, 0x4e, 0x63, 0xb9, 0x50, 0x11, 0x9e, 0xb1, 0xd4, 0x4f, 0x59, 0xc2, 0x32, 0x9f,
-	0xb2, 0x54, 0x50, 0x9f, 0x47, 0x3e, 0x57, 0x62, 0x51, 0x7d, 0x30, 0xcf, 0x99, 0x64, 0x68, 0xbf,
-	0x2c, 0xd9, 0x2f, 0x29, 0x63, 0x34, 0x21, 0xbe, 0xae, 0x46, 0x6a, 0xee, 0x93, 0x94, 0xcb, 0x55,
-	0x69, 0xb2, 0x4f, 0x1f, 0x8a, 0x61, 0x56, 0x4b, 0xc3, 0x4a, 0x0a, 0x79, 0xec, 0x87, 0x59, 0xc6,
-	0x64, 0x28, 0x63, 0x96, 0x89, 0x4a, 0x3d, 0x48, 0x05, 0xe5, 0x91, 0x9f, 0x0a, 0x5a, 0x16, 0xdc,
-	0xcf, 0xd0, 0xbb, 0x49, 0x42, 0x39, 0x67, 0x79, 0xfa, 0x9e, 0x65, 0xf3, 0x98, 0xa2, 0x21, 0x74,
-	0x79, 0x55, 0x11, 0x96, 0xe1, 0xb4, 0xbd, 0x6e, 0xd0, 0x14, 0xd0, 0x39, 0xbc, 0xf8, 0x11, 0xcb,
-	0x05, 0x53, 0x72, 0xda, 0xb8, 0x5a, 0xda, 0xd5, 0xaf, 0x84, 0x9a, 0x27, 0xdc, 0xdf, 0x1d, 0x30,
-	0x6f, 0x94, 0x58, 0x04, 0xe4, 0xbb, 0x22, 0x42, 0x22, 0x04, 0x7b, 0x2a, 0xbe, 0xad, 0xa9, 0xfa,
-	0x8c, 0xde, 0xc2, 0x41, 0x0d, 0x9a, 0xce, 0x74, 0x07, 0x96, 0xe9, 0x18, 0x9e, 0x39, 0x1a, 0xe0,
-	0x32, 0x09, 0xbc, 0xde, 0x5f, 0xd0, 0xe3, 0xeb, 0xfd, 0x26, 0x70, 0x4a, 0x7e, 0xce, 0x12, 0x25,
-	0xe2, 0x25, 0x99, 0x3e, 0x44, 0x3d, 0x77, 0xda, 0x9e, 0x39, 0x7a, 0x75, 0x87, 0x6a, 0x9a, 0xc1,
-	0xe3, 0xfa, 0xd2, 0x3a, 0x7f, 0x9c, 0xc9, 0x7c, 0x15, 0x9c, 0x90, 0xcd, 0x2a, 0xba, 0x04, 0x48,
-	0x05, 0x9d, 0x46, 0xec, 0x36, 0x26, 0xc2, 0x3a, 0xd6, 0xf8, 0x23, 0x5c, 0x66, 0x8e, 0xeb, 0x75,
-	0xe0, 0x77, 0xd9, 0x2a, 0xe8, 0xa6, 0x82, 0x5e, 0x6b, 0x1b, 0x7a, 0x0d, 0x66, 0x71, 0x89, 0x71,
-	0xbd, 0x0a, 0x6b, 0xe0, 0x18, 0x5e, 0x6f, 0x74, 0x84, 0xf5, 0x2e, 0xf0, 0x84, 0x08, 0x11, 0x52,
-	0xf2, 0x41, 0x8b, 0x41, 0x41, 0x2f, 0x8f, 0x02, 0x7d, 0x85, 0xe3, 0x66, 0xb2, 0xfb, 0x80, 0x13,
-	0xfd, 0xdb, 0x8b, 0x9d, 0x53, 0x4d, 0xee, 0x38, 0xe5, 0x44, 0x87, 0xe4, 0xb1, 0x82, 0x30, 0x3c,
-	0xcb, 0x89, 0x20, 0xf9, 0x92, 0x58, 0x1f, 0x75, 0xe8, 0x9b, 0x47, 0xa9, 0x4d, 0x76, 0x04, 0xc3,
-	0x5d, 0xb1, 0xa1, 0x3e, 0xb4, 0xbf, 0x91, 0x95, 0x65, 0x38, 0x86, 0xd7, 0x0d, 0x8a, 0x23, 0xba,
-	0x80, 0xce, 0x32, 0x4c, 0x14, 0xb1, 0x5a, 0x3b, 0x97, 0x5a, 0x9a, 0xae, 0x5a, 0x6f, 0x0c, 0xfb,
-	0x0b, 0x58, 0xdb, 0x86, 0xd8, 0xc0, 0x3f, 0xbb, 0xcf, 0xdf, 0x16, 0x6a, 0x43, 0x1f, 0x4d, 0x60,
-	0xaf, 0x88, 0x0b, 0x8d, 0xab, 0xef, 0xe1, 0x86, 0x10, 0xed, 0xc1, 0xa3, 0x14, 0xc6, 0xc5, 0xe3,
-	0x73, 0xfb, 0xbf, 0xfe, 0xfe, 0xfb, 0xd3, 0x02, 0xb7, 0xa3, 0x1f, 0xec, 0x95, 0x71, 0x76, 0xed,
-	0x7e, 0x72, 0x9e, 0x7a, 0xdc, 0xd1, 0xbe, 0xa6, 0x5c, 0xfe, 0x0f, 0x00, 0x00, 0xff, 0xff, 0xaa,
-	0x6f, 0x1e, 0x9c, 0x07, 0x04, 0x00, 0x00,
+	// 581 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x94, 0xcb, 0x6e, 0xd3, 0x4c,
+	0x14, 0xc7, 0xe5, 0xb4, 0x4d, 0x95, 0x93, 0x2a, 0xcd, 0x37, 0x6d, 0xd3, 0x89, 0x1b, 0x7d, 0x32,
+	0x59, 0x59, 0x6d, 0x65, 0xa3, 0x54, 0x48, 0xd0, 0x0d, 0xa2, 0x25, 0x2b, 0x14, 0x11, 0x59, 0x2c,
+	0x10, 0x20, 0x05, 0x3b, 0x99, 0x4c, 0x2c, 0x6c, 0x8f, 0xf1, 0x8c, 0x03, 0xde, 0xf2, 0x0a, 0xbc,
+	0x16, 0x3b, 0x5e, 0x81, 0x07, 0x41, 0x1e, 0x8f, 0x9b, 0xe6, 0xca, 0xca, 0xe3, 0xf3, 0x3f, 0xf3,
+	0x3b, 0x57, 0x0d, 0x5c, 0x51, 0x5f, 0xcc, 0x52, 0xcf, 0x1a, 0xb3, 0xd0, 0x0e, 0x59, 0xc0, 0x22,
+	0x9b, 0xb2, 0x90, 0x53, 0x3b, 0xf6, 0xec, 0x38, 0xe5, 0x33, 0xf5, 0xb1, 0xe2, 0x84, 0x09, 0x86,
+	0xaa, 0x85, 0x49, 0xbf, 0xa0, 0x8c, 0xd1, 0x80, 0xd8, 0xd2, 0xea, 0xa5, 0x53, 0x9b, 0x84, 0xb1,
+	0xc8, 0x0a, 0x27, 0xbd, 0xbd, 0x2a, 0xba, 0x51, 0x29, 0xfd, 0xbf, 0x2a, 0x4d, 0xd2, 0xc4, 0x15,
+	0x3e, 0x8b, 0x94, 0xde, 0x51, 0xba, 0x1b, 0xfb, 0xb6, 0x1b, 0x45, 0x4c, 0x48, 0x91, 0x2b, 0xf5,
+	0x38, 0xe4, 0x34, 0xf6, 0xec, 0x90, 0xd3, 0xc2, 0xd0, 0xfd, 0x08, 0x8d, 0x61, 0xe0, 0x8a, 0x29,
+	0x4b, 0xc2, 0x7b, 0x16, 0x4d, 0x7d, 0x8a, 0x3a, 0x50, 0x8b, 0x95, 0x85, 0x63, 0xcd, 0xd8, 0x33,
+	0x6b, 0xce, 0xc2, 0x80, 0xae, 0xe0, 0xbf, 0x6f, 0xbe, 0x98, 0xb1, 0x54, 0x8c, 0x16, 0x5e, 0x15,
+	0xe9, 0xd5, 0x54, 0x42, 0xc9, 0xe3, 0xdd, 0x5f, 0x55, 0xa8, 0x0f, 0x53, 0x3e, 0x73, 0xc8, 0xd7,
+	0x94, 0x70, 0x81, 0x10, 0xec, 0xa7, 0xfe, 0xa4, 0xa4, 0xca, 0x33, 0x7a, 0x09, 0xc7, 0x25, 0x68,
+	0x34, 0x96, 0x19, 0xe0, 0xba, 0xa1, 0x99, 0xf5, 0x5e, 0xcb, 0x2a, 0x3a, 0x65, 0x2d, 0xe7, 0xe7,
+	0x34, 0xe2, 0xe5, 0x7c, 0x03, 0x68, 0x93, 0xef, 0xe3, 0x20, 0xe5, 0xfe, 0x9c, 0x8c, 0x56, 0x51,
+	0x47, 0xc6, 0x9e, 0x59, 0xef, 0x3d, 0x7d, 0x40, 0x2d, 0x92, 0xb1, 0xfa, 0xe5, 0xa5, 0x65, 0x7e,
+	0x3f, 0x12, 0x49, 0xe6, 0x9c, 0x93, 0xcd, 0x2a, 0xba, 0x01, 0x08, 0x39, 0x1d, 0x79, 0x6c, 0xe2,
+	0x13, 0x8e, 0xcf, 0x24, 0xfe, 0xd4, 0x2a, 0x7a, 0x6e, 0x95, 0x33, 0xb1, 0x5e, 0x45, 0x99, 0x53,
+	0x0b, 0x39, 0xbd, 0x93, 0x6e, 0xe8, 0x19, 0xd4, 0xf3, 0x4b, 0x2c, 0x96, 0xa3, 0xc0, 0x2d, 0x43,
+	0x33, 0x1b, 0xbd, 0x53, 0x4b, 0xce, 0xc2, 0x1a, 0x10, 0xce, 0x5d, 0x4a, 0xde, 0x4a, 0xd1, 0xc9,
+	0xe9, 0xc5, 0x91, 0xa3, 0xcf, 0x70, 0xb6, 0xa8, 0xec, 0x31, 0xe0, 0x5c, 0x86, 0xbd, 0xde, 0x59,
+	0xd5, 0xe0, 0x81, 0x53, 0x54, 0x74, 0x42, 0xd6, 0x15, 0xd4, 0x83, 0xc3, 0x9c, 0x2b, 0x44, 0x80,
+	0xb1, 0x6c, 0x7a, 0x7b, 0xad, 0x94, 0xd7, 0x6a, 0xbd, 0x9c, 0x6a, 0xc8, 0xe9, 0x3b, 0x11, 0xa0,
+	0x27, 0x70, 0x94, 0xdf, 0x19, 0xbb, 0x82, 0x50, 0x96, 0x64, 0xb8, 0x6d, 0x68, 0x66, 0xcd, 0xc9,
+	0x0b, 0xbc, 0x57, 0x26, 0xf4, 0xa2, 0x70, 0x89, 0x13, 0x9f, 0x25, 0xbe, 0xc8, 0xb0, 0x2e, 0x0b,
+	0x6e, 0x2d, 0x17, 0x3c, 0x54, 0xaa, 0xbc, 0x5a, 0xfe, 0x20, 0x13, 0x9a, 0x92, 0xce, 0x82, 0xc0,
+	0x8d, 0x39, 0x19, 0x7d, 0x21, 0x19, 0xbe, 0x90, 0x11, 0x1a, 0x79, 0x04, 0x65, 0x7e, 0x43, 0x32,
+	0x64, 0xc1, 0x61, 0x42, 0x38, 0x49, 0xe6, 0x04, 0xbf, 0x37, 0xb4, 0xad, 0x63, 0x28, 0x9d, 0x74,
+	0x0f, 0x3a, 0xbb, 0x46, 0x8e, 0x9a, 0xb0, 0x97, 0x07, 0xd3, 0x64, 0xb0, 0xfc, 0x88, 0xae, 0xe1,
+	0x60, 0xee, 0x06, 0x29, 0xc1, 0x95, 0x9d, 0x0b, 0x59, 0x38, 0xdd, 0x56, 0x9e, 0x6b, 0xfa, 0x27,
+	0xc0, 0xdb, 0x06, 0xb0, 0x81, 0x7f, 0xf9, 0x98, 0xbf, 0x6d, 0x21, 0x16, 0xf4, 0xde, 0x00, 0xf6,
+	0xf3, 0x51, 0xa3, 0xbe, 0xfa, 0x9e, 0x6c, 0x58, 0x00, 0xbd, 0xb5, 0xd6, 0x85, 0x7e, 0xfe, 0xb0,
+	0x74, 0x9b, 0x3f, 0x7e, 0xff, 0xf9, 0x59, 0x81, 0xee, 0x81, 0x7c, 0x8c, 0x6e, 0xb5, 0xcb, 0xbb,
+	0xee, 0x07, 0xe3, 0x5f, 0x0f, 0x97, 0x57, 0x95, 0x94, 0x9b, 0xbf, 0x03, 0x00, 0x74, 0x4e, 0x90,
+	0xfc, 0xe3, 0x04, 0x00, 0x00,
 }
@@ -0,0 +1,568 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: github.com/molon/gomsg/pb/querypb/query.proto
+
+/*
+Package querypb is a generated protocol buffer package.
+
+It is generated from these files:
+	github.com/molon/gomsg/pb/querypb/query.proto
+
+It has these top-level messages:
+	UnreadRequest
+	PlatformUnread
+	UnreadResponse
+	SessionsRequest
+	SessionMetadata
+	Session
+	SessionsResponse
+	AuditRequest
+	AuditEvent
+	AuditResponse
+*/
+package querypb
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import _ "google.golang.org/genproto/googleapis/api/annotations"
+import google_protobuf1 "github.com/golang/protobuf/ptypes/timestamp"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type UnreadRequest struct {
+	Uid string `protobuf:"bytes,1,opt,name=uid" json:"uid,omitempty"`
+	// 为空则统计所有平台
+	Platforms []string `protobuf:"bytes,2,rep,name=platforms" json:"platforms,omitempty"`
+	// 是否按消息的category分组，需要读取消息内容，开销较大
+	ByCategory bool `protobuf:"varint,3,opt,name=by_category,json=byCategory" json:"by_category,omitempty"`
+}
+
+func (m *UnreadRequest) Reset()                    { *m = UnreadRequest{} }
+func (m *UnreadRequest) String() string            { return proto.CompactTextString(m) }
+func (*UnreadRequest) ProtoMessage()               {}
+func (*UnreadRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *UnreadRequest) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *UnreadRequest) GetPlatforms() []string {
+	if m != nil {
+		return m.Platforms
+	}
+	return nil
+}
+
+func (m *UnreadRequest) GetByCategory() bool {
+	if m != nil {
+		return m.ByCategory
+	}
+	return false
+}
+
+type PlatformUnread struct {
+	Count int64 `protobuf:"varint,1,opt,name=count" json:"count,omitempty"`
+	// by_category时才有，未设置category的消息其key为空字符串
+	Categories map[string]int64 `protobuf:"bytes,2,rep,name=categories" json:"categories,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+}
+
+func (m *PlatformUnread) Reset()                    { *m = PlatformUnread{} }
+func (m *PlatformUnread) String() string            { return proto.CompactTextString(m) }
+func (*PlatformUnread) ProtoMessage()               {}
+func (*PlatformUnread) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *PlatformUnread) GetCount() int64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *PlatformUnread) GetCategories() map[string]int64 {
+	if m != nil {
+		return m.Categories
+	}
+	return nil
+}
+
+type UnreadResponse struct {
+	// key为平台
+	Platforms map[string]*PlatformUnread `protobuf:"bytes,1,rep,name=platforms" json:"platforms,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (m *UnreadResponse) Reset()                    { *m = UnreadResponse{} }
+func (m *UnreadResponse) String() string            { return proto.CompactTextString(m) }
+func (*UnreadResponse) ProtoMessage()               {}
+func (*UnreadResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *UnreadResponse) GetPlatforms() map[string]*PlatformUnread {
+	if m != nil {
+		return m.Platforms
+	}
+	return nil
+}
+
+type SessionsRequest struct {
+	Uid string `protobuf:"bytes,1,opt,name=uid" json:"uid,omitempty"`
+}
+
+func (m *SessionsRequest) Reset()                    { *m = SessionsRequest{} }
+func (m *SessionsRequest) String() string            { return proto.CompactTextString(m) }
+func (*SessionsRequest) ProtoMessage()               {}
+func (*SessionsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *SessionsRequest) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+// 会话的附加信息，连接建立时记录
+type SessionMetadata struct {
+	ConnectedAt   *google_protobuf1.Timestamp `protobuf:"bytes,1,opt,name=connected_at,json=connectedAt" json:"connected_at,omitempty"`
+	ClientVersion string                      `protobuf:"bytes,2,opt,name=client_version,json=clientVersion" json:"client_version,omitempty"`
+	DeviceId      string                      `protobuf:"bytes,3,opt,name=device_id,json=deviceId" json:"device_id,omitempty"`
+	RemoteIp      string                      `protobuf:"bytes,4,opt,name=remote_ip,json=remoteIp" json:"remote_ip,omitempty"`
+	UserAgent     string                      `protobuf:"bytes,5,opt,name=user_agent,json=userAgent" json:"user_agent,omitempty"`
+	// 鉴权服务反馈的其他信息
+	Claims map[string]string `protobuf:"bytes,6,rep,name=claims" json:"claims,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+}
+
+func (m *SessionMetadata) Reset()                    { *m = SessionMetadata{} }
+func (m *SessionMetadata) String() string            { return proto.CompactTextString(m) }
+func (*SessionMetadata) ProtoMessage()               {}
+func (*SessionMetadata) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *SessionMetadata) GetConnectedAt() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.ConnectedAt
+	}
+	return nil
+}
+
+func (m *SessionMetadata) GetClientVersion() string {
+	if m != nil {
+		return m.ClientVersion
+	}
+	return ""
+}
+
+func (m *SessionMetadata) GetDeviceId() string {
+	if m != nil {
+		return m.DeviceId
+	}
+	return ""
+}
+
+func (m *SessionMetadata) GetRemoteIp() string {
+	if m != nil {
+		return m.RemoteIp
+	}
+	return ""
+}
+
+func (m *SessionMetadata) GetUserAgent() string {
+	if m != nil {
+		return m.UserAgent
+	}
+	return ""
+}
+
+func (m *SessionMetadata) GetClaims() map[string]string {
+	if m != nil {
+		return m.Claims
+	}
+	return nil
+}
+
+type Session struct {
+	Sid      string `protobuf:"bytes,1,opt,name=sid" json:"sid,omitempty"`
+	Platform string `protobuf:"bytes,2,opt,name=platform" json:"platform,omitempty"`
+	// 所在boat服务ID
+	BoatId string `protobuf:"bytes,3,opt,name=boat_id,json=boatId" json:"boat_id,omitempty"`
+	// 没有记录则为空
+	Metadata *SessionMetadata `protobuf:"bytes,4,opt,name=metadata" json:"metadata,omitempty"`
+}
+
+func (m *Session) Reset()                    { *m = Session{} }
+func (m *Session) String() string            { return proto.CompactTextString(m) }
+func (*Session) ProtoMessage()               {}
+func (*Session) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *Session) GetSid() string {
+	if m != nil {
+		return m.Sid
+	}
+	return ""
+}
+
+func (m *Session) GetPlatform() string {
+	if m != nil {
+		return m.Platform
+	}
+	return ""
+}
+
+func (m *Session) GetBoatId() string {
+	if m != nil {
+		return m.BoatId
+	}
+	return ""
+}
+
+func (m *Session) GetMetadata() *SessionMetadata {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+type SessionsResponse struct {
+	// 按建立时间倒序
+	Sessions []*Session `protobuf:"bytes,1,rep,name=sessions" json:"sessions,omitempty"`
+}
+
+func (m *SessionsResponse) Reset()                    { *m = SessionsResponse{} }
+func (m *SessionsResponse) String() string            { return proto.CompactTextString(m) }
+func (*SessionsResponse) ProtoMessage()               {}
+func (*SessionsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *SessionsResponse) GetSessions() []*Session {
+	if m != nil {
+		return m.Sessions
+	}
+	return nil
+}
+
+type AuditRequest struct {
+	Uid string `protobuf:"bytes,1,opt,name=uid" json:"uid,omitempty"`
+	// 消息的seq
+	Seq string `protobuf:"bytes,2,opt,name=seq" json:"seq,omitempty"`
+}
+
+func (m *AuditRequest) Reset()                    { *m = AuditRequest{} }
+func (m *AuditRequest) String() string            { return proto.CompactTextString(m) }
+func (*AuditRequest) ProtoMessage()               {}
+func (*AuditRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *AuditRequest) GetUid() string {
+	if m != nil {
+		return m.Uid
+	}
+	return ""
+}
+
+func (m *AuditRequest) GetSeq() string {
+	if m != nil {
+		return m.Seq
+	}
+	return ""
+}
+
+// 消息生命周期中的一个事件
+type AuditEvent struct {
+	Timestamp *google_protobuf1.Timestamp `protobuf:"bytes,1,opt,name=timestamp" json:"timestamp,omitempty"`
+	// accepted/publish_failed/consumed/pushed/acked/push_failed/stored_offline/evicted/replayed/expired/dead_letter
+	Stage string `protobuf:"bytes,2,opt,name=stage" json:"stage,omitempty"`
+	// 与平台无关的阶段为空
+	Platform string `protobuf:"bytes,3,opt,name=platform" json:"platform,omitempty"`
+	// 推送相关的阶段才有
+	Sid    string `protobuf:"bytes,4,opt,name=sid" json:"sid,omitempty"`
+	BoatId string `protobuf:"bytes,5,opt,name=boat_id,json=boatId" json:"boat_id,omitempty"`
+	// 附加说明，如topic、重试次数、失败原因
+	Detail string `protobuf:"bytes,6,opt,name=detail" json:"detail,omitempty"`
+}
+
+func (m *AuditEvent) Reset()                    { *m = AuditEvent{} }
+func (m *AuditEvent) String() string            { return proto.CompactTextString(m) }
+func (*AuditEvent) ProtoMessage()               {}
+func (*AuditEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *AuditEvent) GetTimestamp() *google_protobuf1.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+func (m *AuditEvent) GetStage() string {
+	if m != nil {
+		return m.Stage
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetPlatform() string {
+	if m != nil {
+		return m.Platform
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetSid() string {
+	if m != nil {
+		return m.Sid
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetBoatId() string {
+	if m != nil {
+		return m.BoatId
+	}
+	return ""
+}
+
+func (m *AuditEvent) GetDetail() string {
+	if m != nil {
+		return m.Detail
+	}
+	return ""
+}
+
+type AuditResponse struct {
+	// 按记录顺序，超出保留时长或者未开启审计时为空
+	Events []*AuditEvent `protobuf:"bytes,1,rep,name=events" json:"events,omitempty"`
+}
+
+func (m *AuditResponse) Reset()                    { *m = AuditResponse{} }
+func (m *AuditResponse) String() string            { return proto.CompactTextString(m) }
+func (*AuditResponse) ProtoMessage()               {}
+func (*AuditResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *AuditResponse) GetEvents() []*AuditEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*UnreadRequest)(nil), "querypb.UnreadRequest")
+	proto.RegisterType((*PlatformUnread)(nil), "querypb.PlatformUnread")
+	proto.RegisterType((*UnreadResponse)(nil), "querypb.UnreadResponse")
+	proto.RegisterType((*SessionsRequest)(nil), "querypb.SessionsRequest")
+	proto.RegisterType((*SessionMetadata)(nil), "querypb.SessionMetadata")
+	proto.RegisterType((*Session)(nil), "querypb.Session")
+	proto.RegisterType((*SessionsResponse)(nil), "querypb.SessionsResponse")
+	proto.RegisterType((*AuditRequest)(nil), "querypb.AuditRequest")
+	proto.RegisterType((*AuditEvent)(nil), "querypb.AuditEvent")
+	proto.RegisterType((*AuditResponse)(nil), "querypb.AuditResponse")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for Query service
+
+type QueryClient interface {
+	// 未读(未投递的离线)消息数目，可用于角标和收件箱计数
+	Unread(ctx context.Context, in *UnreadRequest, opts ...grpc.CallOption) (*UnreadResponse, error)
+	// 用户当前的会话，包括附加信息，供排查和统计使用
+	Sessions(ctx context.Context, in *SessionsRequest, opts ...grpc.CallOption) (*SessionsResponse, error)
+	// 某用户某消息的审计轨迹，排查用户声称没收到消息时使用，需开启audit
+	Audit(ctx context.Context, in *AuditRequest, opts ...grpc.CallOption) (*AuditResponse, error)
+}
+
+type queryClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewQueryClient(cc *grpc.ClientConn) QueryClient {
+	return &queryClient{cc}
+}
+
+func (c *queryClient) Unread(ctx context.Context, in *UnreadRequest, opts ...grpc.CallOption) (*UnreadResponse, error) {
+	out := new(UnreadResponse)
+	err := grpc.Invoke(ctx, "/querypb.Query/Unread", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) Sessions(ctx context.Context, in *SessionsRequest, opts ...grpc.CallOption) (*SessionsResponse, error) {
+	out := new(SessionsResponse)
+	err := grpc.Invoke(ctx, "/querypb.Query/Sessions", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queryClient) Audit(ctx context.Context, in *AuditRequest, opts ...grpc.CallOption) (*AuditResponse, error) {
+	out := new(AuditResponse)
+	err := grpc.Invoke(ctx, "/querypb.Query/Audit", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Server API for Query service
+
+type QueryServer interface {
+	// 未读(未投递的离线)消息数目，可用于角标和收件箱计数
+	Unread(context.Context, *UnreadRequest) (*UnreadResponse, error)
+	// 用户当前的会话，包括附加信息，供排查和统计使用
+	Sessions(context.Context, *SessionsRequest) (*SessionsResponse, error)
+	// 某用户某消息的审计轨迹，排查用户声称没收到消息时使用，需开启audit
+	Audit(context.Context, *AuditRequest) (*AuditResponse, error)
+}
+
+func RegisterQueryServer(s *grpc.Server, srv QueryServer) {
+	s.RegisterService(&_Query_serviceDesc, srv)
+}
+
+func _Query_Unread_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnreadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).Unread(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/querypb.Query/Unread",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).Unread(ctx, req.(*UnreadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_Sessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).Sessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/querypb.Query/Sessions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).Sessions(ctx, req.(*SessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Query_Audit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuditRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueryServer).Audit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/querypb.Query/Audit",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueryServer).Audit(ctx, req.(*AuditRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Query_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "querypb.Query",
+	HandlerType: (*QueryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Unread",
+			Handler:    _Query_Unread_Handler,
+		},
+		{
+			MethodName: "Sessions",
+			Handler:    _Query_Sessions_Handler,
+		},
+		{
+			MethodName: "Audit",
+			Handler:    _Query_Audit_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "github.com/molon/gomsg/pb/querypb/query.proto",
+}
+
+func init() { proto.RegisterFile("github.com/molon/gomsg/pb/querypb/query.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 780 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x55, 0xdd, 0x6e, 0xe3, 0x44,
+	0x14, 0x96, 0x93, 0x8d, 0x1b, 0x9f, 0x6c, 0x92, 0x32, 0xec, 0x36, 0xc6, 0x2c, 0xda, 0xe2, 0xe5,
+	0xa7, 0x12, 0xd4, 0x96, 0x02, 0x17, 0x05, 0xb5, 0x12, 0xa5, 0x54, 0xa8, 0x17, 0x48, 0xc5, 0x50,
+	0x84, 0xb8, 0x09, 0x13, 0x7b, 0x6a, 0xac, 0xc6, 0x1e, 0xc7, 0x33, 0x8e, 0x14, 0x55, 0xbd, 0xe1,
+	0x02, 0x1e, 0x80, 0xe7, 0x80, 0x17, 0xe0, 0x09, 0xb8, 0xe6, 0x15, 0x78, 0x10, 0x34, 0x3f, 0xb6,
+	0x93, 0xb4, 0x51, 0xf7, 0xaa, 0x39, 0xdf, 0xf9, 0xe6, 0xcc, 0xf9, 0xbe, 0x73, 0x3c, 0x85, 0xc3,
+	0x38, 0xe1, 0xbf, 0x94, 0x53, 0x2f, 0xa4, 0xa9, 0x9f, 0xd2, 0x19, 0xcd, 0xfc, 0x98, 0xa6, 0x2c,
+	0xf6, 0xf3, 0xa9, 0x3f, 0x2f, 0x49, 0xb1, 0xac, 0xfe, 0x7a, 0x79, 0x41, 0x39, 0x45, 0x3b, 0x1a,
+	0x74, 0x5e, 0xc4, 0x94, 0xc6, 0x33, 0xe2, 0xe3, 0x3c, 0xf1, 0x71, 0x96, 0x51, 0x8e, 0x79, 0x42,
+	0x33, 0xa6, 0x68, 0xce, 0x4b, 0x9d, 0x95, 0xd1, 0xb4, 0xbc, 0xf6, 0x79, 0x92, 0x12, 0xc6, 0x71,
+	0x9a, 0x2b, 0x82, 0xfb, 0x33, 0xf4, 0xaf, 0xb2, 0x82, 0xe0, 0x28, 0x20, 0xf3, 0x92, 0x30, 0x8e,
+	0x76, 0xa1, 0x5d, 0x26, 0x91, 0x6d, 0xec, 0x1b, 0x07, 0x56, 0x20, 0x7e, 0xa2, 0x17, 0x60, 0xe5,
+	0x33, 0xcc, 0xaf, 0x69, 0x91, 0x32, 0xbb, 0xb5, 0xdf, 0x3e, 0xb0, 0x82, 0x06, 0x40, 0x2f, 0xa1,
+	0x37, 0x5d, 0x4e, 0x42, 0xcc, 0x49, 0x4c, 0x8b, 0xa5, 0xdd, 0xde, 0x37, 0x0e, 0xba, 0x01, 0x4c,
+	0x97, 0x67, 0x1a, 0x71, 0xff, 0x32, 0x60, 0x70, 0xa9, 0xe9, 0xea, 0x2a, 0xf4, 0x0c, 0x3a, 0x21,
+	0x2d, 0x33, 0x2e, 0x6f, 0x69, 0x07, 0x2a, 0x40, 0x5f, 0x03, 0xe8, 0x32, 0x09, 0x51, 0x17, 0xf5,
+	0xc6, 0x1f, 0x7a, 0x5a, 0xa7, 0xb7, 0x5e, 0xc2, 0x3b, 0xab, 0x99, 0xe7, 0x19, 0x2f, 0x96, 0xc1,
+	0xca, 0x51, 0xe7, 0x04, 0x86, 0x1b, 0x69, 0xa1, 0xea, 0x86, 0x2c, 0x2b, 0x55, 0x37, 0x64, 0x29,
+	0x7a, 0x58, 0xe0, 0x59, 0x49, 0xec, 0x96, 0xea, 0x41, 0x06, 0x9f, 0xb7, 0x8e, 0x0c, 0xf7, 0x4f,
+	0x03, 0x06, 0x95, 0x27, 0x2c, 0xa7, 0x19, 0x23, 0xe8, 0xab, 0x55, 0x0b, 0x0c, 0xd9, 0xd9, 0x07,
+	0x75, 0x67, 0xeb, 0xdc, 0xba, 0x51, 0xdd, 0x58, 0x73, 0xd0, 0xb9, 0x82, 0xc1, 0x7a, 0xf2, 0x81,
+	0xb6, 0x0e, 0x57, 0xdb, 0xea, 0x8d, 0x47, 0x5b, 0xf4, 0xaf, 0xf6, 0xfb, 0x0a, 0x86, 0xdf, 0x11,
+	0xc6, 0xc4, 0xd4, 0xb7, 0x0e, 0xd1, 0xfd, 0xa7, 0x55, 0xb3, 0xbe, 0x21, 0x1c, 0x47, 0x98, 0x63,
+	0x74, 0x02, 0x4f, 0x43, 0x9a, 0x65, 0x24, 0xe4, 0x24, 0x9a, 0x60, 0x35, 0x8d, 0xde, 0xd8, 0xf1,
+	0xd4, 0xce, 0x78, 0xd5, 0xce, 0x78, 0xdf, 0x57, 0x3b, 0x13, 0xf4, 0x6a, 0xfe, 0x29, 0x47, 0xef,
+	0xc3, 0x20, 0x9c, 0x25, 0x24, 0xe3, 0x93, 0x05, 0x29, 0x44, 0x61, 0xd9, 0xb3, 0x15, 0xf4, 0x15,
+	0xfa, 0x83, 0x02, 0xd1, 0xdb, 0x60, 0x45, 0x64, 0x91, 0x84, 0x64, 0x92, 0x44, 0x72, 0x3d, 0xac,
+	0xa0, 0xab, 0x80, 0x8b, 0x48, 0x24, 0x0b, 0x92, 0x52, 0x4e, 0x26, 0x49, 0x6e, 0x3f, 0x51, 0x49,
+	0x05, 0x5c, 0xe4, 0xe8, 0x1d, 0x80, 0x92, 0x91, 0x62, 0x82, 0x63, 0x92, 0x71, 0xbb, 0x23, 0xb3,
+	0x96, 0x40, 0x4e, 0x05, 0x80, 0x8e, 0xc1, 0x0c, 0x67, 0x38, 0x49, 0x99, 0x6d, 0xca, 0x89, 0xbc,
+	0x57, 0x7b, 0xb5, 0x21, 0xd4, 0x3b, 0x93, 0x34, 0x35, 0x0f, 0x7d, 0xc6, 0xf9, 0x0c, 0x7a, 0x2b,
+	0xf0, 0x63, 0x0b, 0x62, 0xad, 0x1a, 0xfe, 0x9b, 0x01, 0x3b, 0xfa, 0x0a, 0x71, 0x8e, 0x35, 0x4e,
+	0xb3, 0x24, 0x42, 0x0e, 0x74, 0xab, 0x91, 0xeb, 0xa3, 0x75, 0x8c, 0x46, 0xb0, 0x33, 0xa5, 0x98,
+	0x37, 0x4e, 0x98, 0x22, 0xbc, 0x88, 0xd0, 0xa7, 0xd0, 0x4d, 0x75, 0xb7, 0xd2, 0x86, 0xde, 0xd8,
+	0xde, 0xa6, 0x26, 0xa8, 0x99, 0xee, 0x17, 0xb0, 0xdb, 0x4c, 0x5e, 0xaf, 0xea, 0xc7, 0xd0, 0x65,
+	0x1a, 0xd3, 0x9b, 0xba, 0xbb, 0x59, 0x29, 0xa8, 0x19, 0xee, 0x18, 0x9e, 0x9e, 0x96, 0x51, 0xc2,
+	0xb7, 0x7f, 0xfd, 0x42, 0x20, 0x99, 0x6b, 0x25, 0xe2, 0xa7, 0xfb, 0xb7, 0x01, 0x20, 0x0f, 0x9d,
+	0x2f, 0xc4, 0x18, 0x8e, 0xc0, 0xaa, 0x1f, 0x95, 0xd7, 0x58, 0xa1, 0x86, 0x2c, 0x1c, 0x66, 0x1c,
+	0xc7, 0xb5, 0xc3, 0x32, 0x58, 0xf3, 0xaf, 0xbd, 0xe1, 0x9f, 0x76, 0xfb, 0x49, 0xe3, 0xf6, 0x8a,
+	0xa3, 0x9d, 0x35, 0x47, 0xf7, 0xc0, 0x8c, 0x08, 0xc7, 0xc9, 0xcc, 0x36, 0x15, 0xae, 0x22, 0xf7,
+	0x18, 0xfa, 0x5a, 0xb1, 0x36, 0xec, 0x23, 0x30, 0x89, 0x10, 0x52, 0xd9, 0xf5, 0x66, 0x6d, 0x57,
+	0x23, 0x32, 0xd0, 0x94, 0xf1, 0xef, 0x2d, 0xe8, 0x7c, 0x2b, 0xd2, 0xe8, 0x12, 0x4c, 0xfd, 0x9a,
+	0xed, 0xdd, 0x7b, 0x09, 0xa4, 0x97, 0xce, 0x68, 0xcb, 0x0b, 0xe1, 0x3e, 0xff, 0xf5, 0xdf, 0xff,
+	0xfe, 0x68, 0x0d, 0x51, 0xdf, 0x2f, 0x65, 0xc2, 0xbf, 0x2d, 0x93, 0xe8, 0x0e, 0xfd, 0x08, 0xdd,
+	0x6a, 0x9a, 0xe8, 0xde, 0xf4, 0xab, 0x4f, 0xdb, 0x79, 0xeb, 0x81, 0x8c, 0xae, 0x3b, 0x92, 0x75,
+	0xdf, 0x40, 0x43, 0xbf, 0x9a, 0xaf, 0xae, 0x1c, 0x40, 0x47, 0x6a, 0x41, 0xcf, 0xd7, 0xb5, 0x55,
+	0x35, 0xf7, 0x36, 0x61, 0x5d, 0xd0, 0x91, 0x05, 0x9f, 0x21, 0xe4, 0x63, 0x81, 0xab, 0x6a, 0xfe,
+	0x2d, 0x23, 0xf3, 0xbb, 0x2f, 0x5f, 0xfd, 0xf4, 0xee, 0xa3, 0xff, 0xb1, 0xa6, 0xa6, 0x5c, 0x80,
+	0x4f, 0xfe, 0x1f, 0x00, 0xd0, 0x69, 0xcf, 0xb5, 0xdd, 0x06, 0x00, 0x00,
+}